@@ -13,6 +13,8 @@ import (
 
 	"ClosedWheeler/pkg/agent"
 	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/sandbox"
+	"ClosedWheeler/pkg/secrets"
 	"ClosedWheeler/pkg/tui"
 
 	"github.com/charmbracelet/lipgloss"
@@ -30,9 +32,18 @@ var (
 )
 
 func main() {
+	// Sandboxed commands start this binary as their init; it does not return
+	sandbox.Init()
+
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCommand(os.Args[2:]))
+	}
+
 	// Flags
 	configPath := flag.String("config", "", "Path to configuration file")
 	projectPath := flag.String("project", ".", "Path to project to analyze")
+	resumeID := flag.String("resume", "", "Resume a saved session by ID (or \"last\")")
 	showVersion := flag.Bool("version", false, "Show version")
 	showHelp := flag.Bool("help", false, "Show help")
 	flag.Parse()
@@ -54,7 +65,7 @@ func main() {
 	}
 
 	// Check API key
-	if cfg.APIKey == "" {
+	if cfg.APIKey == "" && cfg.RequiresAPIKey() {
 		fmt.Println("⚡ Welcome to ClosedWheelerAGI!")
 		fmt.Println("   First time setup detected.")
 		fmt.Println()
@@ -77,7 +88,7 @@ func main() {
 		}
 
 		// Re-verify after setup
-		if cfg.APIKey == "" {
+		if cfg.APIKey == "" && cfg.RequiresAPIKey() {
 			fmt.Println("❌ Configuration incomplete. Exiting.")
 			os.Exit(1)
 		}
@@ -115,6 +126,15 @@ func main() {
 		log.Fatalf("❌ Failed to create agent: %v", err)
 	}
 
+	// Resume a saved conversation session if requested
+	if *resumeID != "" {
+		sess, err := ag.ResumeSession(*resumeID)
+		if err != nil {
+			log.Fatalf("❌ Failed to resume session: %v", err)
+		}
+		fmt.Printf("💾 Resumed session %s (%d messages)\n", sess.ID, len(sess.Messages))
+	}
+
 	// Context for graceful shutdown — cancelling it forces bubbletea to exit
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -142,36 +162,8 @@ func main() {
 	// Redirect Go's standard log package to file BEFORE TUI starts.
 	// Any log.Printf from llm, tools, browser, recovery, etc. will go to this file
 	// instead of corrupting the Bubble Tea alternate screen.
-	logDir := filepath.Join(appRoot, ".agi")
-	_ = os.MkdirAll(logDir, 0755)
-	debugLogPath := filepath.Join(logDir, "debug.log")
-	if logFile, err := os.OpenFile(debugLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
-		log.SetOutput(logFile)
-		defer logFile.Close()
-
-		// Redirect trpc-agent-go's Zap loggers to the same file.
-		// Without this, trpc-agent-go writes ERROR/WARN/INFO directly to os.Stdout
-		// via Zap, which corrupts the Bubble Tea alternate screen.
-		zapEnc := zapcore.EncoderConfig{
-			TimeKey:        "ts",
-			LevelKey:       "lvl",
-			CallerKey:      "caller",
-			MessageKey:     "msg",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.CapitalLevelEncoder,
-			EncodeTime:     zapcore.RFC3339TimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		}
-		fileCore := zapcore.NewCore(
-			zapcore.NewConsoleEncoder(zapEnc),
-			zapcore.AddSync(logFile),
-			zapcore.WarnLevel,
-		)
-		fileLogger := zap.New(fileCore, zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
-		agentlog.Default = fileLogger
-		agentlog.ContextDefault = fileLogger
-	}
+	restoreLogs := redirectLogs(appRoot)
+	defer restoreLogs()
 
 	// Run Enhanced TUI (passes context so cancel() forces exit even if bubbletea hangs)
 	if err := tui.RunEnhanced(ag, ctx); err != nil {
@@ -205,6 +197,46 @@ func main() {
 	os.Exit(0)
 }
 
+// redirectLogs sends the standard logger and trpc-agent-go's Zap loggers to
+// .agi/debug.log under appRoot. The returned function closes the log file.
+func redirectLogs(appRoot string) func() {
+	logDir := filepath.Join(appRoot, ".agi")
+	_ = os.MkdirAll(logDir, 0755)
+	debugLogPath := filepath.Join(logDir, "debug.log")
+	logFile, err := os.OpenFile(debugLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return func() {}
+	}
+	// Both loggers write through the redactor
+	out := secrets.NewWriter(logFile)
+	log.SetOutput(out)
+
+	// Redirect trpc-agent-go's Zap loggers to the same file.
+	// Without this, trpc-agent-go writes ERROR/WARN/INFO directly to os.Stdout
+	// via Zap, which corrupts the Bubble Tea alternate screen.
+	zapEnc := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "lvl",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.RFC3339TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	fileCore := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(zapEnc),
+		zapcore.AddSync(out),
+		zapcore.WarnLevel,
+	)
+	fileLogger := zap.New(fileCore, zap.AddCaller(), zap.AddCallerSkip(1)).Sugar()
+	agentlog.Default = fileLogger
+	agentlog.ContextDefault = fileLogger
+
+	return func() { logFile.Close() }
+}
+
 func printBanner() {
 	banner := `
   ╔═══════════════════════════════════════════════════════════════╗
@@ -221,12 +253,15 @@ func printBanner() {
 func printHelp() {
 	fmt.Printf("Coder AGI v%s - Intelligent coding assistant\n\n", version)
 	fmt.Println("Usage: ClosedWheeler [options]")
+	fmt.Println("       ClosedWheeler run [run options]   (headless, see 'ClosedWheeler run -help')")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -project string")
 	fmt.Println("        Path to project directory (default: current directory)")
 	fmt.Println("  -config string")
 	fmt.Println("        Path to configuration file")
+	fmt.Println("  -resume string")
+	fmt.Println("        Resume a saved session from .agi/sessions (ID, prefix or \"last\")")
 	fmt.Println("  -version")
 	fmt.Println("        Show version")
 	fmt.Println("  -help")
@@ -241,4 +276,6 @@ func printHelp() {
 	fmt.Println("  ClosedWheeler")
 	fmt.Println("  ClosedWheeler -project /path/to/myproject")
 	fmt.Println("  ClosedWheeler -config ~/.agi/config.json")
+	fmt.Println("  ClosedWheeler run -prompt \"Summarize the TODOs in main.go\"")
+	fmt.Println("  git diff | ClosedWheeler run -json -tools safe")
 }
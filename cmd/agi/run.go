@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"ClosedWheeler/pkg/agent"
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/providers"
+)
+
+// Exit codes returned by the headless "run" subcommand.
+const (
+	exitOK    = 0 // every prompt completed
+	exitError = 1 // agent or LLM failure
+	exitUsage = 2 // bad flags or missing input
+)
+
+// toolTraceEntry records a single tool invocation made while answering a prompt.
+type toolTraceEntry struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Success   bool   `json:"success"`
+	done      bool
+}
+
+// runResult is the JSON document emitted per prompt when --json is set.
+type runResult struct {
+	Prompt     string           `json:"prompt"`
+	Response   string           `json:"response"`
+	Error      string           `json:"error,omitempty"`
+	ToolCalls  []toolTraceEntry `json:"tool_calls"`
+	Usage      map[string]int   `json:"usage"`
+	DurationMs int64            `json:"duration_ms"`
+	Model      string           `json:"model"`
+	SessionID  string           `json:"session_id"`
+}
+
+// toolTracer collects tool lifecycle events from the agent. Non-sensitive tools
+// run in parallel, so completions are matched to the oldest open call by name.
+type toolTracer struct {
+	mu      sync.Mutex
+	entries []toolTraceEntry
+}
+
+func (t *toolTracer) start(name, args string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, toolTraceEntry{Name: name, Arguments: args})
+}
+
+func (t *toolTracer) finish(name, result string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.entries {
+		e := &t.entries[i]
+		if e.done || e.Name != name {
+			continue
+		}
+		e.done = true
+		e.Result = result
+		e.Success = err == nil
+		if err != nil {
+			e.Error = err.Error()
+		}
+		return
+	}
+}
+
+// take returns the collected entries and resets the tracer for the next prompt.
+func (t *toolTracer) take() []toolTraceEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := t.entries
+	t.entries = nil
+	if out == nil {
+		out = []toolTraceEntry{}
+	}
+	return out
+}
+
+// runCommand implements "agi run": it builds the agent without the TUI, sends
+// one or more prompts through Agent.Chat and writes the answers to stdout.
+// It returns the process exit code.
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	projectPath := fs.String("project", ".", "Path to project to analyze")
+	resumeID := fs.String("resume", "", "Resume a saved session by ID (or \"last\")")
+	prompt := fs.String("prompt", "", "Prompt to send (use \"-\" to read from stdin)")
+	promptsFile := fs.String("prompts-file", "", "File with prompts, separated by blank lines")
+	jsonOut := fs.Bool("json", false, "Emit the final answer and tool trace as JSON")
+	noStream := fs.Bool("no-stream", false, "Print only the final answer instead of streaming")
+	keepGoing := fs.Bool("keep-going", false, "Continue with the next prompt after a failure")
+	toolMode := fs.String("tools", "full", "Tool mode: full, safe or none")
+	timeout := fs.Duration("timeout", 0, "Abort a prompt after this duration (0 = no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ClosedWheeler run [options]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Runs the agent without the TUI. Prompts come from --prompt, --prompts-file")
+		fmt.Fprintln(fs.Output(), "or stdin (when neither flag is given and stdin is not a terminal).")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	// Any positional arguments are treated as the prompt text.
+	if *prompt == "" && fs.NArg() > 0 {
+		*prompt = strings.Join(fs.Args(), " ")
+	}
+
+	prompts, err := collectPrompts(*prompt, *promptsFile, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return exitUsage
+	}
+	if len(prompts) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ No prompt given. Use --prompt, --prompts-file or pipe text to stdin.")
+		return exitUsage
+	}
+
+	switch *toolMode {
+	case "full", "safe", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Invalid --tools value %q (want full, safe or none)\n", *toolMode)
+		return exitUsage
+	}
+
+	cfg, _, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load config: %v\n", err)
+		return exitError
+	}
+	if cfg.APIKey == "" && cfg.RequiresAPIKey() {
+		fmt.Fprintln(os.Stderr, "❌ No API key configured. Run ClosedWheeler interactively once or set API_KEY.")
+		return exitError
+	}
+
+	absProjectPath, err := filepath.Abs(*projectPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid project path: %v\n", err)
+		return exitUsage
+	}
+	if _, err := os.Stat(absProjectPath); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "❌ Project path does not exist: %s\n", absProjectPath)
+		return exitUsage
+	}
+
+	appRoot, err := os.Getwd()
+	if err != nil {
+		appRoot = "."
+	}
+
+	// Keep stdout clean for the answer: library logging goes to .agi/debug.log.
+	restoreLogs := redirectLogs(appRoot)
+	defer restoreLogs()
+
+	ag, err := agent.NewAgent(cfg, absProjectPath, appRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create agent: %v\n", err)
+		return exitError
+	}
+	defer ag.Shutdown()
+
+	if *resumeID != "" {
+		if _, err := ag.ResumeSession(*resumeID); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to resume session: %v\n", err)
+			return exitError
+		}
+	}
+
+	// Route requests across providers.json when routing is enabled there
+	if pc, err := providers.LoadProvidersConfig(""); err == nil && pc.Routing != "" {
+		if pm, err := providers.InitializeFromConfig(pc); err == nil {
+			ag.SetProviderRouter(llm.NewProviderRouter(pm, pc.Routing, pc.FallbackEnabled))
+		}
+	}
+
+	ag.SetToolMode(*toolMode)
+
+	tracer := &toolTracer{}
+	var streamed strings.Builder
+	var streamMu sync.Mutex
+	stream := !*jsonOut && !*noStream
+
+	ag.SetToolCallbacks(func(name, args string) {
+		tracer.start(name, args)
+		if !*jsonOut {
+			fmt.Fprintf(os.Stderr, "🔧 %s\n", name)
+		}
+		// Text streamed before a tool call is not the final answer.
+		streamMu.Lock()
+		streamed.Reset()
+		streamMu.Unlock()
+	}, func(name, result string) {
+		tracer.finish(name, result, nil)
+	}, func(name string, err error) {
+		tracer.finish(name, "", err)
+		if !*jsonOut {
+			fmt.Fprintf(os.Stderr, "⚠️  %s failed: %v\n", name, err)
+		}
+	})
+	if stream {
+		ag.SetStreamCallback(func(content string, thinking string, done bool) {
+			if content == "" {
+				return
+			}
+			streamMu.Lock()
+			streamed.WriteString(content)
+			streamMu.Unlock()
+			fmt.Fprint(os.Stdout, content)
+		})
+	}
+
+	// First SIGINT aborts the in-flight request, a second one exits.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		ag.StopCurrentRequest()
+		<-sigCh
+		os.Exit(exitError)
+	}()
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	exitCode := exitOK
+
+	for _, p := range prompts {
+		streamMu.Lock()
+		streamed.Reset()
+		streamMu.Unlock()
+
+		before := ag.GetUsageStats()
+		start := time.Now()
+		response, chatErr := chatWithTimeout(ag, p, *timeout)
+		after := ag.GetUsageStats()
+
+		if *jsonOut {
+			res := runResult{
+				Prompt:     p,
+				Response:   response,
+				ToolCalls:  tracer.take(),
+				DurationMs: time.Since(start).Milliseconds(),
+				Model:      cfg.Model,
+				SessionID:  ag.CurrentSessionID(),
+				Usage: map[string]int{
+					"prompt_tokens":     toInt(after["prompt_tokens"]) - toInt(before["prompt_tokens"]),
+					"completion_tokens": toInt(after["completion_tokens"]) - toInt(before["completion_tokens"]),
+					"total_tokens":      toInt(after["total_tokens"]) - toInt(before["total_tokens"]),
+				},
+			}
+			if chatErr != nil {
+				res.Error = chatErr.Error()
+			}
+			if err := encoder.Encode(res); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Failed to write JSON: %v\n", err)
+				return exitError
+			}
+		} else {
+			tracer.take()
+			if chatErr == nil {
+				streamMu.Lock()
+				already := strings.TrimSpace(streamed.String())
+				streamMu.Unlock()
+				// The follow-up after tool calls is not streamed, so print the
+				// final answer unless it already went out chunk by chunk.
+				if already != strings.TrimSpace(response) {
+					if already != "" {
+						fmt.Fprintln(os.Stdout)
+					}
+					fmt.Fprint(os.Stdout, response)
+				}
+				fmt.Fprintln(os.Stdout)
+			}
+		}
+
+		if chatErr != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", chatErr)
+			exitCode = exitError
+			if !*keepGoing {
+				break
+			}
+		}
+	}
+
+	return exitCode
+}
+
+// chatWithTimeout runs Agent.Chat, cancelling the request if it exceeds timeout.
+func chatWithTimeout(ag *agent.Agent, prompt string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return ag.Chat(prompt)
+	}
+	timer := time.AfterFunc(timeout, ag.StopCurrentRequest)
+	defer timer.Stop()
+	response, err := ag.Chat(prompt)
+	if err != nil && !timer.Stop() {
+		return response, fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return response, err
+}
+
+// collectPrompts resolves the prompt list from the flag value, a prompts file,
+// or stdin. A prompt of "-" forces reading stdin.
+func collectPrompts(prompt, promptsFile string, stdin *os.File) ([]string, error) {
+	var prompts []string
+
+	switch {
+	case prompt == "-":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if p := strings.TrimSpace(string(data)); p != "" {
+			prompts = append(prompts, p)
+		}
+	case prompt != "":
+		prompts = append(prompts, prompt)
+	}
+
+	if promptsFile != "" {
+		f, err := os.Open(promptsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open prompts file: %w", err)
+		}
+		defer f.Close()
+		fromFile, err := splitPrompts(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompts file: %w", err)
+		}
+		prompts = append(prompts, fromFile...)
+	}
+
+	if prompt == "" && promptsFile == "" && !isTerminal(stdin) {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if p := strings.TrimSpace(string(data)); p != "" {
+			prompts = append(prompts, p)
+		}
+	}
+
+	return prompts, nil
+}
+
+// splitPrompts reads prompts separated by one or more blank lines.
+// Lines starting with "#" are treated as comments.
+func splitPrompts(r io.Reader) ([]string, error) {
+	var prompts []string
+	var current []string
+	flush := func() {
+		if p := strings.TrimSpace(strings.Join(current, "\n")); p != "" {
+			prompts = append(prompts, p)
+		}
+		current = current[:0]
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return prompts, scanner.Err()
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// toInt converts a usage counter from Agent.GetUsageStats to int.
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
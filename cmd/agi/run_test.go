@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitPrompts(t *testing.T) {
+	input := `# comment line
+first prompt
+continues here
+
+
+second prompt
+# ignored
+`
+	prompts, err := splitPrompts(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("splitPrompts failed: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 prompts, got %d: %q", len(prompts), prompts)
+	}
+	if prompts[0] != "first prompt\ncontinues here" {
+		t.Errorf("unexpected first prompt: %q", prompts[0])
+	}
+	if prompts[1] != "second prompt" {
+		t.Errorf("unexpected second prompt: %q", prompts[1])
+	}
+}
+
+func TestToolTracer_MatchesCompletionsByName(t *testing.T) {
+	tr := &toolTracer{}
+	tr.start("read_file", `{"path":"a.go"}`)
+	tr.start("list_files", `{}`)
+	tr.start("read_file", `{"path":"b.go"}`)
+
+	tr.finish("list_files", "a.go\nb.go", nil)
+	tr.finish("read_file", "package a", nil)
+	tr.finish("read_file", "", errors.New("not found"))
+
+	entries := tr.take()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if !entries[0].Success || entries[0].Result != "package a" {
+		t.Errorf("first read_file not matched: %+v", entries[0])
+	}
+	if !entries[1].Success {
+		t.Errorf("list_files should succeed: %+v", entries[1])
+	}
+	if entries[2].Success || entries[2].Error != "not found" {
+		t.Errorf("second read_file should carry the error: %+v", entries[2])
+	}
+
+	if got := tr.take(); len(got) != 0 {
+		t.Errorf("take should reset the tracer, got %d entries", len(got))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -24,12 +25,14 @@ import (
 	"ClosedWheeler/pkg/permissions"
 	"ClosedWheeler/pkg/prompts"
 	"ClosedWheeler/pkg/roadmap"
+	"ClosedWheeler/pkg/sandbox"
+	"ClosedWheeler/pkg/secrets"
 	"ClosedWheeler/pkg/security"
 	"ClosedWheeler/pkg/skills"
+	"ClosedWheeler/pkg/tasks"
 	"ClosedWheeler/pkg/telegram"
 	"ClosedWheeler/pkg/tools"
 	"ClosedWheeler/pkg/tools/builtin"
-	"ClosedWheeler/pkg/utils"
 )
 
 // Agent represents the AGI agent
@@ -41,6 +44,8 @@ type Agent struct {
 	tools             *tools.Registry
 	executor          *tools.Executor
 	editManager       *editor.Manager
+	editReviewer      editor.Reviewer // UI that accepts or rejects file changes (review.enabled)
+	toolApprover      ToolApprover    // UI that approves sensitive tool calls without Telegram
 	logger            *logger.Logger
 	statusCallback    func(string)
 	tgStatusMessageID int    // ID da última mensagem de status no Telegram
@@ -51,6 +56,8 @@ type Agent struct {
 	auditor           *security.Auditor
 	skillManager      *skills.Manager
 	permManager       *permissions.Manager
+	policyPath        string // Project permission policy (.agi/policy.rules)
+	sandbox           *sandbox.Sandbox
 	totalUsage        llm.Usage
 	lastRateLimits    llm.RateLimits
 	approvalChan      chan bool                    // Channel for Telegram approvals
@@ -69,6 +76,30 @@ type Agent struct {
 	toolErrorCb       func(name string, err error) // Called when a tool fails
 	pipeline          *MultiAgentPipeline          // Optional multi-agent pipeline
 	mcpManager        *agimcp.Manager              // MCP server connections
+	sessionStore      *SessionStore                // Persistent conversation sessions (nil for clones)
+	providerRouter    *llm.ProviderRouter          // Optional multi-provider routing, reapplied when the client is rebuilt
+	responseCache     *llm.ResponseCache           // Optional cache of deterministic responses (.agi/cache/)
+	recallStore       *memory.VectorStore          // Embeddings of memories and brain entries (memory.retrieval)
+
+	// lastBudget is the token usage of the last assembled request (see /context).
+	budgetMu   sync.Mutex
+	lastBudget ContextBudget
+	// profileWindow is the context window of windowModel's profile, 0 if
+	// the model has none.
+	windowModel   string
+	profileWindow int
+
+	// Cost tracking (see cost.go). costMu also guards totalUsage.
+	costLedger      *CostLedger
+	costMu          sync.Mutex
+	costRole        string // label for usage of the running turn, set while holding mu
+	defaultCostRole string // "main", or "debate" for clones
+	parentSessionID string // clones bill to their parent's session
+	softWarned      map[string]bool
+
+	// savedSession is the transcript persisted to .agi/sessions/ after each turn.
+	savedMu      sync.Mutex
+	savedSession *SavedSession
 
 	// Per-request cancellation — allows the TUI (Escape key) to abort an in-flight
 	// LLM call without terminating the whole agent.
@@ -82,9 +113,10 @@ type Agent struct {
 
 // NewAgent creates a new agent instance
 func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, error) {
-	if cfg.APIKey == "" {
+	if cfg.APIKey == "" && cfg.RequiresAPIKey() {
 		return nil, fmt.Errorf("API key is required. Set OPENAI_API_KEY (sk-...), ANTHROPIC_API_KEY (sk-ant-...), or NVIDIA_API_KEY (nvapi-...) in your environment or config file")
 	}
+	registerSecrets(cfg)
 
 	// Initialize LLM client with provider support
 	llmClient := llm.NewClientWithProvider(cfg.APIBaseURL, cfg.APIKey, cfg.Model, cfg.Provider)
@@ -99,6 +131,11 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 		llmClient.SetFallbackModels(cfg.FallbackModels, cfg.FallbackTimeout)
 	}
 
+	// Models without native function calling drive tools through text
+	if cfg.TextToolCallsFor(cfg.Model) {
+		llmClient.SetTextToolCalls(true)
+	}
+
 	// Initialize memory manager
 	memConfig := &memory.Config{
 		MaxShortTermItems:  cfg.Memory.MaxShortTermItems,
@@ -140,12 +177,25 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 		CachePath:           filepath.Join(appPath, "browser_cache"),
 	})
 
+	// Commands run in a sandbox when enabled: only the workplace is
+	// writable, and the project's policy stays read-only even inside it
+	policyPath := filepath.Join(projectPath, ".agi", "policy.rules")
+	sb := sandbox.New(cfg.Sandbox, workplacePath)
+	sb.ReadOnly(filepath.Dir(policyPath))
+
 	// Register tools restricted to workplace (git tools only if explicitly enabled)
 	builtin.RegisterBuiltinTools(registry, workplacePath, appPath, auditor, cfg.EnableGitTools, builtin.BuiltinOption{
-		EnableSSH: cfg.SSH.Enabled,
-		SSHConfig: &cfg.SSH,
+		EnableSSH:      cfg.SSH.Enabled,
+		SSHConfig:      &cfg.SSH,
+		Sandbox:        sb,
+		IgnorePatterns: cfg.IgnorePatterns,
 	})
 
+	// Initialize edit manager — edits happen in workplace, session metadata in app .agi/.
+	// edit_file and apply_patch record their changes here so they can be undone.
+	editManager := editor.NewManager(workplacePath, filepath.Join(appPath, ".agi"))
+	builtin.RegisterEditTools(registry, workplacePath, auditor, editManager)
+
 	// Set debug level for tools if enabled
 	if cfg.DebugTools {
 		tools.SetGlobalDebugLevel(tools.DebugVerbose)
@@ -156,6 +206,7 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 
 	// Initialize skill manager in app root .agi/skills/ (NOT in workplace)
 	skillManager := skills.NewManager(appPath, auditor, registry)
+	skillManager.SetSandbox(sb)
 	if err := skillManager.LoadSkills(); err != nil {
 		l.Error("Failed to load skills: %v", err)
 	}
@@ -179,21 +230,23 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 		mcpMgr.ConnectAll()
 	}
 
-	// Initialize edit manager — edits happen in workplace, session metadata in app .agi/
-	editManager := editor.NewManager(workplacePath, filepath.Join(appPath, ".agi"))
-
 	// Initialize permissions manager
 	permManager, err := permissions.NewManager(&cfg.Permissions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create permissions manager: %w", err)
 	}
+	if err := permManager.LoadPolicy(workplacePath, policyPath); err != nil {
+		return nil, fmt.Errorf("failed to load permission policy: %w", err)
+	}
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background()) //nolint:govet
 
 	// Initialize brain, roadmap, and health checker (all in workplacePath)
 	brainMgr := brain.NewBrain(workplacePath)
+	builtin.RegisterBrainTools(registry, brainMgr)
 	roadmapMgr := roadmap.NewRoadmap(workplacePath)
+	builtin.RegisterRoadmapTools(registry, roadmapMgr)
 	healthChecker := health.NewChecker(workplacePath, cfg.TestCommand)
 
 	// Initialize Telegram bot (nil when not configured)
@@ -207,34 +260,44 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 	}
 
 	ag := &Agent{
-		config:         cfg,
-		llm:            llmClient,
-		memory:         memManager,
-		project:        project,
-		tools:          registry,
-		executor:       tools.NewExecutor(registry),
-		editManager:    editManager,
-		logger:         l,
-		statusCallback: func(s string) {}, // Default no-op
-		appPath:        appPath,           // App root: where .agi/ lives
-		projectPath:    workplacePath,     // Workplace: sandbox for agent file operations
-		tgBot:          tgBot,
-		rules:          prompts.NewRulesManager(workplacePath, wpDir, cfg.GetMaxRuleFileSize()),
-		auditor:        auditor,
-		skillManager:   skillManager,
-		mcpManager:     mcpMgr,
-		permManager:    permManager,
-		approvalChan:   make(chan bool, 1), // Buffer of 1 to avoid dropping approvals before listener is ready
-		ctx:            ctx,
-		cancel:         cancel,
-		sessionMgr:     NewSessionManager(cfg.GetSessionMaxMessages()), // Initialize session manager
-		brain:          brainMgr,                                       // Initialize brain
-		roadmap:        roadmapMgr,                                     // Initialize roadmap
-		healthChecker:  healthChecker,                                  // Initialize health checker
-		mu:             sync.Mutex{},                                   // Initialize mutex
-		activityMu:     sync.Mutex{},                                   // Initialize activity mutex
-		lastActivity:   time.Now(),
-	}
+		config:          cfg,
+		llm:             llmClient,
+		memory:          memManager,
+		project:         project,
+		tools:           registry,
+		executor:        tools.NewExecutor(registry),
+		editManager:     editManager,
+		logger:          l,
+		statusCallback:  func(s string) {}, // Default no-op
+		appPath:         appPath,           // App root: where .agi/ lives
+		projectPath:     workplacePath,     // Workplace: sandbox for agent file operations
+		tgBot:           tgBot,
+		rules:           prompts.NewRulesManager(workplacePath, wpDir, cfg.GetMaxRuleFileSize()),
+		auditor:         auditor,
+		skillManager:    skillManager,
+		mcpManager:      mcpMgr,
+		permManager:     permManager,
+		sandbox:         sb,
+		policyPath:      policyPath,
+		approvalChan:    make(chan bool, 1), // Buffer of 1 to avoid dropping approvals before listener is ready
+		ctx:             ctx,
+		cancel:          cancel,
+		sessionMgr:      NewSessionManager(cfg.GetSessionMaxMessages()), // Initialize session manager
+		brain:           brainMgr,                                       // Initialize brain
+		roadmap:         roadmapMgr,                                     // Initialize roadmap
+		healthChecker:   healthChecker,                                  // Initialize health checker
+		mu:              sync.Mutex{},                                   // Initialize mutex
+		activityMu:      sync.Mutex{},                                   // Initialize activity mutex
+		lastActivity:    time.Now(),
+		sessionStore:    NewSessionStore(filepath.Join(appPath, ".agi", "sessions")),
+		savedSession:    newSavedSession(cfg.Model, cfg.Provider),
+		costLedger:      OpenCostLedger(filepath.Join(appPath, ".agi", "usage.jsonl")),
+		responseCache:   newResponseCache(cfg, appPath),
+		recallStore:     newRecallStore(cfg),
+		defaultCostRole: "main",
+	}
+	llmClient.SetResponseCache(ag.responseCache)
+	ag.applyEditReview()
 
 	// Initialize brain and roadmap files
 	if err := ag.brain.Initialize(); err != nil {
@@ -253,6 +316,8 @@ func NewAgent(cfg *config.Config, projectPath string, appPath string) (*Agent, e
 	// Initialize multi-agent pipeline (disabled by default)
 	ag.pipeline = NewMultiAgentPipeline(ag)
 
+	ag.probeLocalModel()
+
 	return ag, nil
 }
 
@@ -366,8 +431,13 @@ func safeToolNames() map[string]bool {
 		"search_code":           true,
 		"get_code_outline":      true,
 		"get_project_metrics":   true,
+		"find_definition":       true,
+		"find_references":       true,
+		"list_implementations":  true,
 		"get_system_info":       true,
 		"manage_tasks":          true,
+		"brain_query":           true,
+		"brain_tags":            true,
 		"git_status":            true,
 		"git_diff":              true,
 		"git_log":               true,
@@ -402,33 +472,40 @@ func (a *Agent) CloneForDebate(name string) *Agent {
 	// Telegram bridge. The parent's callback handler writes approvals to its
 	// own approvalChan, so a clone waiting on its own channel would deadlock.
 	clone := &Agent{
-		config:         a.config,
-		llm:            a.llm,
-		memory:         cloneMemory,
-		project:        a.project,
-		tools:          a.tools,
-		executor:       a.executor,
-		editManager:    a.editManager,
-		logger:         a.logger,
-		statusCallback: func(s string) {}, // Clones have their own (or no) callback by default
-		appPath:        a.appPath,
-		projectPath:    a.projectPath,
-		tgBot:          nil, // no Telegram for debate clones — avoids approval deadlock
-		rules:          a.rules,
-		auditor:        a.auditor,
-		skillManager:   a.skillManager,
-		mcpManager:     a.mcpManager,
-		permManager:    a.permManager,
-		approvalChan:   make(chan bool, 1),
-		ctx:            cloneCtx,
-		cancel:         cloneCancel,
-		sessionMgr:     cloneSessionMgr,
-		brain:          a.brain,
-		roadmap:        a.roadmap,
-		healthChecker:  a.healthChecker,
-		mu:             sync.Mutex{},
-		activityMu:     sync.Mutex{},
-		lastActivity:   time.Now(),
+		config:          a.config,
+		llm:             a.llm,
+		memory:          cloneMemory,
+		project:         a.project,
+		tools:           a.tools,
+		executor:        a.executor,
+		editManager:     a.editManager,
+		logger:          a.logger,
+		statusCallback:  func(s string) {}, // Clones have their own (or no) callback by default
+		appPath:         a.appPath,
+		projectPath:     a.projectPath,
+		tgBot:           nil, // no Telegram for debate clones — avoids approval deadlock
+		toolApprover:    a.toolApprover,
+		rules:           a.rules,
+		auditor:         a.auditor,
+		skillManager:    a.skillManager,
+		mcpManager:      a.mcpManager,
+		permManager:     a.permManager,
+		policyPath:      a.policyPath,
+		approvalChan:    make(chan bool, 1),
+		ctx:             cloneCtx,
+		cancel:          cloneCancel,
+		sessionMgr:      cloneSessionMgr,
+		brain:           a.brain,
+		roadmap:         a.roadmap,
+		healthChecker:   a.healthChecker,
+		mu:              sync.Mutex{},
+		activityMu:      sync.Mutex{},
+		lastActivity:    time.Now(),
+		costLedger:      a.costLedger,
+		responseCache:   a.responseCache,
+		recallStore:     a.recallStore,
+		defaultCostRole: "debate",
+		parentSessionID: a.costSessionID(),
 	}
 
 	return clone
@@ -436,12 +513,27 @@ func (a *Agent) CloneForDebate(name string) *Agent {
 
 // Chat processes a user message and returns the response
 func (a *Agent) Chat(userMessage string) (string, error) {
+	return a.chat("", userMessage)
+}
+
+// chat runs one turn and bills its usage to role ("" for the default). Turns
+// hold mu from start to finish, so the role cannot leak into another turn.
+func (a *Agent) chat(role, userMessage string) (string, error) {
+	// Refuse to spend more once a hard budget limit is reached
+	if paused, reason := a.SpendingPaused(); paused {
+		return "", fmt.Errorf("%w: %s (use /cost resume to continue)", ErrSpendingPaused, reason)
+	}
+	a.beginTurn(userMessage)
+
 	// Reset Telegram status message ID for new conversation
 	a.tgStatusMessageID = 0
 
 	// Create a per-request cancellable context so StopCurrentRequest() can abort
 	// the in-flight LLM call without shutting down the whole agent.
 	reqCtx, reqCancel := context.WithCancel(a.ctx)
+	if a.cacheOptIn(role) {
+		reqCtx = llm.WithCache(reqCtx)
+	}
 	a.requestMu.Lock()
 	a.requestCancel = reqCancel
 	a.requestMu.Unlock()
@@ -452,14 +544,26 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 		a.requestMu.Unlock()
 	}()
 
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.setCostRole(role)
+	defer a.setCostRole("")
+
 	// If the multi-agent pipeline is active, delegate to it.
 	// Clones created by the pipeline have pipeline=nil so they skip this block.
 	if a.pipeline != nil && a.pipeline.IsEnabled() {
-		return a.pipeline.Run(reqCtx, userMessage)
+		response, err := a.pipeline.Run(reqCtx, userMessage)
+		if err == nil {
+			a.addMessage("user", userMessage)
+			a.addMessage("assistant", response)
+			a.persistSession()
+		}
+		return response, err
 	}
 
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	// Persist the conversation after every turn (including failed ones) so it
+	// survives a restart or crash.
+	defer a.persistSession()
 
 	// Add recovery to prevent panics from killing the whole agent
 	defer func() {
@@ -476,13 +580,13 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 	a.memory.AgeWorkingMemory(0.05) // 5% decay per hour/interaction context
 
 	// Add user message to memory
-	a.memory.AddMessage("user", userMessage)
+	a.addMessage("user", userMessage)
 
 	// Detect context and build components
 	ctx := prompts.DetectContext(userMessage)
 	rulesContent := a.rules.GetFormattedRules()
 	projectInfo := a.project.GetSummary()
-	historyInfo := a.getContextSummary()
+	historyInfo := a.getContextSummary(userMessage)
 	toolsSummary := a.getToolsSummary()
 
 	systemPrompt := prompts.NewBuilder(ctx).
@@ -493,27 +597,14 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 		Build()
 
 	// Build messages - only include system prompt if context needs refresh
-	var messages []llm.Message
 	needsContext := a.sessionMgr.NeedsContextRefresh(systemPrompt, rulesContent, projectInfo)
 
 	if needsContext {
 		// First message or context changed - send full context
-		messages = append(messages, llm.Message{
-			Role:    "system",
-			Content: systemPrompt,
-		})
 		a.sessionMgr.MarkContextSent(systemPrompt, rulesContent, projectInfo)
 		a.statusCallback("🔄 Refreshing context...")
 	}
 
-	// Add conversation history
-	for _, msg := range a.memory.GetMessages() {
-		messages = append(messages, llm.Message{
-			Role:    msg["role"],
-			Content: msg["content"],
-		})
-	}
-
 	// Get tool definitions
 	toolDefs := a.getToolDefinitions()
 
@@ -523,6 +614,24 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 		a.logger.Info("Tool %d: %s - %s", i, def.Function.Name, def.Function.Description)
 	}
 
+	// Fit system prompt sections and history into the model's context window
+	parts := promptParts{
+		ctx:           ctx,
+		toolsSummary:  toolsSummary,
+		rules:         rulesContent,
+		project:       projectInfo,
+		memory:        historyInfo,
+		history:       a.historyMessages(),
+		toolDefs:      toolDefs,
+		includeSystem: needsContext,
+	}
+	budgeter := a.newBudgeter()
+	messages, budget := budgeter.assemble(parts)
+	a.setContextBudget(budget)
+	if budget.DroppedMessages > 0 {
+		a.logger.Info("Context budget: %d/%d tokens, %d oldest messages left out", budget.Used(), budget.Window, budget.DroppedMessages)
+	}
+
 	// Send to LLM — use streaming when a callback is registered.
 	// Pass reqCtx so the request is aborted immediately if StopCurrentRequest() is called.
 	var resp *llm.ChatResponse
@@ -533,25 +642,19 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 		resp, err = a.llm.ChatWithToolsContext(reqCtx, messages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens)
 	}
 	if err != nil {
-		// Context-length exceeded: trim oldest messages and retry once
+		// Context-length exceeded: the real window is smaller than configured,
+		// so assemble again against a reduced budget and retry once
 		if llm.IsContextLengthError(err) {
-			a.logger.Info("Context length exceeded — trimming oldest messages and retrying...")
+			a.logger.Info("Context length exceeded — re-budgeting against a smaller window and retrying...")
 			a.statusCallback("✂️ Context too long, trimming history...")
-			a.memory.TrimOldest(0.3) // drop oldest 30% of messages
 
-			// Rebuild message list from trimmed memory
-			var trimmedMessages []llm.Message
-			if needsContext {
-				trimmedMessages = append(trimmedMessages, llm.Message{Role: "system", Content: systemPrompt})
-			}
-			for _, msg := range a.memory.GetMessages() {
-				trimmedMessages = append(trimmedMessages, llm.Message{Role: msg["role"], Content: msg["content"]})
-			}
+			messages, budget = budgeter.shrink(budget).assemble(parts)
+			a.setContextBudget(budget)
 
 			if a.streamCallback != nil {
-				resp, err = a.llm.ChatWithStreamingContext(reqCtx, trimmedMessages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens, a.streamCallback)
+				resp, err = a.llm.ChatWithStreamingContext(reqCtx, messages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens, a.streamCallback)
 			} else {
-				resp, err = a.llm.ChatWithToolsContext(reqCtx, trimmedMessages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens)
+				resp, err = a.llm.ChatWithToolsContext(reqCtx, messages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens)
 			}
 			if err != nil {
 				return "", fmt.Errorf("LLM error (after context trim): %w", err)
@@ -562,9 +665,7 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 	}
 
 	// Update usage and rate limits
-	a.totalUsage.PromptTokens += resp.Usage.PromptTokens
-	a.totalUsage.CompletionTokens += resp.Usage.CompletionTokens
-	a.totalUsage.TotalTokens += resp.Usage.TotalTokens
+	a.recordUsage(resp.Usage)
 	a.lastRateLimits = resp.RateLimits
 
 	// Update session stats
@@ -588,7 +689,7 @@ func (a *Agent) Chat(userMessage string) (string, error) {
 				a.logger.Error("Continuation failed: %v", contErr)
 			}
 		}
-		a.memory.AddMessage("assistant", finalResponse)
+		a.addMessage("assistant", finalResponse)
 	}
 
 	if err != nil {
@@ -650,11 +751,12 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 
 	// Execute tools in parallel where possible
 	type toolExecutionResult struct {
-		tc     llm.ToolCall
-		args   map[string]any
-		result tools.ToolResult
-		err    error
-		index  int
+		tc       llm.ToolCall
+		args     map[string]any
+		decision permissions.Decision
+		result   tools.ToolResult
+		err      error
+		index    int
 	}
 
 	results := make([]toolExecutionResult, len(toolCalls))
@@ -682,10 +784,19 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 		results[i].args = args
 		results[i].index = i
 
-		// Check if tool requires approval
-		if a.permManager.RequiresApproval(tc.Function.Name) {
+		// The permission policy denies the call, asks for approval or allows it
+		decision := a.permManager.Evaluate(tc.Function.Name, args)
+		results[i].decision = decision
+		switch decision.Effect {
+		case permissions.Deny:
+			a.logger.Info("Tool %s denied by permission policy: %s", tc.Function.Name, decision.Reason)
+			results[i].result = tools.ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("permission denied by policy: %s", decision.Reason),
+			}
+		case permissions.Ask:
 			sensitiveCalls = append(sensitiveCalls, i)
-		} else {
+		default:
 			nonSensitiveCalls = append(nonSensitiveCalls, i)
 		}
 	}
@@ -764,18 +875,14 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 			a.toolStartCb(tc.Function.Name, tc.Function.Arguments)
 		}
 
-		// Request approval if Telegram enabled and bot is available
-		// (debate clones have tgBot=nil to avoid approval deadlock)
-		if a.config.Telegram.Enabled && a.tgBot != nil {
-			if err := a.requestTelegramApproval(tc.Function.Name, tc.Function.Arguments); err != nil {
-				a.logger.Error("Telegram approval failed or denied: %v", err)
-				results[idx].result = tools.ToolResult{
-					Success: false,
-					Output:  "Error: Operation denied by user via Telegram.",
-				}
-				results[idx].err = err
-				continue
+		if err := a.approveCall(tc.Function.Name, tc.Function.Arguments, args, results[idx].decision); err != nil {
+			a.logger.Info("Tool %s not approved: %v", tc.Function.Name, err)
+			results[idx].result = tools.ToolResult{
+				Success: false,
+				Output:  fmt.Sprintf("Error: Operation not approved: %v", err),
 			}
+			results[idx].err = err
+			continue
 		}
 
 		result, err := a.executor.Execute(tools.ToolCall{
@@ -830,6 +937,8 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 			}
 		}
 
+		a.recordToolCall(res.tc.Function.Name, res.tc.Function.Arguments, toolContent, result.Success)
+
 		// Add tool result to messages
 		messages = append(messages, llm.Message{
 			Role:       "tool",
@@ -851,6 +960,11 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 	// Get tool definitions for follow-up
 	toolDefs := a.getToolDefinitions()
 
+	// Stop the tool loop if this turn pushed spending over a hard limit
+	if paused, reason := a.SpendingPaused(); paused {
+		return "", fmt.Errorf("%w: %s (use /cost resume to continue)", ErrSpendingPaused, reason)
+	}
+
 	// Continue conversation with tool results
 	var followResp *llm.ChatResponse
 	followResp, err = a.llm.ChatWithTools(messages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens)
@@ -860,9 +974,7 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 	}
 
 	// Accumulate usage from follow-up call — critical for token tracking
-	a.totalUsage.PromptTokens += followResp.Usage.PromptTokens
-	a.totalUsage.CompletionTokens += followResp.Usage.CompletionTokens
-	a.totalUsage.TotalTokens += followResp.Usage.TotalTokens
+	a.recordUsage(followResp.Usage)
 	a.lastRateLimits = followResp.RateLimits
 	a.sessionMgr.UpdateTokenUsage(followResp.Usage.PromptTokens)
 
@@ -882,7 +994,7 @@ func (a *Agent) handleToolCalls(resp *llm.ChatResponse, messages []llm.Message,
 			content += continuation
 		}
 	}
-	a.memory.AddMessage("assistant", content)
+	a.addMessage("assistant", content)
 
 	return content, nil
 }
@@ -994,11 +1106,6 @@ func (a *Agent) getToolsSummary() string {
 	return sb.String()
 }
 
-// getContextSummary returns a summary of long-term context
-func (a *Agent) getContextSummary() string {
-	return a.memory.GetContext()
-}
-
 // compressContext uses LLM to compress old context
 func (a *Agent) compressContext(items []*memory.MemoryItem) {
 	var conversation strings.Builder
@@ -1018,7 +1125,7 @@ Conversation Segment:
 
 Summary:`, conversation.String())
 
-	summary, err := a.llm.SimpleQuery(prompt, utils.FloatPtr(0.3), nil, utils.IntPtr(300))
+	summary, err := a.backgroundQuery(prompt, 0.3, 300)
 	if err != nil {
 		a.logger.Error("Context compression failed: %v", err)
 		return
@@ -1051,7 +1158,7 @@ Recent Interaction:
 
 Insight:`, chat.String())
 
-	insight, err := a.llm.SimpleQuery(prompt, utils.FloatPtr(0.2), nil, utils.IntPtr(150))
+	insight, err := a.backgroundQuery(prompt, 0.2, 150)
 	if err != nil || strings.ToUpper(insight) == "NONE" || insight == "" {
 		return
 	}
@@ -1146,6 +1253,23 @@ func (a *Agent) Config() *config.Config {
 	return a.config
 }
 
+// ProviderName returns the canonical name of the active LLM provider.
+func (a *Agent) ProviderName() string {
+	return a.llm.ProviderName()
+}
+
+// SetProviderRouter routes LLM requests across the providers of r, with
+// failover. nil sends requests to the configured model directly again.
+func (a *Agent) SetProviderRouter(r *llm.ProviderRouter) {
+	a.providerRouter = r
+	a.llm.SetProviderRouter(r)
+}
+
+// ProviderRouter returns the active provider router, or nil.
+func (a *Agent) ProviderRouter() *llm.ProviderRouter {
+	return a.providerRouter
+}
+
 // GetRulesSummary returns a summary of loaded rules
 func (a *Agent) GetRulesSummary() string {
 	return a.rules.GetRulesSummary()
@@ -1158,6 +1282,8 @@ func (a *Agent) GetFormattedRules() string {
 
 // GetUsageStats returns current token usage and rate limit information
 func (a *Agent) GetUsageStats() map[string]any {
+	a.costMu.Lock()
+	defer a.costMu.Unlock()
 	return map[string]any{
 		"prompt_tokens":      a.totalUsage.PromptTokens,
 		"completion_tokens":  a.totalUsage.CompletionTokens,
@@ -1171,7 +1297,12 @@ func (a *Agent) GetUsageStats() map[string]any {
 
 // GetContextStats returns current context session statistics
 func (a *Agent) GetContextStats() ContextStats {
-	return a.sessionMgr.GetContextStats()
+	stats := a.sessionMgr.GetContextStats()
+	a.budgetMu.Lock()
+	stats.ContextTokens = a.lastBudget.Used()
+	stats.ContextWindow = a.lastBudget.Window
+	a.budgetMu.Unlock()
+	return stats
 }
 
 // SaveConfig saves current configuration to the app root .agi/ directory
@@ -1200,6 +1331,7 @@ func (a *Agent) SwitchModel(provider, baseURL, apiKey, model, reasoningEffort st
 	a.config.Provider = provider
 	a.config.APIBaseURL = baseURL
 	a.config.APIKey = apiKey
+	secrets.Add("api-key", apiKey)
 	a.config.Model = model
 	a.config.ReasoningEffort = reasoningEffort
 	a.llm = llm.NewClientWithProvider(baseURL, apiKey, model, provider)
@@ -1210,6 +1342,11 @@ func (a *Agent) SwitchModel(provider, baseURL, apiKey, model, reasoningEffort st
 	if len(a.config.FallbackModels) > 0 {
 		a.llm.SetFallbackModels(a.config.FallbackModels, a.config.FallbackTimeout)
 	}
+	if a.config.TextToolCallsFor(model) {
+		a.llm.SetTextToolCalls(true)
+	}
+	a.llm.SetProviderRouter(a.providerRouter)
+	a.llm.SetResponseCache(a.responseCache)
 
 	if err := a.SaveConfig(); err != nil {
 		// Rollback on save failure
@@ -1223,9 +1360,31 @@ func (a *Agent) SwitchModel(provider, baseURL, apiKey, model, reasoningEffort st
 	}
 
 	a.logger.Info("Model switched: provider=%s model=%s url=%s effort=%s", provider, model, baseURL, reasoningEffort)
+	a.probeLocalModel()
 	return nil
 }
 
+// probeLocalModel asks a local Ollama server for the active model's context
+// length and tool support so requests use native tools only where the model
+// handles them. Failures are logged and the provider keeps its defaults.
+func (a *Agent) probeLocalModel() {
+	if a.llm.ProviderName() != "ollama" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := a.llm.ProbeOllamaModel(ctx)
+	if err != nil {
+		a.logger.Error("Failed to probe Ollama model %s: %v", a.config.Model, err)
+		return
+	}
+	if a.config.TextToolCallsFor(a.config.Model) {
+		a.llm.SetTextToolCalls(true)
+	}
+	a.logger.Info("Ollama model %s: context=%d tools=%v thinking=%v",
+		info.Name, info.EffectiveContext(), info.SupportsTools(), info.SupportsThinking())
+}
+
 // ReloadProject reloads project files, rules, skills, and MCP connections.
 func (a *Agent) ReloadProject() error {
 	a.logger.Info("Reloading project context...")
@@ -1261,12 +1420,26 @@ func (a *Agent) SetTelegramChatID(id int64) error {
 	return a.SaveConfig()
 }
 
+// registerSecrets registers the credentials in cfg with the redactor, so
+// they never reach logs, memory or prompts even where no detector would
+// recognize them.
+func registerSecrets(cfg *config.Config) {
+	secrets.Add("api-key", cfg.APIKey)
+	secrets.Add("embeddings-key", cfg.Memory.Retrieval.APIKey)
+	secrets.Add("telegram-token", cfg.Telegram.BotToken)
+	for _, h := range cfg.SSH.Hosts {
+		secrets.Add("ssh-password", h.Password)
+	}
+	secrets.AddEnv()
+}
+
 // ReconfigureTelegram allows the TUI to set/change the bot token at runtime.
 // It creates a new Bot instance, validates the token, updates config, and
 // restarts polling.
 func (a *Agent) ReconfigureTelegram(token string, enabled bool) error {
 	a.config.Telegram.BotToken = token
 	a.config.Telegram.Enabled = enabled
+	secrets.Add("telegram-token", token)
 
 	if token == "" || !enabled {
 		if a.tgBot != nil {
@@ -1495,16 +1668,30 @@ The AGI has full access to the project and can execute tools.`
 				return
 			}
 			a.config = newConfig
+			registerSecrets(a.config)
 			a.llm = llm.NewClientWithProvider(a.config.APIBaseURL, a.config.APIKey, a.config.Model, a.config.Provider)
 			if len(a.config.FallbackModels) > 0 {
 				a.llm.SetFallbackModels(a.config.FallbackModels, a.config.FallbackTimeout)
 			}
-			if a.permManager != nil {
-				a.permManager.Close()
+			if a.config.TextToolCallsFor(a.config.Model) {
+				a.llm.SetTextToolCalls(true)
 			}
-			a.permManager, err = permissions.NewManager(&a.config.Permissions)
-			if err != nil {
+			a.llm.SetProviderRouter(a.providerRouter)
+			a.responseCache = newResponseCache(a.config, a.appPath)
+			a.llm.SetResponseCache(a.responseCache)
+			a.applyEditReview()
+			a.sandbox.Configure(a.config.Sandbox)
+			if permManager, err := permissions.NewManager(&a.config.Permissions); err != nil {
 				a.logger.Error("Failed to reload permissions: %v", err)
+			} else {
+				if err := permManager.LoadPolicy(a.projectPath, a.policyPath); err != nil {
+					a.logger.Error("Failed to reload permission policy: %v", err)
+				}
+				if a.permManager != nil {
+					permManager.KeepSessionRules(a.permManager)
+					a.permManager.Close()
+				}
+				a.permManager = permManager
 			}
 			a.logger.Info("Configuration reloaded successfully")
 			_ = a.tgBot.SendMessage("✅ *Configuration reloaded!*\n\n*Model:* `" + a.config.Model + "`")
@@ -1611,39 +1798,37 @@ func (a *Agent) ClearMemory(tier memory.MemoryTier) {
 
 // ChatWithStreaming processes a user message with streaming response
 func (a *Agent) ChatWithStreaming(userMessage string, callback llm.StreamingCallback) (string, error) {
+	if paused, reason := a.SpendingPaused(); paused {
+		return "", fmt.Errorf("%w: %s (use /cost resume to continue)", ErrSpendingPaused, reason)
+	}
+	a.beginTurn(userMessage)
+
 	// Age working memory
 	a.memory.AgeWorkingMemory(0.05)
 
 	// Add user message to memory
-	a.memory.AddMessage("user", userMessage)
-
-	// Detect context and build system prompt
-	ctx := prompts.DetectContext(userMessage)
-	systemPrompt := prompts.NewBuilder(ctx).
-		WithToolsSummary(a.getToolsSummary()).
-		WithProjectInfo(a.project.GetSummary()).
-		WithHistory(a.getContextSummary()).
-		WithCustomInstructions(a.rules.GetFormattedRules()).
-		Build()
+	a.addMessage("user", userMessage)
 
-	// Build messages
-	messages := []llm.Message{
-		{Role: "system", Content: systemPrompt},
-	}
-
-	// Add conversation history
-	for _, msg := range a.memory.GetMessages() {
-		messages = append(messages, llm.Message{
-			Role:    msg["role"],
-			Content: msg["content"],
-		})
-	}
+	// Detect context and fit the prompt into the model's context window
+	toolDefs := a.getToolDefinitions()
+	messages, budget := a.newBudgeter().assemble(promptParts{
+		ctx:           prompts.DetectContext(userMessage),
+		toolsSummary:  a.getToolsSummary(),
+		rules:         a.rules.GetFormattedRules(),
+		project:       a.project.GetSummary(),
+		memory:        a.getContextSummary(userMessage),
+		history:       a.historyMessages(),
+		toolDefs:      toolDefs,
+		includeSystem: true,
+	})
+	a.setContextBudget(budget)
 
 	// Send to LLM with streaming
-	resp, err := a.llm.ChatWithStreaming(messages, a.getToolDefinitions(), a.config.Temperature, a.config.TopP, a.config.MaxTokens, callback)
+	resp, err := a.llm.ChatWithStreaming(messages, toolDefs, a.config.Temperature, a.config.TopP, a.config.MaxTokens, callback)
 	if err != nil {
 		return "", fmt.Errorf("LLM error: %w", err)
 	}
+	a.recordUsage(resp.Usage)
 
 	var finalResponse string
 	// Handle tool calls if present (no streaming for tool results)
@@ -1651,7 +1836,7 @@ func (a *Agent) ChatWithStreaming(userMessage string, callback llm.StreamingCall
 		finalResponse, err = a.handleToolCalls(resp, messages, 0)
 	} else {
 		finalResponse = a.llm.GetContent(resp)
-		a.memory.AddMessage("assistant", finalResponse)
+		a.addMessage("assistant", finalResponse)
 	}
 
 	if err != nil {
@@ -1676,6 +1861,9 @@ func (a *Agent) ChatWithStreaming(userMessage string, callback llm.StreamingCall
 	// Sync project tasks
 	a.syncProjectTasks()
 
+	// Persist the conversation so it survives a restart or crash
+	a.persistSession()
+
 	return finalResponse, nil
 }
 
@@ -1684,11 +1872,22 @@ func (a *Agent) syncProjectTasks() {
 	taskPath := filepath.Join(a.GetWorkplacePath(), "task.md")
 	if _, err := os.Stat(taskPath); os.IsNotExist(err) {
 		a.logger.Info("Initializing project task.md in workplace")
-		initialContent := "# 📋 Project Tasks\n\n- [ ] Initial project audit and setup\n"
-		os.WriteFile(taskPath, []byte(initialContent), 0644)
+		if err := tasks.NewList(taskPath).Initialize("Initial project audit and setup"); err != nil {
+			a.logger.Error("Failed to initialize task.md: %v", err)
+		}
 	}
 }
 
+// taskRank orders pending tasks for the heartbeat: in progress first, then
+// by priority.
+func taskRank(t tasks.Task) int {
+	rank := map[string]int{"high": 0, "medium": 1, "low": 2}[t.Priority]
+	if t.Status != tasks.StatusInProgress {
+		rank += 3
+	}
+	return rank
+}
+
 // GetWorkplacePath returns the path to the workplace directory
 func (a *Agent) GetWorkplacePath() string {
 	wpDir := a.config.GetWorkplaceDir()
@@ -1740,6 +1939,32 @@ func (a *Agent) RollbackEdits() error {
 	return a.editManager.RollbackAll()
 }
 
+// UndoTurns restores the files changed by write_file, edit_file and
+// apply_patch during the last n turns. It works without git.
+func (a *Agent) UndoTurns(n int) ([]editor.Turn, error) {
+	return a.editManager.UndoTurns(n)
+}
+
+// RedoTurns reapplies the last n undone turns.
+func (a *Agent) RedoTurns(n int) ([]editor.Turn, error) {
+	return a.editManager.RedoTurns(n)
+}
+
+// Timeline returns the turns that changed files, including undone ones.
+func (a *Agent) Timeline() []editor.Turn {
+	return a.editManager.Timeline()
+}
+
+// beginTurn starts a timeline turn for a user message. Pipeline roles run
+// inside the main agent's turn; debate clones share its edit manager and
+// start a turn of their own for every message, labelled with their role.
+func (a *Agent) beginTurn(userMessage string) {
+	if a.defaultCostRole != "main" {
+		userMessage = a.defaultCostRole + ": " + userMessage
+	}
+	a.editManager.BeginTurn(userMessage)
+}
+
 // isIdle returns true when the agent has been inactive for at least the
 // configured idle threshold. This prevents heartbeat from competing with
 // an active user conversation.
@@ -1773,6 +1998,12 @@ func (a *Agent) StartHeartbeat() {
 			case t := <-ticker.C:
 				heartbeatCount++
 
+				// Guard: skip while a spending limit pauses the agent
+				if paused, reason := a.SpendingPaused(); paused {
+					a.logger.Info("Heartbeat #%d skipped (spending paused: %s)", heartbeatCount, reason)
+					continue
+				}
+
 				// Guard: skip if user is actively using the agent
 				if !a.isIdle() {
 					a.logger.Info("Heartbeat #%d skipped (agent busy, last activity %s ago)",
@@ -1795,17 +2026,22 @@ func (a *Agent) StartHeartbeat() {
 				hasCriticalIssues := healthStatus.BuildStatus == "failing" ||
 					healthStatus.TestStatus == "failing"
 
-				// Read task.md directly from workplace
-				taskPath := filepath.Join(a.GetWorkplacePath(), "task.md")
-				content, err := os.ReadFile(taskPath)
+				// Prefer the next unblocked roadmap goal; without one, fall
+				// back to the open tasks that aren't the user's to do
+				nextGoal, err := a.roadmap.Next()
 				if err != nil {
-					a.logger.Error("Heartbeat failed to read task.md from workplace: %v", err)
-					continue
+					a.logger.Error("Heartbeat failed to read roadmap: %v", err)
 				}
-
-				// Simple heuristic to check for pending tasks
-				taskStr := string(content)
-				hasPending := strings.Contains(taskStr, "- [ ]") || strings.Contains(taskStr, "- [/]")
+				var pending []tasks.Task
+				if nextGoal == nil {
+					pending, err = tasks.NewList(filepath.Join(a.GetWorkplacePath(), "task.md")).Find(tasks.Filter{Status: "open"})
+					if err != nil {
+						a.logger.Error("Heartbeat failed to read task.md from workplace: %v", err)
+						continue
+					}
+					pending = slices.DeleteFunc(pending, func(t tasks.Task) bool { return t.Assignee == tasks.AssigneeUser })
+				}
+				hasPending := nextGoal != nil || len(pending) > 0
 
 				// Decide if agent should wake up
 				shouldAct := hasPending || hasCriticalIssues
@@ -1820,9 +2056,9 @@ func (a *Agent) StartHeartbeat() {
 					a.logger.Info("Heartbeat: waking agent (pending=%v, critical=%v)",
 						hasPending, hasCriticalIssues)
 
-					prompt := a.buildHeartbeatPrompt(healthStatus, hasPending)
+					prompt := a.buildHeartbeatPrompt(healthStatus, pending, nextGoal)
 
-					resp, err := a.Chat(prompt)
+					resp, err := a.chat("heartbeat", prompt)
 					if err != nil {
 						a.logger.Error("Heartbeat chat error: %v", err)
 						if brainErr := a.brain.AddError(
@@ -1864,7 +2100,7 @@ func (a *Agent) StartHeartbeat() {
 
 // buildHeartbeatPrompt constructs a concise, context-aware prompt for heartbeat.
 // The prompt is designed to elicit minimal-token responses when nothing needs doing.
-func (a *Agent) buildHeartbeatPrompt(health *health.Status, hasPending bool) string {
+func (a *Agent) buildHeartbeatPrompt(health *health.Status, pending []tasks.Task, nextGoal *roadmap.GoalState) string {
 	var sb strings.Builder
 
 	sb.WriteString("[Heartbeat] Automated check-in. Status: ")
@@ -1899,9 +2135,35 @@ func (a *Agent) buildHeartbeatPrompt(health *health.Status, hasPending bool) str
 			sb.WriteString(errSnippet)
 			sb.WriteString("\n```\n")
 		}
-	case hasPending:
-		wpDir := a.config.GetWorkplaceDir()
-		sb.WriteString(fmt.Sprintf("There are pending tasks. Read `%s/task.md` and continue from where you left off.\n", wpDir))
+	case nextGoal != nil:
+		sb.WriteString(fmt.Sprintf("Next roadmap goal: `%s` %s (%s priority", nextGoal.ID, nextGoal.Title, nextGoal.Priority))
+		if nextGoal.Progress.Total > 0 {
+			sb.WriteString(fmt.Sprintf(", %d/%d tasks done", nextGoal.Progress.Done, nextGoal.Progress.Total))
+		}
+		sb.WriteString(").\n")
+		if nextGoal.Description != "" {
+			sb.WriteString(truncateAgentContent(nextGoal.Description, 300) + "\n")
+		}
+		if len(nextGoal.OpenTasks) > 0 {
+			sb.WriteString("Open tasks:\n")
+			for _, task := range nextGoal.OpenTasks {
+				sb.WriteString(fmt.Sprintf("- `%s` %s\n", task.ID, task.Title))
+			}
+		}
+		sb.WriteString("Work on it, mark its tasks done with `manage_tasks`, and use the `roadmap` tool to update the goal.\n")
+	case len(pending) > 0:
+		// In-progress and high-priority tasks first
+		slices.SortStableFunc(pending, func(a, b tasks.Task) int {
+			return taskRank(a) - taskRank(b)
+		})
+		sb.WriteString("There are pending tasks. Continue from where you left off:\n")
+		for _, task := range pending[:min(len(pending), 5)] {
+			sb.WriteString(fmt.Sprintf("- `%s` %s (%s, %s priority)\n", task.ID, task.Title, task.Status, task.Priority))
+		}
+		if len(pending) > 5 {
+			sb.WriteString(fmt.Sprintf("...and %d more; use `manage_tasks` to list them.\n", len(pending)-5))
+		}
+		sb.WriteString("Update their status by ID with `manage_tasks`.\n")
 	}
 
 	sb.WriteString("\nRules:\n")
@@ -1916,7 +2178,7 @@ func (a *Agent) performDeepReflection(health *health.Status) {
 	a.logger.Info("🧠 Performing deep reflection...")
 
 	// Read roadmap to check strategic goals
-	_, err := a.roadmap.Read()
+	roadmapSummary, err := a.roadmap.GetSummary()
 	if err != nil {
 		a.logger.Error("Failed to read roadmap: %v", err)
 		return
@@ -1941,7 +2203,7 @@ You are performing a deep reflection on the project state. Please analyze:
 Based on this analysis:
 - Identify patterns or recurring issues
 - Suggest strategic improvements
-- Update roadmap if priorities have shifted
+- Update roadmap goals with the roadmap tool if priorities or dependencies have shifted
 - Record important insights in brain.md
 
 Keep response concise and actionable.
@@ -1955,7 +2217,8 @@ Keep response concise and actionable.
 - Tasks: %d pending
 
 **Brain Summary:** %d recent entries
-**Roadmap Summary:** Available for review
+**Roadmap Summary:**
+%s
 
 Please perform reflection and suggest next steps.`,
 		health.BuildStatus,
@@ -1963,7 +2226,8 @@ Please perform reflection and suggest next steps.`,
 		health.GitStatus,
 		health.GitUncommitted,
 		health.PendingTasks,
-		strings.Count(brainContent, "###"))
+		strings.Count(brainContent, "###"),
+		roadmapSummary)
 
 	// Execute reflection (async, don't block heartbeat).
 	// Abort if user became active before the goroutine starts.
@@ -1972,7 +2236,7 @@ Please perform reflection and suggest next steps.`,
 			a.logger.Info("Deep reflection skipped (agent busy)")
 			return
 		}
-		resp, err := a.Chat(reflectionPrompt)
+		resp, err := a.chat("heartbeat", reflectionPrompt)
 		if err != nil {
 			a.logger.Error("Deep reflection failed: %v", err)
 		} else {
@@ -2045,9 +2309,7 @@ func (a *Agent) continueResponse(messages []llm.Message, currentContent string)
 		}
 
 		// Accumulate usage from continuation
-		a.totalUsage.PromptTokens += resp.Usage.PromptTokens
-		a.totalUsage.CompletionTokens += resp.Usage.CompletionTokens
-		a.totalUsage.TotalTokens += resp.Usage.TotalTokens
+		a.recordUsage(resp.Usage)
 		a.lastRateLimits = resp.RateLimits
 		a.sessionMgr.UpdateTokenUsage(resp.Usage.PromptTokens)
 
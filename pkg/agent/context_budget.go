@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"math"
+
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/prompts"
+	"ClosedWheeler/pkg/secrets"
+)
+
+// Share of the context window each optional system prompt section may use
+// before it is truncated. History gets whatever remains.
+const (
+	rulesShare   = 0.15
+	projectShare = 0.10
+	memoryShare  = 0.10
+)
+
+// defaultCompletionReserve is held back for the reply when max_tokens is unset.
+const defaultCompletionReserve = 4096
+
+// ContextSection is the token usage of one part of the prompt.
+type ContextSection struct {
+	Name    string
+	Tokens  int
+	Trimmed bool // content was truncated or partly dropped to fit
+}
+
+// ContextBudget describes how the last prompt was fitted into the model's
+// context window.
+type ContextBudget struct {
+	Window          int    // model context window in tokens
+	Reserved        int    // tokens held back for the completion
+	Tokenizer       string // encoding used for counting
+	Sections        []ContextSection
+	DroppedMessages int // oldest history messages left out of the request
+}
+
+// Used returns the prompt tokens across all sections.
+func (b ContextBudget) Used() int {
+	total := 0
+	for _, s := range b.Sections {
+		total += s.Tokens
+	}
+	return total
+}
+
+// promptParts are the raw inputs assembled into a request.
+type promptParts struct {
+	ctx           prompts.Context
+	toolsSummary  string
+	rules         string
+	project       string
+	memory        string // long-term memory summary
+	history       []llm.Message
+	toolDefs      []llm.ToolDefinition
+	includeSystem bool // send the system prompt (see SessionManager.NeedsContextRefresh)
+}
+
+// contextBudgeter fits prompt parts into a token budget. A window of 0
+// means the model's window is unknown; everything is sent and only counted.
+type contextBudgeter struct {
+	tok     llm.Tokenizer
+	window  int
+	reserve int
+}
+
+// assemble builds the request messages. The base system prompt, tool
+// definitions and the latest message are always sent; rules, project summary
+// and memory are truncated to their share of the window; history is filled
+// newest first with whatever budget remains.
+func (b *contextBudgeter) assemble(p promptParts) ([]llm.Message, ContextBudget) {
+	budget := ContextBudget{Window: b.window, Reserved: b.reserve, Tokenizer: b.tok.Name()}
+	available := b.window - b.reserve
+	if b.window <= 0 {
+		available = math.MaxInt32
+	}
+
+	toolTokens := llm.CountToolTokens(b.tok, p.toolDefs)
+	available -= toolTokens
+
+	var base string
+	if p.includeSystem {
+		base = prompts.NewBuilder(p.ctx).WithToolsSummary(p.toolsSummary).Build()
+		available -= llm.CountMessage(b.tok, llm.Message{Role: "system", Content: base})
+	}
+
+	// The latest message is the one being answered and is never dropped.
+	var latestTokens int
+	if n := len(p.history); n > 0 {
+		latestTokens = llm.CountMessage(b.tok, p.history[n-1])
+		available -= latestTokens
+	}
+
+	fit := func(name, text string, share float64) (string, ContextSection) {
+		if !p.includeSystem || text == "" {
+			return "", ContextSection{Name: name}
+		}
+		limit := int(float64(b.window) * share)
+		if b.window <= 0 || limit > available {
+			limit = available
+		}
+		fitted := llm.TruncateToTokens(b.tok, text, limit)
+		tokens := b.tok.Count(fitted)
+		available -= tokens
+		return fitted, ContextSection{Name: name, Tokens: tokens, Trimmed: len(fitted) < len(text)}
+	}
+	rules, rulesSection := fit("rules", p.rules, rulesShare)
+	project, projectSection := fit("project", p.project, projectShare)
+	memory, memorySection := fit("memory", p.memory, memoryShare)
+
+	// History, newest first, skipping the latest message counted above.
+	start := len(p.history)
+	historyTokens := latestTokens
+	if start > 0 {
+		start--
+		for start > 0 {
+			cost := llm.CountMessage(b.tok, p.history[start-1])
+			if cost > available {
+				break
+			}
+			available -= cost
+			historyTokens += cost
+			start--
+		}
+	}
+	budget.DroppedMessages = start
+
+	var messages []llm.Message
+	systemTokens := 0
+	if p.includeSystem {
+		systemPrompt := prompts.NewBuilder(p.ctx).
+			WithToolsSummary(p.toolsSummary).
+			WithProjectInfo(project).
+			WithHistory(memory).
+			WithCustomInstructions(rules).
+			Build()
+		// The sections quote project files, which only lose secrets of a
+		// known format; the heuristics would also change ordinary code
+		messages = append(messages, llm.Message{Role: "system", Content: secrets.RedactKnown(systemPrompt)})
+		// The base share is whatever the full prompt costs beyond its sections.
+		systemTokens = llm.CountMessage(b.tok, messages[0]) - rulesSection.Tokens - projectSection.Tokens - memorySection.Tokens
+		if systemTokens < 0 {
+			systemTokens = 0
+		}
+	}
+	// Secrets in rules, project files or the conversation stay out of the prompt
+	for _, msg := range p.history[start:] {
+		msg.Content = secrets.Redact(msg.Content)
+		messages = append(messages, msg)
+	}
+
+	budget.Sections = []ContextSection{
+		{Name: "system", Tokens: systemTokens},
+		{Name: "tools", Tokens: toolTokens},
+		rulesSection,
+		projectSection,
+		memorySection,
+		{Name: "history", Tokens: historyTokens, Trimmed: start > 0},
+	}
+	return messages, budget
+}
+
+// newBudgeter returns a budgeter for the active model's context window.
+// When the window is unknown nothing is trimmed ahead of time; see shrink.
+func (a *Agent) newBudgeter() *contextBudgeter {
+	window := a.contextWindow()
+	reserve := 0
+	if window > 0 {
+		reserve = defaultCompletionReserve
+		if a.config.MaxTokens != nil && *a.config.MaxTokens > 0 {
+			reserve = *a.config.MaxTokens
+		}
+		if reserve > window/2 {
+			reserve = window / 2
+		}
+	}
+	return &contextBudgeter{tok: llm.TokenizerForModel(a.config.Model), window: window, reserve: reserve}
+}
+
+// shrink returns a budgeter for the retry after the provider rejected a
+// prompt as too long: 70% of the window, or of the rejected prompt when the
+// window is unknown.
+func (b *contextBudgeter) shrink(rejected ContextBudget) *contextBudgeter {
+	if b.window <= 0 {
+		return &contextBudgeter{tok: b.tok, window: rejected.Used() * 7 / 10}
+	}
+	return &contextBudgeter{tok: b.tok, window: b.window * 7 / 10, reserve: b.reserve * 7 / 10}
+}
+
+// contextWindow returns the active model's context window from
+// model_parameters or its profile, or 0 when neither knows it. The profile
+// is looked up once per model.
+func (a *Agent) contextWindow() int {
+	if params, ok := a.config.ModelParameters[a.config.Model]; ok && params.ContextWindow > 0 {
+		return params.ContextWindow
+	}
+	a.budgetMu.Lock()
+	defer a.budgetMu.Unlock()
+	if a.windowModel != a.config.Model {
+		a.windowModel = a.config.Model
+		a.profileWindow = 0
+		if profile, ok := llm.LookupModelProfile(a.config.Model); ok {
+			a.profileWindow = profile.ContextWindow
+		}
+	}
+	return a.profileWindow
+}
+
+// historyMessages returns short-term memory as LLM messages.
+func (a *Agent) historyMessages() []llm.Message {
+	stored := a.memory.GetMessages()
+	messages := make([]llm.Message, 0, len(stored))
+	for _, msg := range stored {
+		messages = append(messages, llm.Message{Role: msg["role"], Content: msg["content"]})
+	}
+	return messages
+}
+
+// setContextBudget records the budget of the request just assembled.
+func (a *Agent) setContextBudget(budget ContextBudget) {
+	a.budgetMu.Lock()
+	a.lastBudget = budget
+	a.budgetMu.Unlock()
+}
+
+// GetContextBudget returns the per-section token usage of the last request.
+// Before the first request it reports what the next one would use.
+func (a *Agent) GetContextBudget() ContextBudget {
+	a.budgetMu.Lock()
+	budget := a.lastBudget
+	a.budgetMu.Unlock()
+	if budget.Tokenizer != "" {
+		return budget
+	}
+
+	_, budget = a.newBudgeter().assemble(promptParts{
+		ctx:           prompts.ContextGeneral,
+		toolsSummary:  a.getToolsSummary(),
+		rules:         a.rules.GetFormattedRules(),
+		project:       a.project.GetSummary(),
+		memory:        a.getContextSummary(""),
+		history:       a.historyMessages(),
+		toolDefs:      a.getToolDefinitions(),
+		includeSystem: true,
+	})
+	return budget
+}
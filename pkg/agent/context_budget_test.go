@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/prompts"
+)
+
+func TestContextBudgeter_FitsWindow(t *testing.T) {
+	tok := llm.TokenizerForModel("llama3")
+
+	var history []llm.Message
+	for i := 0; i < 200; i++ {
+		history = append(history, llm.Message{Role: "user", Content: fmt.Sprintf("message %d %s", i, strings.Repeat("word ", 40))})
+	}
+	history = append(history, llm.Message{Role: "user", Content: "latest question"})
+
+	b := &contextBudgeter{tok: tok, window: 8000, reserve: 1000}
+	messages, budget := b.assemble(promptParts{
+		ctx:           prompts.ContextGeneral,
+		rules:         strings.Repeat("Always write tests.\n", 2000),
+		project:       "Go module ClosedWheeler",
+		history:       history,
+		includeSystem: true,
+	})
+
+	if got := llm.CountMessageTokens(tok, messages); got > budget.Window-budget.Reserved {
+		t.Fatalf("prompt uses %d tokens, budget is %d", got, budget.Window-budget.Reserved)
+	}
+	if messages[0].Role != "system" {
+		t.Fatalf("first message should be the system prompt, got %q", messages[0].Role)
+	}
+	if last := messages[len(messages)-1]; last.Content != "latest question" {
+		t.Fatalf("latest message must always be sent, got %q", last.Content)
+	}
+	if budget.DroppedMessages == 0 {
+		t.Fatal("expected oldest history to be dropped")
+	}
+	if len(messages) != 1+len(history)-budget.DroppedMessages {
+		t.Fatalf("message count %d does not match %d dropped", len(messages), budget.DroppedMessages)
+	}
+
+	sections := map[string]ContextSection{}
+	for _, s := range budget.Sections {
+		sections[s.Name] = s
+	}
+	if rules := sections["rules"]; !rules.Trimmed || rules.Tokens > int(8000*rulesShare) {
+		t.Fatalf("rules should be trimmed to their share: %+v", rules)
+	}
+	if project := sections["project"]; project.Trimmed || project.Tokens == 0 {
+		t.Fatalf("project summary should fit untouched: %+v", project)
+	}
+	if !sections["history"].Trimmed {
+		t.Fatal("history section should be marked trimmed")
+	}
+}
+
+func TestContextBudgeter_CachedSystemPrompt(t *testing.T) {
+	b := &contextBudgeter{tok: llm.TokenizerForModel("llama3"), window: 8000, reserve: 1000}
+	messages, budget := b.assemble(promptParts{
+		ctx:     prompts.ContextGeneral,
+		rules:   "Always write tests.",
+		history: []llm.Message{{Role: "user", Content: "hi"}},
+	})
+
+	if len(messages) != 1 || messages[0].Role != "user" {
+		t.Fatalf("expected only the user message, got %+v", messages)
+	}
+	if budget.DroppedMessages != 0 || budget.Sections[0].Tokens != 0 || budget.Sections[2].Tokens != 0 {
+		t.Fatalf("system sections should be empty when not sent: %+v", budget)
+	}
+}
+
+func TestContextBudgeter_UnknownWindow(t *testing.T) {
+	var history []llm.Message
+	for i := 0; i < 50; i++ {
+		history = append(history, llm.Message{Role: "user", Content: fmt.Sprintf("message %d %s", i, strings.Repeat("word ", 40))})
+	}
+	parts := promptParts{
+		ctx:           prompts.ContextGeneral,
+		rules:         strings.Repeat("Always write tests.\n", 500),
+		history:       history,
+		includeSystem: true,
+	}
+
+	b := &contextBudgeter{tok: llm.TokenizerForModel("deepseek-chat")}
+	messages, budget := b.assemble(parts)
+	if len(messages) != 1+len(history) || budget.DroppedMessages != 0 {
+		t.Fatalf("unknown window should send everything: %d messages, %d dropped", len(messages), budget.DroppedMessages)
+	}
+	for _, s := range budget.Sections {
+		if s.Trimmed {
+			t.Fatalf("section %s trimmed without a known window", s.Name)
+		}
+	}
+
+	// After a context-length error the retry fits 70% of the rejected prompt
+	messages, retry := b.shrink(budget).assemble(parts)
+	if retry.Used() > budget.Used()*7/10 {
+		t.Fatalf("retry uses %d of %d tokens", retry.Used(), budget.Used())
+	}
+	if last := messages[len(messages)-1]; last.Content != history[len(history)-1].Content {
+		t.Fatal("latest message must always be sent")
+	}
+}
@@ -0,0 +1,369 @@
+// Package agent provides cost tracking and spending limits
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/utils"
+)
+
+// ErrSpendingPaused is returned by Chat while a hard budget limit is exceeded.
+var ErrSpendingPaused = errors.New("spending limit reached")
+
+// UsageRecord is one LLM call in the cost ledger
+type UsageRecord struct {
+	Time             time.Time `json:"time"`
+	SessionID        string    `json:"session_id"`
+	Model            string    `json:"model"`
+	Provider         string    `json:"provider,omitempty"`
+	Role             string    `json:"role"` // "main", "heartbeat", "debate", "memory" or a pipeline role
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost_usd"`
+	Unpriced         bool      `json:"unpriced,omitempty"` // no price known for the model
+}
+
+// CostSummary aggregates usage records
+type CostSummary struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+	Unpriced         int // calls whose model has no known price
+	ByModel          map[string]float64
+	ByRole           map[string]float64
+}
+
+// CostLedger is an append-only log of LLM usage persisted as JSON lines
+// (.agi/usage.jsonl). Records stay on disk; the ledger keeps running costs
+// per session and day so budget checks need not read them back.
+type CostLedger struct {
+	path        string
+	mu          sync.Mutex
+	sessionCost map[string]float64 // by session ID
+	dayCost     map[string]float64 // by local date, see costDay
+	resumed     bool               // hard limits lifted by the user for the rest of the run
+}
+
+var (
+	ledgersMu sync.Mutex
+	ledgers   = map[string]*CostLedger{}
+)
+
+// OpenCostLedger returns the ledger stored at path, loading existing
+// records. Agents using the same path (e.g. debate clones) share one ledger.
+func OpenCostLedger(path string) *CostLedger {
+	ledgersMu.Lock()
+	defer ledgersMu.Unlock()
+
+	if l, ok := ledgers[path]; ok {
+		return l
+	}
+	l := &CostLedger{path: path}
+	l.load()
+	ledgers[path] = l
+	return l
+}
+
+// load totals the existing records.
+func (l *CostLedger) load() {
+	l.each(l.count)
+}
+
+// each calls fn for every record on disk, skipping lines that fail to
+// parse. Callers hold l.mu or own the ledger.
+func (l *CostLedger) each(fn func(UsageRecord)) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r UsageRecord
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			fn(r)
+		}
+	}
+}
+
+// count adds a record to the running costs.
+func (l *CostLedger) count(r UsageRecord) {
+	if l.sessionCost == nil {
+		l.sessionCost = make(map[string]float64)
+		l.dayCost = make(map[string]float64)
+	}
+	l.sessionCost[r.SessionID] += r.Cost
+	l.dayCost[costDay(r.Time)] += r.Cost
+}
+
+// costDay returns the local calendar day of t.
+func costDay(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
+
+// Append adds a record and writes it to disk.
+func (l *CostLedger) Append(r UsageRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.count(r)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Totals returns the cost of a session and of the local calendar day
+// containing t without reading the records.
+func (l *CostLedger) Totals(sessionID string, t time.Time) (session, day float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sessionCost[sessionID], l.dayCost[costDay(t)]
+}
+
+// Summarize aggregates the records accepted by match. It reads the whole
+// ledger from disk, so it is meant for reports; use Totals for checks.
+func (l *CostLedger) Summarize(match func(UsageRecord) bool) CostSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := CostSummary{ByModel: map[string]float64{}, ByRole: map[string]float64{}}
+	l.each(func(r UsageRecord) {
+		if !match(r) {
+			return
+		}
+		s.Calls++
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+		s.Cost += r.Cost
+		s.ByModel[r.Model] += r.Cost
+		s.ByRole[r.Role] += r.Cost
+		if r.Unpriced {
+			s.Unpriced++
+		}
+	})
+	return s
+}
+
+// SessionSummary aggregates the records of one session.
+func (l *CostLedger) SessionSummary(sessionID string) CostSummary {
+	return l.Summarize(func(r UsageRecord) bool { return r.SessionID == sessionID })
+}
+
+// DaySummary aggregates the records of the local calendar day containing t.
+func (l *CostLedger) DaySummary(t time.Time) CostSummary {
+	y, m, d := t.Date()
+	return l.Summarize(func(r UsageRecord) bool {
+		ry, rm, rd := r.Time.Local().Date()
+		return ry == y && rm == m && rd == d
+	})
+}
+
+// SpendingStatus is the cost of the current session and day against the
+// configured limits.
+type SpendingStatus struct {
+	Session     CostSummary
+	Today       CostSummary
+	SessionSoft float64
+	SessionHard float64
+	DailySoft   float64
+	DailyHard   float64
+	Paused      string // reason the agent is paused, empty when running
+}
+
+// costSessionID returns the session usage is attributed to. Debate clones
+// have no saved session of their own and use their parent's.
+func (a *Agent) costSessionID() string {
+	if id := a.CurrentSessionID(); id != "" {
+		return id
+	}
+	return a.parentSessionID
+}
+
+// setCostRole labels usage recorded from now on (e.g. "heartbeat" or a
+// pipeline role). An empty role restores the default. Callers hold mu, the
+// turn lock, so only the running turn is labelled.
+func (a *Agent) setCostRole(role string) {
+	a.costMu.Lock()
+	a.costRole = role
+	a.costMu.Unlock()
+}
+
+// recordCost prices usage for the active model, appends it to the ledger and
+// enforces the budget. An empty role uses the current cost role.
+func (a *Agent) recordCost(role string, u llm.Usage) {
+	if a.costLedger == nil || (u.PromptTokens == 0 && u.CompletionTokens == 0) {
+		return
+	}
+
+	overrides := make(map[string]llm.ModelPricing, len(a.config.Pricing))
+	for model, p := range a.config.Pricing {
+		overrides[model] = llm.ModelPricing{Input: p.Input, Output: p.Output}
+	}
+	provider, model := a.config.Provider, a.config.Model
+	if a.llm != nil {
+		provider, model = a.llm.ActiveModel()
+	}
+	price, known := llm.PricingForModel(provider, model, overrides)
+
+	if role == "" {
+		a.costMu.Lock()
+		role = a.costRole
+		a.costMu.Unlock()
+	}
+	if role == "" {
+		role = a.defaultCostRole
+	}
+
+	record := UsageRecord{
+		Time:             time.Now(),
+		SessionID:        a.costSessionID(),
+		Model:            model,
+		Provider:         provider,
+		Role:             role,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Cost:             price.Cost(u),
+		Unpriced:         !known,
+	}
+	if err := a.costLedger.Append(record); err != nil {
+		a.logger.Error("Failed to record usage: %v", err)
+	}
+
+	a.checkSoftLimits()
+}
+
+// backgroundQuery sends a one-off prompt for internal bookkeeping (memory
+// compression, insight extraction) and bills it to the "memory" role.
+func (a *Agent) backgroundQuery(prompt string, temperature float64, maxTokens int) (string, error) {
+	messages := []llm.Message{{Role: "user", Content: prompt}}
+	// The same bookkeeping prompt often recurs; let the response cache serve it.
+	resp, err := a.llm.ChatWithToolsContext(llm.WithCache(context.Background()), messages, nil, utils.FloatPtr(temperature), nil, utils.IntPtr(maxTokens))
+	if err != nil {
+		return "", err
+	}
+
+	a.costMu.Lock()
+	a.totalUsage.PromptTokens += resp.Usage.PromptTokens
+	a.totalUsage.CompletionTokens += resp.Usage.CompletionTokens
+	a.totalUsage.TotalTokens += resp.Usage.TotalTokens
+	a.costMu.Unlock()
+	a.recordCost("memory", resp.Usage)
+
+	return a.llm.GetContent(resp), nil
+}
+
+// GetSpendingStatus returns session and daily cost against the limits. It
+// reads the whole ledger, for /cost reports.
+func (a *Agent) GetSpendingStatus() SpendingStatus {
+	b := a.config.Budget
+	status := SpendingStatus{
+		SessionSoft: b.SessionSoft,
+		SessionHard: b.SessionHard,
+		DailySoft:   b.DailySoft,
+		DailyHard:   b.DailyHard,
+	}
+	if a.costLedger == nil {
+		return status
+	}
+	status.Session = a.costLedger.SessionSummary(a.costSessionID())
+	status.Today = a.costLedger.DaySummary(time.Now())
+	status.Paused = a.pausedReason(status.Session.Cost, status.Today.Cost)
+	return status
+}
+
+// pausedReason explains which hard limit the session and daily cost
+// exceed, or returns "" when spending may continue.
+func (a *Agent) pausedReason(session, today float64) string {
+	a.costLedger.mu.Lock()
+	resumed := a.costLedger.resumed
+	a.costLedger.mu.Unlock()
+	if resumed {
+		return ""
+	}
+	b := a.config.Budget
+	switch {
+	case b.SessionHard > 0 && session >= b.SessionHard:
+		return fmt.Sprintf("session cost $%.2f reached the $%.2f limit", session, b.SessionHard)
+	case b.DailyHard > 0 && today >= b.DailyHard:
+		return fmt.Sprintf("today's cost $%.2f reached the $%.2f limit", today, b.DailyHard)
+	}
+	return ""
+}
+
+// SpendingPaused reports whether a hard limit is exceeded. Chat, the
+// heartbeat and debates do not call the LLM while paused.
+func (a *Agent) SpendingPaused() (bool, string) {
+	if a.costLedger == nil {
+		return false, ""
+	}
+	reason := a.pausedReason(a.costLedger.Totals(a.costSessionID(), time.Now()))
+	return reason != "", reason
+}
+
+// ResumeSpending lifts a hard-limit pause for the rest of this run, for
+// this agent and its debate clones. Soft limit warnings still apply.
+func (a *Agent) ResumeSpending() {
+	if a.costLedger == nil {
+		return
+	}
+	a.costLedger.mu.Lock()
+	a.costLedger.resumed = true
+	a.costLedger.mu.Unlock()
+	a.logger.Info("Spending limits overridden by user")
+}
+
+// checkSoftLimits warns once per session or day when a soft limit is crossed.
+func (a *Agent) checkSoftLimits() {
+	session, today := a.costLedger.Totals(a.costSessionID(), time.Now())
+	paused := a.pausedReason(session, today)
+	b := a.config.Budget
+
+	var warnings []string
+	a.costMu.Lock()
+	if a.softWarned == nil {
+		a.softWarned = make(map[string]bool)
+	}
+	sessionKey := "session:" + a.costSessionID()
+	if b.SessionSoft > 0 && session >= b.SessionSoft && !a.softWarned[sessionKey] {
+		a.softWarned[sessionKey] = true
+		warnings = append(warnings, fmt.Sprintf("💸 Session cost $%.2f passed the $%.2f soft limit", session, b.SessionSoft))
+	}
+	dayKey := "day:" + costDay(time.Now())
+	if b.DailySoft > 0 && today >= b.DailySoft && !a.softWarned[dayKey] {
+		a.softWarned[dayKey] = true
+		warnings = append(warnings, fmt.Sprintf("💸 Today's cost $%.2f passed the $%.2f soft limit", today, b.DailySoft))
+	}
+	if paused != "" && !a.softWarned["paused"] {
+		a.softWarned["paused"] = true
+		warnings = append(warnings, "⛔ Spending paused: "+paused+" (/cost resume to continue)")
+	}
+	a.costMu.Unlock()
+
+	for _, w := range warnings {
+		a.logger.Info("%s", w)
+		a.statusCallback(w)
+	}
+}
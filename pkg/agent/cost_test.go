@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/logger"
+)
+
+func TestCostLedger_AppendAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	ledger := &CostLedger{path: path}
+
+	now := time.Now()
+	records := []UsageRecord{
+		{Time: now, SessionID: "s1", Model: "gpt-4o", Role: "main", PromptTokens: 1000, CompletionTokens: 100, Cost: 0.01},
+		{Time: now, SessionID: "s1", Model: "gpt-4o-mini", Role: "coder", PromptTokens: 500, CompletionTokens: 50, Cost: 0.002},
+		{Time: now.Add(-48 * time.Hour), SessionID: "s0", Model: "gpt-4o", Role: "main", Cost: 1},
+	}
+	for _, r := range records {
+		if err := ledger.Append(r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	reloaded := &CostLedger{path: path}
+	reloaded.load()
+	if all := reloaded.Summarize(func(UsageRecord) bool { return true }); all.Calls != len(records) {
+		t.Fatalf("reloaded %d records, want %d", all.Calls, len(records))
+	}
+	if session, day := reloaded.Totals("s1", now); session != 0.012 || day != 0.012 {
+		t.Fatalf("Totals(s1) = %v, %v, want the running cost of s1 and today", session, day)
+	}
+	if session, _ := reloaded.Totals("s0", now); session != 1 {
+		t.Fatalf("Totals(s0) = %v, want 1", session)
+	}
+
+	session := reloaded.SessionSummary("s1")
+	if session.Calls != 2 || session.PromptTokens != 1500 || session.CompletionTokens != 150 {
+		t.Fatalf("unexpected session summary: %+v", session)
+	}
+	if session.ByRole["coder"] != 0.002 || session.ByModel["gpt-4o"] != 0.01 {
+		t.Fatalf("unexpected breakdown: %+v %+v", session.ByModel, session.ByRole)
+	}
+
+	today := reloaded.DaySummary(now)
+	if today.Calls != 2 {
+		t.Fatalf("today should exclude older records, got %d calls", today.Calls)
+	}
+}
+
+func TestRecordCost_HardLimitPausesUntilResumed(t *testing.T) {
+	dir := t.TempDir()
+	log, err := logger.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	a := &Agent{
+		config: &config.Config{
+			Model: "gpt-4o",
+			Budget: config.BudgetConfig{
+				SessionSoft: 0.01,
+				SessionHard: 0.05,
+			},
+		},
+		logger:          log,
+		statusCallback:  func(s string) { warnings = append(warnings, s) },
+		costLedger:      OpenCostLedger(filepath.Join(dir, "usage.jsonl")),
+		defaultCostRole: "main",
+	}
+
+	// 10k prompt tokens of gpt-4o cost $0.025.
+	a.recordCost("", llm.Usage{PromptTokens: 10_000})
+	if paused, _ := a.SpendingPaused(); paused {
+		t.Fatal("should not pause below the hard limit")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "soft limit") {
+		t.Fatalf("expected one soft limit warning, got %q", warnings)
+	}
+
+	a.recordCost("", llm.Usage{PromptTokens: 10_000})
+	paused, reason := a.SpendingPaused()
+	if !paused || !strings.Contains(reason, "session cost") {
+		t.Fatalf("expected a session pause, got %v %q", paused, reason)
+	}
+	if _, err := a.Chat("hello"); !errors.Is(err, ErrSpendingPaused) {
+		t.Fatalf("Chat should refuse while paused, got %v", err)
+	}
+
+	a.ResumeSpending()
+	if paused, _ := a.SpendingPaused(); paused {
+		t.Fatal("resume should lift the pause")
+	}
+	if got := a.GetSpendingStatus().Session.ByRole["main"]; got < 0.049 || got > 0.051 {
+		t.Fatalf("main role cost = %v, want 0.05", got)
+	}
+}
+
+func TestRecordCost_ConfigPricingOverride(t *testing.T) {
+	dir := t.TempDir()
+	log, err := logger.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{
+		config: &config.Config{
+			Model:   "my-finetune",
+			Pricing: map[string]config.ModelPricing{"my-finetune": {Input: 1, Output: 2}},
+		},
+		logger:          log,
+		statusCallback:  func(string) {},
+		costLedger:      OpenCostLedger(filepath.Join(dir, "usage.jsonl")),
+		defaultCostRole: "main",
+	}
+
+	a.setCostRole("heartbeat")
+	a.recordCost("", llm.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000})
+	summary := a.GetSpendingStatus().Session
+	if summary.Cost != 3 || summary.Unpriced != 0 || summary.ByRole["heartbeat"] != 3 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"ClosedWheeler/pkg/editor"
+)
+
+// SetEditReviewer installs the UI that reviews file changes when
+// review.enabled is set. Without a reviewer, changes that need review fail
+// and are not written.
+func (a *Agent) SetEditReviewer(r editor.Reviewer) {
+	a.editReviewer = r
+	a.applyEditReview()
+}
+
+// applyEditReview configures the edit manager from the review config.
+func (a *Agent) applyEditReview() {
+	if !a.config.Review.Enabled {
+		a.editManager.SetReview(nil, nil)
+		return
+	}
+	allowed := a.config.Review.AllowedPaths
+	edits := a.editManager
+	a.editManager.SetReview(func(path string) bool {
+		rel := edits.RelPath(path)
+		for _, pattern := range allowed {
+			if editor.MatchPath(pattern, rel) {
+				return false
+			}
+		}
+		return true
+	}, a.editReviewer)
+}
+
+// EditReviewRequired reports whether file changes currently wait for review.
+func (a *Agent) EditReviewRequired() bool {
+	return a.config.Review.Enabled
+}
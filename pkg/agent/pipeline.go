@@ -108,6 +108,10 @@ func (p *MultiAgentPipeline) initBridge() {
 		p.base.executor,
 		p.base.logger,
 	)
+	// Usage is attributed to the role currently running (see Run).
+	p.bridge.ModelAdapter().SetUsageCallback(p.base.recordUsage)
+	// Role tool calls go through the permission policy like the agent's own
+	p.bridge.SetToolPolicy(p.base.authorizeTool)
 
 	// Build the role prompt function that adapts our roleSystemPrompt.
 	rolePrompt := func(role string) string {
@@ -128,6 +132,7 @@ func (p *MultiAgentPipeline) Run(ctx context.Context, userMessage string) (strin
 
 	// Lazy-init the bridge and chain on first use.
 	p.initBridge()
+	defer p.base.setCostRole("")
 
 	var lastCriticFeedback string
 
@@ -145,9 +150,9 @@ func (p *MultiAgentPipeline) Run(ctx context.Context, userMessage string) (strin
 		// Create event bridge with TUI callbacks.
 		// Wrap the AgentRole-based statusCb into a string-based callback
 		// to avoid an import cycle (trpcbridge cannot import agent).
-		var roleCb trpcbridge.PipelineRoleCallback
-		if p.statusCb != nil {
-			roleCb = func(role string, status string) {
+		roleCb := func(role string, status string) {
+			p.base.setCostRole(role)
+			if p.statusCb != nil {
 				p.statusCb(AgentRole(role), status)
 			}
 		}
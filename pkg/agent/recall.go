@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/memory"
+)
+
+const (
+	defaultRecallTopK  = 5
+	defaultVectorsPath = ".agi/vectors.json"
+
+	// recallTimeout bounds embedding calls made before a request.
+	recallTimeout = 15 * time.Second
+
+	// maxRecalledChars truncates each recalled entry in the prompt.
+	maxRecalledChars = 600
+
+	// brainDocPrefix starts the document IDs of brain entries.
+	brainDocPrefix = "brain:"
+)
+
+// newRecallStore creates the vector store behind memory retrieval, or nil
+// when retrieval is disabled. Without an embedding model, or once the
+// embeddings API fails, texts are embedded locally.
+func newRecallStore(cfg *config.Config) *memory.VectorStore {
+	r := cfg.Memory.Retrieval
+	if !r.Enabled {
+		return nil
+	}
+	var embedder llm.Embedder = llm.NewHashEmbedder(0)
+	if r.EmbeddingModel != "" {
+		baseURL, apiKey := r.BaseURL, r.APIKey
+		if baseURL == "" {
+			baseURL = cfg.APIBaseURL
+		}
+		if apiKey == "" {
+			apiKey = cfg.APIKey
+		}
+		embedder = llm.NewFallbackEmbedder(llm.NewAPIEmbedder(baseURL, apiKey, r.EmbeddingModel), embedder)
+	}
+	path := r.StoragePath
+	if path == "" {
+		path = defaultVectorsPath
+	}
+	return memory.NewVectorStore(path, embedder)
+}
+
+// recallDocuments gathers the long-term memories and brain entries that
+// retrieval chooses from.
+func (a *Agent) recallDocuments() []memory.Document {
+	var docs []memory.Document
+	for _, item := range a.memory.LongTermItems() {
+		docs = append(docs, memory.Document{ID: "memory:" + item.ID, Kind: item.Type, Text: item.Content})
+	}
+	if a.brain != nil {
+		entries, err := a.brain.Entries()
+		if err != nil {
+			a.logger.Error("Failed to read brain entries: %v", err)
+		}
+		for _, e := range entries {
+			docs = append(docs, memory.Document{
+				ID:   brainDocPrefix + e.ID,
+				Kind: "brain:" + e.Category,
+				Text: e.Title + "\n" + e.Description,
+			})
+		}
+	}
+	return docs
+}
+
+// recall returns the documents most related to query. A failed embedding
+// call is retried once, since the embedder may have fallen back to the local
+// one.
+func (a *Agent) recall(query string) ([]memory.Hit, error) {
+	topK := a.config.Memory.Retrieval.TopK
+	if topK <= 0 {
+		topK = defaultRecallTopK
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, recallTimeout)
+	defer cancel()
+
+	docs := a.recallDocuments()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = a.recallStore.Sync(ctx, docs); err != nil {
+			a.logger.Error("Memory retrieval: %v", err)
+			continue
+		}
+		var hits []memory.Hit
+		if hits, err = a.recallStore.Search(ctx, query, topK); err == nil {
+			a.markBrainUsed(hits)
+			return hits, nil
+		}
+		a.logger.Error("Memory retrieval: %v", err)
+	}
+	return nil, err
+}
+
+// markBrainUsed counts a use of the brain entries among hits.
+func (a *Agent) markBrainUsed(hits []memory.Hit) {
+	if a.brain == nil {
+		return
+	}
+	var ids []string
+	for _, h := range hits {
+		if id, ok := strings.CutPrefix(h.ID, brainDocPrefix); ok {
+			ids = append(ids, id)
+		}
+	}
+	if err := a.brain.MarkUsed(ids...); err != nil {
+		a.logger.Error("Failed to update brain usage: %v", err)
+	}
+}
+
+// getContextSummary returns the memory section of the system prompt. With
+// retrieval enabled it holds the long-term memories and brain entries most
+// related to the user message; otherwise the latest long-term memories.
+func (a *Agent) getContextSummary(query string) string {
+	if a.recallStore == nil || strings.TrimSpace(query) == "" {
+		return a.memory.GetContext()
+	}
+	hits, err := a.recall(query)
+	if err != nil {
+		return a.memory.GetContext()
+	}
+
+	var sb strings.Builder
+	if len(hits) > 0 {
+		sb.WriteString("### 🧠 Relevant Memory (Lessons, Decisions & Knowledge)\n")
+		for _, h := range hits {
+			text := h.Text
+			if len(text) > maxRecalledChars {
+				text = strings.ToValidUTF8(text[:maxRecalledChars], "") + "..."
+			}
+			switch {
+			case h.Kind == "decision":
+				sb.WriteString(fmt.Sprintf("📍 [Decision] %s\n", text))
+			case strings.HasPrefix(h.Kind, "brain:"):
+				title, body, _ := strings.Cut(text, "\n")
+				body = strings.Join(strings.Fields(body), " ")
+				sb.WriteString(fmt.Sprintf("📓 [%s] %s: %s\n", strings.TrimPrefix(h.Kind, "brain:"), title, body))
+			default:
+				sb.WriteString(fmt.Sprintf("• %s\n", text))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(a.memory.WorkingContext())
+	return sb.String()
+}
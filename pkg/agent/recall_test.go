@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/brain"
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/logger"
+	"ClosedWheeler/pkg/memory"
+)
+
+func TestGetContextSummary_RecallsRelatedEntries(t *testing.T) {
+	dir := t.TempDir()
+	log, err := logger.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	cfg.Memory.Retrieval = config.RetrievalConfig{Enabled: true, TopK: 2, StoragePath: filepath.Join(dir, "vectors.json")}
+
+	mem := memory.NewManager("", nil)
+	mem.AddDecision("Store uploaded invoices in S3 with server-side encryption", nil)
+	mem.AddSummary("Renamed the CLI flags for verbose logging")
+	mem.AddSummary("Discussed the color scheme of the dashboard")
+	b := brain.NewBrain(dir)
+	if err := b.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddError("Invoice upload timeout", "Large invoices timed out", "Use multipart uploads to S3", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{
+		config:      cfg,
+		ctx:         context.Background(),
+		logger:      log,
+		memory:      mem,
+		brain:       b,
+		recallStore: newRecallStore(cfg),
+	}
+
+	summary := a.getContextSummary("where do invoice uploads go?")
+	if !strings.Contains(summary, "📍 [Decision] Store uploaded invoices in S3") {
+		t.Errorf("decision not recalled:\n%s", summary)
+	}
+	if !strings.Contains(summary, "📓 [error] Invoice upload timeout:") {
+		t.Errorf("brain entry not recalled:\n%s", summary)
+	}
+	if strings.Contains(summary, "color scheme") {
+		t.Errorf("unrelated memory injected:\n%s", summary)
+	}
+	if entries, err := b.Entries(); err != nil || len(entries) != 1 || entries[0].Uses != 1 {
+		t.Errorf("recalled brain entry not marked used: %+v, %v", entries, err)
+	}
+
+	// Without retrieval the latest memories are shown
+	a.recallStore = nil
+	if summary := a.getContextSummary("where do invoice uploads go?"); !strings.Contains(summary, "color scheme") {
+		t.Errorf("expected the latest memories without retrieval:\n%s", summary)
+	}
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/permissions"
+)
+
+// newReplayAgent builds an agent in a temporary workplace whose LLM requests
+// are served from a recorded cassette in testdata.
+func newReplayAgent(t *testing.T, cassette string, rules ...string) (*Agent, *llm.Replayer) {
+	t.Helper()
+	replayer, err := llm.LoadReplayer(filepath.Join("testdata", cassette))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.APIBaseURL = "https://api.openai.com/v1"
+	cfg.APIKey = "sk-test"
+	cfg.Model = "gpt-4o"
+	cfg.Provider = "openai"
+	cfg.Memory.StoragePath = filepath.Join(dir, ".agi", "memory.json")
+	cfg.Permissions.AuditLogPath = filepath.Join(dir, ".agi", "audit.log")
+	cfg.Permissions.Rules = rules
+
+	ag, err := NewAgent(cfg, dir, dir)
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	t.Cleanup(func() { ag.Shutdown() })
+	ag.llm.SetTransport(replayer)
+	return ag, replayer
+}
+
+// lastToolCall returns the last tool call recorded in the session.
+func lastToolCall(t *testing.T, ag *Agent) SessionToolCall {
+	t.Helper()
+	ag.savedMu.Lock()
+	defer ag.savedMu.Unlock()
+	calls := ag.savedSession.ToolCalls
+	if len(calls) == 0 {
+		t.Fatal("no tool call was recorded")
+	}
+	return calls[len(calls)-1]
+}
+
+// TestChat_ReplaysRecordedToolCall drives Agent.Chat and handleToolCalls
+// through a recorded session: the model asks to read a file, the tool runs
+// against the workplace, and the recorded final answer is returned.
+func TestChat_ReplaysRecordedToolCall(t *testing.T) {
+	ag, replayer := newReplayAgent(t, "chat_read_file.json", "allow read_file")
+
+	if err := os.WriteFile(filepath.Join(ag.projectPath, "notes.txt"), []byte("ship on Friday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var toolsRun []string
+	ag.SetToolCallbacks(func(name, args string) { toolsRun = append(toolsRun, name) }, nil, nil)
+
+	answer, err := ag.Chat("What do my notes say?")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if answer != "The notes say: ship on Friday." {
+		t.Fatalf("unexpected answer %q", answer)
+	}
+	if len(toolsRun) != 1 || toolsRun[0] != "read_file" {
+		t.Fatalf("expected read_file to run once, got %v", toolsRun)
+	}
+	if call := lastToolCall(t, ag); !call.Success || !strings.Contains(call.Result, "ship on Friday") {
+		t.Fatalf("read_file should have read the notes, got %+v", call)
+	}
+	if replayer.Remaining() != 0 {
+		t.Fatalf("%d recorded responses were not used", replayer.Remaining())
+	}
+	if usage := ag.GetUsageStats()["total_tokens"]; usage != 1218+1269 {
+		t.Fatalf("usage should add up across both calls, got %v", usage)
+	}
+}
+
+// TestPipeline_ReplaysRecordedRun drives MultiAgentPipeline.Run through the
+// trpcbridge model and tool adapters: the researcher reads a file and the
+// critic approves the executor's answer.
+func TestPipeline_ReplaysRecordedRun(t *testing.T) {
+	ag, replayer := newReplayAgent(t, "pipeline_read_file.json", "allow read_file")
+	if err := os.WriteFile(filepath.Join(ag.projectPath, "notes.txt"), []byte("ship on Friday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ag.EnablePipeline(true)
+
+	var toolsRun []string
+	ag.SetToolCallbacks(func(name, args string) { toolsRun = append(toolsRun, name) }, nil, nil)
+	want := []AgentRole{RolePlanner, RoleResearcher, RoleExecutor, RoleCritic}
+	var roles []AgentRole
+	ag.SetPipelineStatusCallback(func(role AgentRole, status string) {
+		// The chain itself reports as "pipeline"; keep each role's first event.
+		if status == "thinking" && slices.Contains(want, role) && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	})
+
+	answer, err := ag.Chat("What do my notes say?")
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if answer != "The notes say: ship on Friday." {
+		t.Fatalf("unexpected answer %q", answer)
+	}
+	if len(toolsRun) != 1 || toolsRun[0] != "read_file" {
+		t.Fatalf("expected read_file to run once, got %v", toolsRun)
+	}
+	if replayer.Remaining() != 0 {
+		t.Fatalf("%d recorded responses were not used", replayer.Remaining())
+	}
+	if !slices.Equal(roles, want) {
+		t.Fatalf("roles ran as %v, want %v", roles, want)
+	}
+
+	byRole := ag.GetSpendingStatus().Session.ByRole
+	for _, role := range want {
+		if byRole[string(role)] == 0 {
+			t.Errorf("no usage billed to %s: %v", role, byRole)
+		}
+	}
+	if usage := ag.GetUsageStats()["total_tokens"]; usage != 420+618+665+714+780 {
+		t.Fatalf("usage should add up across the roles, got %v", usage)
+	}
+}
+
+// A call the policy asks about must not run when no one can approve it, as
+// in "agi run" or a debate without the TUI.
+func TestChat_RefusesAskWithoutApprover(t *testing.T) {
+	ag, _ := newReplayAgent(t, "chat_read_file.json", "ask read_file path:notes.txt")
+	if err := os.WriteFile(filepath.Join(ag.projectPath, "notes.txt"), []byte("ship on Friday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ag.Chat("What do my notes say?"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	call := lastToolCall(t, ag)
+	if call.Success || strings.Contains(call.Result, "ship on Friday") || !strings.Contains(call.Result, "no approver") {
+		t.Fatalf("read_file should have been refused, got %+v", call)
+	}
+
+	// An approver lets the same call run.
+	var asked []string
+	ag.SetToolApprover(func(req ToolApproval) permissions.Approval {
+		asked = append(asked, req.Tool)
+		return permissions.ApproveOnce
+	})
+	if err := ag.authorizeTool("read_file", map[string]any{"path": "notes.txt"}); err != nil || len(asked) != 1 {
+		t.Fatalf("approved call refused: %v (asked %v)", err, asked)
+	}
+	ag.SetToolApprover(nil)
+	if err := ag.authorizeTool("read_file", map[string]any{"path": "notes.txt"}); !errors.Is(err, ErrNoApprover) {
+		t.Fatalf("expected ErrNoApprover, got %v", err)
+	}
+}
+
+// A heartbeat waiting for the user's turn to finish must not have that
+// turn's usage billed to it.
+func TestChat_HeartbeatDoesNotRelabelRunningTurn(t *testing.T) {
+	ag, _ := newReplayAgent(t, "chat_read_file.json", "allow read_file")
+	if err := os.WriteFile(filepath.Join(ag.projectPath, "notes.txt"), []byte("ship on Friday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	heartbeat := make(chan error, 1)
+	ag.SetToolCallbacks(func(name, args string) {
+		// Start a heartbeat turn while the user's turn is between calls.
+		go func() {
+			_, err := ag.chat("heartbeat", "Any pending work?")
+			heartbeat <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+	}, nil, nil)
+
+	if _, err := ag.Chat("What do my notes say?"); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	<-heartbeat // fails: the cassette has nothing left for it
+
+	byRole := ag.GetSpendingStatus().Session.ByRole
+	if byRole["heartbeat"] != 0 || byRole["main"] == 0 {
+		t.Fatalf("user turn should be billed to main, got %v", byRole)
+	}
+}
@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"path/filepath"
+	"time"
+
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/llm"
+)
+
+// Response cache defaults, used when the config leaves them at zero.
+const (
+	defaultCacheTTLHours  = 24
+	defaultCacheMaxSizeMB = 100
+)
+
+// newResponseCache opens .agi/cache/ when the cache is enabled in cfg.
+func newResponseCache(cfg *config.Config, appPath string) *llm.ResponseCache {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+	ttl := cfg.Cache.TTLHours
+	if ttl <= 0 {
+		ttl = defaultCacheTTLHours
+	}
+	size := cfg.Cache.MaxSizeMB
+	if size <= 0 {
+		size = defaultCacheMaxSizeMB
+	}
+	return llm.NewResponseCache(filepath.Join(appPath, ".agi", "cache"), time.Duration(ttl)*time.Hour, int64(size)<<20)
+}
+
+// cacheOptIn reports whether a turn billed to role may be served from the
+// response cache at any temperature: heartbeat and reflection turns, and
+// pipeline runs when the config asks for it. Calls at temperature 0 are
+// cached regardless. Responses that call tools are never stored, so a
+// repeated heartbeat prompt still asks the model before running tools.
+func (a *Agent) cacheOptIn(role string) bool {
+	if a.responseCache == nil {
+		return false
+	}
+	if role == "heartbeat" {
+		return true
+	}
+	return a.config.Cache.Pipeline && a.pipeline != nil && a.pipeline.IsEnabled()
+}
+
+// GetCacheStats returns response cache statistics; ok is false when the cache
+// is disabled.
+func (a *Agent) GetCacheStats() (stats llm.CacheStats, ok bool) {
+	if a.responseCache == nil {
+		return llm.CacheStats{}, false
+	}
+	return a.responseCache.Stats(), true
+}
@@ -191,12 +191,18 @@ type ContextStats struct {
 	ContextSent       bool
 	SessionAge        time.Duration
 	CompletionCount   int
+	ContextTokens     int // prompt tokens of the last request, 0 if unknown
+	ContextWindow     int // model context window, 0 if unknown
 }
 
-// EstimateContextSize estimates context size in tokens (rough estimate)
+// EstimateContextSize estimates context size in tokens. It returns the
+// tokenizer count of the last request when one is known.
 func (cs *ContextStats) EstimateContextSize() int {
-	// Rough estimate: average 4 chars per token
-	// This is a heuristic - actual tokenization varies
+	if cs.ContextTokens > 0 {
+		return cs.ContextTokens
+	}
+
+	// No request yet - fall back to a per-message heuristic
 	avgTokensPerMessage := 100 // Conservative estimate
 	return cs.MessageCount * avgTokensPerMessage
 }
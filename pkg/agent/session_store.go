@@ -0,0 +1,487 @@
+// Package agent provides persistent conversation sessions
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/memory"
+	"ClosedWheeler/pkg/utils"
+)
+
+// SessionMessage is a single conversation turn in a saved session
+type SessionMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SessionToolCall records a tool invocation made during a saved session
+type SessionToolCall struct {
+	Name      string    `json:"name"`
+	Arguments string    `json:"arguments"`
+	Result    string    `json:"result,omitempty"`
+	Success   bool      `json:"success"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SavedSession is the on-disk form of a conversation that can be resumed later
+type SavedSession struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	ParentID  string            `json:"parent_id,omitempty"` // Set when forked from another session
+	Model     string            `json:"model"`
+	Provider  string            `json:"provider,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Messages  []SessionMessage  `json:"messages"`
+	ToolCalls []SessionToolCall `json:"tool_calls,omitempty"`
+	Usage     llm.Usage         `json:"usage"`
+}
+
+// SessionSummary is a lightweight listing entry for a saved session
+type SessionSummary struct {
+	ID           string
+	Name         string
+	ParentID     string
+	Model        string
+	UpdatedAt    time.Time
+	MessageCount int
+	TotalTokens  int
+}
+
+// maxToolResultLen caps how much tool output is kept per call in a session file.
+const maxToolResultLen = 2000
+
+// SessionStore persists conversation sessions as JSON files under .agi/sessions/
+type SessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewSessionStore creates a session store rooted at dir
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{dir: dir}
+}
+
+// Dir returns the directory holding session files
+func (s *SessionStore) Dir() string {
+	return s.dir
+}
+
+// newSavedSession creates an empty session for the given model
+func newSavedSession(model, provider string) *SavedSession {
+	now := time.Now()
+	return &SavedSession{
+		ID:        now.Format("20060102-150405") + "-" + generateSessionID()[:6],
+		Model:     model,
+		Provider:  provider,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Messages:  make([]SessionMessage, 0),
+	}
+}
+
+func (s *SessionStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// validSessionID rejects IDs that could escape the sessions directory
+func validSessionID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && !strings.Contains(id, "..")
+}
+
+// Save writes the session to disk atomically (temp file + rename) so a crash
+// mid-write never leaves a truncated session behind.
+func (s *SessionStore) Save(sess *SavedSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validSessionID(sess.ID) {
+		return fmt.Errorf("invalid session id %q", sess.ID)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(s.path(sess.ID), data, 0600)
+}
+
+// Load reads a session by ID. A unique ID prefix is also accepted, and
+// "last"/"latest" selects the most recently updated session.
+func (s *SessionStore) Load(id string) (*SavedSession, error) {
+	resolved, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(resolved))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", resolved, err)
+	}
+	var sess SavedSession
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("corrupt session file %s: %w", resolved, err)
+	}
+	return &sess, nil
+}
+
+// Delete removes a session file
+func (s *SessionStore) Delete(id string) error {
+	resolved, err := s.resolve(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.Remove(s.path(resolved))
+}
+
+// Fork copies an existing session under a new ID and returns the copy
+func (s *SessionStore) Fork(id, name string) (*SavedSession, error) {
+	src, err := s.Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	fork := newSavedSession(src.Model, src.Provider)
+	fork.ParentID = src.ID
+	fork.Name = name
+	if fork.Name == "" {
+		fork.Name = src.Name + " (fork)"
+	}
+	fork.Messages = append(fork.Messages, src.Messages...)
+	fork.ToolCalls = append(fork.ToolCalls, src.ToolCalls...)
+	fork.Usage = src.Usage
+
+	if err := s.Save(fork); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
+// List returns summaries of all saved sessions, most recently updated first
+func (s *SessionStore) List() ([]SessionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []SessionSummary
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var sess SavedSession
+		if err := json.Unmarshal(data, &sess); err != nil {
+			continue // skip corrupt files rather than failing the whole listing
+		}
+		out = append(out, SessionSummary{
+			ID:           sess.ID,
+			Name:         sess.Name,
+			ParentID:     sess.ParentID,
+			Model:        sess.Model,
+			UpdatedAt:    sess.UpdatedAt,
+			MessageCount: len(sess.Messages),
+			TotalTokens:  sess.Usage.TotalTokens,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].UpdatedAt.After(out[j].UpdatedAt)
+	})
+	return out, nil
+}
+
+// resolve maps an ID, unique prefix, or "last" to a concrete session ID
+func (s *SessionStore) resolve(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "", fmt.Errorf("session id is required")
+	}
+
+	list, err := s.List()
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("no saved sessions in %s", s.dir)
+	}
+
+	if id == "last" || id == "latest" {
+		return list[0].ID, nil
+	}
+
+	var matches []string
+	for _, sum := range list {
+		if sum.ID == id {
+			return id, nil
+		}
+		if strings.HasPrefix(sum.ID, id) {
+			matches = append(matches, sum.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session %q not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("session id %q is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+// sessionTitle derives a short display name from the first user message
+func sessionTitle(msg string) string {
+	title := strings.Join(strings.Fields(msg), " ")
+	if runes := []rune(title); len(runes) > 60 {
+		title = string(runes[:57]) + "..."
+	}
+	return title
+}
+
+// ---------------------------------------------------------------------------
+// Agent integration
+// ---------------------------------------------------------------------------
+
+// addMessage stores a conversation message in short-term memory and in the
+// persistent session transcript.
+func (a *Agent) addMessage(role, content string) {
+	a.memory.AddMessage(role, content)
+
+	a.savedMu.Lock()
+	defer a.savedMu.Unlock()
+	if a.savedSession == nil {
+		return
+	}
+	if a.savedSession.Name == "" && role == "user" {
+		a.savedSession.Name = sessionTitle(content)
+	}
+	a.savedSession.Messages = append(a.savedSession.Messages, SessionMessage{
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+}
+
+// recordUsage accumulates token usage for the process and the current
+// session, and records its cost in the ledger.
+func (a *Agent) recordUsage(u llm.Usage) {
+	a.costMu.Lock()
+	a.totalUsage.PromptTokens += u.PromptTokens
+	a.totalUsage.CompletionTokens += u.CompletionTokens
+	a.totalUsage.TotalTokens += u.TotalTokens
+	a.costMu.Unlock()
+
+	a.recordCost("", u)
+
+	a.savedMu.Lock()
+	defer a.savedMu.Unlock()
+	if a.savedSession == nil {
+		return
+	}
+	a.savedSession.Usage.PromptTokens += u.PromptTokens
+	a.savedSession.Usage.CompletionTokens += u.CompletionTokens
+	a.savedSession.Usage.TotalTokens += u.TotalTokens
+}
+
+// recordToolCall appends a tool invocation to the session transcript.
+func (a *Agent) recordToolCall(name, args, result string, success bool) {
+	a.savedMu.Lock()
+	defer a.savedMu.Unlock()
+	if a.savedSession == nil {
+		return
+	}
+	if len(result) > maxToolResultLen {
+		result = strings.ToValidUTF8(result[:maxToolResultLen], "") + "\n...(truncated)"
+	}
+	a.savedSession.ToolCalls = append(a.savedSession.ToolCalls, SessionToolCall{
+		Name:      name,
+		Arguments: args,
+		Result:    result,
+		Success:   success,
+		Timestamp: time.Now(),
+	})
+}
+
+// persistSession writes the current session to disk. Empty sessions are
+// skipped so that simply opening the TUI doesn't litter .agi/sessions/.
+func (a *Agent) persistSession() {
+	if err := a.saveSession(false); err != nil {
+		a.logger.Error("Failed to save session %s: %v", a.CurrentSessionID(), err)
+	}
+}
+
+// saveSession writes the current session to disk; keepEmpty also writes a
+// session without messages.
+func (a *Agent) saveSession(keepEmpty bool) error {
+	if a.sessionStore == nil {
+		return nil
+	}
+
+	a.savedMu.Lock()
+	sess := a.savedSession
+	if sess == nil || (len(sess.Messages) == 0 && !keepEmpty) {
+		a.savedMu.Unlock()
+		return nil
+	}
+	sess.UpdatedAt = time.Now()
+	sess.Model = a.config.Model
+	snapshot := *sess
+	snapshot.Messages = append([]SessionMessage(nil), sess.Messages...)
+	snapshot.ToolCalls = append([]SessionToolCall(nil), sess.ToolCalls...)
+	a.savedMu.Unlock()
+
+	return a.sessionStore.Save(&snapshot)
+}
+
+// CurrentSessionID returns the ID of the active persistent session.
+func (a *Agent) CurrentSessionID() string {
+	a.savedMu.Lock()
+	defer a.savedMu.Unlock()
+	if a.savedSession == nil {
+		return ""
+	}
+	return a.savedSession.ID
+}
+
+// CurrentSessionMessages returns a copy of the active session transcript.
+func (a *Agent) CurrentSessionMessages() []SessionMessage {
+	a.savedMu.Lock()
+	defer a.savedMu.Unlock()
+	if a.savedSession == nil {
+		return nil
+	}
+	return append([]SessionMessage(nil), a.savedSession.Messages...)
+}
+
+// ListSessions returns all saved sessions, most recent first.
+func (a *Agent) ListSessions() ([]SessionSummary, error) {
+	if a.sessionStore == nil {
+		return nil, fmt.Errorf("session persistence is not available")
+	}
+	return a.sessionStore.List()
+}
+
+// NewConversation saves the current session and starts an empty one.
+func (a *Agent) NewConversation() {
+	a.persistSession()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.memory.Clear(memory.ShortTerm)
+	a.sessionMgr.ResetSession()
+
+	a.savedMu.Lock()
+	a.savedSession = newSavedSession(a.config.Model, a.config.Provider)
+	a.savedMu.Unlock()
+}
+
+// ResumeSession loads a saved session and makes it the active conversation.
+// Short-term memory is rebuilt from the tail of the transcript and the
+// system context is resent on the next message.
+func (a *Agent) ResumeSession(id string) (*SavedSession, error) {
+	if a.sessionStore == nil {
+		return nil, fmt.Errorf("session persistence is not available")
+	}
+
+	sess, err := a.sessionStore.Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save whatever we were doing before switching away from it.
+	a.persistSession()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.memory.Clear(memory.ShortTerm)
+	start := 0
+	if limit := a.config.Memory.MaxShortTermItems; limit > 0 && len(sess.Messages) > limit {
+		start = len(sess.Messages) - limit
+	}
+	for _, msg := range sess.Messages[start:] {
+		a.memory.AddMessage(msg.Role, msg.Content)
+	}
+	a.sessionMgr.ResetSession()
+
+	a.savedMu.Lock()
+	a.savedSession = sess
+	a.savedMu.Unlock()
+
+	a.logger.Info("Resumed session %s (%d messages)", sess.ID, len(sess.Messages))
+	return sess, nil
+}
+
+// ForkSession copies a saved session (or the current one when id is empty)
+// and resumes the copy, leaving the original untouched.
+func (a *Agent) ForkSession(id, name string) (*SavedSession, error) {
+	if a.sessionStore == nil {
+		return nil, fmt.Errorf("session persistence is not available")
+	}
+	if id == "" {
+		// The current session may be new and not on disk yet
+		if err := a.saveSession(true); err != nil {
+			return nil, err
+		}
+		id = a.CurrentSessionID()
+	}
+
+	fork, err := a.sessionStore.Fork(id, name)
+	if err != nil {
+		return nil, err
+	}
+	return a.ResumeSession(fork.ID)
+}
+
+// DeleteSession removes a saved session. Deleting the active session starts
+// a fresh one so it isn't immediately written back to disk.
+func (a *Agent) DeleteSession(id string) error {
+	if a.sessionStore == nil {
+		return fmt.Errorf("session persistence is not available")
+	}
+
+	sess, err := a.sessionStore.Load(id)
+	if err != nil {
+		return err
+	}
+	if err := a.sessionStore.Delete(sess.ID); err != nil {
+		return err
+	}
+
+	if sess.ID == a.CurrentSessionID() {
+		a.savedMu.Lock()
+		a.savedSession = newSavedSession(a.config.Model, a.config.Provider)
+		a.savedMu.Unlock()
+	}
+	return nil
+}
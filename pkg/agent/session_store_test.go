@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/logger"
+	"ClosedWheeler/pkg/memory"
+)
+
+func TestSessionStore_SaveLoadForkDelete(t *testing.T) {
+	store := NewSessionStore(filepath.Join(t.TempDir(), "sessions"))
+
+	sess := newSavedSession("gpt-4o", "openai")
+	sess.Name = "refactor"
+	sess.Messages = append(sess.Messages,
+		SessionMessage{Role: "user", Content: "rename Foo to Bar", Timestamp: time.Now()},
+		SessionMessage{Role: "assistant", Content: "done", Timestamp: time.Now()},
+	)
+	sess.Usage.TotalTokens = 42
+	if err := store.Save(sess); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load(sess.ID[:10])
+	if err != nil {
+		t.Fatalf("Load by prefix failed: %v", err)
+	}
+	if loaded.ID != sess.ID || len(loaded.Messages) != 2 || loaded.Usage.TotalTokens != 42 {
+		t.Fatalf("loaded session mismatch: %+v", loaded)
+	}
+
+	fork, err := store.Fork(sess.ID, "")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if fork.ParentID != sess.ID || fork.ID == sess.ID || len(fork.Messages) != 2 {
+		t.Fatalf("fork mismatch: %+v", fork)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(list))
+	}
+
+	if err := store.Delete(sess.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Load(sess.ID); err == nil {
+		t.Fatal("expected error loading deleted session")
+	}
+}
+
+func TestSessionStore_LastAndCorruptFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	store := NewSessionStore(dir)
+
+	older := newSavedSession("m", "")
+	older.ID = "older"
+	older.UpdatedAt = time.Now().Add(-time.Hour)
+	newer := newSavedSession("m", "")
+	newer.ID = "newer"
+	for _, s := range []*SavedSession{older, newer} {
+		if err := store.Save(s); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := store.Load("last")
+	if err != nil {
+		t.Fatalf("Load(last) failed: %v", err)
+	}
+	if last.ID != "newer" {
+		t.Errorf("expected newest session, got %s", last.ID)
+	}
+
+	if err := store.Save(&SavedSession{ID: "../escape"}); err == nil {
+		t.Error("expected invalid id to be rejected")
+	}
+}
+
+func TestSessionTitle(t *testing.T) {
+	if got := sessionTitle("  fix   the\nbuild  "); got != "fix the build" {
+		t.Errorf("sessionTitle collapsed whitespace to %q", got)
+	}
+	long := sessionTitle(strings.Repeat("é", 70))
+	if !utf8.ValidString(long) || long != strings.Repeat("é", 57)+"..." {
+		t.Errorf("sessionTitle should cut whole runes, got %q", long)
+	}
+}
+
+func TestRecordToolCall_TruncatesWholeRunes(t *testing.T) {
+	a := &Agent{savedSession: newSavedSession("gpt-4o", "")}
+	a.recordToolCall("read_file", "{}", "a"+strings.Repeat("é", maxToolResultLen), true)
+
+	result := a.savedSession.ToolCalls[0].Result
+	if !utf8.ValidString(result) || result != "a"+strings.Repeat("é", (maxToolResultLen-1)/2)+"\n...(truncated)" {
+		t.Errorf("tool result should be cut at a rune boundary, got %q", result[len(result)-20:])
+	}
+}
+
+func TestForkSession_NewSession(t *testing.T) {
+	dir := t.TempDir()
+	log, err := logger.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{
+		config:       &config.Config{Model: "gpt-4o"},
+		logger:       log,
+		memory:       memory.NewManager("", &memory.Config{MaxShortTermItems: 10}),
+		sessionMgr:   NewSessionManager(10),
+		sessionStore: NewSessionStore(filepath.Join(dir, "sessions")),
+		savedSession: newSavedSession("gpt-4o", ""),
+	}
+	original := a.CurrentSessionID()
+
+	fork, err := a.ForkSession("", "branch")
+	if err != nil {
+		t.Fatalf("forking an unsaved session failed: %v", err)
+	}
+	if fork.ParentID != original || a.CurrentSessionID() != fork.ID {
+		t.Errorf("expected to resume a fork of %s, got %+v", original, fork)
+	}
+}
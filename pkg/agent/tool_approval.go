@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ClosedWheeler/pkg/permissions"
+	"ClosedWheeler/pkg/tools/builtin"
+)
+
+// ToolApproval is a tool call the permission policy wants the user to
+// approve.
+type ToolApproval struct {
+	Tool   string
+	Args   map[string]any
+	Reason string // why the policy asks
+	Diff   string // change a file tool would make, if known
+
+	// Rule is what the session and pattern answers remember; without one
+	// only the call itself can be approved
+	Rule        permissions.Rule
+	CanRemember bool
+}
+
+// ToolApprover asks the user about a tool call and blocks until they answer.
+type ToolApprover func(ToolApproval) permissions.Approval
+
+// ErrNoApprover refuses a call that needs approval when no one can be
+// asked, e.g. in "agi run" or after the TUI exited.
+var ErrNoApprover = errors.New("needs approval, but no approver is available")
+
+// SetToolApprover installs the UI that approves sensitive tool calls when
+// Telegram approvals are off. Without an approver, such calls are refused.
+func (a *Agent) SetToolApprover(fn ToolApprover) {
+	a.toolApprover = fn
+}
+
+// approveCall asks the user about a call the policy marks Ask: over
+// Telegram when it is enabled, otherwise through the tool approver. Without
+// either the call fails closed with ErrNoApprover.
+func (a *Agent) approveCall(name, rawArgs string, args map[string]any, decision permissions.Decision) error {
+	switch {
+	// Debate clones have tgBot=nil to avoid approval deadlock
+	case a.config.Telegram.Enabled && a.tgBot != nil:
+		return a.requestTelegramApproval(name, rawArgs)
+	case a.toolApprover != nil:
+		return a.requestToolApproval(name, args, decision)
+	}
+	a.permManager.LogApprovalDecision(name, false, 0)
+	return fmt.Errorf("%s %w (%s)", name, ErrNoApprover, decision.Reason)
+}
+
+// authorizeTool applies the permission policy to a call made outside
+// handleToolCalls, such as by a pipeline role: denied calls fail, and calls
+// that need approval wait for it.
+func (a *Agent) authorizeTool(name string, args map[string]any) error {
+	decision := a.permManager.Evaluate(name, args)
+	switch decision.Effect {
+	case permissions.Deny:
+		return fmt.Errorf("permission denied by policy: %s", decision.Reason)
+	case permissions.Ask:
+		raw, _ := json.Marshal(args)
+		return a.approveCall(name, string(raw), args, decision)
+	}
+	return nil
+}
+
+// requestToolApproval asks the tool approver about a call and remembers the
+// rule the user chose to apply from now on.
+func (a *Agent) requestToolApproval(name string, args map[string]any, decision permissions.Decision) error {
+	a.statusCallback(fmt.Sprintf("⏳ Waiting for approval of %s...", name))
+
+	req := ToolApproval{
+		Tool:   name,
+		Args:   args,
+		Reason: decision.Reason,
+		Diff:   builtin.PreviewChange(a.projectPath, name, args),
+	}
+	req.Rule, req.CanRemember = a.permManager.SuggestRule(name, args)
+
+	answer := a.toolApprover(req)
+	approved := answer == permissions.ApproveOnce || answer == permissions.ApproveSession || answer == permissions.ApprovePattern
+	a.permManager.LogApprovalDecision(name, approved, 0)
+	if !approved {
+		return fmt.Errorf("user denied the operation")
+	}
+
+	if req.CanRemember && answer != permissions.ApproveOnce {
+		if err := a.permManager.Remember(req.Rule, answer == permissions.ApprovePattern); err != nil {
+			a.logger.Error("Failed to remember %q: %v", req.Rule.Text, err)
+		}
+	}
+	return nil
+}
@@ -1,11 +1,12 @@
 // Package brain provides a transparent knowledge base for the agent to learn from past experiences.
-// All knowledge is stored in Markdown format in workplace/brain.md for visibility.
+// Entries are stored in workplace/brain.json; workplace/brain.md is regenerated from it after
+// every change so the knowledge stays readable.
 package brain
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,18 +14,49 @@ import (
 
 // Entry represents a single knowledge entry in the brain
 type Entry struct {
-	Timestamp   time.Time
-	Category    string // "error", "pattern", "decision", "insight"
-	Title       string
-	Description string
-	Tags        []string
+	ID           string    `json:"id"`
+	Timestamp    time.Time `json:"created_at"`
+	Updated      time.Time `json:"updated_at,omitempty"`
+	Category     string    `json:"category"` // "error", "pattern", "decision", "insight"
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Tags         []string  `json:"tags,omitempty"`
+	Uses         int       `json:"uses,omitempty"`          // Times the entry was recalled or queried
+	Supersedes   string    `json:"supersedes,omitempty"`    // ID of the entry this one replaces
+	SupersededBy string    `json:"superseded_by,omitempty"` // ID of the entry that replaced this one
 }
 
+// Active reports whether the entry has not been superseded.
+func (e Entry) Active() bool {
+	return e.SupersededBy == ""
+}
+
+// HasTags reports whether the entry carries all of the tags, ignoring case.
+func (e Entry) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range e.Tags {
+			if strings.EqualFold(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Categories lists the entry categories in the order brain.md shows them.
+var Categories = []string{"error", "pattern", "decision", "insight"}
+
 // Brain manages the agent's knowledge base
 type Brain struct {
 	projectPath string
-	brainPath   string
-	mu          sync.RWMutex
+	brainPath   string // generated markdown view
+	storePath   string // entries
+	mu          sync.Mutex
 }
 
 // NewBrain creates a new brain instance
@@ -32,101 +64,37 @@ func NewBrain(projectPath string) *Brain {
 	return &Brain{
 		projectPath: projectPath,
 		brainPath:   filepath.Join(projectPath, "brain.md"),
+		storePath:   filepath.Join(projectPath, "brain.json"),
 	}
 }
 
-// Initialize creates the brain.md file if it doesn't exist
+// Initialize creates the store if it doesn't exist, importing the entries of
+// an existing brain.md, and writes brain.md.
 func (b *Brain) Initialize() error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	// Ensure parent directory exists
-	parentDir := filepath.Dir(b.brainPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return fmt.Errorf("failed to create brain directory: %w", err)
-	}
-
-	// Check if brain.md exists
-	if _, err := os.Stat(b.brainPath); os.IsNotExist(err) {
-		initialContent := `# 🧠 Agent Knowledge Base
-
-This file is the agent's "brain", where it records lessons learned, patterns discovered, and important decisions.
-
-## 📚 Index
-
-- [Errors and Solutions](#errors-and-solutions)
-- [Code Patterns](#code-patterns)
-- [Architectural Decisions](#architectural-decisions)
-- [Insights](#insights)
-
----
-
-## Errors and Solutions
-
-<!-- Errors found and how they were resolved -->
-
-## Code Patterns
-
-<!-- Patterns and conventions discovered in the project -->
-
-## Architectural Decisions
-
-<!-- Important technical decisions made -->
-
-## Insights
-
-<!-- General observations and discoveries -->
-
----
-
-*Last update: ` + time.Now().Format("2006-01-02 15:04:05") + `*
-`
-		return os.WriteFile(b.brainPath, []byte(initialContent), 0644)
+	s, err := b.load()
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return b.save(s)
 }
 
 // AddEntry adds a new knowledge entry to the brain
 func (b *Brain) AddEntry(entry Entry) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	// Read current content
-	content, err := os.ReadFile(b.brainPath)
-	if err != nil {
-		return fmt.Errorf("failed to read brain.md: %w", err)
-	}
-
-	lines := strings.Split(string(content), "\n")
-
-	// Find the appropriate section
-	sectionMarker := b.getSectionMarker(entry.Category)
-	sectionIdx := b.findSectionIndex(lines, sectionMarker)
-
-	if sectionIdx == -1 {
-		return fmt.Errorf("section not found for category: %s", entry.Category)
-	}
-
-	// Format the new entry
-	formattedEntry := b.formatEntry(entry)
-
-	// Insert after section header (skip comment line)
-	insertIdx := sectionIdx + 2
-
-	// Insert the entry
-	newLines := append(lines[:insertIdx], append([]string{formattedEntry, ""}, lines[insertIdx:]...)...)
-
-	// Update timestamp
-	for i := len(newLines) - 1; i >= 0; i-- {
-		if strings.HasPrefix(newLines[i], "*Last update:") {
-			newLines[i] = "*Last update: " + time.Now().Format("2006-01-02 15:04:05") + "*"
-			break
-		}
-	}
+	_, err := b.Add(entry)
+	return err
+}
 
-	// Write back
-	return os.WriteFile(b.brainPath, []byte(strings.Join(newLines, "\n")), 0644)
+// Add stores a new entry and returns it with its ID assigned.
+func (b *Brain) Add(entry Entry) (Entry, error) {
+	var added Entry
+	err := b.modify(func(s *store) error {
+		added = s.add(entry)
+		return nil
+	})
+	return added, err
 }
 
 // AddError adds an error and its solution to the knowledge base
@@ -177,107 +145,246 @@ func (b *Brain) AddInsight(title, description string, tags []string) error {
 	return b.AddEntry(entry)
 }
 
-// Read returns the current brain content
-func (b *Brain) Read() (string, error) {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+// Supersede stores entry as the replacement of the entry with oldID, which
+// stays in the store but is no longer shown or recalled.
+func (b *Brain) Supersede(oldID string, entry Entry) (Entry, error) {
+	var added Entry
+	err := b.modify(func(s *store) error {
+		old := s.find(oldID)
+		if old == nil {
+			return fmt.Errorf("no brain entry with id %s", oldID)
+		}
+		if !old.Active() {
+			return fmt.Errorf("brain entry %s is already superseded by %s", oldID, old.SupersededBy)
+		}
+		if entry.Category == "" {
+			entry.Category = old.Category
+		}
+		entry.Supersedes = oldID
+		added = s.add(entry)
+		s.find(oldID).SupersededBy = added.ID
+		return nil
+	})
+	return added, err
+}
+
+// Update changes an entry in place; fn must not change its ID.
+func (b *Brain) Update(id string, fn func(*Entry)) error {
+	return b.modify(func(s *store) error {
+		e := s.find(id)
+		if e == nil {
+			return fmt.Errorf("no brain entry with id %s", id)
+		}
+		fn(e)
+		e.ID = id
+		e.Category = normalizeCategory(e.Category)
+		e.Tags = normalizeTags(e.Tags)
+		e.Updated = time.Now()
+		return nil
+	})
+}
 
-	content, err := os.ReadFile(b.brainPath)
+// Delete removes an entry. An entry it superseded becomes active again.
+func (b *Brain) Delete(id string) error {
+	return b.modify(func(s *store) error {
+		for i, e := range s.Entries {
+			if e.ID != id {
+				continue
+			}
+			s.Entries = append(s.Entries[:i], s.Entries[i+1:]...)
+			for j := range s.Entries {
+				other := &s.Entries[j]
+				if other.SupersededBy == id {
+					other.SupersededBy = e.SupersededBy
+				}
+				if other.Supersedes == id {
+					other.Supersedes = e.Supersedes
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("no brain entry with id %s", id)
+	})
+}
+
+// MarkUsed counts a use of each entry, e.g. when it was recalled into a
+// prompt. Unknown IDs are ignored.
+func (b *Brain) MarkUsed(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return b.modify(func(s *store) error {
+		for _, id := range ids {
+			if e := s.find(id); e != nil {
+				e.Uses++
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the entry with an ID.
+func (b *Brain) Get(id string) (Entry, error) {
+	entries, err := b.All()
 	if err != nil {
-		return "", fmt.Errorf("failed to read brain.md: %w", err)
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
 	}
+	return Entry{}, fmt.Errorf("no brain entry with id %s", id)
+}
 
-	return string(content), nil
+// All returns every entry, including superseded ones, oldest first.
+func (b *Brain) All() ([]Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	return append([]Entry(nil), s.Entries...), nil
 }
 
-// Search finds entries matching a query
-func (b *Brain) Search(query string) ([]string, error) {
-	content, err := b.Read()
+// Entries returns the active entries, oldest first.
+func (b *Brain) Entries() ([]Entry, error) {
+	all, err := b.All()
 	if err != nil {
 		return nil, err
 	}
+	var entries []Entry
+	for _, e := range all {
+		if e.Active() {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
 
-	lines := strings.Split(content, "\n")
-	var matches []string
-	var currentEntry strings.Builder
-	inEntry := false
+// ByTag returns the active entries carrying all of the tags, oldest first.
+func (b *Brain) ByTag(tags ...string) ([]Entry, error) {
+	entries, err := b.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Entry
+	for _, e := range entries {
+		if e.HasTags(tags...) {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
 
-	queryLower := strings.ToLower(query)
+// TagCount is a tag and how many active entries carry it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
 
-	for _, line := range lines {
-		if strings.HasPrefix(line, "### ") {
-			// New entry starts
-			if inEntry && currentEntry.Len() > 0 {
-				entry := currentEntry.String()
-				if strings.Contains(strings.ToLower(entry), queryLower) {
-					matches = append(matches, entry)
-				}
-			}
-			currentEntry.Reset()
-			currentEntry.WriteString(line + "\n")
-			inEntry = true
-		} else if inEntry {
-			if strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "---") {
-				// End of current entry
-				if currentEntry.Len() > 0 {
-					entry := currentEntry.String()
-					if strings.Contains(strings.ToLower(entry), queryLower) {
-						matches = append(matches, entry)
-					}
-				}
-				currentEntry.Reset()
-				inEntry = false
-			} else {
-				currentEntry.WriteString(line + "\n")
-			}
+// Tags returns the tags of the active entries, most used first.
+func (b *Brain) Tags() ([]TagCount, error) {
+	entries, err := b.Entries()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, e := range entries {
+		for _, tag := range e.Tags {
+			counts[strings.ToLower(tag)]++
 		}
 	}
-
-	return matches, nil
+	tags := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
 }
 
-// Helper methods
-
-func (b *Brain) getSectionMarker(category string) string {
-	switch category {
-	case "error":
-		return "## Errors and Solutions"
-	case "pattern":
-		return "## Code Patterns"
-	case "decision":
-		return "## Architectural Decisions"
-	case "insight":
-		return "## Insights"
-	default:
-		return "## Insights"
+// Read returns the current brain content
+func (b *Brain) Read() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, err := b.load()
+	if err != nil {
+		return "", err
 	}
+	return renderMarkdown(s), nil
 }
 
-func (b *Brain) findSectionIndex(lines []string, marker string) int {
-	for i, line := range lines {
-		if strings.TrimSpace(line) == marker {
-			return i
+// Search finds active entries matching a query and returns them formatted
+// as in brain.md, newest first.
+func (b *Brain) Search(query string) ([]string, error) {
+	entries, err := b.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	queryLower := strings.ToLower(query)
+	var matches []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		formatted := formatEntry(entries[i])
+		if strings.Contains(strings.ToLower(formatted), queryLower) {
+			matches = append(matches, formatted)
 		}
 	}
-	return -1
+	return matches, nil
 }
 
-func (b *Brain) formatEntry(entry Entry) string {
-	var sb strings.Builder
+// modify loads the store, applies fn and saves it, holding the lock
+// throughout so concurrent writers cannot lose each other's changes.
+func (b *Brain) modify(fn func(*store) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	sb.WriteString(fmt.Sprintf("### %s\n", entry.Title))
-	sb.WriteString(fmt.Sprintf("*%s*\n\n", entry.Timestamp.Format("2006-01-02 15:04")))
-	sb.WriteString(entry.Description)
+	s, err := b.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return b.save(s)
+}
 
-	if len(entry.Tags) > 0 {
-		sb.WriteString("\n\n**Tags:** ")
-		for i, tag := range entry.Tags {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("`" + tag + "`")
+// normalizeCategory maps unknown categories to "insight".
+func normalizeCategory(category string) string {
+	for _, c := range Categories {
+		if c == category {
+			return c
 		}
 	}
+	return "insight"
+}
 
-	return sb.String()
+// normalizeTags trims tags and drops empty and duplicate ones.
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		tag = strings.Trim(strings.TrimSpace(tag), "`#")
+		if tag == "" {
+			continue
+		}
+		dup := false
+		for _, t := range out {
+			if strings.EqualFold(t, tag) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, tag)
+		}
+	}
+	return out
 }
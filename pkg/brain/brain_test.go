@@ -204,3 +204,199 @@ func TestBrain_MultipleEntries(t *testing.T) {
 		}
 	}
 }
+
+func TestBrain_Entries(t *testing.T) {
+	brain := NewBrain(t.TempDir())
+	if err := brain.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	if err := brain.AddError("Nil map write", "Panic when saving config", "Initialize the map in the constructor", []string{"go", "panic"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := brain.AddInsight("Tests are slow", "The integration suite takes minutes", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := brain.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	e := entries[0]
+	if e.Category != "error" || e.Title != "Nil map write" {
+		t.Errorf("Unexpected first entry: %+v", e)
+	}
+	if e.Description != "Panic when saving config\n\n**Solution:** Initialize the map in the constructor" {
+		t.Errorf("Unexpected description: %q", e.Description)
+	}
+	if len(e.Tags) != 2 || e.Tags[0] != "go" || e.Tags[1] != "panic" {
+		t.Errorf("Unexpected tags: %v", e.Tags)
+	}
+	if e.Timestamp.IsZero() {
+		t.Error("Timestamp was not parsed")
+	}
+	if entries[1].Category != "insight" || entries[1].Description != "The integration suite takes minutes" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestBrain_UpdateDeleteAndTags(t *testing.T) {
+	brain := NewBrain(t.TempDir())
+	if err := brain.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := brain.AddPattern("Wrap errors", "Use fmt.Errorf with %w", []string{"go", "errors"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := brain.AddInsight("Flaky CI", "Retries hide races", []string{"ci", "Go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := brain.Update("1", func(e *Entry) {
+		e.Title = "Wrap errors with context"
+		e.Tags = append(e.Tags, "style", "go")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := brain.Get("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Title != "Wrap errors with context" || strings.Join(e.Tags, ",") != "go,errors,style" || e.Updated.IsZero() {
+		t.Errorf("Unexpected updated entry: %+v", e)
+	}
+
+	tagged, err := brain.ByTag("GO")
+	if err != nil || len(tagged) != 2 {
+		t.Fatalf("ByTag(GO) = %+v, %v", tagged, err)
+	}
+	tags, err := brain.Tags()
+	if err != nil || len(tags) != 4 || tags[0] != (TagCount{Tag: "go", Count: 2}) {
+		t.Errorf("Tags() = %+v, %v", tags, err)
+	}
+
+	if err := brain.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := brain.Get("2"); err == nil {
+		t.Error("Deleted entry still found")
+	}
+	if err := brain.Delete("2"); err == nil {
+		t.Error("Expected an error deleting a missing entry")
+	}
+	content, _ := brain.Read()
+	if strings.Contains(content, "Flaky CI") || !strings.Contains(content, "Wrap errors with context") {
+		t.Errorf("brain.md does not reflect the changes:\n%s", content)
+	}
+}
+
+func TestBrain_SupersedeAndUses(t *testing.T) {
+	dir := t.TempDir()
+	brain := NewBrain(dir)
+	if err := brain.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := brain.AddDecision("Use SQLite", "Embedded database", "Simple", []string{"database"}); err != nil {
+		t.Fatal(err)
+	}
+	replacement, err := brain.Supersede("1", Entry{Title: "Use PostgreSQL", Description: "Concurrent writers", Tags: []string{"database"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.ID != "2" || replacement.Category != "decision" || replacement.Supersedes != "1" {
+		t.Errorf("Unexpected replacement: %+v", replacement)
+	}
+	if _, err := brain.Supersede("1", Entry{Title: "Again"}); err == nil {
+		t.Error("Expected an error superseding a superseded entry")
+	}
+
+	entries, _ := brain.Entries()
+	if len(entries) != 1 || entries[0].ID != "2" {
+		t.Fatalf("Entries() = %+v, want only the replacement", entries)
+	}
+	old, _ := brain.Get("1")
+	if old.SupersededBy != "2" {
+		t.Errorf("Old entry not linked: %+v", old)
+	}
+
+	if err := brain.MarkUsed("2", "2", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "brain.md"))
+	if strings.Contains(string(content), "Use SQLite") || !strings.Contains(string(content), "#2 · supersedes #1 · used 2×") {
+		t.Errorf("Unexpected brain.md:\n%s", content)
+	}
+
+	// Deleting the replacement brings the old decision back
+	if err := brain.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := brain.Entries(); len(entries) != 1 || entries[0].ID != "1" {
+		t.Errorf("Entries() after delete = %+v", entries)
+	}
+}
+
+func TestBrain_ImportsMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `# 🧠 Agent Knowledge Base
+
+## Errors and Solutions
+
+<!-- Errors found and how they were resolved -->
+
+### Nil map write
+*2024-03-01 10:00*
+
+Panic when saving config
+
+**Solution:** Initialize the map
+
+**Tags:** ` + "`go`" + `
+
+## Insights
+
+### Hand-written note
+Builds need CGO disabled
+
+---
+
+*Last update: 2024-03-01 10:00:00*
+`
+	if err := os.WriteFile(filepath.Join(dir, "brain.md"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	brain := NewBrain(dir)
+	if err := brain.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := brain.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Entries() = %+v, %v", entries, err)
+	}
+	if entries[0].ID != "1" || entries[0].Timestamp.Year() != 2024 || entries[0].Tags[0] != "go" {
+		t.Errorf("Unexpected imported entry: %+v", entries[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "brain.json")); err != nil {
+		t.Fatalf("brain.json not written: %v", err)
+	}
+
+	// Edits made to the generated view are picked up
+	content, _ := os.ReadFile(filepath.Join(dir, "brain.md"))
+	edited := strings.Replace(string(content), "Builds need CGO disabled", "Builds need CGO_ENABLED=0", 1)
+	edited = strings.Replace(edited, "## Code Patterns\n\n", "## Code Patterns\n\n### Table tests\nPrefer table-driven tests\n\n", 1)
+	if err := os.WriteFile(filepath.Join(dir, "brain.md"), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = brain.Entries()
+	if len(entries) != 3 || entries[1].Description != "Builds need CGO_ENABLED=0" || entries[2].Title != "Table tests" || entries[2].ID != "3" {
+		t.Errorf("Hand edits not imported: %+v", entries)
+	}
+	if again, _ := brain.Entries(); len(again) != 3 {
+		t.Errorf("Hand-written entry imported twice: %+v", again)
+	}
+}
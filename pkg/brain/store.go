@@ -0,0 +1,138 @@
+package brain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/utils"
+)
+
+// store is the content of brain.json.
+type store struct {
+	NextID  int       `json:"next_id"`
+	Updated time.Time `json:"updated_at"`
+	// ViewHash is the SHA-256 of the brain.md last written, used to notice
+	// when it was edited by hand.
+	ViewHash string  `json:"view_hash"`
+	Entries  []Entry `json:"entries"`
+}
+
+// find returns the entry with an ID, or nil.
+func (s *store) find(id string) *Entry {
+	id = strings.TrimPrefix(id, "#")
+	for i := range s.Entries {
+		if s.Entries[i].ID == id {
+			return &s.Entries[i]
+		}
+	}
+	return nil
+}
+
+// add assigns the next ID to entry and appends it.
+func (s *store) add(entry Entry) Entry {
+	if s.NextID < 1 {
+		s.NextID = 1
+	}
+	entry.ID = strconv.Itoa(s.NextID)
+	s.NextID++
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Category = normalizeCategory(entry.Category)
+	entry.Tags = normalizeTags(entry.Tags)
+	s.Entries = append(s.Entries, entry)
+	return entry
+}
+
+// load reads brain.json. Without one, the entries of an existing brain.md
+// are imported; when brain.md was edited since it was written, the edits
+// are applied. Either way the result is saved so IDs stay stable.
+// Callers must hold b.mu.
+func (b *Brain) load() (*store, error) {
+	s := &store{NextID: 1}
+	data, err := os.ReadFile(b.storePath)
+	exists := err == nil
+	switch {
+	case exists:
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse brain.json: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read brain.json: %w", err)
+	}
+
+	view, err := os.ReadFile(b.brainPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read brain.md: %w", err)
+		}
+		return s, nil
+	}
+	if exists && utils.HashContent(view) == s.ViewHash {
+		return s, nil
+	}
+	if s.applyView(parseMarkdown(string(view))) || !exists {
+		if err := b.save(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// applyView merges entries parsed from brain.md into the store: entries
+// without an ID are new, the others update the stored entry if they differ.
+// Entries missing from brain.md are kept. It reports whether anything changed.
+func (s *store) applyView(parsed []Entry) bool {
+	changed := false
+	for _, p := range parsed {
+		if p.ID == "" {
+			s.add(p)
+			changed = true
+			continue
+		}
+		e := s.find(p.ID)
+		if e == nil {
+			p.ID = ""
+			s.add(p)
+			changed = true
+			continue
+		}
+		tags := normalizeTags(p.Tags)
+		if e.Title == p.Title && strings.TrimSpace(e.Description) == p.Description &&
+			e.Category == p.Category && strings.Join(e.Tags, ",") == strings.Join(tags, ",") {
+			continue
+		}
+		e.Title, e.Description, e.Category, e.Tags = p.Title, p.Description, p.Category, tags
+		e.Updated = time.Now()
+		changed = true
+	}
+	return changed
+}
+
+// save writes brain.json and regenerates brain.md. Callers must hold b.mu.
+func (b *Brain) save(s *store) error {
+	if err := os.MkdirAll(filepath.Dir(b.storePath), 0755); err != nil {
+		return fmt.Errorf("failed to create brain directory: %w", err)
+	}
+
+	s.Updated = time.Now()
+	view := []byte(renderMarkdown(s))
+	s.ViewHash = utils.HashContent(view)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal brain: %w", err)
+	}
+	if err := utils.WriteFileAtomic(b.storePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write brain.json: %w", err)
+	}
+	if err := utils.WriteFileAtomic(b.brainPath, view, 0644); err != nil {
+		return fmt.Errorf("failed to write brain.md: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package brain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// metaSeparator separates the fields of an entry's meta line in brain.md.
+const metaSeparator = " · "
+
+// renderMarkdown renders the active entries as brain.md, newest first
+// within each section.
+func renderMarkdown(s *store) string {
+	var sb strings.Builder
+	sb.WriteString(`# 🧠 Agent Knowledge Base
+
+This file is the agent's "brain", where it records lessons learned, patterns discovered, and important decisions.
+
+> Generated from brain.json. Entries added here by hand are imported, and edits to existing entries are kept; use ` + "`/brain delete`" + ` to remove one.
+
+## 📚 Index
+
+- [Errors and Solutions](#errors-and-solutions)
+- [Code Patterns](#code-patterns)
+- [Architectural Decisions](#architectural-decisions)
+- [Insights](#insights)
+
+---
+`)
+
+	for _, category := range Categories {
+		sb.WriteString("\n" + sectionMarker(category) + "\n\n")
+		sb.WriteString(sectionComment(category) + "\n\n")
+		for i := len(s.Entries) - 1; i >= 0; i-- {
+			e := s.Entries[i]
+			if e.Category != category || !e.Active() {
+				continue
+			}
+			sb.WriteString(formatEntry(e) + "\n\n")
+		}
+	}
+
+	updated := s.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	sb.WriteString("---\n\n*Last update: " + updated.Format("2006-01-02 15:04:05") + "*\n")
+	return sb.String()
+}
+
+// formatEntry renders one entry as it appears in brain.md.
+func formatEntry(entry Entry) string {
+	var sb strings.Builder
+
+	meta := []string{entry.Timestamp.Format("2006-01-02 15:04")}
+	if entry.ID != "" {
+		meta = append(meta, "#"+entry.ID)
+	}
+	if entry.Supersedes != "" {
+		meta = append(meta, "supersedes #"+entry.Supersedes)
+	}
+	if entry.Uses > 0 {
+		meta = append(meta, fmt.Sprintf("used %d×", entry.Uses))
+	}
+
+	sb.WriteString(fmt.Sprintf("### %s\n", entry.Title))
+	sb.WriteString(fmt.Sprintf("*%s*\n\n", strings.Join(meta, metaSeparator)))
+	sb.WriteString(strings.TrimSpace(entry.Description))
+
+	if len(entry.Tags) > 0 {
+		sb.WriteString("\n\n**Tags:** ")
+		for i, tag := range entry.Tags {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("`" + tag + "`")
+		}
+	}
+
+	return sb.String()
+}
+
+// parseMarkdown reads entries back from brain.md, in file order. Entries
+// written by hand are included as long as they use a "### " title under one
+// of the category sections; they have no ID.
+func parseMarkdown(content string) []Entry {
+	var entries []Entry
+	var current *Entry
+	var desc []string
+	metaSeen := false
+	category := ""
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Description = strings.TrimSpace(strings.Join(desc, "\n"))
+		entries = append(entries, *current)
+		current, desc = nil, nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			flush()
+			category = sectionCategory(strings.TrimSpace(line))
+		case strings.HasPrefix(line, "---"):
+			flush()
+			category = ""
+		case strings.HasPrefix(line, "### ") && category != "":
+			flush()
+			current = &Entry{Category: category, Title: strings.TrimSpace(strings.TrimPrefix(line, "### "))}
+			metaSeen = false
+		case current == nil:
+		case !metaSeen && len(desc) == 0 && strings.HasPrefix(line, "*") && strings.HasSuffix(line, "*") && !strings.HasPrefix(line, "**"):
+			metaSeen = true
+			parseMeta(current, strings.Trim(line, "*"))
+		case strings.HasPrefix(line, "**Tags:** "):
+			for _, tag := range strings.Split(strings.TrimPrefix(line, "**Tags:** "), ",") {
+				if tag = strings.Trim(strings.TrimSpace(tag), "`"); tag != "" {
+					current.Tags = append(current.Tags, tag)
+				}
+			}
+		case strings.HasPrefix(strings.TrimSpace(line), "<!--"):
+		default:
+			desc = append(desc, line)
+		}
+	}
+	flush()
+	return entries
+}
+
+// parseMeta fills the timestamp and ID of an entry from its meta line, e.g.
+// "2006-01-02 15:04 · #12 · used 3×". Usage counts are kept in the store only.
+func parseMeta(e *Entry, meta string) {
+	for i, field := range strings.Split(meta, metaSeparator) {
+		field = strings.TrimSpace(field)
+		switch {
+		case i == 0:
+			if t, err := time.ParseInLocation("2006-01-02 15:04", field, time.Local); err == nil {
+				e.Timestamp = t
+			}
+		case strings.HasPrefix(field, "#"):
+			e.ID = strings.TrimPrefix(field, "#")
+		}
+	}
+}
+
+// sectionCategory maps a section heading back to its category, or "".
+func sectionCategory(heading string) string {
+	for _, category := range Categories {
+		if sectionMarker(category) == heading {
+			return category
+		}
+	}
+	return ""
+}
+
+func sectionMarker(category string) string {
+	switch category {
+	case "error":
+		return "## Errors and Solutions"
+	case "pattern":
+		return "## Code Patterns"
+	case "decision":
+		return "## Architectural Decisions"
+	default:
+		return "## Insights"
+	}
+}
+
+func sectionComment(category string) string {
+	switch category {
+	case "error":
+		return "<!-- Errors found and how they were resolved -->"
+	case "pattern":
+		return "<!-- Patterns and conventions discovered in the project -->"
+	case "decision":
+		return "<!-- Important technical decisions made -->"
+	default:
+		return "<!-- General observations and discoveries -->"
+	}
+}
@@ -24,7 +24,7 @@ type Config struct {
 	APIBaseURL      string `json:"api_base_url"`
 	APIKey          string `json:"api_key"`
 	Model           string `json:"model"`
-	Provider        string `json:"provider,omitempty"`         // "openai", "anthropic", or "" for auto-detect
+	Provider        string `json:"provider,omitempty"`         // "openai", "anthropic", "gemini", "ollama", or "" for auto-detect
 	ReasoningEffort string `json:"reasoning_effort,omitempty"` // "low", "medium", "high", "xhigh" for reasoning models
 
 	// Fallback configuration
@@ -108,6 +108,19 @@ type Config struct {
 
 	// Model-specific parameters (for switching models)
 	ModelParameters map[string]ModelParams `json:"model_parameters,omitempty"`
+
+	// Cost tracking: price overrides keyed by model name or prefix, and spending limits
+	Pricing map[string]ModelPricing `json:"pricing,omitempty"`
+	Budget  BudgetConfig            `json:"budget,omitempty"`
+
+	// Response cache for deterministic LLM calls
+	Cache CacheConfig `json:"cache,omitempty"`
+
+	// Review of file changes before they are written
+	Review ReviewConfig `json:"review,omitempty"`
+
+	// OS-level sandbox for commands the agent runs
+	Sandbox SandboxConfig `json:"sandbox,omitempty"`
 }
 
 // MCPServerConfig describes a single MCP server connection in the config file.
@@ -154,6 +167,56 @@ type ModelParams struct {
 	TopP          float64 `json:"top_p"`
 	MaxTokens     int     `json:"max_tokens"`
 	ContextWindow int     `json:"context_window"`
+	TextToolCalls bool    `json:"text_tool_calls,omitempty"` // model lacks native function calling
+}
+
+// ModelPricing is a model's price in USD per million tokens.
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// BudgetConfig holds spending limits in USD; zero disables a limit.
+// Soft limits warn once, hard limits pause the agent until /cost resume.
+type BudgetConfig struct {
+	SessionSoft float64 `json:"session_soft_usd,omitempty"`
+	SessionHard float64 `json:"session_hard_usd,omitempty"`
+	DailySoft   float64 `json:"daily_soft_usd,omitempty"`
+	DailyHard   float64 `json:"daily_hard_usd,omitempty"`
+}
+
+// CacheConfig controls the LLM response cache in .agi/cache/. Only calls at
+// temperature 0, or calls that opt in, are cached.
+type CacheConfig struct {
+	Enabled   bool `json:"enabled"`
+	TTLHours  int  `json:"ttl_hours,omitempty"`   // Entry lifetime (default: 24)
+	MaxSizeMB int  `json:"max_size_mb,omitempty"` // Oldest entries are evicted beyond this (default: 100)
+	Pipeline  bool `json:"pipeline,omitempty"`    // Also cache multi-agent pipeline calls at any temperature
+}
+
+// ReviewConfig makes file changes by write_file, edit_file and apply_patch
+// wait for the user to accept them in the diff review overlay. Paths matching
+// AllowedPaths (globs relative to the workplace; "**" matches any number of
+// directories, and a pattern without "/" matches the file name) are written
+// without review.
+type ReviewConfig struct {
+	Enabled      bool     `json:"enabled"`
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+}
+
+// SandboxConfig runs exec_command, run_tests, go_build and skills in a Linux
+// sandbox: the file system is read-only except the workplace, WritablePaths
+// and a private /tmp, the network is unreachable unless AllowNetwork is set,
+// resources are limited and only PATH, HOME, user, locale and terminal
+// variables are passed on, plus those named in Env.
+type SandboxConfig struct {
+	Enabled       bool     `json:"enabled"`
+	AllowNetwork  bool     `json:"allow_network,omitempty"`
+	CPUSeconds    int      `json:"cpu_seconds,omitempty"`    // CPU time per command (default: 300)
+	MemoryMB      int      `json:"memory_mb,omitempty"`      // Address space per process (default: 4096)
+	MaxProcesses  int      `json:"max_processes,omitempty"`  // Processes per command (default: 256)
+	Env           []string `json:"env,omitempty"`            // Further environment variables to pass on, e.g. "GOFLAGS"
+	WritablePaths []string `json:"writable_paths,omitempty"` // Writable directories besides the workplace, e.g. a build cache
 }
 
 // MemoryConfig holds memory system configuration
@@ -163,6 +226,21 @@ type MemoryConfig struct {
 	MaxLongTermItems   int    `json:"max_long_term_items"`
 	CompressionTrigger int    `json:"compression_trigger"`
 	StoragePath        string `json:"storage_path"`
+
+	Retrieval RetrievalConfig `json:"retrieval"`
+}
+
+// RetrievalConfig puts only the long-term memories and brain.md entries most
+// related to each user message into the system prompt, ranked by embedding
+// similarity. Without an embedding model, texts are embedded offline by
+// hashing their words.
+type RetrievalConfig struct {
+	Enabled        bool   `json:"enabled"`
+	TopK           int    `json:"top_k,omitempty"`           // Entries injected per message (default: 5)
+	EmbeddingModel string `json:"embedding_model,omitempty"` // e.g. "text-embedding-3-small" on an OpenAI-compatible /embeddings endpoint
+	BaseURL        string `json:"base_url,omitempty"`        // Embeddings API base URL (default: api_base_url)
+	APIKey         string `json:"api_key,omitempty"`         // Embeddings API key (default: api_key)
+	StoragePath    string `json:"storage_path,omitempty"`    // Stored vectors (default: .agi/vectors.json)
 }
 
 // UIConfig holds UI configuration
@@ -208,6 +286,12 @@ type PermissionsConfig struct {
 
 	// AuditLogPath defines where audit logs are stored
 	AuditLogPath string `json:"audit_log_path"`
+
+	// Rules are policy rules matching tools and their arguments, such as
+	// `deny exec_command cmd:"git push"` or `allow write_file path:src/**`.
+	// They come before the name-based settings above; rules in the
+	// project's .agi/policy.rules file are added to them.
+	Rules []string `json:"rules,omitempty"`
 }
 
 // DefaultConfig returns sensible defaults
@@ -243,6 +327,11 @@ func DefaultConfig() *Config {
 			MaxLongTermItems:   100,
 			CompressionTrigger: 15,
 			StoragePath:        ".agi/memory.json",
+			Retrieval: RetrievalConfig{
+				Enabled:     true,
+				TopK:        5,
+				StoragePath: ".agi/vectors.json",
+			},
 		},
 
 		MinConfidenceScore: 0.7,
@@ -279,6 +368,8 @@ func DefaultConfig() *Config {
 				"git_checkpoint",
 				"exec_command",
 				"write_file",
+				"edit_file",
+				"apply_patch",
 				"delete_file",
 				"rollback_edits",
 				"complete_edit",
@@ -487,6 +578,18 @@ func applyEnvOverrides(cfg *Config) {
 	}
 }
 
+// RequiresAPIKey reports whether the configured provider needs an API key.
+// Local Ollama servers are unauthenticated.
+func (c *Config) RequiresAPIKey() bool {
+	return !strings.EqualFold(c.Provider, "ollama")
+}
+
+// TextToolCallsFor reports whether model_parameters forces the text-based
+// tool protocol for model.
+func (c *Config) TextToolCallsFor(model string) bool {
+	return c.ModelParameters[model].TextToolCalls
+}
+
 // GetWorkplaceDir returns the workplace directory name, defaulting to "workplace".
 func (c *Config) GetWorkplaceDir() string {
 	if c.WorkplaceDir != "" {
@@ -599,6 +702,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_files_per_batch must be at least 1")
 	}
 
+	// Validate cost settings
+	for model, p := range c.Pricing {
+		if p.Input < 0 || p.Output < 0 {
+			return fmt.Errorf("pricing for %q must not be negative", model)
+		}
+	}
+	b := c.Budget
+	if b.SessionSoft < 0 || b.SessionHard < 0 || b.DailySoft < 0 || b.DailyHard < 0 {
+		return fmt.Errorf("budget limits must not be negative")
+	}
+	if c.Cache.TTLHours < 0 || c.Cache.MaxSizeMB < 0 {
+		return fmt.Errorf("cache ttl_hours and max_size_mb must not be negative")
+	}
+	if c.Memory.Retrieval.TopK < 0 {
+		return fmt.Errorf("memory retrieval top_k must not be negative")
+	}
+	for _, pattern := range c.Review.AllowedPaths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid review allowed_paths pattern %q: %w", pattern, err)
+		}
+	}
+
 	return nil
 }
 
@@ -616,6 +741,8 @@ func validateAPIKey(key, provider string) error {
 			provider = "openai"
 		} else if strings.HasPrefix(key, "nvapi-") {
 			provider = "nvidia"
+		} else if strings.HasPrefix(key, "AIza") {
+			provider = "gemini"
 		}
 	}
 
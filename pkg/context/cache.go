@@ -0,0 +1,86 @@
+package context
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+
+	"ClosedWheeler/pkg/ignore"
+)
+
+// ProjectCache keeps a loaded ProjectContext between calls. Loading
+// type-checks all Go code, so it is only repeated when a file was added,
+// removed or modified since the last load.
+type ProjectCache struct {
+	root   string
+	ignore *ignore.Patterns
+
+	mu    sync.Mutex
+	pc    *ProjectContext
+	stamp [sha256.Size]byte
+}
+
+// NewProjectCache creates a cache for the project at root, skipping the files
+// patterns ignore.
+func NewProjectCache(root string, patterns *ignore.Patterns) *ProjectCache {
+	absRoot, _ := filepath.Abs(root)
+	return &ProjectCache{root: absRoot, ignore: patterns}
+}
+
+// Root returns the absolute project root.
+func (c *ProjectCache) Root() string {
+	return c.root
+}
+
+// Get returns the project context, reloading it when files changed. The
+// returned context is not modified afterwards; a reload builds a new one.
+func (c *ProjectCache) Get() (*ProjectContext, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stamp := c.fingerprint()
+	if c.pc != nil && stamp == c.stamp {
+		return c.pc, nil
+	}
+	pc := NewProjectContext(c.root)
+	if err := pc.LoadIgnoring(c.ignore); err != nil {
+		return nil, err
+	}
+	c.pc, c.stamp = pc, stamp
+	return pc, nil
+}
+
+// fingerprint hashes the path, size and modification time of every file
+// that would be loaded.
+func (c *ProjectCache) fingerprint() [sha256.Size]byte {
+	h := sha256.New()
+	_ = filepath.WalkDir(c.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(c.root, path)
+		if rel == "." {
+			return nil
+		}
+		if c.ignore.ShouldIgnore(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,782 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeInfo holds information about a declared type
+type TypeInfo struct {
+	Name      string
+	Kind      string // "struct", "interface", or the kind of the underlying type, e.g. "map"
+	StartLine int
+	EndLine   int
+	Methods   []string // methods declared on the type, or listed by the interface
+}
+
+// Location is a position in a project file
+type Location struct {
+	File   string // relative to the project root
+	Line   int
+	Column int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// GoSymbol is a package-level declaration, method or struct field found by
+// type-checking the project's Go code
+type GoSymbol struct {
+	ID        string // import path, owning type and name, e.g. "mod/pkg/editor.Manager.ApplyChanges"
+	Name      string // name within the package, e.g. "Manager.ApplyChanges"
+	Package   string // package name
+	Kind      string // "type", "interface", "func", "method", "field", "var" or "const"
+	Receiver  string // owning type of methods and fields
+	Signature string
+	Pos       Location
+}
+
+// QualifiedName returns the symbol as referred to from another package,
+// e.g. "editor.Manager.ApplyChanges".
+func (s *GoSymbol) QualifiedName() string {
+	return s.Package + "." + s.Name
+}
+
+// CallEdge is a static call from one function or method to another
+type CallEdge struct {
+	Caller string // GoSymbol IDs
+	Callee string
+	Pos    Location
+}
+
+// GoIndex is the project's Go code, type-checked package by package. Imports
+// from outside the module are not resolved, so calls into them and
+// interfaces they declare are not recorded.
+type GoIndex struct {
+	Symbols         map[string]*GoSymbol
+	References      map[string][]Location // uses of each symbol, by ID
+	Implementations map[string][]string   // interface ID -> IDs of the types implementing it
+	Calls           []CallEdge
+}
+
+// Lookup finds symbols by name. The name may be qualified by package and
+// type ("editor.Manager.ApplyChanges", "Manager.ApplyChanges") or bare
+// ("ApplyChanges").
+func (g *GoIndex) Lookup(name string) []*GoSymbol {
+	var found []*GoSymbol
+	for _, s := range g.Symbols {
+		q := s.QualifiedName()
+		if q == name || strings.HasSuffix(q, "."+name) {
+			found = append(found, s)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found
+}
+
+// Implementers returns the types implementing an interface.
+func (g *GoIndex) Implementers(id string) []*GoSymbol {
+	return g.symbols(g.Implementations[id])
+}
+
+// Interfaces returns the interfaces a type implements.
+func (g *GoIndex) Interfaces(id string) []*GoSymbol {
+	var ids []string
+	for iface, impls := range g.Implementations {
+		for _, impl := range impls {
+			if impl == id {
+				ids = append(ids, iface)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return g.symbols(ids)
+}
+
+// Callers returns the calls made to a function or method.
+func (g *GoIndex) Callers(id string) []CallEdge {
+	var edges []CallEdge
+	for _, e := range g.Calls {
+		if e.Callee == id {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// Callees returns the calls made by a function or method.
+func (g *GoIndex) Callees(id string) []CallEdge {
+	var edges []CallEdge
+	for _, e := range g.Calls {
+		if e.Caller == id {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+func (g *GoIndex) symbols(ids []string) []*GoSymbol {
+	var out []*GoSymbol
+	for _, id := range ids {
+		if s, ok := g.Symbols[id]; ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// goUnit is a set of files type-checked together: a package, a package with
+// its in-package tests, or an external test package.
+type goUnit struct {
+	path   string // import path
+	files  []*ast.File
+	infos  []*FileInfo
+	record func(fi *FileInfo) bool // files whose declarations this unit records
+}
+
+// goAnalyzer type-checks the project's packages and fills a GoIndex.
+type goAnalyzer struct {
+	root       string
+	fset       *token.FileSet
+	packages   map[string]*goUnit // importable packages by import path
+	checked    map[string]*types.Package
+	inProgress map[string]bool
+	fields     map[*types.Var]string // struct field -> owning type name
+	index      *GoIndex
+
+	interfaces []*types.TypeName
+	concrete   []*types.TypeName
+}
+
+// analyzeGoPackages parses and type-checks the Go files among files, filling
+// their functions, types and imports, and returns the project's index.
+func analyzeGoPackages(root string, files map[string]*FileInfo) *GoIndex {
+	a := &goAnalyzer{
+		root:       root,
+		fset:       token.NewFileSet(),
+		packages:   make(map[string]*goUnit),
+		checked:    make(map[string]*types.Package),
+		inProgress: make(map[string]bool),
+		fields:     make(map[*types.Var]string),
+		index: &GoIndex{
+			Symbols:         make(map[string]*GoSymbol),
+			References:      make(map[string][]Location),
+			Implementations: make(map[string][]string),
+		},
+	}
+	module := readModulePath(root)
+
+	// Group files into packages by directory and package clause.
+	type key struct{ dir, name string }
+	groups := make(map[key]*goUnit)
+	var keys []key
+	for _, fi := range files {
+		if fi.Language != "go" || fi.Content == "" {
+			continue
+		}
+		f, _ := parser.ParseFile(a.fset, fi.Path, fi.Content, parser.ParseComments|parser.SkipObjectResolution)
+		if f == nil || f.Name == nil {
+			continue
+		}
+		fi.Imports = nil
+		for _, imp := range f.Imports {
+			if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+				fi.Imports = append(fi.Imports, p)
+			}
+		}
+
+		k := key{filepath.Dir(fi.Path), f.Name.Name}
+		u, ok := groups[k]
+		if !ok {
+			rel, _ := filepath.Rel(root, k.dir)
+			u = &goUnit{path: importPath(module, rel)}
+			groups[k] = u
+			keys = append(keys, k)
+		}
+		u.files = append(u.files, f)
+		u.infos = append(u.infos, fi)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].dir != keys[j].dir {
+			return keys[i].dir < keys[j].dir
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	var units []*goUnit
+	for _, k := range keys {
+		u := groups[k]
+		if strings.HasSuffix(k.name, "_test") {
+			u.path += "_test"
+			u.record = func(*FileInfo) bool { return true }
+			units = append(units, u)
+			continue
+		}
+
+		// The importable package excludes its tests, which are checked
+		// again together with the package's other files.
+		lib := &goUnit{path: u.path, record: func(*FileInfo) bool { return true }}
+		hasTests := false
+		for i, fi := range u.infos {
+			if strings.HasSuffix(fi.Path, "_test.go") {
+				hasTests = true
+				continue
+			}
+			lib.files = append(lib.files, u.files[i])
+			lib.infos = append(lib.infos, fi)
+		}
+		a.packages[lib.path] = lib
+		units = append(units, lib)
+		if hasTests {
+			u.record = func(fi *FileInfo) bool { return strings.HasSuffix(fi.Path, "_test.go") }
+			units = append(units, u)
+		}
+	}
+
+	for _, u := range units {
+		if _, ok := a.checked[u.path]; ok && a.packages[u.path] == u {
+			continue // already checked as a dependency
+		}
+		a.check(u)
+	}
+	a.findImplementations()
+	for _, refs := range a.index.References {
+		sort.Slice(refs, func(i, j int) bool {
+			if refs[i].File != refs[j].File {
+				return refs[i].File < refs[j].File
+			}
+			return refs[i].Line < refs[j].Line || refs[i].Line == refs[j].Line && refs[i].Column < refs[j].Column
+		})
+	}
+	return a.index
+}
+
+// Import implements types.Importer. Project packages are type-checked from
+// source; anything else becomes an empty package whose uses fail to resolve.
+func (a *goAnalyzer) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := a.checked[importPath]; ok {
+		return pkg, nil
+	}
+	if u, ok := a.packages[importPath]; ok && !a.inProgress[importPath] {
+		return a.check(u), nil
+	}
+	pkg := types.NewPackage(importPath, guessPackageName(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// check type-checks a unit and records what it declares and uses.
+func (a *goAnalyzer) check(u *goUnit) *types.Package {
+	a.inProgress[u.path] = true
+	defer delete(a.inProgress, u.path)
+
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer:    a,
+		Error:       func(error) {}, // keep going; unresolved imports are expected
+		FakeImportC: true,
+	}
+	name := ""
+	if len(u.files) > 0 {
+		name = u.files[0].Name.Name
+	}
+	pkg := types.NewPackage(u.path, name)
+	types.NewChecker(&conf, a.fset, pkg, info).Files(u.files)
+	if a.packages[u.path] == u {
+		a.checked[u.path] = pkg
+	}
+
+	a.recordFields(pkg)
+	recorded := make(map[string]*FileInfo)
+	for i, f := range u.files {
+		fi := u.infos[i]
+		if !u.record(fi) {
+			continue
+		}
+		recorded[fi.Path] = fi
+		a.recordDecls(pkg, f, fi, info)
+	}
+
+	for ident, obj := range info.Uses {
+		pos := a.fset.Position(ident.Pos())
+		if recorded[pos.Filename] == nil {
+			continue
+		}
+		if id := a.objectID(obj); id != "" {
+			a.index.References[id] = append(a.index.References[id], a.location(ident.Pos()))
+		}
+	}
+	return pkg
+}
+
+// recordFields maps the fields of the package's struct types to their type.
+func (a *goAnalyzer) recordFields(pkg *types.Package) {
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if st, ok := tn.Type().Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				a.fields[st.Field(i)] = name
+			}
+		}
+	}
+}
+
+// recordDecls adds a file's declarations to the index and to its FileInfo.
+func (a *goAnalyzer) recordDecls(pkg *types.Package, f *ast.File, fi *FileInfo, info *types.Info) {
+	fi.Functions = nil
+	fi.Types = nil
+
+	// Methods declared in this package, by receiver type
+	methods := make(map[string][]string)
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv != nil {
+			recv := receiverName(fd.Recv)
+			methods[recv] = append(methods[recv], fd.Name.Name)
+		}
+	}
+
+	add := func(ident *ast.Ident, kind, receiver, signature string) {
+		obj := info.Defs[ident]
+		if obj == nil {
+			return
+		}
+		id := a.objectID(obj)
+		if id == "" {
+			return
+		}
+		name := ident.Name
+		if receiver != "" {
+			name = receiver + "." + name
+		}
+		a.index.Symbols[id] = &GoSymbol{
+			ID:        id,
+			Name:      name,
+			Package:   pkg.Name(),
+			Kind:      kind,
+			Receiver:  receiver,
+			Signature: signature,
+			Pos:       a.location(ident.Pos()),
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind, receiver := "func", ""
+			if d.Recv != nil {
+				kind, receiver = "method", receiverName(d.Recv)
+			}
+			signature := a.render(&ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+			add(d.Name, kind, receiver, signature)
+			fi.Functions = append(fi.Functions, FunctionInfo{
+				Name:       d.Name.Name,
+				Receiver:   receiver,
+				StartLine:  a.fset.Position(d.Pos()).Line,
+				EndLine:    a.fset.Position(d.End()).Line,
+				Complexity: cyclomaticComplexity(d),
+				Signature:  signature,
+			})
+			if fn, ok := info.Defs[d.Name].(*types.Func); ok && d.Body != nil {
+				a.recordCalls(a.objectID(fn), d.Body, info)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					a.recordType(s, fi, info, methods[s.Name.Name], add)
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						signature := kind + " " + name.Name
+						if s.Type != nil {
+							signature += " " + a.render(s.Type)
+						}
+						add(name, kind, "", signature)
+					}
+				}
+			}
+		}
+	}
+}
+
+// recordType adds a type, its fields or interface methods, and remembers it
+// for the implementation search.
+func (a *goAnalyzer) recordType(s *ast.TypeSpec, fi *FileInfo, info *types.Info, methods []string, add func(*ast.Ident, string, string, string)) {
+	ti := TypeInfo{
+		Name:      s.Name.Name,
+		StartLine: a.fset.Position(s.Pos()).Line,
+		EndLine:   a.fset.Position(s.End()).Line,
+		Methods:   methods,
+	}
+	kind := "type"
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		ti.Kind = "struct"
+		for _, field := range t.Fields.List {
+			for _, name := range field.Names {
+				add(name, "field", s.Name.Name, name.Name+" "+a.render(field.Type))
+			}
+		}
+	case *ast.InterfaceType:
+		ti.Kind, kind = "interface", "interface"
+		ti.Methods = nil
+		for _, m := range t.Methods.List {
+			for _, name := range m.Names {
+				ti.Methods = append(ti.Methods, name.Name)
+				add(name, "method", s.Name.Name, name.Name+strings.TrimPrefix(a.render(m.Type), "func"))
+			}
+		}
+	default:
+		ti.Kind = "type"
+		if tn, ok := info.Defs[s.Name].(*types.TypeName); ok {
+			ti.Kind = underlyingKind(tn.Type())
+		}
+	}
+	add(s.Name, kind, "", "type "+s.Name.Name+" "+a.render(s.Type))
+	fi.Types = append(fi.Types, ti)
+
+	tn, ok := info.Defs[s.Name].(*types.TypeName)
+	if !ok || s.TypeParams != nil || s.Assign.IsValid() {
+		return
+	}
+	if iface, ok := tn.Type().Underlying().(*types.Interface); ok {
+		if iface.NumMethods() > 0 && iface.IsMethodSet() {
+			a.interfaces = append(a.interfaces, tn)
+		}
+	} else {
+		a.concrete = append(a.concrete, tn)
+	}
+}
+
+// recordCalls adds the calls in a function body that resolve to project
+// functions or methods.
+func (a *goAnalyzer) recordCalls(caller string, body *ast.BlockStmt, info *types.Info) {
+	if caller == "" {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fun := ast.Unparen(call.Fun)
+		switch f := fun.(type) {
+		case *ast.IndexExpr:
+			fun = f.X
+		case *ast.IndexListExpr:
+			fun = f.X
+		}
+		var ident *ast.Ident
+		switch f := fun.(type) {
+		case *ast.Ident:
+			ident = f
+		case *ast.SelectorExpr:
+			ident = f.Sel
+		}
+		if ident == nil {
+			return true
+		}
+		// Only project code resolves, since other imports are empty packages.
+		if fn, ok := info.Uses[ident].(*types.Func); ok {
+			if callee := a.objectID(fn); callee != "" {
+				a.index.Calls = append(a.index.Calls, CallEdge{Caller: caller, Callee: callee, Pos: a.location(call.Pos())})
+			}
+		}
+		return true
+	})
+}
+
+// findImplementations records which project types implement which project
+// interfaces, directly or through a pointer receiver.
+func (a *goAnalyzer) findImplementations() {
+	for _, itn := range a.interfaces {
+		iface := itn.Type().Underlying().(*types.Interface)
+		ifaceID := a.objectID(itn)
+		for _, tn := range a.concrete {
+			t := tn.Type()
+			if types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface) {
+				a.index.Implementations[ifaceID] = append(a.index.Implementations[ifaceID], a.objectID(tn))
+			}
+		}
+		sort.Strings(a.index.Implementations[ifaceID])
+	}
+}
+
+// objectID returns the index key of a package-level object, method or
+// struct field, or "" for anything local or outside the project.
+func (a *goAnalyzer) objectID(obj types.Object) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	p := obj.Pkg().Path()
+	switch o := obj.(type) {
+	case *types.Func:
+		o = o.Origin()
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			recv := namedTypeName(sig.Recv().Type())
+			if recv == "" {
+				return ""
+			}
+			return p + "." + recv + "." + o.Name()
+		}
+	case *types.Var:
+		if o.IsField() {
+			owner := a.fields[o.Origin()]
+			if owner == "" {
+				return ""
+			}
+			return p + "." + owner + "." + o.Name()
+		}
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		return ""
+	}
+	return p + "." + obj.Name()
+}
+
+func (a *goAnalyzer) location(pos token.Pos) Location {
+	position := a.fset.Position(pos)
+	rel, err := filepath.Rel(a.root, position.Filename)
+	if err != nil {
+		rel = position.Filename
+	}
+	return Location{File: filepath.ToSlash(rel), Line: position.Line, Column: position.Column}
+}
+
+// render prints a node as source on one line.
+func (a *goAnalyzer) render(node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, a.fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// cyclomaticComplexity counts the independent paths through a function: one
+// plus a branch for each if, loop, non-default case and && or || operator.
+func cyclomaticComplexity(fd *ast.FuncDecl) int {
+	complexity := 1
+	if fd.Body == nil {
+		return complexity
+	}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// or type parameters.
+func receiverName(recv *ast.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+	t := recv.List[0].Type
+	for {
+		switch e := t.(type) {
+		case *ast.StarExpr:
+			t = e.X
+		case *ast.ParenExpr:
+			t = e.X
+		case *ast.IndexExpr:
+			t = e.X
+		case *ast.IndexListExpr:
+			t = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// namedTypeName returns the name of a (pointer to a) named type.
+func namedTypeName(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok {
+		return n.Obj().Name()
+	}
+	return ""
+}
+
+// underlyingKind describes a non-struct, non-interface type.
+func underlyingKind(t types.Type) string {
+	switch t.Underlying().(type) {
+	case *types.Basic:
+		return "basic"
+	case *types.Map:
+		return "map"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Signature:
+		return "func"
+	case *types.Chan:
+		return "chan"
+	case *types.Pointer:
+		return "pointer"
+	}
+	return "type"
+}
+
+// readModulePath returns the module path declared in root/go.mod, or "".
+func readModulePath(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		}
+	}
+	return ""
+}
+
+// importPath returns the import path of a project directory.
+func importPath(module, rel string) string {
+	rel = filepath.ToSlash(rel)
+	switch {
+	case module == "":
+		return rel
+	case rel == ".":
+		return module
+	default:
+		return module + "/" + rel
+	}
+}
+
+// guessPackageName returns the usual package name for an import path, e.g.
+// "yaml" for "gopkg.in/yaml.v3".
+func guessPackageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(path.Base(name), "go-")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// goOutline outlines a single Go file from its syntax tree alone, for callers
+// that cannot afford to type-check the whole project. Named types that are
+// neither structs nor interfaces are reported with kind "type".
+type goOutline struct{}
+
+// Extract implements OutlineExtractor.
+func (goOutline) Extract(content string) Outline {
+	fset := token.NewFileSet()
+	// A file with syntax errors still yields the declarations that parsed
+	f, _ := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if f == nil {
+		return Outline{}
+	}
+	a := &goAnalyzer{fset: fset}
+
+	var o Outline
+	for _, imp := range f.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			o.Imports = append(o.Imports, p)
+		}
+	}
+	methods := make(map[string][]string)
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		fn := FunctionInfo{
+			Name:       fd.Name.Name,
+			Receiver:   receiverName(fd.Recv),
+			StartLine:  fset.Position(fd.Pos()).Line,
+			EndLine:    fset.Position(fd.End()).Line,
+			Complexity: cyclomaticComplexity(fd),
+			Signature:  a.render(&ast.FuncDecl{Recv: fd.Recv, Name: fd.Name, Type: fd.Type}),
+		}
+		if fn.Receiver != "" {
+			methods[fn.Receiver] = append(methods[fn.Receiver], fn.Name)
+		}
+		o.Functions = append(o.Functions, fn)
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			ti := TypeInfo{
+				Name:      s.Name.Name,
+				Kind:      "type",
+				StartLine: fset.Position(s.Pos()).Line,
+				EndLine:   fset.Position(s.End()).Line,
+				Methods:   methods[s.Name.Name],
+			}
+			switch t := s.Type.(type) {
+			case *ast.StructType:
+				ti.Kind = "struct"
+			case *ast.InterfaceType:
+				ti.Kind, ti.Methods = "interface", nil
+				for _, m := range t.Methods.List {
+					for _, name := range m.Names {
+						ti.Methods = append(ti.Methods, name.Name)
+					}
+				}
+			}
+			o.Types = append(o.Types, ti)
+		}
+	}
+	return o
+}
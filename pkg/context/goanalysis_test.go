@@ -0,0 +1,185 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ClosedWheeler/pkg/ignore"
+)
+
+const shapesSrc = `package shapes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shape has an area.
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+
+func (s *Square) String() string {
+	return fmt.Sprintf("square {%v}", strings.Repeat("}", 2))
+}
+
+func Max[T int | float64](a, b T) T {
+	if a > b && a != 0 {
+		return a
+	}
+	return b
+}
+
+func Classify(n int) string {
+	switch {
+	case n < 0:
+		return "negative"
+	case n == 0:
+		return "zero"
+	default:
+		for i := 0; i < n; i++ {
+		}
+		return "positive"
+	}
+}
+`
+
+const mainSrc = `package main
+
+import "example.com/geo/shapes"
+
+func main() {
+	var s shapes.Shape = &shapes.Square{Side: 2}
+	_ = s.Area()
+	_ = shapes.Max(1, 2)
+}
+`
+
+func loadFixture(t *testing.T) *ProjectContext {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/geo\n\ngo 1.22\n",
+		"shapes/shapes.go": shapesSrc,
+		"cmd/main.go":      mainSrc,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pc := NewProjectContext(root)
+	if err := pc.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func TestAnalyzeGo_FileInfo(t *testing.T) {
+	pc := loadFixture(t)
+	fi, ok := pc.GetFile("shapes/shapes.go")
+	if !ok {
+		t.Fatal("shapes.go not loaded")
+	}
+
+	if len(fi.Imports) != 2 || fi.Imports[0] != "fmt" || fi.Imports[1] != "strings" {
+		t.Errorf("Imports = %v", fi.Imports)
+	}
+	funcs := make(map[string]FunctionInfo)
+	for _, fn := range fi.Functions {
+		funcs[fn.Receiver+"."+fn.Name] = fn
+	}
+	if len(funcs) != 4 {
+		t.Fatalf("expected 4 functions, got %+v", fi.Functions)
+	}
+	if fn := funcs["Square.String"]; fn.StartLine != 19 || fn.EndLine != 21 {
+		t.Errorf("braces in strings should not end String early, got lines %d-%d", fn.StartLine, fn.EndLine)
+	}
+	if got := funcs[".Max"].Complexity; got != 3 {
+		t.Errorf("Max complexity = %d, want 3 (if, &&)", got)
+	}
+	if got := funcs[".Classify"].Complexity; got != 4 {
+		t.Errorf("Classify complexity = %d, want 4 (two cases, for)", got)
+	}
+	if got := funcs["Square.Area"].Signature; got != "func (s *Square) Area() float64" {
+		t.Errorf("Area signature = %q", got)
+	}
+
+	if len(fi.Types) != 2 || fi.Types[0].Kind != "interface" || fi.Types[1].Kind != "struct" {
+		t.Fatalf("Types = %+v", fi.Types)
+	}
+	if m := fi.Types[1].Methods; len(m) != 2 {
+		t.Errorf("Square methods = %v", m)
+	}
+}
+
+func TestAnalyzeGo_Index(t *testing.T) {
+	pc := loadFixture(t)
+	g := pc.Go
+
+	shape := g.Lookup("shapes.Shape")
+	if len(shape) != 1 || shape[0].Kind != "interface" || shape[0].Pos.File != "shapes/shapes.go" || shape[0].Pos.Line != 9 {
+		t.Fatalf("Lookup(shapes.Shape) = %+v", shape)
+	}
+	impls := g.Implementers(shape[0].ID)
+	if len(impls) != 1 || impls[0].Name != "Square" {
+		t.Errorf("Shape implementers = %+v", impls)
+	}
+
+	area := g.Lookup("Square.Area")
+	if len(area) != 1 {
+		t.Fatalf("Lookup(Square.Area) = %+v", area)
+	}
+	if refs := g.References[shape[0].ID]; len(refs) != 1 || refs[0].File != "cmd/main.go" {
+		t.Errorf("Shape references = %+v", refs)
+	}
+
+	// s.Area() calls the interface method; Max is called across packages.
+	ifaceArea := g.Lookup("Shape.Area")
+	if len(ifaceArea) != 1 || len(g.Callers(ifaceArea[0].ID)) != 1 {
+		t.Errorf("expected one call to Shape.Area, got %+v", g.Calls)
+	}
+	max := g.Lookup("Max")
+	if len(max) != 1 || len(g.Callers(max[0].ID)) != 1 || g.Callers(max[0].ID)[0].Caller != "example.com/geo/cmd.main" {
+		t.Errorf("expected main to call Max, got %+v", g.Calls)
+	}
+}
+
+func TestProjectCache_ReloadsOnChange(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/app\n")
+	write("a.go", "package app\n\nfunc A() {}\n")
+
+	cache := NewProjectCache(root, ignore.Load(root))
+	first, err := cache.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cache.Get(); again != first {
+		t.Error("expected an unchanged project to be served from the cache")
+	}
+
+	write("b.go", "package app\n\nfunc B() {}\n")
+	second, err := cache.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || len(second.Go.Lookup("B")) != 1 {
+		t.Error("expected a new file to reload the project")
+	}
+}
@@ -0,0 +1,80 @@
+package context
+
+import (
+	"sync"
+)
+
+// Outline is what an extractor finds in a source file
+type Outline struct {
+	Functions []FunctionInfo
+	Types     []TypeInfo
+	Imports   []string
+}
+
+// OutlineExtractor finds the functions, types and imports of one language.
+// Extractors work on a single file without resolving anything, so they only
+// need to be good enough for navigation and metrics.
+type OutlineExtractor interface {
+	Extract(content string) Outline
+}
+
+var (
+	outlineMu         sync.RWMutex
+	outlineExtractors = map[string]OutlineExtractor{
+		"python":     pythonOutline{},
+		"javascript": jsOutline,
+		"typescript": jsOutline,
+		"rust":       rustOutline,
+		"java":       javaOutline,
+	}
+)
+
+// RegisterOutlineExtractor sets the extractor for a language as named by
+// detectLanguage, replacing any built-in one. Go files are type-checked
+// instead (see analyzeGoPackages).
+func RegisterOutlineExtractor(lang string, e OutlineExtractor) {
+	outlineMu.Lock()
+	defer outlineMu.Unlock()
+	outlineExtractors[lang] = e
+}
+
+// outlineExtractor returns the extractor for a language, or nil.
+func outlineExtractor(lang string) OutlineExtractor {
+	outlineMu.RLock()
+	defer outlineMu.RUnlock()
+	return outlineExtractors[lang]
+}
+
+// HasOutline reports whether functions and types are extracted for a
+// language.
+func HasOutline(lang string) bool {
+	return lang == "go" || outlineExtractor(lang) != nil
+}
+
+// extractOutline fills a file's functions, types and imports using the
+// extractor for its language.
+func (fi *FileInfo) extractOutline() {
+	e := outlineExtractor(fi.Language)
+	if e == nil || fi.Content == "" {
+		return
+	}
+	o := e.Extract(fi.Content)
+	fi.Functions = o.Functions
+	fi.Types = o.Types
+	fi.Imports = o.Imports
+}
+
+// OutlineFile extracts the outline of one file, choosing the extractor from
+// the file name. Go files are parsed but not type-checked. It reports false
+// when the language has no extractor.
+func OutlineFile(path, content string) (Outline, bool) {
+	lang := detectLanguage(path)
+	if lang == "go" {
+		return goOutline{}.Extract(content), true
+	}
+	e := outlineExtractor(lang)
+	if e == nil {
+		return Outline{}, false
+	}
+	return e.Extract(content), true
+}
@@ -0,0 +1,411 @@
+package context
+
+import (
+	"regexp"
+	"strings"
+)
+
+// cSyntax describes the literals and comments of a brace-delimited language.
+type cSyntax struct {
+	singleQuoteStrings bool // 'x' is a string (JS), not a char literal
+	templates          bool // `x` is a string (JS)
+	rawStrings         bool // r"x" and r#"x"# (Rust)
+	nestedComments     bool // /* /* */ */ (Rust)
+	asi                bool // a line break may end a statement (JS)
+}
+
+// codeLines is a source file with comments and the contents of strings
+// blanked out, so braces and keywords can be matched line by line.
+type codeLines struct {
+	raw   []string
+	code  []string
+	depth []int // brace depth at the start of each line
+	asi   bool
+}
+
+// newCodeLines blanks out the comments and literals of content. Quote
+// characters are kept, so an empty string literal still reads as one.
+func newCodeLines(content string, syntax cSyntax) *codeLines {
+	src := []rune(content)
+	out := make([]rune, len(src))
+	copy(out, src)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(out); k++ {
+			if out[k] != '\n' {
+				out[k] = ' '
+			}
+		}
+	}
+	at := func(i int, s string) bool {
+		return strings.HasPrefix(string(src[i:min(i+len(s), len(src))]), s)
+	}
+
+	for i := 0; i < len(src); {
+		r := src[i]
+		switch {
+		case at(i, "//"):
+			j := i
+			for j < len(src) && src[j] != '\n' {
+				j++
+			}
+			blank(i, j)
+			i = j
+
+		case at(i, "/*"):
+			j, depth := i+2, 1
+			for j < len(src) && depth > 0 {
+				switch {
+				case syntax.nestedComments && at(j, "/*"):
+					depth++
+					j += 2
+				case at(j, "*/"):
+					depth--
+					j += 2
+				default:
+					j++
+				}
+			}
+			blank(i, j)
+			i = j
+
+		case syntax.rawStrings && r == 'r' && (i == 0 || !isIdentRune(src[i-1])) && i+1 < len(src) && (src[i+1] == '"' || src[i+1] == '#'):
+			j := i + 1
+			hashes := 0
+			for j < len(src) && src[j] == '#' {
+				hashes++
+				j++
+			}
+			if j >= len(src) || src[j] != '"' {
+				i++
+				continue
+			}
+			closing := "\"" + strings.Repeat("#", hashes)
+			k := j + 1
+			for k < len(src) && !at(k, closing) {
+				k++
+			}
+			blank(j+1, k)
+			i = k + len(closing)
+
+		case r == '"' || (r == '`' && syntax.templates) || (r == '\'' && syntax.singleQuoteStrings):
+			j := i + 1
+			for j < len(src) && src[j] != r {
+				if src[j] == '\\' {
+					j++
+				} else if src[j] == '\n' && r != '`' {
+					break // unterminated
+				}
+				j++
+			}
+			blank(i+1, j)
+			i = j + 1
+
+		case r == '\'':
+			// A char literal ('x', '\n', '\u{1F600}'); otherwise a Rust
+			// lifetime or label.
+			j := i + 1
+			if j < len(src) && src[j] == '\\' {
+				for j < len(src) && src[j] != '\'' && src[j] != '\n' {
+					if src[j] == '\\' {
+						j++
+					}
+					j++
+				}
+			} else if j+1 < len(src) && src[j+1] == '\'' {
+				j++
+			} else {
+				i++
+				continue
+			}
+			blank(i+1, j)
+			i = j + 1
+
+		default:
+			i++
+		}
+	}
+
+	c := &codeLines{
+		raw:  strings.Split(content, "\n"),
+		code: strings.Split(string(out), "\n"),
+		asi:  syntax.asi,
+	}
+	depth := 0
+	for _, line := range c.code {
+		c.depth = append(c.depth, depth)
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	return c
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// blockEnd returns the line on which the declaration starting at line ends:
+// the line of the brace closing its body, or the end of a declaration
+// without one. Braces inside the parameter list do not open the body.
+func (c *codeLines) blockEnd(line int) int {
+	parens, depth := 0, 0
+	opened := false
+	for l := line; l < len(c.code); l++ {
+		for _, r := range c.code[l] {
+			switch r {
+			case '(', '[':
+				parens++
+			case ')', ']':
+				parens--
+			case '{':
+				if opened || parens == 0 {
+					depth++
+					opened = true
+				}
+			case '}':
+				if opened {
+					depth--
+					if depth == 0 {
+						return l
+					}
+				}
+			case ';':
+				if !opened && parens == 0 {
+					return l
+				}
+			}
+		}
+		if c.asi && !opened && parens == 0 && !continues(c.code[l]) {
+			return l
+		}
+	}
+	return len(c.code) - 1
+}
+
+// continues reports whether a line obviously continues on the next one.
+func continues(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	return strings.ContainsAny(line[len(line)-1:], ",([{=>|&+-*/?:.")
+}
+
+// signature returns the declaration's first line without its opening brace.
+func (c *codeLines) signature(line int) string {
+	s := strings.TrimSpace(c.raw[line])
+	s = strings.TrimSpace(strings.TrimSuffix(s, "{"))
+	if len(s) > 160 {
+		s = s[:157] + "..."
+	}
+	return s
+}
+
+// countMatches counts the matches of re in code lines from..to inclusive.
+func (c *codeLines) countMatches(re *regexp.Regexp, from, to int) int {
+	n := 0
+	for l := from; l <= to && l < len(c.code); l++ {
+		n += len(re.FindAllStringIndex(c.code[l], -1))
+	}
+	return n
+}
+
+// declPattern matches a declaration on one line; the last submatch is its
+// name.
+type declPattern struct {
+	re   *regexp.Regexp
+	kind string
+}
+
+func (p declPattern) match(line string) string {
+	m := p.re.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	return m[len(m)-1]
+}
+
+// clikeOutline extracts outlines from brace-delimited languages with a set
+// of line patterns.
+type clikeOutline struct {
+	syntax    cSyntax
+	types     []declPattern    // classes, interfaces, structs...; their bodies hold methods
+	blocks    []declPattern    // other blocks whose functions are methods of the named type (Rust impl)
+	functions []declPattern    // functions at any level
+	methods   *regexp.Regexp   // members, only matched directly in a type's body
+	imports   []*regexp.Regexp // matched on raw lines; the first submatch is the import
+	branches  *regexp.Regexp   // adds one to a function's complexity per match
+	notNames  map[string]bool  // keywords a method pattern may catch
+}
+
+// container is a type or block whose direct functions are its methods.
+type container struct {
+	name       string
+	start, end int
+	bodyDepth  int
+	typeIndex  int // index in Types, or -1
+}
+
+// Extract implements OutlineExtractor.
+func (x clikeOutline) Extract(content string) Outline {
+	c := newCodeLines(content, x.syntax)
+	var o Outline
+	var containers []container
+
+	seen := make(map[string]bool)
+	for l, raw := range c.raw {
+		if strings.TrimSpace(c.code[l]) == "" {
+			continue // comment or inside a string
+		}
+		for _, re := range x.imports {
+			for _, m := range re.FindAllStringSubmatch(raw, -1) {
+				if !seen[m[1]] {
+					seen[m[1]] = true
+					o.Imports = append(o.Imports, m[1])
+				}
+			}
+		}
+	}
+
+	var functionLines []int
+lines:
+	for l, line := range c.code {
+		for _, p := range x.types {
+			if name := p.match(line); name != "" {
+				end := c.blockEnd(l)
+				o.Types = append(o.Types, TypeInfo{Name: name, Kind: p.kind, StartLine: l + 1, EndLine: end + 1})
+				containers = append(containers, container{name, l, end, c.depth[l] + 1, len(o.Types) - 1})
+				continue lines
+			}
+		}
+		for _, p := range x.blocks {
+			if name := p.match(line); name != "" {
+				containers = append(containers, container{name, l, c.blockEnd(l), c.depth[l] + 1, -1})
+				continue lines
+			}
+		}
+		name := ""
+		for _, p := range x.functions {
+			if name = p.match(line); name != "" {
+				break
+			}
+		}
+		if name == "" && x.methods != nil && inContainerBody(containers, l, c.depth[l]) {
+			if m := x.methods.FindStringSubmatch(line); m != nil && !x.notNames[m[len(m)-1]] {
+				name = m[len(m)-1]
+			}
+		}
+		if name == "" {
+			continue
+		}
+		end := c.blockEnd(l)
+		o.Functions = append(o.Functions, FunctionInfo{
+			Name:       name,
+			StartLine:  l + 1,
+			EndLine:    end + 1,
+			Complexity: 1 + c.countMatches(x.branches, l, end),
+			Signature:  c.signature(l),
+		})
+		functionLines = append(functionLines, l)
+	}
+
+	// A function directly in a type's body or impl block is its method.
+	for i, l := range functionLines {
+		for k := len(containers) - 1; k >= 0; k-- {
+			ct := containers[k]
+			if ct.start < l && l <= ct.end && c.depth[l] == ct.bodyDepth {
+				o.Functions[i].Receiver = ct.name
+				if ct.typeIndex < 0 {
+					for t := range o.Types {
+						if o.Types[t].Name == ct.name {
+							ct.typeIndex = t
+						}
+					}
+				}
+				if ct.typeIndex >= 0 {
+					o.Types[ct.typeIndex].Methods = append(o.Types[ct.typeIndex].Methods, o.Functions[i].Name)
+				}
+				break
+			}
+		}
+	}
+	return o
+}
+
+// inContainerBody reports whether line l is directly in a container's body.
+func inContainerBody(containers []container, l, depth int) bool {
+	for _, ct := range containers {
+		if ct.start < l && l <= ct.end && depth == ct.bodyDepth {
+			return true
+		}
+	}
+	return false
+}
+
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"function": true, "new": true, "else": true, "do": true, "try": true, "synchronized": true,
+	"throw": true, "await": true, "typeof": true, "super": true, "this": true,
+}
+
+// jsOutline covers JavaScript and TypeScript.
+var jsOutline = clikeOutline{
+	syntax: cSyntax{singleQuoteStrings: true, templates: true, asi: true},
+	types: []declPattern{
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`), "class"},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?interface\s+([A-Za-z_$][\w$]*)`), "interface"},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+([A-Za-z_$][\w$]*)`), "enum"},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?type\s+([A-Za-z_$][\w$]*)\s*(?:<[^=]*>)?\s*=`), "type"},
+	},
+	functions: []declPattern{
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`), "function"},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*(?::\s*[^=]+)?=>)`), "function"},
+	},
+	methods: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|readonly|abstract|override|async|get|set)\s+)*\*?\s*(?:#)?([A-Za-z_$][\w$]*)\s*(?:<[^>]*>)?\s*\(`),
+	imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(?:import|export)\b[^'"]*?\bfrom\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\s*\}\s*from\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`^\s*import\s+['"]([^'"]+)['"]`),
+		regexp.MustCompile(`\brequire\(\s*['"]([^'"]+)['"]\s*\)`),
+	},
+	branches: regexp.MustCompile(`\b(?:if|for|while|case|catch)\b|&&|\|\||\?\?`),
+	notNames: controlKeywords,
+}
+
+// rustOutline covers Rust; functions in impl blocks are methods of the
+// implementing type.
+var rustOutline = clikeOutline{
+	syntax: cSyntax{rawStrings: true, nestedComments: true},
+	types: []declPattern{
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(\w+)`), "struct"},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(\w+)`), "enum"},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?union\s+(\w+)`), "union"},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+(\w+)`), "trait"},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(\w+)`), "type"},
+	},
+	blocks: []declPattern{
+		{regexp.MustCompile(`^\s*(?:unsafe\s+)?impl\b(?:\s*<[^>]*>)?\s+(?:[\w:]+(?:<[^>]*>)?\s+for\s+)?(?:[\w]+::)*(\w+)`), "impl"},
+	},
+	functions: []declPattern{
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:default\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+(\w+)`), "fn"},
+	},
+	imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?use\s+([\w:]+(?:\{[^}]*\}|\*)?)`),
+		regexp.MustCompile(`^\s*extern\s+crate\s+(\w+)`),
+	},
+	branches: regexp.MustCompile(`\b(?:if|for|while|loop)\b|=>|&&|\|\||\?`),
+}
+
+// javaOutline covers Java.
+var javaOutline = clikeOutline{
+	syntax: cSyntax{},
+	types: []declPattern{
+		{regexp.MustCompile(`^\s*(?:@\w+(?:\([^)]*\))?\s+)*(?:(?:public|protected|private|abstract|final|static|sealed|non-sealed|strictfp)\s+)*(?:class|record)\s+(\w+)`), "class"},
+		{regexp.MustCompile(`^\s*(?:(?:public|protected|private|abstract|static|sealed|non-sealed|strictfp)\s+)*@?interface\s+(\w+)`), "interface"},
+		{regexp.MustCompile(`^\s*(?:(?:public|protected|private|static|strictfp)\s+)*enum\s+(\w+)`), "enum"},
+	},
+	methods: regexp.MustCompile(`^\s*(?:@\w+(?:\([^)]*\))?\s+)*(?:(?:public|protected|private|static|final|abstract|synchronized|native|default|strictfp)\s+)*(?:<[^>]+>\s+)?(?:[\w.$]+(?:<.*>)?(?:\[\])*\s+)?(\w+)\s*\(`),
+	imports: []*regexp.Regexp{
+		regexp.MustCompile(`^\s*import\s+(?:static\s+)?([\w.]+(?:\.\*)?)\s*;`),
+	},
+	branches: regexp.MustCompile(`\b(?:if|for|while|case|catch)\b|&&|\|\|`),
+	notNames: controlKeywords,
+}
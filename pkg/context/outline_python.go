@@ -0,0 +1,188 @@
+package context
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	pyDef        = regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`)
+	pyClass      = regexp.MustCompile(`^\s*class\s+(\w+)`)
+	pyImport     = regexp.MustCompile(`^\s*import\s+(.+)`)
+	pyFromImport = regexp.MustCompile(`^\s*from\s+(\S+)\s+import\b`)
+	pyBranches   = regexp.MustCompile(`\b(?:if|elif|for|while|except|and|or|case)\b`)
+)
+
+// pythonOutline extracts outlines from Python, using indentation to find
+// where definitions end and which class a function belongs to.
+type pythonOutline struct{}
+
+// Extract implements OutlineExtractor.
+func (pythonOutline) Extract(content string) Outline {
+	raw := strings.Split(content, "\n")
+	code, starts := pythonCode(content)
+
+	type block struct {
+		indent int
+		class  int // index in Types, or -1 for functions
+	}
+	var o Outline
+	var stack []block
+	var decls []int     // start line of each function
+	var typeLines []int // start line of each class
+
+	for l, line := range code {
+		if !starts[l] || strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := pythonIndent(raw[l])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		if m := pyFromImport.FindStringSubmatch(line); m != nil {
+			o.Imports = append(o.Imports, m[1])
+		} else if m := pyImport.FindStringSubmatch(line); m != nil {
+			for _, name := range strings.Split(m[1], ",") {
+				if fields := strings.Fields(name); len(fields) > 0 {
+					o.Imports = append(o.Imports, fields[0])
+				}
+			}
+		}
+
+		if m := pyClass.FindStringSubmatch(line); m != nil {
+			o.Types = append(o.Types, TypeInfo{Name: m[1], Kind: "class", StartLine: l + 1})
+			typeLines = append(typeLines, l)
+			stack = append(stack, block{indent, len(o.Types) - 1})
+			continue
+		}
+		if m := pyDef.FindStringSubmatch(line); m != nil {
+			fn := FunctionInfo{Name: m[1], StartLine: l + 1, Signature: strings.TrimSuffix(strings.TrimSpace(raw[l]), ":")}
+			if len(stack) > 0 && stack[len(stack)-1].class >= 0 {
+				t := &o.Types[stack[len(stack)-1].class]
+				fn.Receiver = t.Name
+				t.Methods = append(t.Methods, fn.Name)
+			}
+			o.Functions = append(o.Functions, fn)
+			decls = append(decls, l)
+			stack = append(stack, block{indent, -1})
+		}
+	}
+
+	for i, l := range decls {
+		end := pythonBlockEnd(raw, code, starts, l)
+		o.Functions[i].EndLine = end + 1
+		o.Functions[i].Complexity = 1
+		for k := l; k <= end; k++ {
+			o.Functions[i].Complexity += len(pyBranches.FindAllStringIndex(code[k], -1))
+		}
+	}
+	for i, l := range typeLines {
+		o.Types[i].EndLine = pythonBlockEnd(raw, code, starts, l) + 1
+	}
+	return o
+}
+
+// pythonBlockEnd returns the last non-blank line of the block opened at line.
+func pythonBlockEnd(raw, code []string, starts []bool, line int) int {
+	indent := pythonIndent(raw[line])
+	end := line
+	for l := line + 1; l < len(code); l++ {
+		if strings.TrimSpace(code[l]) == "" && strings.TrimSpace(raw[l]) == "" {
+			continue
+		}
+		if starts[l] && strings.TrimSpace(code[l]) != "" && pythonIndent(raw[l]) <= indent {
+			break
+		}
+		end = l
+	}
+	return end
+}
+
+// pythonIndent returns the width of a line's indentation, counting a tab as
+// four columns.
+func pythonIndent(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// pythonCode blanks out comments and string contents, and reports which
+// lines start a statement (are not inside brackets or a multi-line string).
+func pythonCode(content string) ([]string, []bool) {
+	src := []rune(content)
+	out := make([]rune, len(src))
+	copy(out, src)
+
+	var starts []bool
+	starts = append(starts, true)
+	brackets := 0
+	for i := 0; i < len(src); {
+		r := src[i]
+		switch {
+		case r == '\n':
+			starts = append(starts, brackets == 0 && (i == 0 || src[i-1] != '\\'))
+			i++
+
+		case r == '#':
+			for i < len(src) && src[i] != '\n' {
+				out[i] = ' '
+				i++
+			}
+
+		case r == '"' || r == '\'':
+			quote := string(r)
+			if i+2 < len(src) && src[i+1] == r && src[i+2] == r {
+				quote = strings.Repeat(quote, 3)
+			}
+			closes := func(j int) bool {
+				return strings.HasPrefix(string(src[j:min(j+len(quote), len(src))]), quote)
+			}
+			j := i + len(quote)
+		scan:
+			for j < len(src) && !closes(j) {
+				switch {
+				case src[j] == '\n':
+					if len(quote) == 1 && src[j-1] != '\\' {
+						break scan // unterminated; the outer loop ends the line
+					}
+					starts = append(starts, false)
+					j++
+					continue
+				case src[j] == '\\' && j+1 < len(src) && src[j+1] != '\n':
+					out[j] = ' '
+					j++
+				}
+				out[j] = ' '
+				j++
+			}
+			if j < len(src) && closes(j) {
+				j += len(quote)
+			}
+			i = j
+
+		case strings.ContainsRune("([{", r):
+			brackets++
+			i++
+
+		case strings.ContainsRune(")]}", r):
+			if brackets > 0 {
+				brackets--
+			}
+			i++
+
+		default:
+			i++
+		}
+	}
+	return strings.Split(string(out), "\n"), starts
+}
@@ -0,0 +1,285 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// outlineSymbol finds a function by receiver-qualified name.
+func outlineSymbol(t *testing.T, o Outline, name string) FunctionInfo {
+	t.Helper()
+	for _, fn := range o.Functions {
+		qualified := fn.Name
+		if fn.Receiver != "" {
+			qualified = fn.Receiver + "." + fn.Name
+		}
+		if qualified == name {
+			return fn
+		}
+	}
+	t.Fatalf("function %s not found in %+v", name, o.Functions)
+	return FunctionInfo{}
+}
+
+func outlineType(t *testing.T, o Outline, name string) TypeInfo {
+	t.Helper()
+	for _, ti := range o.Types {
+		if ti.Name == name {
+			return ti
+		}
+	}
+	t.Fatalf("type %s not found in %+v", name, o.Types)
+	return TypeInfo{}
+}
+
+func checkLines(t *testing.T, name string, gotStart, gotEnd, start, end int) {
+	t.Helper()
+	if gotStart != start || gotEnd != end {
+		t.Errorf("%s spans %d-%d, want %d-%d", name, gotStart, gotEnd, start, end)
+	}
+}
+
+func TestPythonOutline(t *testing.T) {
+	src := `import os, sys as system
+from collections import OrderedDict
+
+class Greeter(Base):
+    """A class with def inside the docstring:
+    def fake():
+    """
+
+    def __init__(self, name):
+        self.name = name  # def comment()
+
+    async def greet(self,
+                    loud=False):
+        if loud:
+            return self.name.upper()
+        return self.name
+
+
+def helper(x):
+    return [
+        x
+    ]
+`
+	o := pythonOutline{}.Extract(src)
+
+	if want := []string{"os", "sys", "collections"}; !reflect.DeepEqual(o.Imports, want) {
+		t.Errorf("imports = %v, want %v", o.Imports, want)
+	}
+	if len(o.Functions) != 3 {
+		t.Fatalf("got %d functions, want 3: %+v", len(o.Functions), o.Functions)
+	}
+
+	greeter := outlineType(t, o, "Greeter")
+	checkLines(t, "Greeter", greeter.StartLine, greeter.EndLine, 4, 16)
+	if greeter.Kind != "class" || !reflect.DeepEqual(greeter.Methods, []string{"__init__", "greet"}) {
+		t.Errorf("Greeter = %+v", greeter)
+	}
+
+	greet := outlineSymbol(t, o, "Greeter.greet")
+	checkLines(t, "greet", greet.StartLine, greet.EndLine, 12, 16)
+	if greet.Complexity != 2 {
+		t.Errorf("greet complexity = %d, want 2", greet.Complexity)
+	}
+	helper := outlineSymbol(t, o, "helper")
+	checkLines(t, "helper", helper.StartLine, helper.EndLine, 19, 22)
+}
+
+func TestTypeScriptOutline(t *testing.T) {
+	src := `import { readFile } from "fs";
+import * as path from 'path';
+
+export interface Named {
+  name: string;
+}
+
+export class Store<T> implements Named {
+  name = "store";
+  private items: T[] = [];
+
+  constructor(private readonly dir: string) {}
+
+  async load(file: string): Promise<T[]> {
+    const text = "}" + ` + "`${file} {`" + `;
+    if (text && file) {
+      return [];
+    }
+    return this.items;
+  }
+}
+
+export const parse = (s: string): number => {
+  return Number(s);
+};
+
+function main() {
+  for (const x of [1, 2]) {
+    console.log(x);
+  }
+}
+`
+	o := jsOutline.Extract(src)
+
+	if want := []string{"fs", "path"}; !reflect.DeepEqual(o.Imports, want) {
+		t.Errorf("imports = %v, want %v", o.Imports, want)
+	}
+	named := outlineType(t, o, "Named")
+	checkLines(t, "Named", named.StartLine, named.EndLine, 4, 6)
+	store := outlineType(t, o, "Store")
+	checkLines(t, "Store", store.StartLine, store.EndLine, 8, 21)
+	if store.Kind != "class" {
+		t.Errorf("Store kind = %q", store.Kind)
+	}
+
+	load := outlineSymbol(t, o, "Store.load")
+	checkLines(t, "load", load.StartLine, load.EndLine, 14, 20)
+	if load.Complexity != 3 {
+		t.Errorf("load complexity = %d, want 3", load.Complexity)
+	}
+	outlineSymbol(t, o, "Store.constructor")
+	parse := outlineSymbol(t, o, "parse")
+	checkLines(t, "parse", parse.StartLine, parse.EndLine, 23, 25)
+	main := outlineSymbol(t, o, "main")
+	checkLines(t, "main", main.StartLine, main.EndLine, 27, 31)
+
+	for _, fn := range o.Functions {
+		if controlKeywords[fn.Name] {
+			t.Errorf("control statement %q reported as a function", fn.Name)
+		}
+	}
+}
+
+func TestRustOutline(t *testing.T) {
+	src := `use std::collections::HashMap;
+use crate::io::{self, Read};
+
+/// A point. fn not_a_function() {}
+pub struct Point<'a> {
+    label: &'a str,
+}
+
+pub enum Shape {
+    Circle(f64),
+}
+
+impl<'a> Point<'a> {
+    pub fn new(label: &'a str) -> Self {
+        let c = '{';
+        Point { label }
+    }
+
+    fn describe(&self) -> String {
+        match self.label {
+            "" => r#"empty }"#.to_string(),
+            _ => self.label.to_string(),
+        }
+    }
+}
+
+fn main() {
+    let p = Point::new("a");
+}
+`
+	o := rustOutline.Extract(src)
+
+	if want := []string{"std::collections::HashMap", "crate::io::{self, Read}"}; !reflect.DeepEqual(o.Imports, want) {
+		t.Errorf("imports = %v, want %v", o.Imports, want)
+	}
+	point := outlineType(t, o, "Point")
+	checkLines(t, "Point", point.StartLine, point.EndLine, 5, 7)
+	if point.Kind != "struct" {
+		t.Errorf("Point kind = %q", point.Kind)
+	}
+	if shape := outlineType(t, o, "Shape"); shape.Kind != "enum" {
+		t.Errorf("Shape kind = %q", shape.Kind)
+	}
+
+	newFn := outlineSymbol(t, o, "Point.new")
+	checkLines(t, "new", newFn.StartLine, newFn.EndLine, 14, 17)
+	describe := outlineSymbol(t, o, "Point.describe")
+	checkLines(t, "describe", describe.StartLine, describe.EndLine, 19, 24)
+	main := outlineSymbol(t, o, "main")
+	checkLines(t, "main", main.StartLine, main.EndLine, 27, 29)
+	if len(o.Functions) != 3 {
+		t.Errorf("got %d functions, want 3: %+v", len(o.Functions), o.Functions)
+	}
+}
+
+func TestJavaOutline(t *testing.T) {
+	src := `package com.example;
+
+import java.util.List;
+import static java.util.Objects.requireNonNull;
+
+public class Repo implements Iterable<String> {
+    private final List<String> items;
+
+    public Repo(List<String> items) {
+        this.items = requireNonNull(items);
+    }
+
+    @Override
+    public java.util.Iterator<String> iterator() {
+        if (items.isEmpty()) {
+            throw new IllegalStateException("empty {");
+        }
+        return items.iterator();
+    }
+
+    interface Listener {
+        void changed(String item);
+    }
+}
+`
+	o := javaOutline.Extract(src)
+
+	if want := []string{"java.util.List", "java.util.Objects.requireNonNull"}; !reflect.DeepEqual(o.Imports, want) {
+		t.Errorf("imports = %v, want %v", o.Imports, want)
+	}
+	repo := outlineType(t, o, "Repo")
+	checkLines(t, "Repo", repo.StartLine, repo.EndLine, 6, 24)
+	if listener := outlineType(t, o, "Listener"); listener.Kind != "interface" {
+		t.Errorf("Listener kind = %q", listener.Kind)
+	}
+
+	ctor := outlineSymbol(t, o, "Repo.Repo")
+	checkLines(t, "Repo()", ctor.StartLine, ctor.EndLine, 9, 11)
+	iter := outlineSymbol(t, o, "Repo.iterator")
+	checkLines(t, "iterator", iter.StartLine, iter.EndLine, 14, 19)
+	if iter.Complexity != 2 {
+		t.Errorf("iterator complexity = %d, want 2", iter.Complexity)
+	}
+}
+
+func TestLoadOutlinesOtherLanguages(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app.py":   "class App:\n    def run(self):\n        pass\n",
+		"notes.md": "# def not_code():\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pc := NewProjectContext(root)
+	if err := pc.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	fi, ok := pc.GetFile(filepath.Join(root, "app.py"))
+	if !ok || len(fi.Functions) != 1 || fi.Functions[0].Receiver != "App" {
+		t.Fatalf("app.py outline = %+v", fi)
+	}
+	if pc.Metrics.TotalFunctions != 1 || pc.Metrics.TotalTypes != 1 {
+		t.Errorf("metrics = %+v", pc.Metrics)
+	}
+	if summary := pc.GetSummary(); !strings.Contains(summary, "Types: 1") {
+		t.Errorf("summary missing type count:\n%s", summary)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+
+	"ClosedWheeler/pkg/ignore"
 )
 
 // ProjectContext holds all information about the project
@@ -15,6 +17,7 @@ type ProjectContext struct {
 	Files        map[string]*FileInfo
 	Dependencies map[string][]string
 	Metrics      *Metrics
+	Go           *GoIndex // symbols of the project's Go code
 	mu           sync.RWMutex
 }
 
@@ -27,12 +30,14 @@ type FileInfo struct {
 	Size      int64
 	LineCount int
 	Functions []FunctionInfo
+	Types     []TypeInfo
 	Imports   []string
 }
 
 // FunctionInfo holds information about a function
 type FunctionInfo struct {
 	Name       string
+	Receiver   string // type the method is declared on, empty for functions
 	StartLine  int
 	EndLine    int
 	Complexity int
@@ -44,6 +49,7 @@ type Metrics struct {
 	TotalFiles     int
 	TotalLines     int
 	TotalFunctions int
+	TotalTypes     int // classes, structs, interfaces...
 	Languages      map[string]int
 }
 
@@ -60,6 +66,25 @@ func NewProjectContext(rootPath string) *ProjectContext {
 
 // Load loads all files in the project
 func (pc *ProjectContext) Load(ignorePatterns []string) error {
+	return pc.load(func(relPath string) bool {
+		for _, pattern := range ignorePatterns {
+			if matchIgnorePattern(relPath, pattern) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// LoadIgnoring loads all files in the project that patterns do not ignore,
+// e.g. those of the workplace's .agiignore.
+func (pc *ProjectContext) LoadIgnoring(patterns *ignore.Patterns) error {
+	return pc.load(func(relPath string) bool {
+		return relPath != "." && patterns.ShouldIgnore(relPath)
+	})
+}
+
+func (pc *ProjectContext) load(skip func(relPath string) bool) error {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
@@ -76,13 +101,11 @@ func (pc *ProjectContext) Load(ignorePatterns []string) error {
 		relPath, _ := filepath.Rel(pc.RootPath, path)
 
 		// Check ignore patterns
-		for _, pattern := range ignorePatterns {
-			if matchIgnorePattern(relPath, pattern) {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
+		if skip(relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
 		if info.IsDir() {
@@ -112,26 +135,36 @@ func (pc *ProjectContext) Load(ignorePatterns []string) error {
 			}
 		}
 
-		// Analyze Go files
-		if lang == "go" {
-			fi.analyzeGo()
+		// Outline other languages file by file; Go is type-checked below
+		if lang != "go" {
+			fi.extractOutline()
 		}
 
 		pc.Files[path] = fi
 		pc.Metrics.TotalFiles++
 		pc.Metrics.TotalLines += fi.LineCount
-		pc.Metrics.TotalFunctions += len(fi.Functions)
 		pc.Metrics.Languages[lang]++
 
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Analyze Go files package by package
+	pc.Go = analyzeGoPackages(pc.RootPath, pc.Files)
+
+	pc.Dependencies = make(map[string][]string)
+	for path, fi := range pc.Files {
+		pc.Metrics.TotalFunctions += len(fi.Functions)
+		pc.Metrics.TotalTypes += len(fi.Types)
 		// Track dependencies
 		if len(fi.Imports) > 0 {
 			pc.Dependencies[path] = fi.Imports
 		}
+	}
 
-		return nil
-	})
-
-	return err
+	return nil
 }
 
 // GetFile returns file info for a path
@@ -183,9 +216,14 @@ func (pc *ProjectContext) GetSummary() string {
 	sb.WriteString(fmt.Sprintf("Files: %d\n", pc.Metrics.TotalFiles))
 	sb.WriteString(fmt.Sprintf("Lines: %d\n", pc.Metrics.TotalLines))
 	sb.WriteString(fmt.Sprintf("Functions: %d\n", pc.Metrics.TotalFunctions))
+	sb.WriteString(fmt.Sprintf("Types: %d\n", pc.Metrics.TotalTypes))
 	sb.WriteString("\nLanguages:\n")
 	for lang, count := range pc.Metrics.Languages {
-		sb.WriteString(fmt.Sprintf("  %s: %d files\n", lang, count))
+		if HasOutline(lang) {
+			sb.WriteString(fmt.Sprintf("  %s: %d files\n", lang, count))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s: %d files (no outline)\n", lang, count))
+		}
 	}
 	return sb.String()
 }
@@ -202,81 +240,6 @@ func (pc *ProjectContext) GetFileList() []string {
 	return files
 }
 
-// analyzeGo performs basic Go file analysis
-func (fi *FileInfo) analyzeGo() {
-	lines := strings.Split(fi.Content, "\n")
-
-	// Find imports
-	inImport := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "import (") {
-			inImport = true
-			continue
-		}
-		if inImport {
-			if line == ")" {
-				inImport = false
-				continue
-			}
-			if line != "" && !strings.HasPrefix(line, "//") {
-				pkg := strings.Trim(line, "\"")
-				fi.Imports = append(fi.Imports, pkg)
-			}
-		}
-		if strings.HasPrefix(line, "import \"") {
-			pkg := strings.TrimPrefix(line, "import \"")
-			pkg = strings.TrimSuffix(pkg, "\"")
-			fi.Imports = append(fi.Imports, pkg)
-		}
-	}
-
-	// Find functions
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "func ") {
-			fn := FunctionInfo{
-				StartLine: i + 1,
-				Signature: line,
-			}
-
-			// Extract name
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				fnName := parts[1]
-				if idx := strings.Index(fnName, "("); idx != -1 {
-					fn.Name = fnName[:idx]
-				}
-			}
-
-			// Find end of function
-			braceCount := 0
-			for j := i; j < len(lines); j++ {
-				l := lines[j]
-				braceCount += strings.Count(l, "{") - strings.Count(l, "}")
-
-				// Count complexity
-				trimmed := strings.TrimSpace(l)
-				if strings.HasPrefix(trimmed, "if ") ||
-					strings.HasPrefix(trimmed, "for ") ||
-					strings.HasPrefix(trimmed, "switch ") ||
-					strings.HasPrefix(trimmed, "case ") {
-					fn.Complexity++
-				}
-
-				if braceCount == 0 && j > i {
-					fn.EndLine = j + 1
-					break
-				}
-			}
-
-			fn.Complexity++ // Base complexity
-			fi.Functions = append(fi.Functions, fn)
-		}
-	}
-}
-
 // Helper functions
 
 func matchIgnorePattern(path, pattern string) bool {
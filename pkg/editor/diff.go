@@ -0,0 +1,484 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the path unified diffs use for a missing side of a created or
+// deleted file.
+const DevNull = "/dev/null"
+
+const noNewlineMarker = `\ No newline at end of file`
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// maxDiffCells bounds the LCS table; larger changed regions are shown as a
+// whole-region replacement instead of a minimal diff.
+const maxDiffCells = 4_000_000
+
+// Hunk is one changed region of a file. Lines keep their unified diff prefix:
+// ' ' for context, '-' for removed and '+' for added lines, and '\' for a
+// "No newline at end of file" marker after the line it applies to.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []string
+}
+
+// Header returns the hunk's "@@ -a,b +c,d @@" line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// FilePatch is the part of a unified diff that changes one file.
+type FilePatch struct {
+	OldPath string // DevNull when the file is created
+	NewPath string // DevNull when the file is deleted
+	Hunks   []Hunk
+}
+
+// Path returns the file the patch applies to.
+func (p FilePatch) Path() string {
+	if p.NewPath == DevNull {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+// Operation returns "create", "delete" or "modify".
+func (p FilePatch) Operation() string {
+	switch {
+	case p.OldPath == DevNull:
+		return "create"
+	case p.NewPath == DevNull:
+		return "delete"
+	default:
+		return "modify"
+	}
+}
+
+// String formats the patch as a unified diff.
+func (p FilePatch) String() string {
+	if len(p.Hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("--- " + diffPath("a/", p.OldPath) + "\n")
+	sb.WriteString("+++ " + diffPath("b/", p.NewPath) + "\n")
+	for _, h := range p.Hunks {
+		sb.WriteString(h.Header() + "\n")
+		for _, line := range h.Lines {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func diffPath(prefix, path string) string {
+	if path == DevNull {
+		return path
+	}
+	return prefix + path
+}
+
+// UnifiedDiff returns the unified diff between two versions of a file, or an
+// empty string when they are equal. Use DevNull as oldPath or newPath for a
+// created or deleted file.
+func UnifiedDiff(oldPath, newPath, oldContent, newContent string) string {
+	return FilePatch{OldPath: oldPath, NewPath: newPath, Hunks: Diff(oldContent, newContent)}.String()
+}
+
+// splitLines splits content into lines and reports whether it ends with a
+// newline. Empty content has no lines.
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	eol := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return lines, eol
+}
+
+func joinLines(lines []string, eol bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	s := strings.Join(lines, "\n")
+	if eol {
+		s += "\n"
+	}
+	return s
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	a, b int  // line index in old and new
+}
+
+// Diff returns the hunks that turn oldContent into newContent.
+func Diff(oldContent, newContent string) []Hunk {
+	a, aEOL := splitLines(oldContent)
+	b, bEOL := splitLines(newContent)
+
+	// A last line that loses or gains its newline counts as changed.
+	keyA, keyB := a, b
+	if !aEOL || !bEOL {
+		keyA = append([]string(nil), a...)
+		keyB = append([]string(nil), b...)
+		if !aEOL {
+			keyA[len(keyA)-1] += "\x00"
+		}
+		if !bEOL {
+			keyB[len(keyB)-1] += "\x00"
+		}
+	}
+
+	ops := diffLines(keyA, keyB)
+	line := func(op diffOp) []string {
+		if op.kind == '+' {
+			out := []string{"+" + b[op.b]}
+			if !bEOL && op.b == len(b)-1 {
+				out = append(out, noNewlineMarker)
+			}
+			return out
+		}
+		out := []string{string(op.kind) + a[op.a]}
+		if !aEOL && op.a == len(a)-1 {
+			out = append(out, noNewlineMarker)
+		}
+		return out
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk while changes are close enough to share context.
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext+1, len(ops))
+
+		h := Hunk{OldStart: ops[start].a + 1, NewStart: ops[start].b + 1}
+		for _, op := range ops[start:end] {
+			h.Lines = append(h.Lines, line(op)...)
+			if op.kind != '+' {
+				h.OldLines++
+			}
+			if op.kind != '-' {
+				h.NewLines++
+			}
+		}
+		// An empty side starts at the line before the hunk.
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// diffLines returns a line-level edit script from a to b. Common prefixes
+// and suffixes are matched first; the rest uses a longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', i, i})
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(ma), len(mb)
+	if n*m > maxDiffCells {
+		for i := range ma {
+			ops = append(ops, diffOp{'-', prefix + i, prefix})
+		}
+		for j := range mb {
+			ops = append(ops, diffOp{'+', prefix + n, prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && ma[i] == mb[j]:
+				ops = append(ops, diffOp{' ', prefix + i, prefix + j})
+				i++
+				j++
+			case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{'-', prefix + i, prefix + j})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', prefix + i, prefix + j})
+				j++
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, diffOp{' ', len(a) - suffix + k, len(b) - suffix + k})
+	}
+	return ops
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch parses a unified diff that may change several files. Line
+// counts in hunk headers are recomputed from the hunk body, since patches
+// written by hand (or by a model) often get them wrong; the start lines are
+// only used as a hint for where to apply a hunk.
+func ParsePatch(patch string) ([]FilePatch, error) {
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+
+	var patches []FilePatch
+	var current *FilePatch
+	var hunk *Hunk
+
+	endHunk := func() {
+		if hunk != nil {
+			current.Hunks = append(current.Hunks, *hunk)
+			hunk = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			endHunk()
+			patches = append(patches, FilePatch{
+				OldPath: patchPath(line[4:], "a/"),
+				NewPath: patchPath(strings.TrimSuffix(lines[i+1], "\r")[4:], "b/"),
+			})
+			current = &patches[len(patches)-1]
+			i++
+			continue
+		}
+
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk before any --- / +++ file header", i+1)
+			}
+			endHunk()
+			oldStart, _ := strconv.Atoi(m[1])
+			newStart, _ := strconv.Atoi(m[3])
+			hunk = &Hunk{OldStart: oldStart, NewStart: newStart}
+			continue
+		}
+
+		if hunk == nil {
+			// diff --git, index, mode lines and commentary between files.
+			continue
+		}
+
+		switch {
+		case line == "":
+			// Editors and models often strip the space of empty context lines.
+			hunk.Lines = append(hunk.Lines, " ")
+			hunk.OldLines++
+			hunk.NewLines++
+		case line[0] == ' ':
+			hunk.Lines = append(hunk.Lines, lines[i])
+			hunk.OldLines++
+			hunk.NewLines++
+		case line[0] == '-':
+			hunk.Lines = append(hunk.Lines, lines[i])
+			hunk.OldLines++
+		case line[0] == '+':
+			hunk.Lines = append(hunk.Lines, lines[i])
+			hunk.NewLines++
+		case line[0] == '\\':
+			hunk.Lines = append(hunk.Lines, noNewlineMarker)
+		default:
+			endHunk()
+		}
+	}
+	endHunk()
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file headers (--- a/path, +++ b/path) found in patch")
+	}
+	for _, p := range patches {
+		if len(p.Hunks) == 0 && p.Operation() != "delete" {
+			return nil, fmt.Errorf("patch for %s has no hunks", p.Path())
+		}
+	}
+	return patches, nil
+}
+
+// patchPath extracts the path from a ---/+++ header, dropping a trailing
+// timestamp and the conventional a/ or b/ prefix.
+func patchPath(header, prefix string) string {
+	if tab := strings.IndexByte(header, '\t'); tab >= 0 {
+		header = header[:tab]
+	}
+	header = strings.TrimSpace(header)
+	if header == DevNull {
+		return header
+	}
+	return strings.TrimPrefix(header, prefix)
+}
+
+// ApplyHunks applies hunks to content. Each hunk is applied where its
+// context and removed lines match, preferring the position nearest its
+// header's line number, so patches still apply after unrelated edits shift
+// the file. Nothing is applied if any hunk does not match.
+func ApplyHunks(content string, hunks []Hunk) (string, error) {
+	lines, eol := splitLines(content)
+	if content == "" {
+		eol = true
+	}
+
+	var result []string
+	pos, delta := 0, 0
+	for n, h := range hunks {
+		var old, repl []string
+		for k, line := range h.Lines {
+			if line == "" {
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				old = append(old, line[1:])
+				repl = append(repl, line[1:])
+			case '-':
+				old = append(old, line[1:])
+			case '+':
+				repl = append(repl, line[1:])
+			case '\\':
+				if k > 0 && h.Lines[k-1] != "" {
+					switch h.Lines[k-1][0] {
+					case '-':
+						eol = true // the old last line had none; the new one does unless marked
+					case '+', ' ':
+						eol = false
+					}
+				}
+			}
+		}
+
+		want := h.OldStart - 1 + delta
+		if len(old) == 0 {
+			want = h.OldStart + delta
+		}
+		at := findBlock(lines, old, pos, want)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (%s) does not apply: its context and removed lines were not found", n+1, h.Header())
+		}
+		result = append(result, lines[pos:at]...)
+		result = append(result, repl...)
+		pos = at + len(old)
+		delta = at - (want - delta)
+	}
+	result = append(result, lines[pos:]...)
+	return joinLines(result, eol), nil
+}
+
+// findBlock returns the index at or after from where block occurs in lines,
+// choosing the match nearest want, or -1.
+func findBlock(lines, block []string, from, want int) int {
+	if len(block) == 0 {
+		return min(max(want, from), len(lines))
+	}
+	best := -1
+	for i := from; i+len(block) <= len(lines); i++ {
+		if !matchAt(lines, block, i) {
+			continue
+		}
+		if best < 0 || abs(i-want) < abs(best-want) {
+			best = i
+		}
+	}
+	return best
+}
+
+func matchAt(lines, block []string, at int) bool {
+	for k, line := range block {
+		if lines[at+k] != line {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// MatchPath reports whether a slash-separated path relative to the project
+// root matches a glob pattern. "**" matches any number of directories, and a
+// pattern without "/" is matched against the file name alone, unless it
+// starts with "./", which anchors it to the root.
+func MatchPath(pattern, path string) bool {
+	pattern, anchored := strings.CutPrefix(filepath.ToSlash(pattern), "./")
+	path = filepath.ToSlash(path)
+	if !anchored && !strings.Contains(pattern, "/") && pattern != "**" {
+		ok, _ := filepath.Match(pattern, filepath.Base(path))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(parts); skip++ {
+				if matchSegments(pattern[1:], parts[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
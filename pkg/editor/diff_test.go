@@ -0,0 +1,267 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff_RoundTripsThroughApplyHunks(t *testing.T) {
+	base := strings.Repeat("line\n", 20)
+	cases := map[string][2]string{
+		"modify middle":    {base, base[:50] + "inserted\n" + base[50:]},
+		"create":           {"", "a\nb\n"},
+		"delete all":       {"a\nb\n", ""},
+		"drop final eol":   {"a\nb\n", "a\nb"},
+		"add final eol":    {"a\nb", "a\nb\n"},
+		"two hunks":        {"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n"},
+		"replace no eol":   {"x", "y"},
+		"insert at top":    {"b\nc\n", "a\nb\nc\n"},
+		"append at bottom": {"a\nb\n", "a\nb\nc\n"},
+	}
+	for name, c := range cases {
+		hunks := Diff(c[0], c[1])
+		got, err := ApplyHunks(c[0], hunks)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != c[1] {
+			t.Errorf("%s: got %q, want %q", name, got, c[1])
+		}
+
+		// The formatted diff must parse back to the same change.
+		text := UnifiedDiff("f", "f", c[0], c[1])
+		patches, err := ParsePatch(text)
+		if err != nil {
+			t.Errorf("%s: ParsePatch: %v\n%s", name, err, text)
+			continue
+		}
+		if got, err := ApplyHunks(c[0], patches[0].Hunks); err != nil || got != c[1] {
+			t.Errorf("%s: reparsed patch gave %q, %v", name, got, err)
+		}
+	}
+}
+
+func TestUnifiedDiff_Format(t *testing.T) {
+	got := UnifiedDiff("main.go", "main.go", "a\nb\nc\n", "a\nB\nc\n")
+	want := "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if UnifiedDiff("x", "x", "same\n", "same\n") != "" {
+		t.Error("equal content should produce no diff")
+	}
+}
+
+func TestApplyHunks_ToleratesShiftedLinesAndBadCounts(t *testing.T) {
+	content := "header\nextra\nfunc a() {\n\treturn 1\n}\n"
+	patch := `--- a/x.go
++++ b/x.go
+@@ -1,3 +1,3 @@
+ func a() {
+-	return 1
++	return 2
+ }
+`
+	patches, err := ParsePatch(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyHunks(content, patches[0].Hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "header\nextra\nfunc a() {\n\treturn 2\n}\n" {
+		t.Errorf("unexpected result %q", got)
+	}
+
+	if _, err := ApplyHunks("unrelated\n", patches[0].Hunks); err == nil {
+		t.Error("expected an error when the context is missing")
+	}
+}
+
+func TestManager_ApplyChangesAndUndo(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	a := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(a, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set := func(content string) func(string, bool) (string, bool, error) {
+		return func(string, bool) (string, bool, error) { return content, false, nil }
+	}
+	m.BeginTurn("batch")
+	edits, err := m.ApplyChanges("batch", []FileChange{
+		{Path: "a.txt", Update: set("new\n")},
+		{Path: "b.txt", Update: set("created\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 2 || edits[0].Operation != "modify" || edits[1].Operation != "create" {
+		t.Fatalf("unexpected edits %+v", edits)
+	}
+	if diff := m.GetDiff(); !strings.Contains(diff, "-old\n+new\n") || !strings.Contains(diff, "+++ b/b.txt") {
+		t.Errorf("GetDiff should include unified diffs, got:\n%s", diff)
+	}
+
+	undone, err := m.UndoTurns(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 1 || len(undone[0].Files) != 2 {
+		t.Fatalf("the whole batch should be undone, got %+v", undone)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "old\n" {
+		t.Errorf("a.txt not restored: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); !os.IsNotExist(err) {
+		t.Error("created file should be removed by undo")
+	}
+	if _, err := m.UndoTurns(1); err == nil {
+		t.Error("expected nothing left to undo")
+	}
+}
+
+func TestManager_ReviewSelectsHunks(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	path := filepath.Join(root, "src", "a.txt")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	os.WriteFile(path, []byte(old), 0644)
+
+	changed := append([]string(nil), lines...)
+	changed[1], changed[17] = "first change", "second change"
+	var reviewed []EditRecord
+	m.SetReview(func(p string) bool { return !MatchPath("docs/**", m.RelPath(p)) }, func(edits []EditRecord) []Review {
+		reviewed = edits
+		return []Review{{Hunks: []bool{false, true}}, {Accept: false}}
+	})
+
+	set := func(content string) func(string, bool) (string, bool, error) {
+		return func(string, bool) (string, bool, error) { return content, false, nil }
+	}
+	edits, err := m.ApplyChanges("review", []FileChange{
+		{Path: path, Update: set(strings.Join(changed, "\n") + "\n")},
+		{Path: "rejected.txt", Update: set("no\n")},
+		{Path: "docs/notes.md", Update: set("allowed\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviewed) != 2 {
+		t.Fatalf("only files outside docs/ should be reviewed, got %d", len(reviewed))
+	}
+	if edits[0].Review != ReviewPartial || edits[1].Review != ReviewRejected || edits[1].Applied || !edits[2].Applied {
+		t.Errorf("unexpected review results %+v", edits)
+	}
+
+	data, _ := os.ReadFile(path)
+	if want := strings.Replace(old, "line 18\n", "second change\n", 1); string(data) != want {
+		t.Errorf("only the accepted hunk should be applied, got:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "rejected.txt")); !os.IsNotExist(err) {
+		t.Error("rejected file should not be created")
+	}
+	if n := len(m.CurrentSession().Edits); n != 2 {
+		t.Errorf("rejected edits should leave the session, %d left", n)
+	}
+}
+
+func TestManager_ReviewWithoutReviewer(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	m.SetReview(func(string) bool { return true }, nil)
+
+	_, err := m.ApplyChanges("unreviewed", []FileChange{{Path: "a.txt", Update: func(string, bool) (string, bool, error) {
+		return "content\n", false, nil
+	}}})
+	if err == nil || !strings.Contains(err.Error(), "no reviewer") {
+		t.Fatalf("expected a missing reviewer to fail the batch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("unreviewed edit must not be written")
+	}
+	if s := m.CurrentSession(); s != nil && len(s.Edits) > 0 {
+		t.Errorf("failed batch should not be recorded, %d edits", len(s.Edits))
+	}
+}
+
+func TestManager_PendingWithoutDecisions(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	m.SetReview(func(string) bool { return true }, func([]EditRecord) []Review { return nil })
+
+	edits, err := m.ApplyChanges("pending", []FileChange{{Path: "a.txt", Update: func(string, bool) (string, bool, error) {
+		return "content\n", false, nil
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edits[0].Review != ReviewPending || edits[0].Applied {
+		t.Fatalf("edit should wait for review, got %+v", edits[0])
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("pending edit must not be written")
+	}
+
+	if _, err := m.ApplyReviewed(edits, []Review{{Accept: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "content\n" {
+		t.Errorf("accepted edit not written, got %q", data)
+	}
+}
+
+func TestManager_ReviewDetectsConcurrentChange(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	path := filepath.Join(root, "a.txt")
+	os.WriteFile(path, []byte("original\n"), 0644)
+
+	m.SetReview(func(string) bool { return true }, func(edits []EditRecord) []Review {
+		// The file changes on disk while the user is looking at the diff.
+		os.WriteFile(path, []byte("edited meanwhile\n"), 0644)
+		return []Review{{Accept: true}}
+	})
+	_, err := m.ApplyChanges("stale", []FileChange{{Path: path, Update: func(string, bool) (string, bool, error) {
+		return "from the agent\n", false, nil
+	}}})
+	if err == nil || !strings.Contains(err.Error(), "changed while") {
+		t.Fatalf("expected the stale edit to fail, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "edited meanwhile\n" {
+		t.Errorf("concurrent change was overwritten: %q", data)
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"*.md", "docs/guide/intro.md", true},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"src/**", "docs/intro.md", false},
+		{"**/testdata/*", "pkg/a/testdata/x.json", true},
+		{"main.go", "cmd/agi/main.go", true},
+		{"./main.go", "cmd/agi/main.go", false},
+		{"./main.go", "main.go", true},
+	}
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -39,14 +40,56 @@ type EditRecord struct {
 	Timestamp   time.Time `json:"timestamp"`
 	Applied     bool      `json:"applied"`
 	Description string    `json:"description"`
+	Batch       string    `json:"batch,omitempty"`  // edits applied together by ApplyChanges
+	Review      string    `json:"review,omitempty"` // review state of edits that needed review
 }
 
-// Manager manages edit sessions
+// Review states of an EditRecord.
+const (
+	ReviewPending  = "pending"
+	ReviewAccepted = "accepted"
+	ReviewPartial  = "partial" // some hunks were rejected
+	ReviewRejected = "rejected"
+)
+
+// Review is the user's decision on one pending edit. When Hunks is set it
+// selects which hunks of Diff(OldContent, NewContent) to apply; otherwise
+// Accept applies or rejects the whole edit.
+type Review struct {
+	Accept bool
+	Hunks  []bool
+}
+
+// Reviewer shows pending edits to the user and blocks until each has a
+// Review, returned in the same order. It returns nil when the user could not
+// decide (e.g. the UI closed), leaving the edits pending.
+type Reviewer func(edits []EditRecord) []Review
+
+// FileChange is an edit computed from a file's current content, so the
+// read and the write happen under the manager's lock.
+type FileChange struct {
+	Path string // absolute, or relative to the project root
+	// Update returns the new content; exists is false when the file does not
+	// exist yet. Returning remove deletes the file instead.
+	Update func(content string, exists bool) (newContent string, remove bool, err error)
+}
+
+// Manager manages edit sessions. It is safe for concurrent use, since tools
+// may edit files in parallel.
 type Manager struct {
+	mu          sync.Mutex
 	projectRoot string
 	storagePath string
 	sessions    map[string]*Session
 	current     *Session
+	needsReview func(path string) bool
+	reviewer    Reviewer
+
+	// Undo/redo timeline (see timeline.go)
+	turn     *Turn   // turn being recorded
+	turns    []*Turn // finished turns, oldest first
+	redo     []*Turn // undone turns, most recently undone last
+	nextTurn int
 }
 
 // NewManager creates a new edit manager
@@ -72,8 +115,24 @@ func (m *Manager) validatePath(path string) error {
 	return nil
 }
 
+// SetReview makes ApplyChanges hold back edits to files for which
+// needsReview returns true until reviewer decides on them. A nil needsReview
+// turns review off; a nil reviewer leaves such edits pending in the session.
+func (m *Manager) SetReview(needsReview func(path string) bool, reviewer Reviewer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.needsReview = needsReview
+	m.reviewer = reviewer
+}
+
 // StartSession begins a new editing session
 func (m *Manager) StartSession(description string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.startSession(description)
+}
+
+func (m *Manager) startSession(description string) *Session {
 	session := &Session{
 		ID:          fmt.Sprintf("session_%d", time.Now().UnixNano()),
 		StartedAt:   time.Now(),
@@ -90,13 +149,17 @@ func (m *Manager) StartSession(description string) *Session {
 
 // CurrentSession returns the current active session
 func (m *Manager) CurrentSession() *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.current
 }
 
 // RecordEdit records a file edit without applying it
 func (m *Manager) RecordEdit(filePath, operation, oldContent, newContent, description string) *EditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
-		m.StartSession("Auto-session")
+		m.startSession("Auto-session")
 	}
 
 	edit := EditRecord{
@@ -116,6 +179,8 @@ func (m *Manager) RecordEdit(filePath, operation, oldContent, newContent, descri
 
 // ApplyEdit applies a single edit
 func (m *Manager) ApplyEdit(editID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -135,12 +200,18 @@ func (m *Manager) ApplyEdit(editID string) error {
 
 // ApplyAll applies all pending edits in the session
 func (m *Manager) ApplyAll() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.applyAll()
+}
+
+func (m *Manager) applyAll() error {
 	if m.current == nil {
 		return fmt.Errorf("no active session")
 	}
 
 	for i, edit := range m.current.Edits {
-		if !edit.Applied {
+		if !edit.Applied && edit.Review != ReviewPending {
 			if err := m.applyFileEdit(&edit); err != nil {
 				return fmt.Errorf("failed to apply edit %s: %w", edit.ID, err)
 			}
@@ -153,6 +224,8 @@ func (m *Manager) ApplyAll() error {
 
 // RollbackEdit rolls back a single applied edit
 func (m *Manager) RollbackEdit(editID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -172,6 +245,8 @@ func (m *Manager) RollbackEdit(editID string) error {
 
 // RollbackAll rolls back all applied edits in reverse order
 func (m *Manager) RollbackAll() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return fmt.Errorf("no active session")
 	}
@@ -193,19 +268,21 @@ func (m *Manager) RollbackAll() error {
 
 // CompleteSession marks the session as completed
 func (m *Manager) CompleteSession() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return fmt.Errorf("no active session")
 	}
 
 	// Apply all pending edits
-	if err := m.ApplyAll(); err != nil {
+	if err := m.applyAll(); err != nil {
 		return err
 	}
 
 	m.current.Status = StatusCompleted
 
 	// Save session
-	if err := m.SaveSession(m.current.ID); err != nil {
+	if err := m.saveSession(m.current.ID); err != nil {
 		return err
 	}
 
@@ -215,6 +292,8 @@ func (m *Manager) CompleteSession() error {
 
 // GetPendingEdits returns all unapplied edits
 func (m *Manager) GetPendingEdits() []EditRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return nil
 	}
@@ -228,8 +307,10 @@ func (m *Manager) GetPendingEdits() []EditRecord {
 	return pending
 }
 
-// GetDiff returns a diff-style summary of all edits
+// GetDiff returns a summary of all edits, each followed by its unified diff
 func (m *Manager) GetDiff() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.current == nil {
 		return ""
 	}
@@ -242,12 +323,303 @@ func (m *Manager) GetDiff() string {
 		}
 		result += fmt.Sprintf("%s %s: %s - %s\n",
 			status, edit.Operation, edit.FilePath, edit.Description)
+		result += m.EditDiff(edit)
 	}
 	return result
 }
 
+// RelPath returns path relative to the project root when it is inside it.
+func (m *Manager) RelPath(path string) string {
+	if rel, err := filepath.Rel(m.projectRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// EditDiff returns the unified diff of an edit, with paths relative to the
+// project root.
+func (m *Manager) EditDiff(edit EditRecord) string {
+	path := m.RelPath(edit.FilePath)
+	oldPath, newPath := path, path
+	switch edit.Operation {
+	case "create":
+		oldPath = DevNull
+	case "delete":
+		newPath = DevNull
+	}
+	return UnifiedDiff(oldPath, newPath, edit.OldContent, edit.NewContent)
+}
+
+// ApplyChanges computes, records and applies a set of file changes as one
+// batch. Every change is computed before anything is written, and files
+// already written are restored if a later write fails, so the batch applies
+// completely or not at all. Changes that leave a file as it was are skipped.
+//
+// When a review policy is set (see SetReview) and any file in the batch needs
+// review, the batch waits for the reviewer's decisions; edits it rejects are
+// returned with Applied false. Without a reviewer such batches fail and
+// nothing is written. When the reviewer returns no decisions, e.g. because
+// the UI exited, the batch stays pending in the session until ApplyReviewed
+// is called. Files are re-read after the review, and the batch fails if any
+// of them changed meanwhile.
+func (m *Manager) ApplyChanges(description string, changes []FileChange) ([]EditRecord, error) {
+	m.mu.Lock()
+	edits, err := m.computeChanges(description, changes)
+	if err != nil || len(edits) == 0 {
+		m.mu.Unlock()
+		return edits, err
+	}
+
+	var held []EditRecord
+	if m.needsReview != nil {
+		for _, edit := range edits {
+			if m.needsReview(edit.FilePath) {
+				held = append(held, edit)
+			}
+		}
+	}
+	if len(held) == 0 {
+		err := m.applyBatch(edits)
+		if err == nil {
+			m.record(edits)
+		}
+		m.mu.Unlock()
+		return edits, err
+	}
+
+	reviewer := m.reviewer
+	if reviewer == nil {
+		m.mu.Unlock()
+		names := make([]string, len(held))
+		for i, edit := range held {
+			names[i] = m.RelPath(edit.FilePath)
+		}
+		return nil, fmt.Errorf("changes to %s need review, but no reviewer is available; nothing was applied", strings.Join(names, ", "))
+	}
+	for i := range edits {
+		edits[i].Review = ReviewPending
+	}
+	m.record(edits)
+	m.mu.Unlock()
+
+	// Edits that do not need review are accepted with the rest of the batch.
+	decisions := reviewer(held)
+	if decisions == nil {
+		return edits, nil
+	}
+	reviews := make([]Review, len(edits))
+	for i, edit := range edits {
+		reviews[i] = Review{Accept: true}
+		for j, h := range held {
+			if h.ID == edit.ID && j < len(decisions) {
+				reviews[i] = decisions[j]
+			}
+		}
+	}
+	return m.ApplyReviewed(edits, reviews)
+}
+
+// ApplyReviewed applies pending edits according to the user's reviews,
+// which are matched to edits by index. Accepted hunks are applied, rejected
+// edits are dropped from the session, and the batch fails without writing
+// anything if a file changed while its edit was awaiting review.
+func (m *Manager) ApplyReviewed(edits []EditRecord, reviews []Review) ([]EditRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(reviews) != len(edits) {
+		return nil, fmt.Errorf("got %d reviews for %d edits", len(reviews), len(edits))
+	}
+
+	result := make([]EditRecord, len(edits))
+	var accepted []EditRecord
+	for i, edit := range edits {
+		if m.sessionIndex(edit.ID) < 0 {
+			return nil, fmt.Errorf("edit %s to %s is no longer pending", edit.ID, edit.FilePath)
+		}
+
+		r := reviews[i]
+		edit.Review = ReviewRejected
+		if r.Hunks == nil && r.Accept {
+			edit.Review = ReviewAccepted
+		} else if r.Hunks != nil {
+			hunks := Diff(edit.OldContent, edit.NewContent)
+			var selected []Hunk
+			for k, h := range hunks {
+				if k < len(r.Hunks) && r.Hunks[k] {
+					selected = append(selected, h)
+				}
+			}
+			switch {
+			case len(selected) == len(hunks):
+				edit.Review = ReviewAccepted
+			case len(selected) > 0:
+				content, err := ApplyHunks(edit.OldContent, selected)
+				if err != nil {
+					return nil, fmt.Errorf("failed to apply selected hunks to %s: %w", edit.FilePath, err)
+				}
+				edit.NewContent = content
+				edit.Review = ReviewPartial
+				if edit.Operation == "delete" {
+					edit.Operation = "modify"
+				}
+			}
+		}
+		result[i] = edit
+		if edit.Review != ReviewRejected {
+			accepted = append(accepted, edit)
+		}
+	}
+
+	drop := func() {
+		kept := m.current.Edits[:0]
+		for _, e := range m.current.Edits {
+			if !containsEdit(edits, e.ID) {
+				kept = append(kept, e)
+			}
+		}
+		m.current.Edits = kept
+	}
+
+	for _, edit := range accepted {
+		data, err := os.ReadFile(edit.FilePath)
+		unchanged := err == nil && string(data) == edit.OldContent
+		if edit.Operation == "create" {
+			unchanged = os.IsNotExist(err)
+		}
+		if !unchanged {
+			drop()
+			return nil, fmt.Errorf("%s changed while the edit was awaiting review; nothing was applied", edit.FilePath)
+		}
+	}
+	if err := m.applyBatch(accepted); err != nil {
+		drop()
+		return nil, err
+	}
+
+	drop()
+	for i := range result {
+		if result[i].Review != ReviewRejected {
+			result[i].Applied = true
+		}
+	}
+	for _, edit := range result {
+		if edit.Applied {
+			m.current.Edits = append(m.current.Edits, edit)
+		}
+	}
+	return result, nil
+}
+
+// computeChanges reads each file and computes its edit. Callers hold m.mu.
+func (m *Manager) computeChanges(description string, changes []FileChange) ([]EditRecord, error) {
+	batch := fmt.Sprintf("batch_%d", time.Now().UnixNano())
+	var edits []EditRecord
+	for _, change := range changes {
+		path := change.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(m.projectRoot, path)
+		}
+		if err := m.validatePath(path); err != nil {
+			return nil, err
+		}
+
+		exists := true
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			exists = false
+		} else if err != nil {
+			return nil, err
+		}
+
+		content := string(data)
+		newContent, remove, err := change.Update(content, exists)
+		if err != nil {
+			return nil, err
+		}
+
+		edit := EditRecord{
+			ID:          fmt.Sprintf("edit_%d_%d", time.Now().UnixNano(), len(edits)),
+			FilePath:    path,
+			OldContent:  content,
+			NewContent:  newContent,
+			Timestamp:   time.Now(),
+			Description: description,
+			Batch:       batch,
+		}
+		switch {
+		case remove && !exists:
+			return nil, fmt.Errorf("cannot delete %s: file does not exist", change.Path)
+		case remove:
+			edit.Operation = "delete"
+			edit.NewContent = ""
+		case !exists:
+			edit.Operation = "create"
+		case newContent == content:
+			continue
+		default:
+			edit.Operation = "modify"
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+// applyBatch writes edits in order, restoring the files already written if
+// one fails, and marks them applied. Callers hold m.mu.
+func (m *Manager) applyBatch(edits []EditRecord) error {
+	for i := range edits {
+		if err := m.applyFileEdit(&edits[i]); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				m.rollbackFileEdit(&edits[j])
+				edits[j].Applied = false
+			}
+			return fmt.Errorf("failed to apply edit to %s: %w", edits[i].FilePath, err)
+		}
+		edits[i].Applied = true
+	}
+	return nil
+}
+
+// record appends edits to the current session. Callers hold m.mu.
+func (m *Manager) record(edits []EditRecord) {
+	if m.current == nil {
+		m.startSession("Auto-session")
+	}
+	m.current.Edits = append(m.current.Edits, edits...)
+}
+
+// sessionIndex returns the index of a pending edit in the current session,
+// or -1. Callers hold m.mu.
+func (m *Manager) sessionIndex(id string) int {
+	if m.current == nil {
+		return -1
+	}
+	for i, e := range m.current.Edits {
+		if e.ID == id && !e.Applied {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsEdit(edits []EditRecord, id string) bool {
+	for _, e := range edits {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // SaveSession saves session to disk
 func (m *Manager) SaveSession(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saveSession(sessionID)
+}
+
+func (m *Manager) saveSession(sessionID string) error {
 	session, exists := m.sessions[sessionID]
 	if !exists {
 		return fmt.Errorf("session not found: %s", sessionID)
@@ -283,6 +655,8 @@ func (m *Manager) LoadSession(sessionID string) (*Session, error) {
 		return nil, err
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.sessions[session.ID] = &session
 	return &session, nil
 }
@@ -293,23 +667,28 @@ func (m *Manager) applyFileEdit(edit *EditRecord) error {
 		return err
 	}
 
+	var err error
 	switch edit.Operation {
 	case "create":
 		dir := filepath.Dir(edit.FilePath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return err
 		}
-		return os.WriteFile(edit.FilePath, []byte(edit.NewContent), 0644)
+		err = os.WriteFile(edit.FilePath, []byte(edit.NewContent), 0644)
 
 	case "modify":
-		return os.WriteFile(edit.FilePath, []byte(edit.NewContent), 0644)
+		err = os.WriteFile(edit.FilePath, []byte(edit.NewContent), 0644)
 
 	case "delete":
-		return os.Remove(edit.FilePath)
+		err = os.Remove(edit.FilePath)
 
 	default:
 		return fmt.Errorf("unknown operation: %s", edit.Operation)
 	}
+	if err == nil {
+		m.snapshot(edit.FilePath, edit.OldContent, edit.Operation != "create", edit.NewContent, edit.Operation != "delete")
+	}
+	return err
 }
 
 // rollbackFileEdit reverses a file edit
@@ -318,14 +697,15 @@ func (m *Manager) rollbackFileEdit(edit *EditRecord) error {
 		return err
 	}
 
+	var err error
 	switch edit.Operation {
 	case "create":
 		// Rollback create = delete
-		return os.Remove(edit.FilePath)
+		err = os.Remove(edit.FilePath)
 
 	case "modify":
 		// Rollback modify = restore old content
-		return os.WriteFile(edit.FilePath, []byte(edit.OldContent), 0644)
+		err = os.WriteFile(edit.FilePath, []byte(edit.OldContent), 0644)
 
 	case "delete":
 		// Rollback delete = restore file
@@ -333,9 +713,13 @@ func (m *Manager) rollbackFileEdit(edit *EditRecord) error {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return err
 		}
-		return os.WriteFile(edit.FilePath, []byte(edit.OldContent), 0644)
+		err = os.WriteFile(edit.FilePath, []byte(edit.OldContent), 0644)
 
 	default:
 		return fmt.Errorf("unknown operation: %s", edit.Operation)
 	}
+	if err == nil {
+		m.snapshot(edit.FilePath, edit.NewContent, edit.Operation != "delete", edit.OldContent, edit.Operation != "create")
+	}
+	return err
 }
@@ -0,0 +1,208 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxTimelineTurns bounds how many turns the timeline keeps in memory.
+const maxTimelineTurns = 100
+
+// Turn is one entry of the undo/redo timeline: the files changed while the
+// agent handled one user message, with their content before and after.
+type Turn struct {
+	ID      int
+	Label   string // the user message that started the turn
+	Started time.Time
+	Files   []FileSnapshot
+	Undone  bool
+}
+
+// FileSnapshot is a file's state before and after a turn. A file that did
+// not exist on one side has the matching Exists flag unset.
+type FileSnapshot struct {
+	Path         string
+	Before       string
+	BeforeExists bool
+	After        string
+	AfterExists  bool
+}
+
+// BeginTurn closes the turn in progress and starts recording a new one.
+// Writes made through the manager are snapshotted into the open turn, so
+// changes resolved later (e.g. by review) count towards the last turn. Files
+// changed any other way, e.g. by commands the agent runs, are not recorded
+// and undo does not restore them.
+func (m *Manager) BeginTurn(label string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeTurn()
+	m.nextTurn++
+	m.turn = &Turn{ID: m.nextTurn, Label: label, Started: time.Now()}
+}
+
+// closeTurn moves the open turn to the timeline if it changed anything.
+// Callers hold m.mu.
+func (m *Manager) closeTurn() {
+	if m.turn == nil {
+		return
+	}
+	if len(m.turn.Files) > 0 {
+		m.turns = append(m.turns, m.turn)
+		if len(m.turns) > maxTimelineTurns {
+			m.turns = m.turns[len(m.turns)-maxTimelineTurns:]
+		}
+	}
+	m.turn = nil
+}
+
+// snapshot records a write into the open turn, starting one when there is
+// none. The first write of a turn discards the redo history. Callers hold
+// m.mu.
+func (m *Manager) snapshot(path, before string, beforeExists bool, after string, afterExists bool) {
+	if m.turn == nil {
+		m.nextTurn++
+		m.turn = &Turn{ID: m.nextTurn, Label: "changes outside a turn", Started: time.Now()}
+	}
+	if len(m.turn.Files) == 0 {
+		m.redo = nil
+	}
+	for i := range m.turn.Files {
+		if f := &m.turn.Files[i]; f.Path == path {
+			f.After, f.AfterExists = after, afterExists
+			return
+		}
+	}
+	m.turn.Files = append(m.turn.Files, FileSnapshot{
+		Path:         path,
+		Before:       before,
+		BeforeExists: beforeExists,
+		After:        after,
+		AfterExists:  afterExists,
+	})
+}
+
+// Timeline returns the recorded turns, oldest first, followed by the undone
+// turns that can be redone.
+func (m *Manager) Timeline() []Turn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var turns []Turn
+	for _, t := range m.turns {
+		turns = append(turns, *t)
+	}
+	if m.turn != nil && len(m.turn.Files) > 0 {
+		turns = append(turns, *m.turn)
+	}
+	for i := len(m.redo) - 1; i >= 0; i-- {
+		t := *m.redo[i]
+		t.Undone = true
+		turns = append(turns, t)
+	}
+	return turns
+}
+
+// UndoTurns restores the files of the last n turns to their state before
+// those turns, newest first. It stops at the first turn whose files were
+// changed since (by hand or outside the manager) and returns the turns
+// undone so far together with the error.
+func (m *Manager) UndoTurns(n int) ([]Turn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeTurn()
+
+	if len(m.turns) == 0 {
+		return nil, fmt.Errorf("no changes to undo")
+	}
+	var undone []Turn
+	for ; n > 0 && len(m.turns) > 0; n-- {
+		t := m.turns[len(m.turns)-1]
+		if err := m.restoreTurn(t, false); err != nil {
+			return undone, err
+		}
+		m.turns = m.turns[:len(m.turns)-1]
+		m.redo = append(m.redo, t)
+		undone = append(undone, *t)
+	}
+	return undone, nil
+}
+
+// RedoTurns reapplies the last n undone turns, oldest first.
+func (m *Manager) RedoTurns(n int) ([]Turn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeTurn()
+
+	if len(m.redo) == 0 {
+		return nil, fmt.Errorf("nothing to redo")
+	}
+	var redone []Turn
+	for ; n > 0 && len(m.redo) > 0; n-- {
+		t := m.redo[len(m.redo)-1]
+		if err := m.restoreTurn(t, true); err != nil {
+			return redone, err
+		}
+		m.redo = m.redo[:len(m.redo)-1]
+		m.turns = append(m.turns, t)
+		redone = append(redone, *t)
+	}
+	return redone, nil
+}
+
+// restoreTurn writes every file of a turn back to its state before the turn,
+// or after it when forward is set. Files must still be in the opposite
+// state; nothing is written otherwise, and files already written are put
+// back if a write fails. Callers hold m.mu.
+func (m *Manager) restoreTurn(t *Turn, forward bool) error {
+	for _, f := range t.Files {
+		want, wantExists := f.After, f.AfterExists
+		if forward {
+			want, wantExists = f.Before, f.BeforeExists
+		}
+		data, err := os.ReadFile(f.Path)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if exists != wantExists || string(data) != want {
+			return fmt.Errorf("%s has changed since turn %d; resolve it by hand first", m.RelPath(f.Path), t.ID)
+		}
+	}
+
+	for i, f := range t.Files {
+		content, exists := f.Before, f.BeforeExists
+		if forward {
+			content, exists = f.After, f.AfterExists
+		}
+		if err := writeState(f.Path, content, exists); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				g := t.Files[j]
+				if forward {
+					writeState(g.Path, g.Before, g.BeforeExists)
+				} else {
+					writeState(g.Path, g.After, g.AfterExists)
+				}
+			}
+			return fmt.Errorf("failed to restore %s: %w", m.RelPath(f.Path), err)
+		}
+	}
+	return nil
+}
+
+// writeState writes content to path, or removes the file when it should not
+// exist.
+func writeState(path, content string, exists bool) error {
+	if !exists {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
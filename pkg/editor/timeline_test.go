@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTimeline_UndoRedoTurns(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	path := filepath.Join(root, "a.txt")
+	os.WriteFile(path, []byte("v0\n"), 0644)
+
+	write := func(name, content string) {
+		t.Helper()
+		_, err := m.ApplyChanges("write", []FileChange{{Path: name, Update: func(string, bool) (string, bool, error) {
+			return content, false, nil
+		}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	m.BeginTurn("first")
+	write("a.txt", "v1\n")
+	write("a.txt", "v2\n")
+	m.BeginTurn("nothing changed")
+	m.BeginTurn("second")
+	write("a.txt", "v3\n")
+	write("b.txt", "new\n")
+
+	if turns := m.Timeline(); len(turns) != 2 || len(turns[0].Files) != 1 || turns[0].Files[0].Before != "v0\n" || turns[0].Files[0].After != "v2\n" {
+		t.Fatalf("unexpected timeline %+v", turns)
+	}
+
+	undone, err := m.UndoTurns(2)
+	if err != nil || len(undone) != 2 {
+		t.Fatalf("UndoTurns(2) = %d turns, %v", len(undone), err)
+	}
+	if read("a.txt") != "v0\n" || read("b.txt") != "<missing>" {
+		t.Fatalf("undo should restore the state before the first turn, got %q / %q", read("a.txt"), read("b.txt"))
+	}
+
+	if _, err := m.RedoTurns(1); err != nil {
+		t.Fatal(err)
+	}
+	if read("a.txt") != "v2\n" || read("b.txt") != "<missing>" {
+		t.Fatalf("redo should reapply the first turn only, got %q / %q", read("a.txt"), read("b.txt"))
+	}
+	if turns := m.Timeline(); len(turns) != 2 || turns[0].Undone || !turns[1].Undone {
+		t.Fatalf("timeline should show the second turn as undone, got %+v", turns)
+	}
+
+	m.BeginTurn("third")
+	write("c.txt", "c\n")
+	if _, err := m.RedoTurns(1); err == nil {
+		t.Error("a new change should discard the redo history")
+	}
+}
+
+func TestTimeline_UndoRefusesChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, filepath.Join(root, ".agi"))
+	path := filepath.Join(root, "a.txt")
+
+	m.BeginTurn("create")
+	_, err := m.ApplyChanges("create", []FileChange{{Path: path, Update: func(string, bool) (string, bool, error) {
+		return "agent\n", false, nil
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("by hand\n"), 0644)
+
+	if _, err := m.UndoTurns(1); err == nil {
+		t.Fatal("undo should refuse to overwrite a file changed by hand")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "by hand\n" {
+		t.Errorf("file should be untouched, got %q", data)
+	}
+}
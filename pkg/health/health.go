@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"ClosedWheeler/pkg/tasks"
 )
 
 // Status represents the health status of the project
@@ -181,19 +183,16 @@ func (c *Checker) checkGit(status *Status) {
 func (c *Checker) checkTasks(status *Status) {
 	taskPath := filepath.Join(c.projectPath, "workplace", "task.md")
 
-	content, err := os.ReadFile(taskPath)
-	if err != nil {
+	if _, err := os.Stat(taskPath); err != nil {
 		// Also check root task.md
 		taskPath = filepath.Join(c.projectPath, "task.md")
-		content, err = os.ReadFile(taskPath)
-		if err != nil {
-			return
-		}
 	}
 
-	taskStr := string(content)
-	pending := strings.Count(taskStr, "- [ ]") + strings.Count(taskStr, "- [/]")
-	status.PendingTasks = pending
+	pending, err := tasks.NewList(taskPath).Find(tasks.Filter{Status: "open"})
+	if err != nil {
+		return
+	}
+	status.PendingTasks = len(pending)
 }
 
 // generateRecommendations generates actionable recommendations
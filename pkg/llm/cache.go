@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ClosedWheeler/pkg/utils"
+)
+
+// ResponseCache is a content-addressed store of chat responses on disk. A
+// request is cached only when it is deterministic (temperature 0) or its
+// context was marked with WithCache. Entries expire after a TTL and the
+// oldest are evicted once the cache grows past its size limit.
+type ResponseCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+
+	mu          sync.Mutex
+	scanned     bool  // size has been read from disk
+	size        int64 // bytes on disk
+	entries     int
+	hits        int64
+	misses      int64
+	savedTokens int64 // tokens not spent thanks to hits
+}
+
+// CacheStats summarises cache activity since the cache was opened.
+type CacheStats struct {
+	Hits        int64
+	Misses      int64
+	Entries     int
+	Bytes       int64
+	SavedTokens int64
+}
+
+// HitRate returns the share of lookups served from the cache.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// NewResponseCache opens a cache in dir. Zero ttl or maxBytes disable expiry
+// or eviction.
+func NewResponseCache(dir string, ttl time.Duration, maxBytes int64) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+type cacheOptInKey struct{}
+
+// WithCache marks ctx so calls made with it use the response cache even at a
+// non-zero temperature.
+func WithCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheOptInKey{}, true)
+}
+
+func cacheRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(cacheOptInKey{}).(bool)
+	return v
+}
+
+// cacheKeyInput is everything that determines a response.
+type cacheKeyInput struct {
+	Provider        string           `json:"provider"`
+	BaseURL         string           `json:"base_url"`
+	Model           string           `json:"model"`
+	Messages        []Message        `json:"messages"`
+	Tools           []ToolDefinition `json:"tools,omitempty"`
+	Temperature     *float64         `json:"temperature,omitempty"`
+	TopP            *float64         `json:"top_p,omitempty"`
+	MaxTokens       *int             `json:"max_tokens,omitempty"`
+	ReasoningEffort string           `json:"reasoning_effort,omitempty"`
+	TextTools       bool             `json:"text_tools,omitempty"`
+}
+
+// cacheKey returns the cache key for a request, or false when the request
+// must not be cached.
+func (c *Client) cacheKey(ctx context.Context, model string, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int) (string, bool) {
+	if c.cache == nil {
+		return "", false
+	}
+	deterministic := temperature != nil && *temperature == 0
+	if !deterministic && !cacheRequested(ctx) {
+		return "", false
+	}
+
+	data, err := json.Marshal(cacheKeyInput{
+		Provider:        c.providerName,
+		BaseURL:         c.baseURL,
+		Model:           model,
+		Messages:        messages,
+		Tools:           tools,
+		Temperature:     temperature,
+		TopP:            topP,
+		MaxTokens:       maxTokens,
+		ReasoningEffort: c.reasoningEffort,
+		TextTools:       c.textTools,
+	})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// SetResponseCache enables the response cache for this client; nil disables it.
+func (c *Client) SetResponseCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
+// ResponseCache returns the client's response cache, or nil.
+func (c *Client) ResponseCache() *ResponseCache {
+	return c.cache
+}
+
+// withCache serves a request from the cache when possible, otherwise sends it
+// and stores a successful response. Responses that call tools are not stored:
+// replaying them would run the tools again without asking the model.
+func (c *Client) withCache(ctx context.Context, model string, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, send func() (*ChatResponse, error)) (*ChatResponse, error) {
+	key, ok := c.cacheKey(ctx, model, messages, tools, temperature, topP, maxTokens)
+	if !ok {
+		return send()
+	}
+	if resp, hit := c.cache.Get(key); hit {
+		return resp, nil
+	}
+	resp, err := send()
+	if err == nil && !c.HasToolCalls(resp) {
+		c.cache.Put(key, resp)
+	}
+	return resp, err
+}
+
+func (rc *ResponseCache) path(key string) string {
+	return filepath.Join(rc.dir, key+".json")
+}
+
+// Get returns the cached response for key. The response has zero usage,
+// since serving it cost nothing.
+func (rc *ResponseCache) Get(key string) (*ChatResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.scan()
+
+	path := rc.path(key)
+	info, err := os.Stat(path)
+	if err == nil && rc.ttl > 0 && time.Since(info.ModTime()) > rc.ttl {
+		rc.remove(path, info.Size())
+		err = os.ErrNotExist
+	}
+	var resp ChatResponse
+	if err == nil {
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			err = json.Unmarshal(data, &resp)
+		}
+	}
+	if err != nil {
+		rc.misses++
+		return nil, false
+	}
+
+	rc.hits++
+	rc.savedTokens += int64(resp.Usage.TotalTokens)
+	resp.Usage = Usage{}
+	resp.Cached = true
+	return &resp, true
+}
+
+// Put stores a response under key, evicting the oldest entries when the cache
+// is over its size limit. Failures are ignored; the cache is best effort.
+func (rc *ResponseCache) Put(key string, resp *ChatResponse) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.scan()
+
+	// Entries hold full prompts, so only the owner may read them.
+	if err := os.MkdirAll(rc.dir, 0700); err != nil {
+		return
+	}
+	path := rc.path(key)
+	var previous int64 = -1
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+	if err := utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return
+	}
+	if previous >= 0 {
+		rc.size -= previous
+		rc.entries--
+	}
+	rc.size += int64(len(data))
+	rc.entries++
+
+	if rc.maxBytes > 0 && rc.size > rc.maxBytes {
+		rc.evict()
+	}
+}
+
+// Stats returns hit and size statistics.
+func (rc *ResponseCache) Stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.scan()
+	return CacheStats{
+		Hits:        rc.hits,
+		Misses:      rc.misses,
+		Entries:     rc.entries,
+		Bytes:       rc.size,
+		SavedTokens: rc.savedTokens,
+	}
+}
+
+// Clear deletes every entry.
+func (rc *ResponseCache) Clear() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, f := range rc.files() {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	rc.size, rc.entries = 0, 0
+	return nil
+}
+
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (rc *ResponseCache) files() []cacheFile {
+	dirEntries, err := os.ReadDir(rc.dir)
+	if err != nil {
+		return nil
+	}
+	var files []cacheFile
+	for _, e := range dirEntries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{filepath.Join(rc.dir, e.Name()), info.Size(), info.ModTime()})
+	}
+	return files
+}
+
+// scan reads the current size of the cache once. Callers hold rc.mu.
+func (rc *ResponseCache) scan() {
+	if rc.scanned {
+		return
+	}
+	rc.scanned = true
+	for _, f := range rc.files() {
+		rc.size += f.size
+		rc.entries++
+	}
+}
+
+// evict removes the oldest entries until the cache fits. Callers hold rc.mu.
+func (rc *ResponseCache) evict() {
+	files := rc.files()
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if rc.size <= rc.maxBytes {
+			return
+		}
+		rc.remove(f.path, f.size)
+	}
+}
+
+func (rc *ResponseCache) remove(path string, size int64) {
+	if os.Remove(path) == nil {
+		rc.size -= size
+		rc.entries--
+	}
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCachedClient(t *testing.T, hits *int32) (*Client, *ResponseCache) {
+	t.Helper()
+	server := newRouteServer(t, "gpt-4o", false, hits)
+	t.Cleanup(server.Close)
+
+	cache := NewResponseCache(t.TempDir(), time.Hour, 1<<20)
+	c := NewClientWithProvider(server.URL, "key", "gpt-4o", "openai")
+	c.SetResponseCache(cache)
+	return c, cache
+}
+
+func TestResponseCache_DeterministicCallsHit(t *testing.T) {
+	var hits int32
+	c, cache := newCachedClient(t, &hits)
+	zero := 0.0
+	messages := []Message{{Role: "user", Content: "2+2?"}}
+
+	first, err := c.Chat(messages, &zero, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, first.Cached)
+	assert.Equal(t, 15, first.Usage.TotalTokens)
+
+	second, err := c.Chat(messages, &zero, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, c.GetContent(first), c.GetContent(second))
+	assert.Zero(t, second.Usage.TotalTokens, "cache hits cost nothing")
+	assert.EqualValues(t, 1, hits)
+
+	// Different sampling parameters are a different request.
+	maxTok := 10
+	_, err = c.Chat(messages, &zero, nil, &maxTok)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, hits)
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats.Hits)
+	assert.EqualValues(t, 2, stats.Misses)
+	assert.Equal(t, 2, stats.Entries)
+	assert.EqualValues(t, 15, stats.SavedTokens)
+	assert.InDelta(t, 1.0/3, stats.HitRate(), 0.001)
+}
+
+func TestResponseCache_OptIn(t *testing.T) {
+	var hits int32
+	c, _ := newCachedClient(t, &hits)
+	temp := 0.7
+	messages := []Message{{Role: "user", Content: "write a poem"}}
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Chat(messages, &temp, nil, nil)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, hits, "sampled calls are not cached by default")
+
+	for i := 0; i < 2; i++ {
+		_, err := c.ChatWithToolsContext(WithCache(context.Background()), messages, nil, &temp, nil, nil)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 3, hits)
+}
+
+func TestResponseCache_SkipsToolCalls(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "tool_calls": [
+			{"id": "1", "type": "function", "function": {"name": "exec_command", "arguments": "{}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL, "key", "gpt-4o", "openai")
+	cache := NewResponseCache(t.TempDir(), time.Hour, 1<<20)
+	c.SetResponseCache(cache)
+	ctx := WithCache(context.Background())
+	messages := []Message{{Role: "user", Content: "check the heartbeat"}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.ChatWithToolsContext(ctx, messages, nil, nil, nil, nil)
+		require.NoError(t, err)
+		assert.False(t, resp.Cached)
+		assert.True(t, c.HasToolCalls(resp))
+	}
+	assert.EqualValues(t, 2, hits, "responses that call tools are not replayed")
+	assert.Zero(t, cache.Stats().Entries)
+}
+
+func TestResponseCache_StreamingReplaysHit(t *testing.T) {
+	var hits int32
+	c, _ := newCachedClient(t, &hits)
+	zero := 0.0
+	messages := []Message{{Role: "user", Content: "hello"}}
+
+	_, err := c.Chat(messages, &zero, nil, nil)
+	require.NoError(t, err)
+
+	var streamed string
+	var done bool
+	resp, err := c.ChatWithStreaming(messages, nil, &zero, nil, nil, func(content, thinking string, d bool) {
+		streamed += content
+		done = done || d
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Cached)
+	assert.Equal(t, "from gpt-4o", streamed)
+	assert.True(t, done)
+	assert.EqualValues(t, 1, hits)
+}
+
+func TestResponseCache_ExpiryAndEviction(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewResponseCache(dir, time.Hour, 0)
+	resp := &ChatResponse{Model: "m", Choices: []Choice{{Message: Message{Role: "assistant", Content: "cached"}}}}
+
+	cache.Put("old", resp)
+	fi, err := os.Stat(filepath.Join(dir, "old.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "entries hold full prompts")
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "old.json"), old, old))
+	_, ok := cache.Get("old")
+	assert.False(t, ok, "expired entries are misses")
+	assert.NoFileExists(t, filepath.Join(dir, "old.json"))
+
+	// Room for two entries: adding a third evicts the oldest.
+	info := func(name string) int64 {
+		fi, err := os.Stat(filepath.Join(dir, name+".json"))
+		require.NoError(t, err)
+		return fi.Size()
+	}
+	cache.Put("a", resp)
+	limited := NewResponseCache(dir, time.Hour, 2*info("a"))
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.json"), past, past))
+	limited.Put("b", resp)
+	limited.Put("c", resp)
+
+	assert.NoFileExists(t, filepath.Join(dir, "a.json"))
+	assert.FileExists(t, filepath.Join(dir, "b.json"))
+	assert.FileExists(t, filepath.Join(dir, "c.json"))
+	assert.Equal(t, 2, limited.Stats().Entries)
+
+	require.NoError(t, limited.Clear())
+	assert.Zero(t, limited.Stats().Entries)
+}
+
+// The cache must not be consulted for calls that bypass it entirely.
+func TestResponseCache_DisabledByDefault(t *testing.T) {
+	var hits int32
+	server := newRouteServer(t, "gpt-4o", false, &hits)
+	defer server.Close()
+	c := NewClientWithProvider(server.URL, "key", "gpt-4o", "openai")
+	zero := 0.0
+	for i := 0; i < 2; i++ {
+		_, err := c.Chat([]Message{{Role: "user", Content: "hi"}}, &zero, nil, nil)
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+}
@@ -0,0 +1,278 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cassettes record the HTTP traffic of a real session so it can be replayed
+// offline, e.g. in end-to-end tests of the agent. Set AGI_CASSETTE to a file
+// path to record every client's traffic there, or additionally set
+// AGI_CASSETTE_MODE=replay to serve the recorded responses instead of calling
+// the network. Request headers are not stored and API keys in query strings
+// are redacted, but request bodies (prompts) are kept verbatim.
+const (
+	CassetteEnv     = "AGI_CASSETTE"
+	CassetteModeEnv = "AGI_CASSETTE_MODE"
+)
+
+// ErrCassette is wrapped by replay failures. They are not retried, since a
+// cassette gives the same answer every time.
+var ErrCassette = errors.New("cassette")
+
+// Cassette is a recorded sequence of HTTP interactions.
+type Cassette struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteInteraction is one request and the response it received. Streamed
+// (SSE) responses are stored as their raw event text.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest is the recorded part of a request.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response.
+type CassetteResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Recorder captures interactions into a cassette file, rewriting the file
+// after each one so a crashed session still leaves a usable cassette.
+type Recorder struct {
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder starts an empty cassette at path.
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path}
+}
+
+// Transport returns a RoundTripper that sends requests through next (the
+// default transport when nil) and records them.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		var reqBody []byte
+		if req.Body != nil {
+			var err error
+			if reqBody, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			req.Body.Close()
+			req.Body = io.NopCloser(bytes.NewReader(reqBody))
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		// Buffering the whole body also captures SSE streams; the caller
+		// still parses them chunk by chunk from the copy.
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+		header := resp.Header.Clone()
+		header.Del("Set-Cookie")
+		r.add(CassetteInteraction{
+			Request:  CassetteRequest{Method: req.Method, URL: redactURL(req.URL), Body: string(reqBody)},
+			Response: CassetteResponse{Status: resp.StatusCode, Header: header, Body: string(respBody)},
+		})
+		return resp, nil
+	})
+}
+
+func (r *Recorder) add(it CassetteInteraction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, it)
+	if err := r.cassette.Save(r.path); err != nil {
+		log.Printf("[WARN] Failed to write cassette %s: %v", r.path, err)
+	}
+}
+
+// Replayer serves recorded responses in the order they were recorded. Each
+// request must have the method and path of the next interaction; bodies are
+// not compared, so prompts may differ in timestamps or ordering of context.
+type Replayer struct {
+	name         string
+	mu           sync.Mutex
+	interactions []CassetteInteraction
+	next         int
+}
+
+// NewReplayer replays c. name identifies it in errors.
+func NewReplayer(name string, c *Cassette) *Replayer {
+	return &Replayer{name: name, interactions: c.Interactions}
+}
+
+// LoadReplayer loads a cassette file for replay.
+func LoadReplayer(path string) (*Replayer, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(path, c), nil
+}
+
+// RoundTrip serves the next recorded response.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.interactions) {
+		return nil, fmt.Errorf("%w %s: no recorded response left for %s %s", ErrCassette, r.name, req.Method, req.URL.Path)
+	}
+	it := r.interactions[r.next]
+	recorded, err := url.Parse(it.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: invalid recorded URL %q: %v", ErrCassette, r.name, it.Request.URL, err)
+	}
+	if it.Request.Method != req.Method || recorded.Path != req.URL.Path {
+		return nil, fmt.Errorf("%w %s: interaction %d is %s %s, got %s %s",
+			ErrCassette, r.name, r.next+1, it.Request.Method, recorded.Path, req.Method, req.URL.Path)
+	}
+	r.next++
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", it.Response.Status, http.StatusText(it.Response.Status)),
+		StatusCode:    it.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        it.Response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(it.Response.Body)),
+		ContentLength: int64(len(it.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Remaining returns how many recorded interactions have not been served.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.interactions) - r.next
+}
+
+// SetTransport replaces the HTTP transport, e.g. with a cassette Recorder or
+// Replayer.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// redactURL drops credentials from a URL before it is written to disk.
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	q := clean.Query()
+	for key := range q {
+		if k := strings.ToLower(key); k == "key" || k == "api_key" || k == "apikey" || k == "access_token" {
+			q.Set(key, "REDACTED")
+		}
+	}
+	clean.RawQuery = q.Encode()
+	return clean.String()
+}
+
+var (
+	envCassetteMu sync.Mutex
+	envRecorders  = map[string]*Recorder{}
+	envReplayers  = map[string]http.RoundTripper{}
+)
+
+// envCassetteTransport wraps next according to AGI_CASSETTE, or returns next
+// unchanged when it is unset. Clients recording to the same file share one
+// cassette; clients replaying one share its position.
+func envCassetteTransport(next http.RoundTripper) http.RoundTripper {
+	path := os.Getenv(CassetteEnv)
+	if path == "" {
+		return next
+	}
+
+	envCassetteMu.Lock()
+	defer envCassetteMu.Unlock()
+
+	if strings.EqualFold(os.Getenv(CassetteModeEnv), "replay") {
+		if rt, ok := envReplayers[path]; ok {
+			return rt
+		}
+		var rt http.RoundTripper
+		replayer, err := LoadReplayer(path)
+		if err != nil {
+			// Never fall back to the network in replay mode.
+			err = fmt.Errorf("%w: %v", ErrCassette, err)
+			rt = roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, err })
+		} else {
+			rt = replayer
+		}
+		envReplayers[path] = rt
+		return rt
+	}
+
+	rec, ok := envRecorders[path]
+	if !ok {
+		rec = NewRecorder(path)
+		envRecorders[path] = rec
+	}
+	return rec.Transport(next)
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("x-ratelimit-remaining-requests", "99")
+		_, _ = w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "recorded"}}], "usage": {"total_tokens": 7}}`))
+	}))
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	rec := NewRecorder(path)
+	c := NewClientWithProvider(server.URL, "secret", "gpt-4o", "openai")
+	c.SetTransport(rec.Transport(nil))
+
+	messages := []Message{{Role: "user", Content: "hi"}}
+	resp, err := c.Chat(messages, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "recorded", c.GetContent(resp))
+
+	streamClient := NewClientWithProvider(server.URL, "secret", "gpt-4o", "openai")
+	streamClient.SetTransport(rec.Transport(nil))
+	var chunks []string
+	_, err = streamClient.ChatWithStreaming(messages, nil, nil, nil, nil, func(content, _ string, _ bool) {
+		if content != "" {
+			chunks = append(chunks, content)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hel", "lo"}, chunks)
+	server.Close()
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 2)
+	assert.Contains(t, cassette.Interactions[0].Request.Body, `"content":"hi"`)
+	assert.NotContains(t, cassette.Interactions[0].Request.Body, "secret", "headers are not recorded")
+
+	// Replay with the server gone.
+	replayer, err := LoadReplayer(path)
+	require.NoError(t, err)
+	c.SetTransport(replayer)
+	streamClient.SetTransport(replayer)
+
+	resp, err = c.Chat(messages, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "recorded", c.GetContent(resp))
+	assert.Equal(t, 7, resp.Usage.TotalTokens)
+	assert.Equal(t, 99, resp.RateLimits.RemainingRequests)
+
+	chunks = nil
+	resp, err = streamClient.ChatWithStreaming(messages, nil, nil, nil, nil, func(content, _ string, _ bool) {
+		if content != "" {
+			chunks = append(chunks, content)
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hel", "lo"}, chunks, "streams replay chunk by chunk")
+	assert.Equal(t, "Hello", streamClient.GetContent(resp))
+	assert.Zero(t, replayer.Remaining())
+}
+
+func TestReplayer_RejectsUnexpectedRequests(t *testing.T) {
+	replayer := NewReplayer("test", &Cassette{Interactions: []CassetteInteraction{{
+		Request:  CassetteRequest{Method: "POST", URL: "https://api.example.com/v1/chat/completions"},
+		Response: CassetteResponse{Status: 200, Body: "{}"},
+	}}})
+
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1/models", nil)
+	_, err := replayer.RoundTrip(req)
+	assert.ErrorIs(t, err, ErrCassette)
+	assert.ErrorContains(t, err, "interaction 1 is POST /v1/chat/completions")
+
+	req, _ = http.NewRequest("POST", "https://other.example.com/v1/chat/completions", nil)
+	_, err = replayer.RoundTrip(req)
+	require.NoError(t, err, "hosts may differ between recording and replay")
+
+	_, err = replayer.RoundTrip(req)
+	assert.ErrorContains(t, err, "no recorded response left")
+}
+
+func TestRedactURL(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://user:pw@generativelanguage.googleapis.com/v1beta/models/gemini:generateContent?key=AIzaSecret&alt=sse", nil)
+	got := redactURL(req.URL)
+	assert.NotContains(t, got, "AIzaSecret")
+	assert.NotContains(t, got, "pw@")
+	assert.Contains(t, got, "alt=sse")
+}
+
+func TestEnvCassette_ReplayNeverUsesNetwork(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	t.Setenv(CassetteEnv, filepath.Join(t.TempDir(), "missing.json"))
+	t.Setenv(CassetteModeEnv, "replay")
+
+	c := NewClientWithProvider(server.URL, "key", "gpt-4o", "openai")
+	_, err := c.Chat([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+	assert.ErrorContains(t, err, "failed to read cassette")
+	assert.Zero(t, hits)
+}
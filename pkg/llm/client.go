@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -104,6 +105,10 @@ type Client struct {
 	fallbackTimeout time.Duration
 	reasoningEffort string
 	httpClient      *http.Client
+	native          Provider        // protocol adapter for non-OpenAI-compatible APIs; nil uses the helpers below
+	textTools       bool            // describe tools in the prompt and parse calls from text
+	router          *ProviderRouter // optional; routes requests across configured providers
+	cache           *ResponseCache  // optional; see cache.go
 }
 
 // ---------------------------------------------------------------------------
@@ -131,7 +136,7 @@ func NewClientWithProvider(baseURL, apiKey, model, providerName string) *Client
 	// third-party OpenAI-compatible endpoints, so we silently skip.
 	g, _ := newGollmInstance(baseURL, apiKey, model, providerName)
 
-	return &Client{
+	c := &Client{
 		baseURL:         baseURL,
 		apiKey:          apiKey,
 		model:           model,
@@ -139,6 +144,8 @@ func NewClientWithProvider(baseURL, apiKey, model, providerName string) *Client
 		gollmLLM:        g,
 		fallbackModels:  []string{},
 		fallbackTimeout: 30 * time.Second,
+		native:          nativeProvider(mapped),
+		textTools:       GetModelProfile(model).TextToolCalls,
 		// Configure timeouts for security and reliability
 		httpClient: &http.Client{
 			Timeout: 5 * time.Minute, // Maximum time for a complete request
@@ -154,6 +161,9 @@ func NewClientWithProvider(baseURL, apiKey, model, providerName string) *Client
 			},
 		},
 	}
+	// Record or replay traffic when AGI_CASSETTE is set (see cassette.go)
+	c.httpClient.Transport = envCassetteTransport(c.httpClient.Transport)
+	return c
 }
 
 // ---------------------------------------------------------------------------
@@ -174,6 +184,9 @@ func (c *Client) SetFallbackModels(models []string, timeoutSeconds int) {
 // SetReasoningEffort sets the reasoning effort level.
 func (c *Client) SetReasoningEffort(effort string) {
 	c.reasoningEffort = effort
+	if p, ok := c.native.(interface{ SetReasoningEffort(string) }); ok {
+		p.SetReasoningEffort(effort)
+	}
 }
 
 // GetReasoningEffort returns the current reasoning effort level.
@@ -181,6 +194,17 @@ func (c *Client) GetReasoningEffort() string {
 	return c.reasoningEffort
 }
 
+// SetTextToolCalls switches between native function calling and the
+// text-based tool protocol for models that lack it.
+func (c *Client) SetTextToolCalls(enabled bool) {
+	c.textTools = enabled
+}
+
+// TextToolCalls reports whether the text-based tool protocol is active.
+func (c *Client) TextToolCalls() bool {
+	return c.textTools
+}
+
 // ---------------------------------------------------------------------------
 // Chat methods
 // ---------------------------------------------------------------------------
@@ -197,10 +221,15 @@ func (c *Client) ChatWithTools(messages []Message, tools []ToolDefinition, tempe
 
 // ChatWithToolsContext is like ChatWithTools but honours ctx for cancellation.
 func (c *Client) ChatWithToolsContext(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int) (*ChatResponse, error) {
-	if len(c.fallbackModels) > 0 {
-		return c.chatWithFallbackCtx(ctx, messages, tools, temperature, topP, maxTokens)
+	if c.router != nil {
+		return c.chatRouted(ctx, messages, tools, temperature, topP, maxTokens)
 	}
-	return c.chatWithModelCtx(ctx, c.model, messages, tools, temperature, topP, maxTokens, 0)
+	return c.withCache(ctx, c.model, messages, tools, temperature, topP, maxTokens, func() (*ChatResponse, error) {
+		if len(c.fallbackModels) > 0 {
+			return c.chatWithFallbackCtx(ctx, messages, tools, temperature, topP, maxTokens)
+		}
+		return c.chatWithModelCtx(ctx, c.model, messages, tools, temperature, topP, maxTokens, 0)
+	})
 }
 
 func (c *Client) chatWithFallbackCtx(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int) (*ChatResponse, error) {
@@ -238,7 +267,14 @@ func (c *Client) chatWithModelCtx(ctx context.Context, model string, messages []
 		return nil, fmt.Errorf("rate limit wait cancelled: %w", err)
 	}
 
-	jsonData, err := buildRequestBody(model, messages, tools, temperature, topP, maxTokens, false, c.reasoningEffort)
+	// Models without native function calling get the tools in the prompt.
+	var textTools []ToolDefinition
+	if c.textTools && len(tools) > 0 {
+		textTools, tools = tools, nil
+		messages = textToolMessages(messages, textTools)
+	}
+
+	jsonData, err := c.buildRequest(model, messages, tools, temperature, topP, maxTokens, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -252,15 +288,18 @@ func (c *Client) chatWithModelCtx(ctx context.Context, model string, messages []
 
 	var chatResp *ChatResponse
 	operation := func() error {
-		req, reqErr := http.NewRequestWithContext(reqCtx, "POST", endpointURL(c.baseURL, c.providerName), bytes.NewBuffer(jsonData))
+		req, reqErr := http.NewRequestWithContext(reqCtx, "POST", c.requestURL(model, false), bytes.NewBuffer(jsonData))
 		if reqErr != nil {
 			return fmt.Errorf("failed to create request: %w", reqErr)
 		}
 
-		setProviderHeaders(req, c.providerName, c.apiKey)
+		c.setHeaders(req)
 
 		resp, doErr := c.httpClient.Do(req)
 		if doErr != nil {
+			if errors.Is(doErr, ErrCassette) {
+				return utils.Permanent(fmt.Errorf("failed to send request: %w", doErr))
+			}
 			return fmt.Errorf("failed to send request: %w", doErr)
 		}
 		defer resp.Body.Close()
@@ -287,15 +326,19 @@ func (c *Client) chatWithModelCtx(ctx context.Context, model string, messages []
 				}
 				return apiErr
 			}
-			return apiErr
+			// Client errors (bad key, bad request) will not succeed on retry
+			return utils.Permanent(apiErr)
 		}
 
-		parsed, parseErr := parseResponseBody(body)
+		parsed, parseErr := c.parseResponse(body)
 		if parseErr != nil {
 			return parseErr
 		}
 		chatResp = parsed
-		chatResp.RateLimits = parseRateLimitHeaders(c.providerName, resp)
+		chatResp.RateLimits = c.parseRateLimits(resp)
+		if textTools != nil {
+			applyTextToolCalls(chatResp, textTools)
+		}
 
 		return nil
 	}
@@ -427,6 +470,70 @@ func ToolsToDefinitions(tools []map[string]any) []ToolDefinition {
 	return defs
 }
 
+// ---------------------------------------------------------------------------
+// Native provider dispatch
+// ---------------------------------------------------------------------------
+
+// nativeProvider returns the protocol adapter for providers whose API is not
+// OpenAI-compatible, or nil when the generic helpers should be used.
+func nativeProvider(providerName string) Provider {
+	switch providerName {
+	case "gemini":
+		return &GeminiProvider{}
+	case "ollama":
+		return &OllamaProvider{}
+	}
+	return nil
+}
+
+func (c *Client) buildRequest(model string, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, stream bool) ([]byte, error) {
+	if c.native != nil {
+		return c.native.BuildRequestBody(model, messages, tools, temperature, topP, maxTokens, stream)
+	}
+	return buildRequestBody(model, messages, tools, temperature, topP, maxTokens, stream, c.reasoningEffort)
+}
+
+func (c *Client) requestURL(model string, stream bool) string {
+	if p, ok := c.native.(interface {
+		ModelEndpoint(baseURL, model string, stream bool) string
+	}); ok {
+		return p.ModelEndpoint(c.baseURL, model, stream)
+	}
+	if c.native != nil {
+		return c.native.Endpoint(c.baseURL)
+	}
+	return endpointURL(c.baseURL, c.providerName)
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	if c.native != nil {
+		c.native.SetHeaders(req, c.apiKey)
+		return
+	}
+	setProviderHeaders(req, c.providerName, c.apiKey)
+}
+
+func (c *Client) parseResponse(body []byte) (*ChatResponse, error) {
+	if c.native != nil {
+		return c.native.ParseResponseBody(body)
+	}
+	return parseResponseBody(body)
+}
+
+func (c *Client) parseRateLimits(resp *http.Response) RateLimits {
+	if c.native != nil {
+		return c.native.ParseRateLimits(resp.Header)
+	}
+	return parseRateLimitHeaders(c.providerName, resp)
+}
+
+func (c *Client) parseStream(body io.Reader, callback StreamingCallback) (*ChatResponse, error) {
+	if c.native != nil {
+		return c.native.ParseSSEStream(body, callback)
+	}
+	return parseSSEStream(body, callback)
+}
+
 // ---------------------------------------------------------------------------
 // Helper functions for gollm integration
 // ---------------------------------------------------------------------------
@@ -434,7 +541,28 @@ func ToolsToDefinitions(tools []map[string]any) []ToolDefinition {
 // mapProviderName maps provider names to canonical names
 func mapProviderName(providerName, model, apiKey, baseURL string) string {
 	if providerName != "" {
-		return strings.ToLower(providerName)
+		name := strings.ToLower(providerName)
+		if name == "google" {
+			return "gemini"
+		}
+		return name
+	}
+
+	// Gemini: native endpoint, AIza-style Google API key, or gemini-* model.
+	// Google's OpenAI-compatible endpoint (.../v1beta/openai) stays on the
+	// generic path.
+	lowerBase := strings.TrimSuffix(strings.ToLower(baseURL), "/")
+	if !strings.HasSuffix(lowerBase, "/openai") &&
+		(strings.Contains(lowerBase, "generativelanguage.googleapis.com") ||
+			strings.HasPrefix(apiKey, "AIza") ||
+			strings.HasPrefix(strings.ToLower(model), "gemini")) {
+		return "gemini"
+	}
+
+	// Ollama's default port; /v1 is its OpenAI-compatible mount, which is
+	// served natively instead so tools and context length are reliable.
+	if strings.Contains(strings.ToLower(baseURL), ":11434") {
+		return "ollama"
 	}
 
 	// Check baseURL for NVIDIA
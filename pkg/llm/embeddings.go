@@ -0,0 +1,284 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// related the texts are.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the vector space. Vectors from different models cannot
+	// be compared, so stores re-embed their texts when it changes.
+	Model() string
+}
+
+// APIEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type APIEmbedder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewAPIEmbedder creates an embedder for model at baseURL (e.g.
+// "https://api.openai.com/v1").
+func NewAPIEmbedder(baseURL, apiKey, model string) *APIEmbedder {
+	return &APIEmbedder{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Model implements Embedder.
+func (e *APIEmbedder) Model() string {
+	return e.model
+}
+
+// Embed implements Embedder.
+func (e *APIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseAPIError(resp.StatusCode, data)
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+	sort.Slice(parsed.Data, func(i, j int) bool { return parsed.Data[i].Index < parsed.Data[j].Index })
+	vectors := make([][]float32, len(texts))
+	for i, d := range parsed.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// DefaultHashDims is the vector size of a HashEmbedder created with 0 dims.
+const DefaultHashDims = 1024
+
+// HashEmbedder embeds texts locally by hashing their words and word pairs
+// into a fixed number of dimensions, weighted by log term frequency. It
+// needs no network or model, and finds texts that share vocabulary rather
+// than meaning.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hashing embedder; dims <= 0 uses DefaultHashDims.
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = DefaultHashDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Model implements Embedder.
+func (e *HashEmbedder) Model() string {
+	return fmt.Sprintf("hash-%d", e.dims)
+}
+
+// Embed implements Embedder.
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	words := embeddingTerms(text)
+	counts := make(map[string]float64)
+	for i, w := range words {
+		counts[w]++
+		if i > 0 {
+			counts[words[i-1]+" "+w] += 0.5
+		}
+	}
+
+	v := make([]float32, e.dims)
+	for term, tf := range counts {
+		h := fnv.New32a()
+		h.Write([]byte(term))
+		sum := h.Sum32()
+		// The top bit picks a sign so colliding terms tend to cancel out
+		weight := float32(1 + math.Log(tf+1))
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		v[int(sum%uint32(e.dims))] += weight
+	}
+	normalize(v)
+	return v
+}
+
+// stopWords are left out of hashed embeddings.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true, "to": true, "in": true,
+	"on": true, "for": true, "with": true, "is": true, "are": true, "was": true, "be": true, "it": true,
+	"this": true, "that": true, "as": true, "at": true, "by": true, "from": true, "we": true, "i": true,
+	"you": true, "do": true, "not": true, "but": true, "if": true, "so": true, "can": true, "how": true,
+}
+
+// embeddingTerms splits text into lower-case words without stop words,
+// splitting camelCase and snake_case identifiers and dropping a plural "s"
+// or past tense "ed".
+func embeddingTerms(text string) []string {
+	var terms []string
+	var word []rune
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := strings.ToLower(string(word))
+		word = word[:0]
+		switch {
+		case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+			w = w[:len(w)-1]
+		case len(w) > 5 && strings.HasSuffix(w, "ed"):
+			w = w[:len(w)-2]
+		}
+		if !stopWords[w] {
+			terms = append(terms, w)
+		}
+	}
+	var prev rune
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && unicode.IsLower(prev) {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+		prev = r
+	}
+	flush()
+	return terms
+}
+
+// FallbackEmbedder uses a primary embedder until a call fails, then the
+// fallback for the rest of the session, e.g. to keep working offline. Model reports the
+// embedder in use so stored vectors are re-embedded after a switch.
+type FallbackEmbedder struct {
+	primary  Embedder
+	fallback Embedder
+
+	mu     sync.Mutex
+	failed bool
+}
+
+// NewFallbackEmbedder creates an embedder that falls back when primary fails.
+func NewFallbackEmbedder(primary, fallback Embedder) *FallbackEmbedder {
+	return &FallbackEmbedder{primary: primary, fallback: fallback}
+}
+
+func (e *FallbackEmbedder) current() Embedder {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failed {
+		return e.fallback
+	}
+	return e.primary
+}
+
+// Model implements Embedder.
+func (e *FallbackEmbedder) Model() string {
+	return e.current().Model()
+}
+
+// Embed implements Embedder. The call that fails returns the error, so the
+// caller sees the switch and can re-embed what it stored.
+func (e *FallbackEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	current := e.current()
+	vectors, err := current.Embed(ctx, texts)
+	if err != nil && current == e.primary && ctx.Err() == nil {
+		e.mu.Lock()
+		e.failed = true
+		e.mu.Unlock()
+		return nil, fmt.Errorf("%s embeddings failed, falling back to %s: %w", e.primary.Model(), e.fallback.Model(), err)
+	}
+	return vectors, err
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, or 0
+// when their lengths differ or either is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// normalize scales v to unit length in place.
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+}
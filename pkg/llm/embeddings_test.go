@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEmbedder_OrdersByIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "embed-small", req.Model)
+		assert.Equal(t, []string{"a", "b"}, req.Input)
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	e := NewAPIEmbedder(server.URL+"/v1/", "key", "embed-small")
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	assert.Equal(t, "embed-small", e.Model())
+}
+
+func TestHashEmbedder_RelatedTextsAreCloser(t *testing.T) {
+	e := NewHashEmbedder(0)
+	vectors, err := e.Embed(context.Background(), []string{
+		"How do we parse the config file?",
+		"Config files are parsed with parseConfigFile in loader.go",
+		"The deploy pipeline pushes images to the registry",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors[0], DefaultHashDims)
+
+	related := CosineSimilarity(vectors[0], vectors[1])
+	unrelated := CosineSimilarity(vectors[0], vectors[2])
+	assert.Greater(t, related, unrelated)
+	assert.InDelta(t, 1.0, CosineSimilarity(vectors[1], vectors[1]), 1e-6)
+}
+
+func TestEmbeddingTerms(t *testing.T) {
+	assert.Equal(t, []string{"parse", "config", "file", "user", "id"}, embeddingTerms("parseConfigFiles for the user_id"))
+}
+
+func TestFallbackEmbedder_SwitchesAfterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"unavailable"}}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	local := NewHashEmbedder(64)
+	e := NewFallbackEmbedder(NewAPIEmbedder(server.URL, "", "embed-small"), local)
+	assert.Equal(t, "embed-small", e.Model())
+
+	_, err := e.Embed(context.Background(), []string{"x"})
+	require.Error(t, err)
+	assert.Equal(t, "hash-64", e.Model())
+
+	vectors, err := e.Embed(context.Background(), []string{"x"})
+	require.NoError(t, err)
+	assert.Len(t, vectors[0], 64)
+}
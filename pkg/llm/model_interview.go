@@ -76,7 +76,10 @@ func (c *Client) InterviewModel(ctx context.Context) (*ModelSelfConfig, error) {
 	temp := float64(0.3) // Low temp for structured output
 	maxTok := int(2000)  // Enough for JSON response
 
-	resp, err := c.chatWithModel(c.model, messages, nil, &temp, nil, &maxTok, 30*time.Second)
+	// Interviews are repeated for the same model; serve them from the cache.
+	resp, err := c.withCache(WithCache(ctx), c.model, messages, nil, &temp, nil, &maxTok, func() (*ChatResponse, error) {
+		return c.chatWithModel(c.model, messages, nil, &temp, nil, &maxTok, 30*time.Second)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("interview failed: %w", err)
 	}
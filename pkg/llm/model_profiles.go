@@ -5,6 +5,7 @@ import (
 	"context"
 	"log"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,8 +21,12 @@ type ModelProfile struct {
 	ContextWindow   int
 	RecommendedTemp *float64 // Best for agent work
 	RecommendedTopP *float64
+	TextToolCalls   bool // no native function calling; use the text tool protocol
 }
 
+// profilesMu guards KnownProfiles against profiles registered at runtime.
+var profilesMu sync.RWMutex
+
 // KnownProfiles contains pre-tested model configurations
 var KnownProfiles = map[string]ModelProfile{
 	// Claude models
@@ -137,6 +142,66 @@ var KnownProfiles = map[string]ModelProfile{
 	},
 
 	// Gemini models
+	"gemini-2.5-pro": {
+		Name:            "gemini-2.5-pro",
+		SupportsTemp:    true,
+		SupportsTopP:    true,
+		SupportsMaxTok:  true,
+		DefaultTemp:     float64Ptr(1.0),
+		DefaultTopP:     float64Ptr(0.95),
+		DefaultMaxTok:   intPtr(8192),
+		ContextWindow:   1048576,
+		RecommendedTemp: float64Ptr(0.7),
+		RecommendedTopP: float64Ptr(0.95),
+	},
+	"gemini-2.5-flash": {
+		Name:            "gemini-2.5-flash",
+		SupportsTemp:    true,
+		SupportsTopP:    true,
+		SupportsMaxTok:  true,
+		DefaultTemp:     float64Ptr(1.0),
+		DefaultTopP:     float64Ptr(0.95),
+		DefaultMaxTok:   intPtr(8192),
+		ContextWindow:   1048576,
+		RecommendedTemp: float64Ptr(0.7),
+		RecommendedTopP: float64Ptr(0.95),
+	},
+	"gemini-2.0-flash": {
+		Name:            "gemini-2.0-flash",
+		SupportsTemp:    true,
+		SupportsTopP:    true,
+		SupportsMaxTok:  true,
+		DefaultTemp:     float64Ptr(1.0),
+		DefaultTopP:     float64Ptr(0.95),
+		DefaultMaxTok:   intPtr(8192),
+		ContextWindow:   1048576,
+		RecommendedTemp: float64Ptr(0.7),
+		RecommendedTopP: float64Ptr(0.95),
+	},
+	"gemini-1.5-pro": {
+		Name:            "gemini-1.5-pro",
+		SupportsTemp:    true,
+		SupportsTopP:    true,
+		SupportsMaxTok:  true,
+		DefaultTemp:     float64Ptr(1.0),
+		DefaultTopP:     float64Ptr(0.95),
+		DefaultMaxTok:   intPtr(8192),
+		ContextWindow:   2097152,
+		RecommendedTemp: float64Ptr(0.7),
+		RecommendedTopP: float64Ptr(0.95),
+	},
+	"gemini-1.5-flash": {
+		Name:            "gemini-1.5-flash",
+		SupportsTemp:    true,
+		SupportsTopP:    true,
+		SupportsMaxTok:  true,
+		DefaultTemp:     float64Ptr(1.0),
+		DefaultTopP:     float64Ptr(0.95),
+		DefaultMaxTok:   intPtr(8192),
+		ContextWindow:   1048576,
+		RecommendedTemp: float64Ptr(0.7),
+		RecommendedTopP: float64Ptr(0.95),
+	},
 	"gemini-pro": {
 		Name:            "gemini-pro",
 		SupportsTemp:    true,
@@ -179,49 +244,71 @@ var KnownProfiles = map[string]ModelProfile{
 
 // GetModelProfile retrieves profile for a model (matches partial names)
 func GetModelProfile(modelName string) ModelProfile {
+	if profile, ok := LookupModelProfile(modelName); ok {
+		return profile
+	}
+	log.Printf("[WARN] Unknown model '%s', using default profile", modelName)
+	return KnownProfiles["default"]
+}
+
+// LookupModelProfile is GetModelProfile without the default: ok is false
+// for a model no profile matches.
+func LookupModelProfile(modelName string) (ModelProfile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+
 	lowerModel := strings.ToLower(modelName)
 
 	// Exact match first
 	if profile, ok := KnownProfiles[lowerModel]; ok {
-		return profile
+		return profile, true
 	}
 
 	// Partial match (e.g., "claude-sonnet-4.5" matches "claude-sonnet-4")
 	for key, profile := range KnownProfiles {
 		if strings.Contains(lowerModel, key) {
-			return profile
+			return profile, true
 		}
 	}
 
 	// Check by model family
 	if strings.Contains(lowerModel, "claude") {
 		if strings.Contains(lowerModel, "opus") {
-			return KnownProfiles["claude-opus-4"]
+			return KnownProfiles["claude-opus-4"], true
 		}
 		if strings.Contains(lowerModel, "sonnet") {
-			return KnownProfiles["claude-sonnet-4"]
+			return KnownProfiles["claude-sonnet-4"], true
 		}
 		if strings.Contains(lowerModel, "haiku") {
-			return KnownProfiles["claude-haiku-4"]
+			return KnownProfiles["claude-haiku-4"], true
 		}
 	}
 
 	if strings.Contains(lowerModel, "gpt") {
 		if strings.Contains(lowerModel, "gpt-4") {
-			return KnownProfiles["gpt-4"]
+			return KnownProfiles["gpt-4"], true
 		}
 		if strings.Contains(lowerModel, "gpt-3.5") {
-			return KnownProfiles["gpt-3.5-turbo"]
+			return KnownProfiles["gpt-3.5-turbo"], true
 		}
 	}
 
 	if strings.Contains(lowerModel, "gemini") {
-		return KnownProfiles["gemini-pro"]
+		if strings.Contains(lowerModel, "pro") {
+			return KnownProfiles["gemini-2.5-pro"], true
+		}
+		return KnownProfiles["gemini-2.5-flash"], true
 	}
 
-	// Unknown model - return default
-	log.Printf("[WARN] Unknown model '%s', using default profile", modelName)
-	return KnownProfiles["default"]
+	return ModelProfile{}, false
+}
+
+// RegisterModelProfile stores a profile discovered at runtime (e.g. from a
+// local server's model metadata) so later lookups use it.
+func RegisterModelProfile(modelName string, profile ModelProfile) {
+	profilesMu.Lock()
+	KnownProfiles[strings.ToLower(modelName)] = profile
+	profilesMu.Unlock()
 }
 
 // DetectModelCapabilities tests what parameters a model accepts
@@ -102,9 +102,15 @@ func ListModelsWithProvider(baseURL, apiKey, providerName string) ([]ModelInfo,
 
 	if !supportsModelListing(mapped) {
 		switch mapped {
+		case "ollama":
+			models, err := ListOllamaModels(baseURL)
+			if err != nil {
+				return knownToModelInfo(OllamaKnownModels), nil
+			}
+			return models, nil
 		case "anthropic":
 			return knownToModelInfo(AnthropicKnownModels), nil
-		case "google":
+		case "gemini":
 			return knownToModelInfo(GoogleKnownModels), nil
 		default:
 			return knownToModelInfo(AnthropicKnownModels), nil
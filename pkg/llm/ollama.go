@@ -0,0 +1,232 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ollamaMaxNumCtx caps the num_ctx requested from Ollama. The KV cache is
+// allocated up front, so asking for a 128K window on a workstation GPU
+// usually fails to load the model.
+const ollamaMaxNumCtx = 32768
+
+// OllamaModelInfo describes a locally installed model as reported by /api/show.
+type OllamaModelInfo struct {
+	Name          string
+	Family        string
+	ParameterSize string
+	Quantization  string
+	ContextLength int      // maximum context the model was trained for
+	Capabilities  []string // e.g. "completion", "tools", "thinking"
+}
+
+// SupportsTools reports whether the model's chat template handles native tools.
+func (i *OllamaModelInfo) SupportsTools() bool { return i.hasCapability("tools") }
+
+// SupportsThinking reports whether the model can emit separate thinking output.
+func (i *OllamaModelInfo) SupportsThinking() bool { return i.hasCapability("thinking") }
+
+func (i *OllamaModelInfo) hasCapability(name string) bool {
+	for _, c := range i.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveContext returns the num_ctx ClosedWheeler requests for the model.
+func (i *OllamaModelInfo) EffectiveContext() int {
+	if i.ContextLength > ollamaMaxNumCtx {
+		return ollamaMaxNumCtx
+	}
+	return i.ContextLength
+}
+
+var ollamaHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// ListOllamaModels returns the models installed on an Ollama server (/api/tags).
+func ListOllamaModels(baseURL string) ([]ModelInfo, error) {
+	resp, err := ollamaHTTPClient.Get(ollamaRoot(baseURL) + "/api/tags")
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, parseAPIError(resp.StatusCode, body)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			ModifiedAt time.Time `json:"modified_at"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to parse Ollama tags: %w", err)
+	}
+
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, ModelInfo{
+			ID:      m.Name,
+			Object:  "model",
+			Created: m.ModifiedAt.Unix(),
+			OwnedBy: "ollama",
+		})
+	}
+	return models, nil
+}
+
+// ShowOllamaModel fetches model metadata and capabilities (/api/show).
+func ShowOllamaModel(ctx context.Context, baseURL, model string) (*OllamaModelInfo, error) {
+	payload, _ := json.Marshal(map[string]string{"model": model})
+	req, err := http.NewRequestWithContext(ctx, "POST", ollamaRoot(baseURL)+"/api/show", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ollamaHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseAPIError(resp.StatusCode, body)
+	}
+
+	var show struct {
+		Template string `json:"template"`
+		Details  struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+		ModelInfo    map[string]interface{} `json:"model_info"`
+		Capabilities []string               `json:"capabilities"`
+	}
+	if err := json.Unmarshal(body, &show); err != nil {
+		return nil, fmt.Errorf("failed to parse Ollama model info: %w", err)
+	}
+
+	info := &OllamaModelInfo{
+		Name:          model,
+		Family:        show.Details.Family,
+		ParameterSize: show.Details.ParameterSize,
+		Quantization:  show.Details.QuantizationLevel,
+		Capabilities:  show.Capabilities,
+	}
+
+	// Context length is stored under "<architecture>.context_length".
+	for key, val := range show.ModelInfo {
+		if strings.HasSuffix(key, ".context_length") {
+			if n, ok := val.(float64); ok {
+				info.ContextLength = int(n)
+			}
+		}
+	}
+
+	// Servers older than 0.6 omit capabilities; infer tool support from
+	// whether the chat template renders .Tools.
+	if len(info.Capabilities) == 0 {
+		info.Capabilities = []string{"completion"}
+		if strings.Contains(show.Template, ".Tools") {
+			info.Capabilities = append(info.Capabilities, "tools")
+		}
+	}
+
+	return info, nil
+}
+
+// OllamaPullProgress is called for each status update while pulling a model.
+// total and completed are zero for steps without a download.
+type OllamaPullProgress func(status string, completed, total int64)
+
+// PullOllamaModel downloads a model onto the Ollama server (/api/pull).
+func PullOllamaModel(ctx context.Context, baseURL, model string, progress OllamaPullProgress) error {
+	payload, _ := json.Marshal(map[string]interface{}{"model": model, "stream": true})
+	req, err := http.NewRequestWithContext(ctx, "POST", ollamaRoot(baseURL)+"/api/pull", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Pulls can take a long time; rely on ctx rather than a client timeout.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return parseAPIError(resp.StatusCode, body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var update struct {
+			Status    string `json:"status"`
+			Total     int64  `json:"total"`
+			Completed int64  `json:"completed"`
+			Error     string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pull failed: %s", update.Error)
+		}
+		if progress != nil {
+			progress(update.Status, update.Completed, update.Total)
+		}
+	}
+	return scanner.Err()
+}
+
+// ProbeOllamaModel queries the server for the active model's capabilities,
+// configures the provider (num_ctx, thinking), switches models without tool
+// support to the text tool protocol and registers a matching ModelProfile.
+func (c *Client) ProbeOllamaModel(ctx context.Context) (*OllamaModelInfo, error) {
+	p, ok := c.native.(*OllamaProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %q is not ollama", c.providerName)
+	}
+
+	info, err := ShowOllamaModel(ctx, c.baseURL, c.model)
+	if err != nil {
+		return nil, err
+	}
+
+	p.SetContextLength(info.EffectiveContext())
+	p.SetThinking(info.SupportsThinking())
+	c.SetTextToolCalls(!info.SupportsTools())
+
+	profile := GetModelProfile(c.model)
+	profile.Name = c.model
+	if n := info.EffectiveContext(); n > 0 {
+		profile.ContextWindow = n
+	}
+	profile.TextToolCalls = !info.SupportsTools()
+	RegisterModelProfile(c.model, profile)
+
+	log.Printf("[INFO] Ollama model %s: context=%d (max %d) tools=%v thinking=%v",
+		c.model, info.EffectiveContext(), info.ContextLength, info.SupportsTools(), info.SupportsThinking())
+	return info, nil
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+)
+
+// ModelPricing is the list price of a model in USD per million tokens.
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns the USD cost of usage at this price.
+func (p ModelPricing) Cost(u Usage) float64 {
+	return (float64(u.PromptTokens)*p.Input + float64(u.CompletionTokens)*p.Output) / 1_000_000
+}
+
+// KnownPricing holds list prices keyed by model name or prefix. The longest
+// matching key wins, so "gpt-4o-mini" is not billed as "gpt-4o". Prices
+// change; override them with the "pricing" section of the config.
+var KnownPricing = map[string]ModelPricing{
+	// Anthropic
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3.7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3.5-sonnet": {Input: 3, Output: 15},
+	"claude-sonnet-3.5": {Input: 3, Output: 15},
+	"claude-haiku-4":    {Input: 1, Output: 5},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3.5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+
+	// OpenAI
+	"gpt-5":         {Input: 1.25, Output: 10},
+	"gpt-5-mini":    {Input: 0.25, Output: 2},
+	"gpt-5-nano":    {Input: 0.05, Output: 0.4},
+	"gpt-4.1":       {Input: 2, Output: 8},
+	"gpt-4.1-mini":  {Input: 0.4, Output: 1.6},
+	"gpt-4.1-nano":  {Input: 0.1, Output: 0.4},
+	"gpt-4o":        {Input: 2.5, Output: 10},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.6},
+	"gpt-4-turbo":   {Input: 10, Output: 30},
+	"gpt-4":         {Input: 30, Output: 60},
+	"gpt-3.5-turbo": {Input: 0.5, Output: 1.5},
+	"o1":            {Input: 15, Output: 60},
+	"o1-mini":       {Input: 1.1, Output: 4.4},
+	"o3":            {Input: 2, Output: 8},
+	"o3-mini":       {Input: 1.1, Output: 4.4},
+	"o4-mini":       {Input: 1.1, Output: 4.4},
+
+	// Google
+	"gemini-2.5-pro":   {Input: 1.25, Output: 10},
+	"gemini-2.5-flash": {Input: 0.3, Output: 2.5},
+	"gemini-2.0-flash": {Input: 0.1, Output: 0.4},
+	"gemini-1.5-pro":   {Input: 1.25, Output: 5},
+	"gemini-1.5-flash": {Input: 0.075, Output: 0.3},
+}
+
+// PricingForModel looks up the price of a model. overrides (from config) are
+// checked before KnownPricing; both match by longest prefix, ignoring router
+// prefixes such as "anthropic/". Local providers are free. ok is false when
+// the model has no known price.
+func PricingForModel(provider, model string, overrides map[string]ModelPricing) (ModelPricing, bool) {
+	if p, ok := overrides[model]; ok {
+		return p, true
+	}
+	if strings.EqualFold(provider, "ollama") {
+		return ModelPricing{}, true
+	}
+
+	name := strings.ToLower(model)
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	if p, ok := longestPrefix(name, overrides); ok {
+		return p, true
+	}
+	return longestPrefix(name, KnownPricing)
+}
+
+func longestPrefix(name string, table map[string]ModelPricing) (ModelPricing, bool) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, key := range keys {
+		if strings.HasPrefix(name, strings.ToLower(key)) {
+			return table[key], true
+		}
+	}
+	return ModelPricing{}, false
+}
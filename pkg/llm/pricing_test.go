@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricingForModel(t *testing.T) {
+	p, ok := PricingForModel("openai", "gpt-4o-mini-2024-07-18", nil)
+	assert.True(t, ok)
+	assert.Equal(t, KnownPricing["gpt-4o-mini"], p, "longest prefix should win")
+
+	p, ok = PricingForModel("openrouter", "anthropic/claude-sonnet-4", nil)
+	assert.True(t, ok)
+	assert.Equal(t, KnownPricing["claude-sonnet-4"], p)
+
+	p, ok = PricingForModel("ollama", "llama3", nil)
+	assert.True(t, ok)
+	assert.Zero(t, p.Cost(Usage{PromptTokens: 1000}))
+
+	_, ok = PricingForModel("openai", "mystery-model", nil)
+	assert.False(t, ok)
+
+	overrides := map[string]ModelPricing{"gpt-4o": {Input: 1, Output: 1}}
+	p, ok = PricingForModel("openai", "gpt-4o-2024-11-20", overrides)
+	assert.True(t, ok)
+	assert.Equal(t, overrides["gpt-4o"], p, "config overrides take precedence")
+}
+
+func TestModelPricing_Cost(t *testing.T) {
+	p := ModelPricing{Input: 3, Output: 15}
+	assert.InDelta(t, 0.0105, p.Cost(Usage{PromptTokens: 1000, CompletionTokens: 500}), 1e-9)
+}
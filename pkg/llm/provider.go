@@ -49,6 +49,10 @@ func DetectProvider(providerName, modelName, apiKey string) Provider {
 		return &AnthropicProvider{}
 	case "openai":
 		return &OpenAIProvider{}
+	case "gemini", "google":
+		return &GeminiProvider{}
+	case "ollama":
+		return &OllamaProvider{}
 	}
 
 	// Auto-detect by model name
@@ -56,11 +60,17 @@ func DetectProvider(providerName, modelName, apiKey string) Provider {
 	if strings.HasPrefix(lowerModel, "claude") {
 		return &AnthropicProvider{}
 	}
+	if strings.HasPrefix(lowerModel, "gemini") {
+		return &GeminiProvider{}
+	}
 
 	// Auto-detect by API key prefix
 	if strings.HasPrefix(apiKey, "sk-ant-") {
 		return &AnthropicProvider{}
 	}
+	if strings.HasPrefix(apiKey, "AIza") {
+		return &GeminiProvider{}
+	}
 
 	// Default to OpenAI-compatible
 	return &OpenAIProvider{}
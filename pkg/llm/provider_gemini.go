@@ -0,0 +1,523 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider implements the Provider interface for the Google Gemini
+// generateContent API. Unlike the OpenAI-compatible shim it keeps native
+// function calling and thought summaries intact.
+type GeminiProvider struct {
+	mu              sync.Mutex
+	lastModel       string // model of the last built request, used by Endpoint
+	lastStream      bool
+	reasoningEffort string // "low", "medium", "high" map to thinking budgets
+}
+
+// geminiThinkingBudgets maps effort levels to thinkingConfig.thinkingBudget.
+var geminiThinkingBudgets = map[string]int{
+	"minimal": 512,
+	"low":     2048,
+	"medium":  8192,
+	"high":    24576,
+	"xhigh":   24576, // clamped to the Gemini 2.5 maximum
+}
+
+// SetReasoningEffort sets the reasoning effort level.
+func (p *GeminiProvider) SetReasoningEffort(effort string) { p.reasoningEffort = effort }
+
+// GetReasoningEffort returns the current reasoning effort level.
+func (p *GeminiProvider) GetReasoningEffort() string { return p.reasoningEffort }
+
+func (p *GeminiProvider) Name() string { return "gemini" }
+
+// Endpoint returns the URL for the model and mode of the last built request.
+// Gemini encodes both in the path, so callers that know them up front should
+// prefer ModelEndpoint.
+func (p *GeminiProvider) Endpoint(baseURL string) string {
+	p.mu.Lock()
+	model, stream := p.lastModel, p.lastStream
+	p.mu.Unlock()
+	return p.ModelEndpoint(baseURL, model, stream)
+}
+
+// ModelEndpoint returns the generateContent (or streamGenerateContent) URL for model.
+func (p *GeminiProvider) ModelEndpoint(baseURL, model string, stream bool) string {
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	model = strings.TrimPrefix(model, "models/")
+	if stream {
+		return baseURL + "/models/" + model + ":streamGenerateContent?alt=sse"
+	}
+	return baseURL + "/models/" + model + ":generateContent"
+}
+
+func (p *GeminiProvider) SetHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("x-goog-api-key", apiKey)
+	}
+}
+
+// --- Gemini request types ---
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" or "model"
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode string `json:"mode"`
+	} `json:"functionCallingConfig"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64              `json:"temperature,omitempty"`
+	TopP            *float64              `json:"topP,omitempty"`
+	MaxOutputTokens *int                  `json:"maxOutputTokens,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+
+// --- Gemini response types ---
+
+type geminiResponse struct {
+	Candidates    []geminiCandidate   `json:"candidates"`
+	UsageMetadata geminiUsageMetadata `json:"usageMetadata"`
+	ModelVersion  string              `json:"modelVersion"`
+	ResponseID    string              `json:"responseId"`
+	Error         *geminiError        `json:"error,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+	Index        int           `json:"index"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// --- Provider interface implementation ---
+
+func (p *GeminiProvider) BuildRequestBody(model string, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, stream bool) ([]byte, error) {
+	p.mu.Lock()
+	p.lastModel = model
+	p.lastStream = stream
+	p.mu.Unlock()
+
+	var systemParts []geminiPart
+	var chatMessages []Message
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			if msg.Content != "" {
+				systemParts = append(systemParts, geminiPart{Text: msg.Content})
+			}
+		} else {
+			chatMessages = append(chatMessages, msg)
+		}
+	}
+
+	req := geminiRequest{
+		Contents: convertToGeminiContents(chatMessages),
+	}
+	if len(systemParts) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: systemParts}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  sanitizeGeminiSchema(t.Function.Parameters),
+			})
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+		req.ToolConfig = &geminiToolConfig{}
+		req.ToolConfig.FunctionCallingConfig.Mode = "AUTO"
+	}
+
+	gen := &geminiGenerationConfig{
+		Temperature:     temperature,
+		TopP:            topP,
+		MaxOutputTokens: maxTokens,
+	}
+	if p.reasoningEffort == "off" {
+		gen.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: 0}
+	} else if budget, ok := geminiThinkingBudgets[p.reasoningEffort]; ok {
+		gen.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+	}
+	if gen.Temperature != nil || gen.TopP != nil || gen.MaxOutputTokens != nil || gen.ThinkingConfig != nil {
+		req.GenerationConfig = gen
+	}
+
+	return json.Marshal(req)
+}
+
+// convertToGeminiContents translates canonical messages to Gemini contents.
+// Tool calls become functionCall parts, tool results become functionResponse
+// parts (Gemini matches them by function name, so names are recovered from
+// the preceding assistant message), and consecutive same-role turns are merged.
+func convertToGeminiContents(messages []Message) []geminiContent {
+	var result []geminiContent
+	callNames := make(map[string]string) // tool call ID -> function name
+
+	for _, msg := range messages {
+		var parts []geminiPart
+		role := "user"
+
+		switch {
+		case msg.Role == "assistant":
+			role = "model"
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: tc.Function.Name,
+					Args: args,
+				}})
+			}
+
+		case msg.Role == "tool":
+			name := callNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.ToolCallID
+			}
+			parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     name,
+				Response: map[string]interface{}{"content": msg.Content},
+			}})
+
+		default:
+			text := msg.Content
+			if text == "" {
+				text = " " // Gemini rejects empty parts
+			}
+			parts = append(parts, geminiPart{Text: text})
+		}
+
+		if len(parts) == 0 {
+			parts = append(parts, geminiPart{Text: " "})
+		}
+
+		if len(result) > 0 && result[len(result)-1].Role == role {
+			result[len(result)-1].Parts = append(result[len(result)-1].Parts, parts...)
+		} else {
+			result = append(result, geminiContent{Role: role, Parts: parts})
+		}
+	}
+
+	return result
+}
+
+// geminiUnsupportedSchemaKeys are JSON Schema keywords the Gemini API rejects
+// in function declarations.
+var geminiUnsupportedSchemaKeys = []string{"additionalProperties", "$schema", "$id", "$ref", "definitions", "default"}
+
+// sanitizeGeminiSchema returns a copy of a JSON schema with keywords that
+// Gemini does not accept removed at every level.
+func sanitizeGeminiSchema(schema interface{}) interface{} {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			out[key] = sanitizeGeminiSchema(val)
+		}
+		for _, key := range geminiUnsupportedSchemaKeys {
+			delete(out, key)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = sanitizeGeminiSchema(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func (p *GeminiProvider) ParseResponseBody(body []byte) (*ChatResponse, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Gemini response: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("Gemini API error (%s): %s", resp.Error.Status, resp.Error.Message)
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("Gemini returned no candidates")
+	}
+
+	var content, thinking strings.Builder
+	var toolCalls []ToolCall
+	cand := resp.Candidates[0]
+	for _, part := range cand.Content.Parts {
+		appendGeminiPart(part, &content, &thinking, &toolCalls)
+	}
+
+	return &ChatResponse{
+		ID:      resp.ResponseID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.ModelVersion,
+		Choices: []Choice{
+			{
+				Index: 0,
+				Message: Message{
+					Role:      "assistant",
+					Content:   content.String(),
+					Thinking:  thinking.String(),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: mapGeminiFinishReason(cand.FinishReason, len(toolCalls) > 0),
+			},
+		},
+		Usage: resp.UsageMetadata.toUsage(),
+	}, nil
+}
+
+// appendGeminiPart routes a response part to the content, thinking or tool
+// call accumulators. Gemini does not always assign call IDs, so missing ones
+// are synthesised to keep tool results matchable.
+func appendGeminiPart(part geminiPart, content, thinking *strings.Builder, toolCalls *[]ToolCall) {
+	switch {
+	case part.FunctionCall != nil:
+		args := string(part.FunctionCall.Args)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%s_%d", part.FunctionCall.Name, len(*toolCalls))
+		}
+		*toolCalls = append(*toolCalls, ToolCall{
+			ID:   id,
+			Type: "function",
+			Function: FunctionCall{
+				Name:      part.FunctionCall.Name,
+				Arguments: args,
+			},
+		})
+	case part.Thought:
+		thinking.WriteString(part.Text)
+	default:
+		content.WriteString(part.Text)
+	}
+}
+
+func (u geminiUsageMetadata) toUsage() Usage {
+	completion := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + completion
+	}
+	return Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: completion,
+		TotalTokens:      total,
+	}
+}
+
+func mapGeminiFinishReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+// ParseRateLimits reads the x-ratelimit-* headers some Gemini gateways send.
+// The public API only reports quota exhaustion through 429 responses, in
+// which case the result is empty.
+func (p *GeminiProvider) ParseRateLimits(h http.Header) RateLimits {
+	rl := RateLimits{}
+	if v := h.Get("x-ratelimit-remaining-requests"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil {
+			rl.RemainingRequests = val
+		}
+	}
+	if v := h.Get("x-ratelimit-remaining-tokens"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil {
+			rl.RemainingTokens = val
+		}
+	}
+	if v := h.Get("x-ratelimit-reset-requests"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			rl.ResetRequests = time.Now().Add(d).Unix()
+		}
+	}
+	if v := h.Get("x-ratelimit-reset-tokens"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			rl.ResetTokens = time.Now().Add(d).Unix()
+		}
+	}
+	return rl
+}
+
+// ParseSSEStream parses a streamGenerateContent?alt=sse stream. Each event is
+// a complete GenerateContentResponse carrying only the new parts.
+func (p *GeminiProvider) ParseSSEStream(body io.Reader, callback StreamingCallback) (*ChatResponse, error) {
+	reader := bufio.NewReader(body)
+
+	var content, thinking strings.Builder
+	var toolCalls []ToolCall
+	var responseID, model, finishReason string
+	var usage geminiUsageMetadata
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		eof := err == io.EOF
+
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "data:") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+			var chunk geminiResponse
+			if jsonErr := json.Unmarshal([]byte(data), &chunk); jsonErr != nil {
+				log.Printf("[WARN] Skipping malformed Gemini chunk: %v (data: %s)", jsonErr, data)
+			} else if chunk.Error != nil {
+				return nil, fmt.Errorf("Gemini stream error (%s): %s", chunk.Error.Status, chunk.Error.Message)
+			} else {
+				if chunk.ResponseID != "" {
+					responseID = chunk.ResponseID
+				}
+				if chunk.ModelVersion != "" {
+					model = chunk.ModelVersion
+				}
+				if chunk.UsageMetadata.TotalTokenCount > 0 {
+					usage = chunk.UsageMetadata
+				}
+				for _, cand := range chunk.Candidates {
+					for _, part := range cand.Content.Parts {
+						appendGeminiPart(part, &content, &thinking, &toolCalls)
+						if callback == nil || part.FunctionCall != nil || part.Text == "" {
+							continue
+						}
+						if part.Thought {
+							callback("", part.Text, false)
+						} else {
+							callback(part.Text, "", false)
+						}
+					}
+					if cand.FinishReason != "" {
+						finishReason = cand.FinishReason
+					}
+				}
+			}
+		}
+
+		if eof {
+			break
+		}
+	}
+
+	if callback != nil {
+		callback("", "", true)
+	}
+
+	return &ChatResponse{
+		ID:      responseID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []Choice{
+			{
+				Index: 0,
+				Message: Message{
+					Role:      "assistant",
+					Content:   content.String(),
+					Thinking:  thinking.String(),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: mapGeminiFinishReason(finishReason, len(toolCalls) > 0),
+			},
+		},
+		Usage: usage.toUsage(),
+	}, nil
+}
+
+func (p *GeminiProvider) SupportsModelListing() bool { return false }
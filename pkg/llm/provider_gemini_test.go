@@ -0,0 +1,164 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectProvider_Gemini(t *testing.T) {
+	assert.Equal(t, "gemini", DetectProvider("google", "", "").Name())
+	assert.Equal(t, "gemini", DetectProvider("", "gemini-2.5-pro", "").Name())
+	assert.Equal(t, "gemini", DetectProvider("", "custom", "AIzaSyTest").Name())
+	assert.Equal(t, "gemini", mapProviderName("", "x", "", "https://generativelanguage.googleapis.com/v1beta"))
+	assert.Equal(t, "openai", mapProviderName("", "gemini-2.5-pro", "", "https://generativelanguage.googleapis.com/v1beta/openai"))
+}
+
+func TestGeminiProvider_BuildRequestBody(t *testing.T) {
+	p := &GeminiProvider{}
+	p.SetReasoningEffort("low")
+
+	messages := []Message{
+		{Role: "system", Content: "be terse"},
+		{Role: "user", Content: "list files"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "list_files", Arguments: `{"path":"."}`}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "main.go"},
+	}
+	tools := []ToolDefinition{{Type: "function", Function: FunctionSchema{
+		Name:        "list_files",
+		Description: "List files",
+		Parameters: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{"type": "string", "default": "."},
+			},
+		},
+	}}}
+	maxTok := 256
+
+	body, err := p.BuildRequestBody("gemini-2.5-flash", messages, tools, nil, nil, &maxTok, false)
+	require.NoError(t, err)
+
+	var req geminiRequest
+	require.NoError(t, json.Unmarshal(body, &req))
+
+	require.NotNil(t, req.SystemInstruction)
+	assert.Equal(t, "be terse", req.SystemInstruction.Parts[0].Text)
+
+	require.Len(t, req.Contents, 3)
+	assert.Equal(t, "user", req.Contents[0].Role)
+	assert.Equal(t, "model", req.Contents[1].Role)
+	require.NotNil(t, req.Contents[1].Parts[0].FunctionCall)
+	assert.JSONEq(t, `{"path":"."}`, string(req.Contents[1].Parts[0].FunctionCall.Args))
+	require.NotNil(t, req.Contents[2].Parts[0].FunctionResponse)
+	assert.Equal(t, "list_files", req.Contents[2].Parts[0].FunctionResponse.Name)
+
+	require.Len(t, req.Tools, 1)
+	params := req.Tools[0].FunctionDeclarations[0].Parameters.(map[string]interface{})
+	assert.NotContains(t, params, "additionalProperties")
+	path := params["properties"].(map[string]interface{})["path"].(map[string]interface{})
+	assert.NotContains(t, path, "default")
+
+	require.NotNil(t, req.GenerationConfig)
+	assert.Equal(t, 256, *req.GenerationConfig.MaxOutputTokens)
+	require.NotNil(t, req.GenerationConfig.ThinkingConfig)
+	assert.Equal(t, 2048, req.GenerationConfig.ThinkingConfig.ThinkingBudget)
+	assert.True(t, req.GenerationConfig.ThinkingConfig.IncludeThoughts)
+}
+
+func TestGeminiProvider_ParseResponseBody(t *testing.T) {
+	p := &GeminiProvider{}
+
+	resp, err := p.ParseResponseBody([]byte(`{
+		"candidates": [{"content": {"role": "model", "parts": [
+			{"text": "weighing options", "thought": true},
+			{"text": "Reading it now."},
+			{"functionCall": {"name": "read_file", "args": {"path": "go.mod"}}}
+		]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "thoughtsTokenCount": 3, "totalTokenCount": 18},
+		"modelVersion": "gemini-2.5-flash",
+		"responseId": "resp-1"
+	}`))
+	require.NoError(t, err)
+
+	msg := resp.Choices[0].Message
+	assert.Equal(t, "Reading it now.", msg.Content)
+	assert.Equal(t, "weighing options", msg.Thinking)
+	require.Len(t, msg.ToolCalls, 1)
+	assert.Equal(t, "read_file", msg.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"path":"go.mod"}`, msg.ToolCalls[0].Function.Arguments)
+	assert.NotEmpty(t, msg.ToolCalls[0].ID)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 8, TotalTokens: 18}, resp.Usage)
+
+	_, err = p.ParseResponseBody([]byte(`{"error": {"code": 400, "message": "bad key", "status": "INVALID_ARGUMENT"}}`))
+	assert.ErrorContains(t, err, "bad key")
+}
+
+func TestGeminiClient_ChatAgainstServer(t *testing.T) {
+	var gotPath, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("x-goog-api-key")
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "pong"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 3, "candidatesTokenCount": 1, "totalTokenCount": 4}}`))
+	}))
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL, "AIzaTestKey", "gemini-2.5-flash", "gemini")
+	resp, err := c.Chat([]Message{{Role: "user", Content: "ping"}}, nil, nil, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/models/gemini-2.5-flash:generateContent", gotPath)
+	assert.Equal(t, "AIzaTestKey", gotKey)
+	assert.Equal(t, "pong", c.GetContent(resp))
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+}
+
+func TestGeminiClient_StreamingAgainstServer(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "hmm", "thought": true}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hel"}]}}]}`,
+			`{"candidates": [{"content": {"role": "model", "parts": [{"text": "lo"}]}, "finishReason": "STOP"}],
+			  "usageMetadata": {"promptTokenCount": 2, "candidatesTokenCount": 2, "totalTokenCount": 4}}`,
+		}
+		for _, e := range events {
+			_, _ = w.Write([]byte("data: " + strings.ReplaceAll(e, "\n", "") + "\r\n\r\n"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL, "AIzaTestKey", "gemini-2.5-flash", "")
+	assert.Equal(t, "gemini", c.ProviderName())
+
+	var streamed, thought strings.Builder
+	done := false
+	resp, err := c.ChatWithStreaming([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil, nil,
+		func(content, thinking string, isDone bool) {
+			streamed.WriteString(content)
+			thought.WriteString(thinking)
+			done = done || isDone
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, "alt=sse", gotQuery)
+	assert.Equal(t, "Hello", streamed.String())
+	assert.Equal(t, "hmm", thought.String())
+	assert.True(t, done)
+	assert.Equal(t, "Hello", resp.Choices[0].Message.Content)
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+}
@@ -0,0 +1,316 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ollamaDefaultBaseURL = "http://localhost:11434"
+
+// OllamaProvider implements the Provider interface for Ollama's native
+// /api/chat endpoint. Models without native tool support are switched to the
+// client's text tool protocol by Client.ProbeOllamaModel.
+type OllamaProvider struct {
+	mu         sync.Mutex
+	numCtx     int  // options.num_ctx; 0 leaves the server default
+	think      bool // request thinking output from reasoning models
+	thinkKnown bool // model capabilities were probed, so think is authoritative
+	effort     string
+}
+
+// SetContextLength sets the num_ctx option sent with every request.
+func (p *OllamaProvider) SetContextLength(n int) {
+	p.mu.Lock()
+	p.numCtx = n
+	p.mu.Unlock()
+}
+
+// SetThinking records whether the model supports thinking output.
+func (p *OllamaProvider) SetThinking(supported bool) {
+	p.mu.Lock()
+	p.think = supported
+	p.thinkKnown = true
+	p.mu.Unlock()
+}
+
+// SetReasoningEffort enables thinking for any effort other than "" or "off".
+func (p *OllamaProvider) SetReasoningEffort(effort string) { p.effort = effort }
+
+// GetReasoningEffort returns the current reasoning effort level.
+func (p *OllamaProvider) GetReasoningEffort() string { return p.effort }
+
+func (p *OllamaProvider) Name() string { return "ollama" }
+
+// Endpoint returns the /api/chat URL. A trailing /v1 (the OpenAI-compatible
+// mount point) is stripped so existing base URLs keep working.
+func (p *OllamaProvider) Endpoint(baseURL string) string {
+	return ollamaRoot(baseURL) + "/api/chat"
+}
+
+// ollamaRoot normalises a configured base URL to the server root.
+func ollamaRoot(baseURL string) string {
+	if baseURL == "" {
+		return ollamaDefaultBaseURL
+	}
+	root := strings.TrimSuffix(baseURL, "/")
+	root = strings.TrimSuffix(root, "/v1")
+	return strings.TrimSuffix(root, "/api")
+}
+
+func (p *OllamaProvider) SetHeaders(req *http.Request, apiKey string) {
+	req.Header.Set("Content-Type", "application/json")
+	// Ollama itself is unauthenticated; a key is only useful behind a proxy.
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// --- Ollama request/response types ---
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Stream   bool                   `json:"stream"`
+	Think    *bool                  `json:"think,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// --- Provider interface implementation ---
+
+func (p *OllamaProvider) BuildRequestBody(model string, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, stream bool) ([]byte, error) {
+	p.mu.Lock()
+	numCtx, think, thinkKnown := p.numCtx, p.think, p.thinkKnown
+	p.mu.Unlock()
+
+	req := ollamaChatRequest{
+		Model:    model,
+		Messages: convertToOllamaMessages(messages),
+		Tools:    tools,
+		Stream:   stream,
+	}
+
+	options := map[string]interface{}{}
+	if temperature != nil {
+		options["temperature"] = *temperature
+	}
+	if topP != nil {
+		options["top_p"] = *topP
+	}
+	if maxTokens != nil && *maxTokens > 0 {
+		options["num_predict"] = *maxTokens
+	}
+	if numCtx > 0 {
+		options["num_ctx"] = numCtx
+	}
+	if len(options) > 0 {
+		req.Options = options
+	}
+
+	// Only send "think" when the model is known to support it; older servers
+	// and non-reasoning models reject the field.
+	if thinkKnown && think && p.effort != "" && p.effort != "off" {
+		enabled := true
+		req.Think = &enabled
+	}
+
+	return json.Marshal(req)
+}
+
+// convertToOllamaMessages translates canonical messages to Ollama's format.
+// Tool results carry the function name, which Ollama uses to match calls.
+func convertToOllamaMessages(messages []Message) []ollamaMessage {
+	result := make([]ollamaMessage, 0, len(messages))
+	callNames := make(map[string]string) // tool call ID -> function name
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+			}
+			om := ollamaMessage{Role: "assistant", Content: msg.Content}
+			for _, tc := range msg.ToolCalls {
+				var call ollamaToolCall
+				call.Function.Name = tc.Function.Name
+				call.Function.Arguments = json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(call.Function.Arguments) {
+					call.Function.Arguments = json.RawMessage("{}")
+				}
+				om.ToolCalls = append(om.ToolCalls, call)
+			}
+			result = append(result, om)
+
+		case msg.Role == "tool":
+			result = append(result, ollamaMessage{Role: "tool", Content: msg.Content, ToolName: callNames[msg.ToolCallID]})
+
+		default:
+			result = append(result, ollamaMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
+	return result
+}
+
+func (p *OllamaProvider) ParseResponseBody(body []byte) (*ChatResponse, error) {
+	var resp ollamaChatResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Ollama response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("Ollama error: %s", resp.Error)
+	}
+	return p.convertResponse(resp.Model, resp.Message.Content, resp.Message.Thinking, resp.Message.ToolCalls,
+		resp.DoneReason, resp.PromptEvalCount, resp.EvalCount), nil
+}
+
+// convertResponse assembles a canonical ChatResponse.
+func (p *OllamaProvider) convertResponse(model, content, thinking string, native []ollamaToolCall, doneReason string, promptTokens, completionTokens int) *ChatResponse {
+	var toolCalls []ToolCall
+	for i, tc := range native {
+		args := string(tc.Function.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:       fmt.Sprintf("call_%d_%s", i, tc.Function.Name),
+			Type:     "function",
+			Function: FunctionCall{Name: tc.Function.Name, Arguments: args},
+		})
+	}
+
+	finishReason := "stop"
+	switch {
+	case len(toolCalls) > 0:
+		finishReason = "tool_calls"
+	case doneReason == "length":
+		finishReason = "length"
+	}
+
+	return &ChatResponse{
+		ID:      fmt.Sprintf("ollama-%d", time.Now().UnixNano()),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []Choice{
+			{
+				Index: 0,
+				Message: Message{
+					Role:      "assistant",
+					Content:   content,
+					Thinking:  thinking,
+					ToolCalls: toolCalls,
+				},
+				FinishReason: finishReason,
+			},
+		},
+		Usage: Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}
+}
+
+// ParseRateLimits returns an empty result: local servers have no rate limits.
+func (p *OllamaProvider) ParseRateLimits(h http.Header) RateLimits {
+	return RateLimits{}
+}
+
+// ParseSSEStream parses Ollama's streaming output, which is newline-delimited
+// JSON rather than SSE.
+func (p *OllamaProvider) ParseSSEStream(body io.Reader, callback StreamingCallback) (*ChatResponse, error) {
+	reader := bufio.NewReader(body)
+
+	var content, thinking strings.Builder
+	var toolCalls []ollamaToolCall
+	var model, doneReason string
+	var promptTokens, completionTokens int
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		eof := err == io.EOF
+
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "data:"))
+		if line != "" {
+			var chunk ollamaChatResponse
+			if jsonErr := json.Unmarshal([]byte(line), &chunk); jsonErr != nil {
+				log.Printf("[WARN] Skipping malformed Ollama chunk: %v (data: %s)", jsonErr, line)
+			} else if chunk.Error != "" {
+				return nil, fmt.Errorf("Ollama stream error: %s", chunk.Error)
+			} else {
+				if chunk.Model != "" {
+					model = chunk.Model
+				}
+				if chunk.Message.Thinking != "" {
+					thinking.WriteString(chunk.Message.Thinking)
+					if callback != nil {
+						callback("", chunk.Message.Thinking, false)
+					}
+				}
+				if chunk.Message.Content != "" {
+					content.WriteString(chunk.Message.Content)
+					if callback != nil {
+						callback(chunk.Message.Content, "", false)
+					}
+				}
+				toolCalls = append(toolCalls, chunk.Message.ToolCalls...)
+				if chunk.Done {
+					doneReason = chunk.DoneReason
+					promptTokens = chunk.PromptEvalCount
+					completionTokens = chunk.EvalCount
+				}
+			}
+		}
+
+		if eof {
+			break
+		}
+	}
+
+	if callback != nil {
+		callback("", "", true)
+	}
+
+	return p.convertResponse(model, content.String(), thinking.String(), toolCalls, doneReason, promptTokens, completionTokens), nil
+}
+
+// SupportsModelListing reports false because Ollama lists models via
+// /api/tags rather than /models; see ListOllamaModels.
+func (p *OllamaProvider) SupportsModelListing() bool { return false }
@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOllamaServer serves /api/tags, /api/show and /api/chat. chat receives the
+// decoded request and writes the response.
+func newOllamaServer(t *testing.T, capabilities []string, chat func(w http.ResponseWriter, req ollamaChatRequest)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models": [{"name": "qwen2.5-coder:7b", "modified_at": "2025-01-02T03:04:05Z"}]}`))
+		case "/api/show":
+			resp := map[string]interface{}{
+				"details":      map[string]string{"family": "qwen2", "parameter_size": "7.6B"},
+				"model_info":   map[string]interface{}{"qwen2.context_length": 131072},
+				"capabilities": capabilities,
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case "/api/chat":
+			var req ollamaChatRequest
+			body, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &req))
+			chat(w, req)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+var readFileTool = []ToolDefinition{{Type: "function", Function: FunctionSchema{
+	Name:        "read_file",
+	Description: "Read a file",
+	Parameters:  map[string]interface{}{"type": "object"},
+}}}
+
+func TestOllamaClient_NativeTools(t *testing.T) {
+	var got ollamaChatRequest
+	server := newOllamaServer(t, []string{"completion", "tools"}, func(w http.ResponseWriter, req ollamaChatRequest) {
+		got = req
+		_, _ = w.Write([]byte(`{"model": "qwen2.5-coder:7b", "done": true, "done_reason": "stop",
+			"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "read_file", "arguments": {"path": "go.mod"}}}]},
+			"prompt_eval_count": 12, "eval_count": 4}`))
+	})
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL+"/v1", "", "qwen2.5-coder:7b", "ollama")
+	info, err := c.ProbeOllamaModel(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 131072, info.ContextLength)
+	assert.True(t, info.SupportsTools())
+	assert.Equal(t, ollamaMaxNumCtx, GetModelProfile("qwen2.5-coder:7b").ContextWindow)
+
+	resp, err := c.ChatWithTools([]Message{{Role: "user", Content: "show go.mod"}}, readFileTool, nil, nil, nil)
+	require.NoError(t, err)
+
+	assert.Len(t, got.Tools, 1)
+	assert.EqualValues(t, ollamaMaxNumCtx, got.Options["num_ctx"])
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.JSONEq(t, `{"path":"go.mod"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+	assert.Equal(t, 16, resp.Usage.TotalTokens)
+}
+
+func TestOllamaClient_TextToolFallback(t *testing.T) {
+	var got ollamaChatRequest
+	server := newOllamaServer(t, []string{"completion"}, func(w http.ResponseWriter, req ollamaChatRequest) {
+		got = req
+		reply := map[string]interface{}{
+			"model": "llama2", "done": true,
+			"message": map[string]string{
+				"role":    "assistant",
+				"content": "Sure.\n```json\n{\"tool_calls\": [{\"name\": \"read_file\", \"arguments\": {\"path\": \"a.go\"}}]}\n```",
+			},
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	})
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL, "", "llama2", "ollama")
+	_, err := c.ProbeOllamaModel(context.Background())
+	require.NoError(t, err)
+	assert.True(t, c.TextToolCalls())
+	assert.True(t, GetModelProfile("llama2").TextToolCalls)
+
+	history := []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "read b.go then a.go"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"b.go"}`}}}},
+		{Role: "tool", ToolCallID: "c1", Content: "package b"},
+	}
+	resp, err := c.ChatWithTools(history, readFileTool, nil, nil, nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, got.Tools, "tools must not be sent natively")
+	assert.Contains(t, got.Messages[0].Content, "read_file")
+	assert.Contains(t, got.Messages[0].Content, "<tool_call>")
+	assert.Equal(t, "user", got.Messages[3].Role)
+	assert.Contains(t, got.Messages[3].Content, "package b")
+
+	msg := resp.Choices[0].Message
+	require.Len(t, msg.ToolCalls, 1)
+	assert.Equal(t, "read_file", msg.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"path":"a.go"}`, msg.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "Sure.", msg.Content)
+}
+
+func TestOllamaClient_Streaming(t *testing.T) {
+	server := newOllamaServer(t, nil, func(w http.ResponseWriter, req ollamaChatRequest) {
+		assert.True(t, req.Stream)
+		lines := []string{
+			`{"model":"llama3","message":{"role":"assistant","content":"Hel"},"done":false}`,
+			`{"model":"llama3","message":{"role":"assistant","content":"lo"},"done":false}`,
+			`{"model":"llama3","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":3,"eval_count":2}`,
+		}
+		_, _ = w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	})
+	defer server.Close()
+
+	c := NewClient("http://localhost:11434", "", "llama3")
+	assert.Equal(t, "ollama", c.ProviderName())
+	c.baseURL = server.URL
+
+	var streamed strings.Builder
+	resp, err := c.ChatWithStreaming([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil, nil,
+		func(content, thinking string, done bool) { streamed.WriteString(content) })
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello", streamed.String())
+	assert.Equal(t, "Hello", resp.Choices[0].Message.Content)
+	assert.Equal(t, 5, resp.Usage.TotalTokens)
+}
+
+func TestListModelsWithProvider_Ollama(t *testing.T) {
+	server := newOllamaServer(t, nil, nil)
+	defer server.Close()
+
+	models, err := ListModelsWithProvider(server.URL, "", "ollama")
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "qwen2.5-coder:7b", models[0].ID)
+}
@@ -0,0 +1,250 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"ClosedWheeler/pkg/providers"
+)
+
+// ProviderRouter sends requests through a providers.ProviderManager: each
+// request goes to the best provider for the routing criteria and fails over
+// to the rest of the chain, recording latency and failures as it goes. One
+// router is shared by every Client the agent creates, so health and routing
+// state survive model switches.
+type ProviderRouter struct {
+	manager  *providers.ProviderManager
+	criteria string
+	failover bool
+
+	mu        sync.Mutex
+	clients   map[string]*Client // per provider ID
+	active    string             // provider that served the last request
+	failovers int                // requests served by a provider other than the first choice
+	lastErr   string             // most recent provider failure
+}
+
+// RoutingState is a snapshot of a router for display.
+type RoutingState struct {
+	Criteria  string
+	Failover  bool
+	Chain     []string // provider IDs in the order the next request tries them
+	Active    string
+	Failovers int
+	LastError string
+}
+
+// NewProviderRouter creates a router over manager. criteria is passed to
+// ProviderManager.RoutingOrder; with failover false only the first provider
+// is tried.
+func NewProviderRouter(manager *providers.ProviderManager, criteria string, failover bool) *ProviderRouter {
+	return &ProviderRouter{
+		manager:  manager,
+		criteria: criteria,
+		failover: failover,
+		clients:  make(map[string]*Client),
+	}
+}
+
+// Manager returns the provider manager requests are routed through.
+func (r *ProviderRouter) Manager() *providers.ProviderManager { return r.manager }
+
+// State returns the current routing state.
+func (r *ProviderRouter) State() RoutingState {
+	var chain []string
+	for _, p := range r.chain() {
+		chain = append(chain, p.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RoutingState{
+		Criteria:  r.criteria,
+		Failover:  r.failover,
+		Chain:     chain,
+		Active:    r.active,
+		Failovers: r.failovers,
+		LastError: r.lastErr,
+	}
+}
+
+// Active returns the provider that served the last request, or nil.
+func (r *ProviderRouter) Active() *providers.Provider {
+	r.mu.Lock()
+	id := r.active
+	r.mu.Unlock()
+	if id == "" {
+		return nil
+	}
+	p, err := r.manager.GetProvider(id)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+func (r *ProviderRouter) chain() []*providers.Provider {
+	order := r.manager.RoutingOrder(r.criteria)
+	if !r.failover && len(order) > 1 {
+		order = order[:1]
+	}
+	return order
+}
+
+// clientFor returns the client for a provider. It takes over the settings of
+// parent, the client being routed: its transport (so a cassette records and
+// replays routed traffic), response cache, fallback models, reasoning effort
+// and text tool protocol when parent uses it. A client is never changed once
+// built, since other requests may be using it; when the provider or parent
+// settings change, a new one replaces it.
+func (r *ProviderRouter) clientFor(p *providers.Provider, parent *Client) *Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.clients[p.ID]; ok && c.routes(p, parent) {
+		return c
+	}
+	c := NewClientWithProvider(p.BaseURL, p.APIKey, p.Model, routeProviderName(p))
+	c.httpClient = parent.httpClient
+	c.cache = parent.cache
+	c.fallbackModels = slices.Clone(parent.fallbackModels)
+	c.fallbackTimeout = parent.fallbackTimeout
+	c.textTools = parent.textTools || GetModelProfile(c.model).TextToolCalls
+	c.SetReasoningEffort(parent.reasoningEffort)
+	r.clients[p.ID] = c
+	return c
+}
+
+// routes reports whether c was built for p with the settings of parent.
+func (c *Client) routes(p *providers.Provider, parent *Client) bool {
+	return c.baseURL == p.BaseURL && c.apiKey == p.APIKey && c.model == p.Model &&
+		c.httpClient == parent.httpClient &&
+		c.cache == parent.cache &&
+		slices.Equal(c.fallbackModels, parent.fallbackModels) &&
+		c.fallbackTimeout == parent.fallbackTimeout &&
+		c.textTools == (parent.textTools || GetModelProfile(c.model).TextToolCalls) &&
+		c.reasoningEffort == parent.reasoningEffort
+}
+
+// routeProviderName maps a provider type to the client provider name. Local
+// and custom providers are detected from their URL and model.
+func routeProviderName(p *providers.Provider) string {
+	switch p.Type {
+	case providers.ProviderOpenAI:
+		return "openai"
+	case providers.ProviderAnthropic:
+		return "anthropic"
+	case providers.ProviderGoogle:
+		return "gemini"
+	}
+	return ""
+}
+
+// do runs send against each provider in the chain until one succeeds.
+// canFailover reports whether a failed attempt may be retried elsewhere,
+// e.g. a stream that has not emitted anything yet.
+func (r *ProviderRouter) do(ctx context.Context, parent *Client, send func(*Client) (*ChatResponse, error), canFailover func() bool) (*ChatResponse, error) {
+	chain := r.chain()
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no enabled providers available")
+	}
+
+	var errs []error
+	for i, p := range chain {
+		start := time.Now()
+		resp, err := send(r.clientFor(p, parent))
+		if err == nil {
+			p.RecordSuccess(int64(resp.Usage.TotalTokens), time.Since(start), routeCost(p, resp.Usage))
+			r.mu.Lock()
+			r.active = p.ID
+			if i > 0 {
+				r.failovers++
+			}
+			r.mu.Unlock()
+			if i > 0 {
+				log.Printf("[INFO] Provider %s served the request after %d failover(s)", p.ID, i)
+			}
+			return resp, nil
+		}
+
+		// Cancellation and oversized prompts are not the provider's fault.
+		if ctx.Err() != nil || IsContextLengthError(err) {
+			return nil, err
+		}
+
+		p.RecordFailure()
+		errs = append(errs, fmt.Errorf("%s: %w", p.ID, err))
+		r.mu.Lock()
+		r.lastErr = fmt.Sprintf("%s: %v", p.ID, err)
+		r.mu.Unlock()
+
+		if canFailover != nil && !canFailover() {
+			break
+		}
+		if i+1 < len(chain) {
+			log.Printf("[WARN] Provider %s failed: %v. Trying %s...", p.ID, err, chain[i+1].ID)
+		}
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// routeCost prices usage with the provider's configured cost per 1K tokens,
+// falling back to the known list price of its model.
+func routeCost(p *providers.Provider, u Usage) float64 {
+	if p.CostPerToken > 0 {
+		return float64(u.TotalTokens) / 1000 * p.CostPerToken
+	}
+	price, _ := PricingForModel(routeProviderName(p), p.Model, nil)
+	return price.Cost(u)
+}
+
+// SetProviderRouter routes every request of this client through r instead of
+// its own endpoint. nil restores direct requests.
+func (c *Client) SetProviderRouter(r *ProviderRouter) {
+	c.router = r
+}
+
+// ActiveModel returns the provider name and model that served the last
+// request, which differ from the client's own when a router is in use.
+func (c *Client) ActiveModel() (provider, model string) {
+	if c.router != nil {
+		if p := c.router.Active(); p != nil {
+			return mapProviderName(routeProviderName(p), p.Model, p.APIKey, p.BaseURL), p.Model
+		}
+	}
+	return c.providerName, c.model
+}
+
+// chatRouted sends a blocking request through the router. Each provider's
+// client caches under its own model.
+func (c *Client) chatRouted(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int) (*ChatResponse, error) {
+	return c.router.do(ctx, c, func(sub *Client) (*ChatResponse, error) {
+		return sub.ChatWithToolsContext(ctx, messages, tools, temperature, topP, maxTokens)
+	}, nil)
+}
+
+// streamRouted streams through the router. Once a provider has emitted
+// output the request is not retried elsewhere, so chunks are never repeated.
+func (c *Client) streamRouted(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, callback StreamingCallback) (*ChatResponse, error) {
+	emitted := false
+	wrapped := func(content, thinking string, done bool) {
+		if content != "" || thinking != "" {
+			emitted = true
+		}
+		if callback != nil {
+			callback(content, thinking, done)
+		}
+	}
+	return c.router.do(ctx, c, func(sub *Client) (*ChatResponse, error) {
+		return sub.ChatWithStreamingContext(ctx, messages, tools, temperature, topP, maxTokens, wrapped)
+	}, func() bool { return !emitted })
+}
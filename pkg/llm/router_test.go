@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ClosedWheeler/pkg/providers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRouteServer answers chat completions as model, or fails with 401.
+func newRouteServer(t *testing.T, model string, fail bool, hits *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if fail {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"message": "invalid key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model": "` + model + `", "choices": [{"message": {"role": "assistant", "content": "from ` + model + `"}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+	}))
+}
+
+func TestProviderRouter_FailsOverAcrossProviders(t *testing.T) {
+	var downHits, upHits int32
+	down := newRouteServer(t, "gpt-4o", true, &downHits)
+	defer down.Close()
+	up := newRouteServer(t, "backup-model", false, &upHits)
+	defer up.Close()
+
+	pm := providers.NewProviderManager()
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "main", Type: providers.ProviderOpenAI, BaseURL: down.URL, APIKey: "k1", Model: "gpt-4o", Priority: 1}))
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "backup", Type: providers.ProviderCustom, BaseURL: up.URL, APIKey: "k2", Model: "backup-model", Priority: 2}))
+
+	router := NewProviderRouter(pm, "priority", true)
+	c := NewClientWithProvider("http://unused.invalid", "", "configured-model", "openai")
+	c.SetProviderRouter(router)
+
+	resp, err := c.Chat([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "from backup-model", c.GetContent(resp))
+	assert.EqualValues(t, 1, downHits)
+
+	state := router.State()
+	assert.Equal(t, "backup", state.Active)
+	assert.Equal(t, 1, state.Failovers)
+	assert.Contains(t, state.LastError, "main: ")
+	_, model := c.ActiveModel()
+	assert.Equal(t, "backup-model", model)
+
+	// The failed provider is now unhealthy and skipped until it cools down.
+	main, _ := pm.GetProvider("main")
+	assert.False(t, main.IsHealthy())
+	assert.Equal(t, []string{"backup"}, state.Chain)
+
+	_, err = c.Chat([]Message{{Role: "user", Content: "again"}}, nil, nil, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, downHits, "unhealthy provider should not be retried")
+	assert.EqualValues(t, 2, upHits)
+	assert.EqualValues(t, 3, pm.GetTotalStats()["total_requests"], "one failure and two successes")
+}
+
+func TestProviderRouter_RoutedClientsShareParentSettings(t *testing.T) {
+	var hits int32
+	server := newRouteServer(t, "routed-model", false, &hits)
+	defer server.Close()
+
+	pm := providers.NewProviderManager()
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "main", BaseURL: server.URL, Model: "routed-model", Priority: 1}))
+
+	var transported int32
+	c := NewClientWithProvider("http://unused.invalid", "", "configured-model", "openai")
+	c.SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&transported, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	}))
+	c.SetResponseCache(NewResponseCache(t.TempDir(), time.Hour, 1<<20))
+	c.SetFallbackModels([]string{"spare-model"}, 5)
+	c.SetTextToolCalls(true)
+	c.SetProviderRouter(NewProviderRouter(pm, "priority", true))
+
+	zero := 0.0
+	messages := []Message{{Role: "user", Content: "hi"}}
+	first, err := c.Chat(messages, &zero, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, first.Cached)
+	assert.EqualValues(t, 1, transported, "routed requests use the parent's transport")
+
+	second, err := c.Chat(messages, &zero, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, second.Cached, "routed responses use the parent's cache")
+	assert.EqualValues(t, 1, hits)
+
+	sub := c.router.clients["main"]
+	assert.True(t, sub.TextToolCalls())
+	assert.Equal(t, []string{"spare-model"}, sub.fallbackModels)
+	assert.Equal(t, 5*time.Second, sub.fallbackTimeout)
+}
+
+// Requests running at once share routed clients; run with -race.
+func TestProviderRouter_ConcurrentRequests(t *testing.T) {
+	var hits int32
+	server := newRouteServer(t, "routed-model", false, &hits)
+	defer server.Close()
+
+	pm := providers.NewProviderManager()
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "main", BaseURL: server.URL, Model: "routed-model", Priority: 1}))
+
+	c := NewClientWithProvider("http://unused.invalid", "", "configured-model", "openai")
+	c.SetReasoningEffort("high")
+	c.SetFallbackModels([]string{"spare-model"}, 5)
+	c.SetProviderRouter(NewProviderRouter(pm, "priority", true))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.ChatWithTools([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil, nil)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, hits)
+
+	// Changing the parent's settings replaces the routed client.
+	sub := c.router.clients["main"]
+	assert.Equal(t, "high", sub.GetReasoningEffort())
+	c.SetReasoningEffort("low")
+	_, err := c.ChatWithTools([]Message{{Role: "user", Content: "again"}}, nil, nil, nil, nil)
+	require.NoError(t, err)
+	assert.NotSame(t, sub, c.router.clients["main"])
+	assert.Equal(t, "low", c.router.clients["main"].GetReasoningEffort())
+	assert.Equal(t, "high", sub.GetReasoningEffort(), "clients in use are not changed")
+}
+
+func TestProviderRouter_NoFailover(t *testing.T) {
+	var downHits, upHits int32
+	down := newRouteServer(t, "a", true, &downHits)
+	defer down.Close()
+	up := newRouteServer(t, "b", false, &upHits)
+	defer up.Close()
+
+	pm := providers.NewProviderManager()
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "a", BaseURL: down.URL, Model: "a", Priority: 1}))
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "b", BaseURL: up.URL, Model: "b", Priority: 2}))
+
+	c := NewClientWithProvider("http://unused.invalid", "", "configured-model", "openai")
+	c.SetProviderRouter(NewProviderRouter(pm, "priority", false))
+
+	_, err := c.Chat([]Message{{Role: "user", Content: "hi"}}, nil, nil, nil)
+	require.Error(t, err)
+	assert.EqualValues(t, 0, upHits)
+}
+
+func TestProviderRouter_CheapestFirst(t *testing.T) {
+	pm := providers.NewProviderManager()
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "pricey", Priority: 1, CostPerToken: 0.03}))
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "cheap", Priority: 5, CostPerToken: 0.001}))
+	require.NoError(t, pm.AddProvider(&providers.Provider{ID: "mid", Priority: 2, CostPerToken: 0.01}))
+
+	state := NewProviderRouter(pm, "cheapest", true).State()
+	assert.Equal(t, []string{"cheap", "pricey", "mid"}, state.Chain)
+}
@@ -48,6 +48,22 @@ func (c *Client) ChatWithStreaming(messages []Message, tools []ToolDefinition, t
 
 // ChatWithStreamingContext is like ChatWithStreaming but cancellable via ctx.
 func (c *Client) ChatWithStreamingContext(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, callback StreamingCallback) (*ChatResponse, error) {
+	if c.router != nil {
+		return c.streamRouted(ctx, messages, tools, temperature, topP, maxTokens, callback)
+	}
+	resp, err := c.withCache(ctx, c.model, messages, tools, temperature, topP, maxTokens, func() (*ChatResponse, error) {
+		return c.streamDirect(ctx, messages, tools, temperature, topP, maxTokens, callback)
+	})
+	// A cached response is replayed as a single chunk.
+	if err == nil && resp.Cached && callback != nil {
+		callback(c.GetContent(resp), c.GetThinking(resp), false)
+		callback("", "", true)
+	}
+	return resp, err
+}
+
+// streamDirect streams a request from the client's own endpoint.
+func (c *Client) streamDirect(ctx context.Context, messages []Message, tools []ToolDefinition, temperature *float64, topP *float64, maxTokens *int, callback StreamingCallback) (*ChatResponse, error) {
 
 	// Apply rate limiting
 	rateLimiter := utils.GetRateLimiter(c.providerName)
@@ -55,17 +71,28 @@ func (c *Client) ChatWithStreamingContext(ctx context.Context, messages []Messag
 		return nil, fmt.Errorf("rate limit wait cancelled: %w", err)
 	}
 
-	jsonData, err := buildRequestBody(c.model, messages, tools, temperature, topP, maxTokens, true, c.reasoningEffort)
+	// Models without native function calling get the tools in the prompt;
+	// the stream is filtered so tool-call blocks are not shown.
+	var textTools []ToolDefinition
+	var filter *textToolStream
+	if c.textTools && len(tools) > 0 {
+		textTools, tools = tools, nil
+		messages = textToolMessages(messages, textTools)
+		filter = newTextToolStream(callback)
+		callback = filter.onChunk
+	}
+
+	jsonData, err := c.buildRequest(c.model, messages, tools, temperature, topP, maxTokens, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL(c.baseURL, c.providerName), bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.requestURL(c.model, true), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	setProviderHeaders(req, c.providerName, c.apiKey)
+	c.setHeaders(req)
 	req.Header.Set("Accept", "text/event-stream")
 
 	resp, err := c.httpClient.Do(req)
@@ -91,7 +118,13 @@ func (c *Client) ChatWithStreamingContext(ctx context.Context, messages []Messag
 		return nil, apiErr
 	}
 
-	return parseSSEStream(resp.Body, callback)
+	chatResp, err := c.parseStream(resp.Body, callback)
+	if err != nil || filter == nil {
+		return chatResp, err
+	}
+	applyTextToolCalls(chatResp, textTools)
+	filter.finish(chatResp)
+	return chatResp, nil
 }
 
 // SimpleQueryStreaming sends a simple query with streaming.
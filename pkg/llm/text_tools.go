@@ -0,0 +1,303 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Text-based tool calling lets models without native function calling drive
+// tools. The tool schemas are described in the system prompt, the model
+// replies with <tool_call> blocks, and those blocks are parsed back into
+// ToolCalls. Tool results are returned as <tool_result> user messages.
+//
+// Besides the instructed format, the parser accepts the other shapes models
+// commonly produce: <invoke name="..."><parameter .../></invoke> XML and a
+// {"tool_calls": [...]} JSON object.
+
+// TextToolPrompt describes the available tools and the reply format the
+// model must use to call them.
+func TextToolPrompt(tools []ToolDefinition) string {
+	schemas, err := json.Marshal(tools)
+	if err != nil {
+		schemas = []byte("[]")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Tool Calling\n")
+	sb.WriteString("You can call tools. To call one, write a block exactly like this:\n")
+	sb.WriteString("<tool_call>\n{\"name\": \"tool_name\", \"arguments\": {\"arg\": \"value\"}}\n</tool_call>\n")
+	sb.WriteString("You may write several blocks in one reply. After the last block, stop and wait: ")
+	sb.WriteString("results arrive in the next message inside <tool_result> tags. ")
+	sb.WriteString("Only call the tools listed below, with arguments matching their schema. ")
+	sb.WriteString("When no tool is needed, answer normally without any block.\n\n")
+	sb.WriteString("Available tools (OpenAI function format):\n")
+	sb.Write(schemas)
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// textToolMessages rewrites a conversation for a model that cannot see
+// native tool calls: the tool prompt is added to the system message,
+// assistant tool calls become <tool_call> blocks and tool results become
+// <tool_result> user messages.
+func textToolMessages(messages []Message, tools []ToolDefinition) []Message {
+	prompt := TextToolPrompt(tools)
+	result := make([]Message, 0, len(messages)+1)
+	callNames := make(map[string]string) // tool call ID -> function name
+	injected := false
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "system" && !injected:
+			result = append(result, Message{Role: "system", Content: msg.Content + "\n\n" + prompt})
+			injected = true
+
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+			}
+			result = append(result, Message{
+				Role:    "assistant",
+				Content: strings.TrimSpace(msg.Content + "\n" + FormatTextToolCalls(msg.ToolCalls)),
+			})
+
+		case msg.Role == "tool":
+			block := fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", callNames[msg.ToolCallID], msg.Content)
+			// Consecutive results go into one user turn so roles keep alternating.
+			if n := len(result); n > 0 && result[n-1].Role == "user" && strings.HasPrefix(result[n-1].Content, "<tool_result") {
+				result[n-1].Content += "\n" + block
+			} else {
+				result = append(result, Message{Role: "user", Content: block})
+			}
+
+		default:
+			result = append(result, Message{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
+	if !injected {
+		result = append([]Message{{Role: "system", Content: prompt}}, result...)
+	}
+	return result
+}
+
+// FormatTextToolCalls renders tool calls as <tool_call> blocks.
+func FormatTextToolCalls(calls []ToolCall) string {
+	blocks := make([]string, 0, len(calls))
+	for _, tc := range calls {
+		args := json.RawMessage(tc.Function.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage("{}")
+		}
+		body, err := json.Marshal(textToolCall{Name: tc.Function.Name, Arguments: args})
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, "<tool_call>\n"+string(body)+"\n</tool_call>")
+	}
+	return strings.Join(blocks, "\n")
+}
+
+type textToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+var (
+	toolCallBlockRe = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*</tool_call>`)
+	invokeBlockRe   = regexp.MustCompile(`(?s)<invoke\s+name="([^"]+)"\s*>(.*?)</invoke>`)
+	invokeParamRe   = regexp.MustCompile(`(?s)<parameter\s+name="([^"]+)"\s*>(.*?)</parameter>`)
+	functionCallsRe = regexp.MustCompile(`(?s)</?function_calls>`)
+	codeFenceRe     = regexp.MustCompile("```(?:json|xml)?")
+)
+
+// ParseTextToolCalls extracts tool calls written as text. Calls naming tools
+// outside tools are ignored (when tools is non-empty) so that examples in
+// prose are not executed. It returns the calls and the remaining text.
+func ParseTextToolCalls(content string, tools []ToolDefinition) ([]ToolCall, string) {
+	known := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		known[t.Function.Name] = true
+	}
+	accept := func(name string) bool { return name != "" && (len(known) == 0 || known[name]) }
+
+	var parsed []textToolCall
+	rest := content
+
+	// <tool_call>{"name": ..., "arguments": ...}</tool_call>
+	rest = toolCallBlockRe.ReplaceAllStringFunc(rest, func(block string) string {
+		body := toolCallBlockRe.FindStringSubmatch(block)[1]
+		body = strings.TrimSpace(codeFenceRe.ReplaceAllString(body, ""))
+		var call textToolCall
+		if err := json.Unmarshal([]byte(body), &call); err != nil || !accept(call.Name) {
+			return block
+		}
+		parsed = append(parsed, call)
+		return ""
+	})
+
+	// <invoke name="..."><parameter name="...">value</parameter></invoke>
+	rest = invokeBlockRe.ReplaceAllStringFunc(rest, func(block string) string {
+		m := invokeBlockRe.FindStringSubmatch(block)
+		if !accept(m[1]) {
+			return block
+		}
+		args := make(map[string]interface{})
+		for _, p := range invokeParamRe.FindAllStringSubmatch(m[2], -1) {
+			value := strings.TrimSpace(p[2])
+			var decoded interface{}
+			if json.Unmarshal([]byte(value), &decoded) == nil {
+				args[p[1]] = decoded
+			} else {
+				args[p[1]] = value
+			}
+		}
+		raw, _ := json.Marshal(args)
+		parsed = append(parsed, textToolCall{Name: m[1], Arguments: raw})
+		return ""
+	})
+
+	// {"tool_calls": [{"name": ..., "arguments": ...}]}
+	if len(parsed) == 0 {
+		if calls, remaining, ok := parseJSONToolCallEnvelope(rest); ok {
+			for _, c := range calls {
+				if accept(c.Name) {
+					parsed = append(parsed, c)
+				}
+			}
+			if len(parsed) > 0 {
+				rest = remaining
+			}
+		}
+	}
+
+	if len(parsed) == 0 {
+		return nil, content
+	}
+
+	calls := make([]ToolCall, 0, len(parsed))
+	for i, c := range parsed {
+		args := string(c.Arguments)
+		if args == "" || args == "null" {
+			args = "{}"
+		}
+		calls = append(calls, ToolCall{
+			ID:       fmt.Sprintf("call_%d_%s", i, c.Name),
+			Type:     "function",
+			Function: FunctionCall{Name: c.Name, Arguments: args},
+		})
+	}
+
+	rest = functionCallsRe.ReplaceAllString(rest, "")
+	rest = codeFenceRe.ReplaceAllString(rest, "")
+	return calls, strings.TrimSpace(rest)
+}
+
+// parseJSONToolCallEnvelope finds a {"tool_calls": [...]} object in content
+// and returns its calls plus the text around it.
+func parseJSONToolCallEnvelope(content string) ([]textToolCall, string, bool) {
+	start := strings.Index(content, "{")
+	if start < 0 || !strings.Contains(content[start:], `"tool_calls"`) {
+		return nil, content, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content[start:]))
+	var env struct {
+		ToolCalls []textToolCall `json:"tool_calls"`
+	}
+	if err := dec.Decode(&env); err != nil || len(env.ToolCalls) == 0 {
+		return nil, content, false
+	}
+	end := start + int(dec.InputOffset())
+	return env.ToolCalls, content[:start] + content[end:], true
+}
+
+// applyTextToolCalls moves text tool calls found in a response's content
+// into its ToolCalls.
+func applyTextToolCalls(resp *ChatResponse, tools []ToolDefinition) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return
+	}
+	choice := &resp.Choices[0]
+	if len(choice.Message.ToolCalls) > 0 {
+		return
+	}
+	calls, rest := ParseTextToolCalls(choice.Message.Content, tools)
+	if len(calls) == 0 {
+		return
+	}
+	choice.Message.ToolCalls = calls
+	choice.Message.Content = rest
+	choice.FinishReason = "tool_calls"
+}
+
+// textToolMarkers start the blocks ParseTextToolCalls recognises.
+var textToolMarkers = []string{"<tool_call", "<function_calls", "<invoke", "```", `{"tool_calls"`}
+
+// textToolStream wraps a streaming callback so that tool-call blocks are not
+// shown to the user. Text is forwarded until something that may start a
+// block appears; the remainder is held back and released by finish if it
+// turns out not to contain a tool call.
+type textToolStream struct {
+	callback StreamingCallback
+	buf      strings.Builder
+	emitted  int
+	holding  bool
+}
+
+func newTextToolStream(callback StreamingCallback) *textToolStream {
+	return &textToolStream{callback: callback}
+}
+
+func (s *textToolStream) onChunk(content, thinking string, done bool) {
+	if s.callback == nil {
+		return
+	}
+	if thinking != "" {
+		s.callback("", thinking, false)
+	}
+	if content == "" || s.holding {
+		s.buf.WriteString(content)
+		return
+	}
+	s.buf.WriteString(content)
+
+	text := s.buf.String()
+	safe := len(text)
+	for _, marker := range textToolMarkers {
+		if idx := strings.Index(text[s.emitted:], marker); idx >= 0 && s.emitted+idx < safe {
+			safe = s.emitted + idx
+			s.holding = true
+		}
+	}
+	if !s.holding {
+		// Keep back a trailing partial marker such as "<tool".
+		for _, marker := range textToolMarkers {
+			for n := len(marker) - 1; n > 0; n-- {
+				if strings.HasSuffix(text, marker[:n]) && len(text)-n < safe {
+					safe = len(text) - n
+					break
+				}
+			}
+		}
+	}
+	if safe > s.emitted {
+		s.callback(text[s.emitted:safe], "", false)
+		s.emitted = safe
+	}
+}
+
+// finish releases held-back text when the response had no tool calls and
+// signals completion.
+func (s *textToolStream) finish(resp *ChatResponse) {
+	if s.callback == nil {
+		return
+	}
+	hasCalls := resp != nil && len(resp.Choices) > 0 && len(resp.Choices[0].Message.ToolCalls) > 0
+	if text := s.buf.String(); !hasCalls && s.emitted < len(text) {
+		s.callback(text[s.emitted:], "", false)
+	}
+	s.callback("", "", true)
+}
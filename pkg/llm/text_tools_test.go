@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextToolCalls(t *testing.T) {
+	tools := []ToolDefinition{
+		{Type: "function", Function: FunctionSchema{Name: "read_file"}},
+		{Type: "function", Function: FunctionSchema{Name: "exec_command"}},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		wantName []string
+		wantArgs []string
+		wantRest string
+	}{
+		{
+			name:     "tool_call block",
+			content:  "Let me look.\n<tool_call>\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"go.mod\"}}\n</tool_call>",
+			wantName: []string{"read_file"},
+			wantArgs: []string{`{"path": "go.mod"}`},
+			wantRest: "Let me look.",
+		},
+		{
+			name:     "multiple blocks with fenced json",
+			content:  "<tool_call>```json\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"a\"}}\n```</tool_call><tool_call>{\"name\": \"exec_command\", \"arguments\": {\"command\": \"ls\"}}</tool_call>",
+			wantName: []string{"read_file", "exec_command"},
+			wantArgs: []string{`{"path": "a"}`, `{"command": "ls"}`},
+		},
+		{
+			name:     "invoke xml",
+			content:  "<function_calls><invoke name=\"exec_command\"><parameter name=\"command\">go test ./...</parameter><parameter name=\"timeout\">60</parameter></invoke></function_calls>",
+			wantName: []string{"exec_command"},
+			wantArgs: []string{`{"command": "go test ./...", "timeout": 60}`},
+		},
+		{
+			name:     "json envelope",
+			content:  "{\"tool_calls\": [{\"name\": \"read_file\", \"arguments\": {\"path\": \"b\"}}]}",
+			wantName: []string{"read_file"},
+			wantArgs: []string{`{"path": "b"}`},
+		},
+		{
+			name:     "unknown tool is ignored",
+			content:  "<tool_call>{\"name\": \"rm_rf\", \"arguments\": {}}</tool_call>",
+			wantRest: "<tool_call>{\"name\": \"rm_rf\", \"arguments\": {}}</tool_call>",
+		},
+		{
+			name:     "plain prose",
+			content:  `Set {"a": 1} in config.json.`,
+			wantRest: `Set {"a": 1} in config.json.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, rest := ParseTextToolCalls(tt.content, tools)
+			require.Len(t, calls, len(tt.wantName))
+			for i, c := range calls {
+				assert.Equal(t, tt.wantName[i], c.Function.Name)
+				assert.JSONEq(t, tt.wantArgs[i], c.Function.Arguments)
+				assert.NotEmpty(t, c.ID)
+			}
+			assert.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+func TestTextToolMessages_FeedsResultsBack(t *testing.T) {
+	tools := []ToolDefinition{{Type: "function", Function: FunctionSchema{Name: "read_file", Description: "Read a file"}}}
+	messages := []Message{
+		{Role: "system", Content: "base prompt"},
+		{Role: "user", Content: "read a and b"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "1", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"a"}`}},
+			{ID: "2", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"b"}`}},
+		}},
+		{Role: "tool", ToolCallID: "1", Content: "A"},
+		{Role: "tool", ToolCallID: "2", Content: "B"},
+	}
+
+	out := textToolMessages(messages, tools)
+	require.Len(t, out, 4)
+	assert.True(t, strings.HasPrefix(out[0].Content, "base prompt"))
+	assert.Contains(t, out[0].Content, `"name":"read_file"`)
+	assert.Equal(t, 2, strings.Count(out[2].Content, "<tool_call>"))
+	assert.Empty(t, out[2].ToolCalls)
+	assert.Equal(t, "user", out[3].Role)
+	assert.Contains(t, out[3].Content, "<tool_result name=\"read_file\">\nA\n</tool_result>")
+	assert.Contains(t, out[3].Content, "B")
+
+	// Round trip: rendered calls parse back to the same calls.
+	calls, _ := ParseTextToolCalls(out[2].Content, tools)
+	require.Len(t, calls, 2)
+	assert.JSONEq(t, `{"path":"b"}`, calls[1].Function.Arguments)
+}
+
+func TestClient_TextToolStreamingHidesBlocks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunks := []string{"Checking", " now.\n<tool", "_call>{\"name\": \"read_file\", ", "\"arguments\": {\"path\": \"x\"}}</tool_call>"}
+		for _, c := range chunks {
+			_, _ = w.Write([]byte(`data: {"choices":[{"delta":{"content":` + quoteJSON(c) + `}}]}` + "\n\n"))
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	c := NewClientWithProvider(server.URL, "sk-test", "plain-model", "openai")
+	c.SetTextToolCalls(true)
+
+	var shown strings.Builder
+	resp, err := c.ChatWithStreaming([]Message{{Role: "user", Content: "read x"}},
+		[]ToolDefinition{{Type: "function", Function: FunctionSchema{Name: "read_file"}}}, nil, nil, nil,
+		func(content, thinking string, done bool) { shown.WriteString(content) })
+	require.NoError(t, err)
+
+	assert.Equal(t, "Checking now.\n", shown.String())
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "read_file", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, "Checking now.", resp.Choices[0].Message.Content)
+}
+
+func quoteJSON(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
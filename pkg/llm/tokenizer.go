@@ -0,0 +1,328 @@
+package llm
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"ClosedWheeler/pkg/utils"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Tokenizer counts tokens the way a model family does. Counts are used for
+// context budgeting, so an approximation is acceptable where no BPE table
+// is available.
+type Tokenizer interface {
+	// Name identifies the encoding, e.g. "o200k_base" or "approx-claude".
+	Name() string
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// Per-message framing overhead. OpenAI documents 3 tokens per message plus
+// 3 priming the reply; other providers are close enough.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// CountMessageTokens returns the prompt tokens used by messages, including
+// tool calls and per-message framing.
+func CountMessageTokens(tok Tokenizer, messages []Message) int {
+	total := tokensPerReply
+	for _, msg := range messages {
+		total += CountMessage(tok, msg)
+	}
+	return total
+}
+
+// CountMessage returns the tokens used by a single message.
+func CountMessage(tok Tokenizer, msg Message) int {
+	n := tokensPerMessage + tok.Count(msg.Role) + tok.Count(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		n += tok.Count(tc.Function.Name) + tok.Count(tc.Function.Arguments)
+	}
+	return n
+}
+
+// CountToolTokens returns the tokens used by tool definitions. Providers
+// render schemas differently; the JSON form is a close upper bound.
+func CountToolTokens(tok Tokenizer, tools []ToolDefinition) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return tok.Count(string(data))
+}
+
+// TruncateToTokens shortens text to at most max tokens, cutting at a line
+// break when one is close to the limit.
+func TruncateToTokens(tok Tokenizer, text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if tok.Count(text) <= max {
+		return text
+	}
+
+	// Binary search for the longest rune prefix that fits.
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tok.Count(string(runes[:mid])) <= max {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	cut := string(runes[:lo])
+	if idx := strings.LastIndex(cut, "\n"); idx > len(cut)*3/4 {
+		cut = cut[:idx]
+	}
+	return cut
+}
+
+// --- Approximate tokenizer ---
+
+// approxTokenizer estimates counts by splitting text the way BPE
+// pre-tokenizers do: letter runs cost about one token per six characters
+// (common words are a single token), digit runs one per three, punctuation
+// and non-Latin characters one each. scale adjusts for vocabularies that
+// produce more tokens than cl100k.
+type approxTokenizer struct {
+	name  string
+	scale float64
+}
+
+func (t *approxTokenizer) Name() string { return t.name }
+
+func (t *approxTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	var tokens, letters, digits int
+	flush := func() {
+		tokens += (letters+5)/6 + (digits+2)/3
+		letters, digits = 0, 0
+	}
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			if digits > 0 {
+				flush()
+			}
+			letters++
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			if letters > 0 {
+				flush()
+			}
+			digits++
+		case unicode.IsSpace(r):
+			// Whitespace attaches to the following word.
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	if t.scale > 0 && t.scale != 1 {
+		return int(float64(tokens)*t.scale + 0.5)
+	}
+	return tokens
+}
+
+// --- BPE tokenizer ---
+
+// bpeTokenizer counts with tiktoken tables. Tables are loaded in the
+// background on first use; until they are ready (or if they cannot be
+// fetched, e.g. offline) counts come from the approximation.
+type bpeTokenizer struct {
+	encoding string
+	fallback Tokenizer
+
+	once  sync.Once
+	mu    sync.RWMutex
+	codec *tiktoken.Tiktoken
+}
+
+func (t *bpeTokenizer) Name() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.codec == nil {
+		return t.encoding + " (approx)"
+	}
+	return t.encoding
+}
+
+func (t *bpeTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	t.once.Do(func() { go t.load() })
+
+	t.mu.RLock()
+	codec := t.codec
+	t.mu.RUnlock()
+	if codec == nil {
+		return t.fallback.Count(text)
+	}
+	return len(codec.EncodeOrdinary(text))
+}
+
+func (t *bpeTokenizer) load() {
+	codec, err := tiktoken.GetEncoding(t.encoding)
+	if err != nil {
+		log.Printf("[WARN] Tokenizer %s unavailable, using approximation: %v", t.encoding, err)
+		return
+	}
+	t.mu.Lock()
+	t.codec = codec
+	t.mu.Unlock()
+}
+
+// --- Selection ---
+
+var (
+	tokenizersMu sync.Mutex
+	tokenizers   = map[string]Tokenizer{}
+)
+
+func init() {
+	tiktoken.SetBpeLoader(&bpeFileLoader{})
+}
+
+// TokenizerForModel returns the tokenizer for a model. OpenAI models use
+// their BPE encoding; other families use a scaled approximation.
+func TokenizerForModel(model string) Tokenizer {
+	key := tokenizerKey(model)
+
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	if tok, ok := tokenizers[key]; ok {
+		return tok
+	}
+
+	var tok Tokenizer
+	switch key {
+	case tiktoken.MODEL_O200K_BASE, tiktoken.MODEL_CL100K_BASE:
+		tok = &bpeTokenizer{encoding: key, fallback: &approxTokenizer{name: "approx", scale: 1}}
+	case "approx-claude":
+		// Claude's vocabulary yields roughly 15% more tokens than cl100k.
+		tok = &approxTokenizer{name: key, scale: 1.15}
+	default:
+		tok = &approxTokenizer{name: key, scale: 1}
+	}
+	tokenizers[key] = tok
+	return tok
+}
+
+// tokenizerKey maps a model name to an encoding or approximation name.
+// Router prefixes such as "openai/" are ignored.
+func tokenizerKey(model string) string {
+	m := strings.ToLower(model)
+	if idx := strings.LastIndex(m, "/"); idx >= 0 {
+		m = m[idx+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(m, "gpt-4o"), strings.HasPrefix(m, "gpt-4.1"), strings.HasPrefix(m, "gpt-4.5"),
+		strings.HasPrefix(m, "gpt-5"), strings.HasPrefix(m, "chatgpt-"),
+		strings.HasPrefix(m, "o1"), strings.HasPrefix(m, "o3"), strings.HasPrefix(m, "o4"):
+		return tiktoken.MODEL_O200K_BASE
+	case strings.HasPrefix(m, "gpt-4"), strings.HasPrefix(m, "gpt-3.5"), strings.HasPrefix(m, "text-embedding-"):
+		return tiktoken.MODEL_CL100K_BASE
+	case strings.HasPrefix(m, "claude"):
+		return "approx-claude"
+	case strings.HasPrefix(m, "gemini"):
+		return "approx-gemini"
+	default:
+		return "approx"
+	}
+}
+
+// bpeFileLoader fetches tiktoken tables with a timeout and caches them under
+// ~/.agi/tiktoken (or $TIKTOKEN_CACHE_DIR) so later runs work offline.
+type bpeFileLoader struct{}
+
+var bpeHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func (l *bpeFileLoader) LoadTiktokenBpe(url string) (map[string]int, error) {
+	contents, err := l.read(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed BPE line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		rank, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(token)] = rank
+	}
+	return ranks, nil
+}
+
+func (l *bpeFileLoader) read(url string) ([]byte, error) {
+	cacheDir := os.Getenv("TIKTOKEN_CACHE_DIR")
+	if cacheDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDir = filepath.Join(home, ".agi", "tiktoken")
+	}
+	cachePath := filepath.Join(cacheDir, fmt.Sprintf("%x", sha1.Sum([]byte(url))))
+	if data, err := os.ReadFile(cachePath); err == nil {
+		return data, nil
+	}
+
+	resp, err := bpeHTTPClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download BPE table: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download BPE table: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download BPE table: %w", err)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err == nil {
+		_ = utils.WriteFileAtomic(cachePath, data, 0644)
+	}
+	return data, nil
+}
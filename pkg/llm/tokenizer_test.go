@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizerKey(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":                 "o200k_base",
+		"openai/gpt-4.1":              "o200k_base",
+		"o3-mini":                     "o200k_base",
+		"gpt-4-turbo":                 "cl100k_base",
+		"gpt-3.5-turbo":               "cl100k_base",
+		"claude-sonnet-4-20250514":    "approx-claude",
+		"anthropic/claude-3.5-sonnet": "approx-claude",
+		"gemini-2.5-flash":            "approx-gemini",
+		"qwen2.5-coder:7b":            "approx",
+	}
+	for model, want := range tests {
+		assert.Equal(t, want, tokenizerKey(model), model)
+	}
+}
+
+func TestApproxTokenizer_Count(t *testing.T) {
+	tok := &approxTokenizer{name: "approx", scale: 1}
+	assert.Equal(t, 0, tok.Count(""))
+	assert.Equal(t, 2, tok.Count("hello world"))
+	// func, main, (, ), {, }
+	assert.Equal(t, 6, tok.Count("func main() {}"))
+	assert.Equal(t, 4, tok.Count("internationalization")) // 20 letters
+	assert.Equal(t, 3, tok.Count("12345678"))             // digits in groups of three
+
+	scaled := &approxTokenizer{name: "approx-claude", scale: 1.15}
+	text := strings.Repeat("token ", 100)
+	assert.Equal(t, 115, scaled.Count(text)) // 100 words
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	tok := &approxTokenizer{name: "approx", scale: 1}
+	text := strings.Repeat("line of text\n", 50)
+
+	cut := TruncateToTokens(tok, text, 30)
+	assert.LessOrEqual(t, tok.Count(cut), 30)
+	assert.True(t, strings.HasPrefix(text, cut))
+	assert.True(t, strings.HasSuffix(cut, "text"), "should cut at a line break")
+
+	assert.Equal(t, "short", TruncateToTokens(tok, "short", 30))
+	assert.Empty(t, TruncateToTokens(tok, text, 0))
+}
+
+func TestCountMessageTokens(t *testing.T) {
+	tok := &approxTokenizer{name: "approx", scale: 1}
+	msgs := []Message{
+		{Role: "user", Content: "hello world"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Function: FunctionCall{Name: "read", Arguments: `{"p":"a"}`}}}},
+	}
+	// framing 3 + (3 + user 1 + 2) + (3 + assistant 2 + read 1 + args 9)
+	assert.Equal(t, 3+6+15, CountMessageTokens(tok, msgs))
+}
+
+func TestBPETokenizer_LoadsCachedTable(t *testing.T) {
+	// A byte-level table with one merge is enough to exercise the loader and
+	// the BPE path without network access.
+	dir := t.TempDir()
+	t.Setenv("TIKTOKEN_CACHE_DIR", dir)
+
+	var table strings.Builder
+	for b := 0; b < 256; b++ {
+		fmt.Fprintf(&table, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	fmt.Fprintf(&table, "%s %d\n", base64.StdEncoding.EncodeToString([]byte("ab")), 256)
+	url := "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken"
+	name := fmt.Sprintf("%x", sha1.Sum([]byte(url)))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(table.String()), 0644))
+
+	tok := &bpeTokenizer{encoding: "cl100k_base", fallback: &approxTokenizer{name: "approx", scale: 1}}
+	assert.Equal(t, "cl100k_base (approx)", tok.Name())
+
+	tok.once.Do(func() {}) // load synchronously instead of in the background
+	tok.load()
+	assert.Equal(t, "cl100k_base", tok.Name())
+	// "ab" merges into one token; " c" is two single-byte tokens.
+	assert.Equal(t, 3, tok.Count("ab c"))
+}
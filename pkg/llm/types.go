@@ -59,6 +59,7 @@ type ChatResponse struct {
 	Choices    []Choice   `json:"choices"`
 	Usage      Usage      `json:"usage"`
 	RateLimits RateLimits `json:"rate_limits"`
+	Cached     bool       `json:"-"` // served from the response cache
 }
 
 // Choice represents a response choice.
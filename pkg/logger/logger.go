@@ -1,7 +1,7 @@
 package logger
 
 import (
-	"ClosedWheeler/pkg/utils"
+	"ClosedWheeler/pkg/secrets"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -136,5 +136,5 @@ func joinLines(lines []string) string {
 }
 
 func sanitize(s string) string {
-	return utils.SanitizeLog(s)
+	return secrets.Redact(s)
 }
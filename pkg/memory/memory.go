@@ -11,6 +11,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"ClosedWheeler/pkg/secrets"
 )
 
 // MemoryTier represents the type of memory storage
@@ -99,7 +101,7 @@ func (m *Manager) AddMessage(role, content string) *MemoryItem {
 		ID:         generateID(),
 		Tier:       ShortTerm,
 		Type:       "message",
-		Content:    content,
+		Content:    secrets.Redact(content),
 		Metadata:   Metadata{Role: role},
 		Relevance:  1.0,
 		CreatedAt:  time.Now(),
@@ -125,7 +127,7 @@ func (m *Manager) AddFile(filePath, content string, relevance float64) *MemoryIt
 		ID:         generateID(),
 		Tier:       WorkingMem,
 		Type:       "file",
-		Content:    content,
+		Content:    secrets.RedactKnown(content),
 		Metadata:   Metadata{FilePath: filePath},
 		Relevance:  relevance,
 		CreatedAt:  time.Now(),
@@ -153,7 +155,7 @@ func (m *Manager) AddFunction(filePath, funcName, content string, lineStart, lin
 		ID:      generateID(),
 		Tier:    WorkingMem,
 		Type:    "function",
-		Content: content,
+		Content: secrets.RedactKnown(content),
 		Metadata: Metadata{
 			FilePath:     filePath,
 			FunctionName: funcName,
@@ -178,7 +180,7 @@ func (m *Manager) AddDecision(decision string, tags []string) *MemoryItem {
 		ID:         generateID(),
 		Tier:       LongTerm,
 		Type:       "decision",
-		Content:    decision,
+		Content:    secrets.Redact(decision),
 		Metadata:   Metadata{Tags: tags},
 		Relevance:  1.0,
 		CreatedAt:  time.Now(),
@@ -198,7 +200,7 @@ func (m *Manager) AddSummary(summary string) *MemoryItem {
 		ID:         generateID(),
 		Tier:       LongTerm,
 		Type:       "summary",
-		Content:    summary,
+		Content:    secrets.Redact(summary),
 		Relevance:  0.9,
 		CreatedAt:  time.Now(),
 		AccessedAt: time.Now(),
@@ -382,7 +384,7 @@ func (m *Manager) CompressItems(summary string) {
 		ID:         generateID(),
 		Tier:       LongTerm,
 		Type:       "summary",
-		Content:    summary,
+		Content:    secrets.Redact(summary),
 		Relevance:  0.9,
 		CreatedAt:  time.Now(),
 		AccessedAt: time.Now(),
@@ -419,7 +421,35 @@ func (m *Manager) GetContext() string {
 		sb.WriteString("\n")
 	}
 
-	// 2. Working Memory (Active Code Context)
+	sb.WriteString(m.workingContext())
+	return sb.String()
+}
+
+// LongTermItems returns copies of the long-term memories, oldest first.
+func (m *Manager) LongTermItems() []MemoryItem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items := make([]MemoryItem, len(m.longTerm))
+	for i, item := range m.longTerm {
+		items[i] = *item
+	}
+	return items
+}
+
+// WorkingContext describes the files and functions in working memory, the
+// part of GetContext that does not depend on long-term memory.
+func (m *Manager) WorkingContext() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.workingContext()
+}
+
+// workingContext implements WorkingContext. Callers hold m.mu.
+func (m *Manager) workingContext() string {
+	var sb strings.Builder
+
+	// Working Memory (Active Code Context)
 	if len(m.working) > 0 {
 		sb.WriteString("### 🛠️ Working Context (Files Analyzed)\n")
 		// Only show items with relevance > 0.5
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"ClosedWheeler/pkg/llm"
+)
+
+// embedBatchSize bounds how many texts are sent in one embeddings call.
+const embedBatchSize = 64
+
+// Document is a text the vector store can retrieve.
+type Document struct {
+	ID   string // stable across restarts, e.g. "memory:<item id>"
+	Kind string // e.g. "decision", "summary", "brain:error"
+	Text string
+}
+
+// Hit is a document found by VectorStore.Search.
+type Hit struct {
+	Document
+	Score float64 // cosine similarity to the query
+}
+
+// vectorEntry is a stored document and its embedding.
+type vectorEntry struct {
+	Kind   string    `json:"kind"`
+	Text   string    `json:"text"`
+	Hash   string    `json:"hash"`
+	Vector []float32 `json:"vector"`
+}
+
+// vectorFile is the on-disk form of a VectorStore.
+type vectorFile struct {
+	Model   string                  `json:"model"`
+	Entries map[string]*vectorEntry `json:"entries"`
+}
+
+// VectorStore keeps embeddings of documents on disk so each text is
+// embedded once, and finds the documents most similar to a query.
+type VectorStore struct {
+	path     string
+	embedder llm.Embedder
+
+	mu      sync.Mutex
+	loaded  bool
+	model   string
+	entries map[string]*vectorEntry
+}
+
+// NewVectorStore opens a store persisted at path (e.g. .agi/vectors.json).
+func NewVectorStore(path string, embedder llm.Embedder) *VectorStore {
+	return &VectorStore{
+		path:     path,
+		embedder: embedder,
+		entries:  make(map[string]*vectorEntry),
+	}
+}
+
+// Len returns the number of stored documents.
+func (s *VectorStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Sync makes the store hold exactly docs: new and changed documents are
+// embedded, others dropped. Everything is re-embedded when the embedder's
+// model changed since the store was saved.
+func (s *VectorStore) Sync(ctx context.Context, docs []Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		s.load()
+		s.loaded = true
+	}
+
+	model := s.embedder.Model()
+	if s.model != model {
+		s.entries = make(map[string]*vectorEntry)
+		s.model = model
+	}
+
+	changed := false
+	wanted := make(map[string]bool, len(docs))
+	var pending []Document
+	for _, d := range docs {
+		wanted[d.ID] = true
+		if e, ok := s.entries[d.ID]; ok && e.Hash == textHash(d.Text) {
+			continue
+		}
+		pending = append(pending, d)
+	}
+	for id := range s.entries {
+		if !wanted[id] {
+			delete(s.entries, id)
+			changed = true
+		}
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		batch := pending[start:min(start+embedBatchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, d := range batch {
+			texts[i] = d.Text
+		}
+		vectors, err := s.embedder.Embed(ctx, texts)
+		if err != nil {
+			if changed || start > 0 {
+				s.save()
+			}
+			return err
+		}
+		for i, d := range batch {
+			s.entries[d.ID] = &vectorEntry{Kind: d.Kind, Text: d.Text, Hash: textHash(d.Text), Vector: vectors[i]}
+		}
+		changed = true
+	}
+
+	if changed {
+		return s.save()
+	}
+	return nil
+}
+
+// Search returns up to k stored documents most similar to query, best
+// first. Documents unrelated to the query (score <= 0) are left out.
+func (s *VectorStore) Search(ctx context.Context, query string, k int) ([]Hit, error) {
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected one query embedding, got %d", len(vectors))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hits []Hit
+	for id, e := range s.entries {
+		score := llm.CosineSimilarity(vectors[0], e.Vector)
+		if score <= 0 {
+			continue
+		}
+		hits = append(hits, Hit{Document: Document{ID: id, Kind: e.Kind, Text: e.Text}, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+// load reads the saved store; a missing or unreadable file leaves it empty.
+// Callers hold s.mu.
+func (s *VectorStore) load() {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var f vectorFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Entries == nil {
+		return
+	}
+	s.model = f.Model
+	s.entries = f.Entries
+}
+
+// save writes the store to disk. Callers hold s.mu.
+func (s *VectorStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(vectorFile{Model: s.model, Entries: s.entries})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
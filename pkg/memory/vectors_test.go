@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"ClosedWheeler/pkg/llm"
+)
+
+// countingEmbedder counts the texts it embeds.
+type countingEmbedder struct {
+	*llm.HashEmbedder
+	texts int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts += len(texts)
+	return e.HashEmbedder.Embed(ctx, texts)
+}
+
+func TestVectorStoreSyncAndSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	embedder := &countingEmbedder{HashEmbedder: llm.NewHashEmbedder(0)}
+	store := NewVectorStore(path, embedder)
+	ctx := context.Background()
+
+	docs := []Document{
+		{ID: "1", Kind: "decision", Text: "Use PostgreSQL for the orders database"},
+		{ID: "2", Kind: "summary", Text: "Fixed flaky login test by mocking the clock"},
+		{ID: "3", Kind: "brain:pattern", Text: "Handlers return errors wrapped with the request id"},
+	}
+	if err := store.Sync(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	if embedder.texts != 3 {
+		t.Errorf("embedded %d texts, want 3", embedder.texts)
+	}
+
+	hits, err := store.Search(ctx, "which database stores orders?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) == 0 || hits[0].ID != "1" {
+		t.Fatalf("hits = %+v, want document 1 first", hits)
+	}
+
+	// Unchanged documents are not embedded again, even after a reload
+	reopened := NewVectorStore(path, embedder)
+	docs[1].Text = "Fixed flaky login test by freezing time"
+	if err := reopened.Sync(ctx, docs[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if embedder.texts != 5 { // 3 documents, the query, and the changed one
+		t.Errorf("embedded %d texts after changing one, want 5", embedder.texts)
+	}
+	if reopened.Len() != 2 {
+		t.Errorf("store holds %d documents, want 2", reopened.Len())
+	}
+}
+
+func TestVectorStoreReembedsOnModelChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	ctx := context.Background()
+	docs := []Document{{ID: "1", Text: "alpha beta"}}
+
+	if err := NewVectorStore(path, llm.NewHashEmbedder(64)).Sync(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	embedder := &countingEmbedder{HashEmbedder: llm.NewHashEmbedder(128)}
+	store := NewVectorStore(path, embedder)
+	if err := store.Sync(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	if embedder.texts != 1 {
+		t.Errorf("embedded %d texts, want 1 after a model change", embedder.texts)
+	}
+	hits, err := store.Search(ctx, "alpha", 1)
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("hits = %+v, err = %v", hits, err)
+	}
+}
@@ -0,0 +1,194 @@
+package permissions
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/security"
+)
+
+// Approval is the user's answer to a tool call the policy asks about.
+type Approval string
+
+// Approval answers. The session and pattern answers remember the rule
+// SuggestRule returns for the call.
+const (
+	ApproveOnce    Approval = "once"    // run this call only
+	ApproveSession Approval = "session" // allow calls like it until the agent exits
+	ApprovePattern Approval = "pattern" // allow calls like it from now on, via the policy file
+	Reject         Approval = "deny"
+)
+
+// subcommandPattern matches a second command word that names a subcommand,
+// as in "go test" or "git push", rather than a flag or a file.
+var subcommandPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// SuggestRule returns the allow rule that approving calls like this one
+// would add: the tool with the directory of its paths, the leading words of
+// its commands, its SSH host or its URL domain. It returns false when no
+// single rule covers the call, such as a command line running different
+// programs.
+func (pm *Manager) SuggestRule(tool string, args map[string]any) (Rule, bool) {
+	pm.policyMu.Lock()
+	targets := callTargets(pm.root, tool, args)
+	pm.policyMu.Unlock()
+
+	var matchers []Matcher
+	if paths := targets[MatchPath]; len(paths) > 0 {
+		pattern, ok := pathPattern(paths)
+		if !ok {
+			return Rule{}, false
+		}
+		matchers = append(matchers, Matcher{Kind: MatchPath, Pattern: pattern})
+	}
+	if commands := targets[MatchCmd]; len(commands) > 0 {
+		prefix := ""
+		for i, command := range commands {
+			if substitutes(command) {
+				return Rule{}, false // allow rules never cover these
+			}
+			if security.IsInterpreter(strings.Fields(command)[0]) {
+				return Rule{}, false // allowing the program would allow any code
+			}
+			p := commandPrefix(command)
+			if i > 0 && p != prefix {
+				return Rule{}, false
+			}
+			prefix = p
+		}
+		matchers = append(matchers, Matcher{Kind: MatchCmd, Pattern: prefix})
+	}
+	for _, kind := range []string{MatchHost, MatchDomain} {
+		values := slices.Compact(slices.Sorted(slices.Values(targets[kind])))
+		switch len(values) {
+		case 0:
+		case 1:
+			matchers = append(matchers, Matcher{Kind: kind, Pattern: values[0]})
+		default:
+			return Rule{}, false
+		}
+	}
+
+	fields := []string{string(Allow), tool}
+	for _, m := range matchers {
+		if strings.Contains(m.Pattern, `"`) {
+			return Rule{}, false // a policy line cannot hold it
+		}
+		if strings.ContainsAny(m.Pattern, " \t") {
+			fields = append(fields, m.Kind+`:"`+m.Pattern+`"`)
+		} else {
+			fields = append(fields, m.Kind+":"+m.Pattern)
+		}
+	}
+	rule, err := ParseRule(strings.Join(fields, " "))
+	if err != nil {
+		return Rule{}, false
+	}
+	return rule, true
+}
+
+// pathPattern returns a pattern covering the paths: their common directory
+// and everything below it, or the file itself for a single file at the
+// root or outside the workplace.
+func pathPattern(paths []string) (string, bool) {
+	common := strings.Split(path.Dir(paths[0]), "/")
+	for _, p := range paths[1:] {
+		dir := strings.Split(path.Dir(p), "/")
+		n := 0
+		for n < len(common) && n < len(dir) && common[n] == dir[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	dir := strings.Join(common, "/")
+	switch dir {
+	case "", ".", "/":
+		// Not the whole workplace or file system; and a pattern without "/"
+		// would match the name at any depth, so root files are anchored
+		for _, p := range paths[1:] {
+			if p != paths[0] {
+				return "", false
+			}
+		}
+		if dir == "/" {
+			return paths[0], true
+		}
+		return "./" + paths[0], true
+	}
+	return dir + "/**", true
+}
+
+// commandPrefix returns the program of a command and, when the next word
+// names a subcommand, that word too.
+func commandPrefix(command string) string {
+	words := strings.Fields(command)
+	if len(words) > 1 && subcommandPattern.MatchString(words[1]) {
+		return words[0] + " " + words[1]
+	}
+	return words[0]
+}
+
+// Remember adds a rule the user chose while approving a call. Without
+// persist it lasts until the agent exits; with persist it is appended to
+// the policy file, so later sessions follow it too. Deny rules keep
+// winning over remembered rules.
+func (pm *Manager) Remember(rule Rule, persist bool) error {
+	pm.policyMu.Lock()
+	defer pm.policyMu.Unlock()
+
+	if !persist {
+		rule.Source = "session"
+		pm.sessionRules = append(pm.sessionRules, rule)
+		pm.writeAudit(AuditEntry{Action: "approval", Name: rule.Tool, Allowed: true, Reason: "remembered for the session: " + rule.Text})
+		return nil
+	}
+
+	if pm.policyPath == "" {
+		return fmt.Errorf("no policy file to remember %q in", rule.Text)
+	}
+	if err := os.MkdirAll(filepath.Dir(pm.policyPath), 0755); err != nil {
+		return fmt.Errorf("failed to create policy directory: %w", err)
+	}
+	data, err := os.ReadFile(pm.policyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+	line := rule.Text + "\n"
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		line = "\n" + line
+	}
+	f, err := os.OpenFile(pm.policyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open policy file: %w", err)
+	}
+	if _, err := f.WriteString(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+
+	// Reread on the next call even if the modification time did not move
+	pm.policyMod = time.Time{}
+	pm.writeAudit(AuditEntry{Action: "approval", Name: rule.Tool, Allowed: true, Reason: "added to " + filepath.Base(pm.policyPath) + ": " + rule.Text})
+	return nil
+}
+
+// KeepSessionRules carries the rules remembered for the session over from
+// the manager this one replaces.
+func (pm *Manager) KeepSessionRules(old *Manager) {
+	old.policyMu.Lock()
+	rules := slices.Clone(old.sessionRules)
+	old.policyMu.Unlock()
+
+	pm.policyMu.Lock()
+	pm.sessionRules = append(pm.sessionRules, rules...)
+	pm.policyMu.Unlock()
+}
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/security"
 )
 
 // Manager handles permission checks and audit logging
@@ -17,22 +21,40 @@ type Manager struct {
 	config    *config.PermissionsConfig
 	auditFile *os.File
 	mu        sync.Mutex
+
+	// Policy rules from the config, the project policy file and the
+	// approvals remembered for this session
+	policyMu     sync.Mutex
+	root         string // workplace the path rules are relative to
+	policyPath   string
+	policyMod    time.Time
+	rules        []Rule
+	fileRules    []Rule
+	builtinRule  []Rule
+	sessionRules []Rule
 }
 
 // AuditEntry represents a single audit log entry
 type AuditEntry struct {
 	Timestamp string `json:"timestamp"`
-	Action    string `json:"action"` // "command", "tool", "approval"
+	Action    string `json:"action"` // "command", "tool", "approval", "policy"
 	Name      string `json:"name"`
 	Allowed   bool   `json:"allowed"`
+	Effect    string `json:"effect,omitempty"` // policy decisions: "allow", "ask", "deny"
+	Target    string `json:"target,omitempty"` // the argument a policy rule matched
 	Reason    string `json:"reason,omitempty"`
 	UserID    int64  `json:"user_id,omitempty"`
 }
 
 // NewManager creates a new permissions manager
 func NewManager(cfg *config.PermissionsConfig) (*Manager, error) {
+	rules, err := ParseRules(strings.Join(cfg.Rules, "\n"), "config")
+	if err != nil {
+		return nil, fmt.Errorf("invalid permission rule: %w", err)
+	}
 	pm := &Manager{
 		config: cfg,
+		rules:  rules,
 	}
 
 	// Open audit log file if enabled
@@ -93,6 +115,155 @@ func (pm *Manager) RequiresApproval(tool string) bool {
 	return !pm.config.AutoApproveNonSensitive
 }
 
+// LoadPolicy reads the project policy file, one rule per line, and sets
+// the workplace that path rules are relative to. A missing file has no
+// rules. The file is read again whenever it changes. Tools may not touch
+// a policy file inside the workplace.
+func (pm *Manager) LoadPolicy(root, policyPath string) error {
+	pm.policyMu.Lock()
+	defer pm.policyMu.Unlock()
+
+	pm.root, pm.policyPath = root, policyPath
+	pm.policyMod, pm.fileRules, pm.builtinRule = time.Time{}, nil, nil
+	if rel, err := filepath.Rel(root, policyPath); err == nil && !strings.HasPrefix(rel, "..") {
+		rule, err := ParseRule(fmt.Sprintf("deny * path:%q", filepath.ToSlash(rel)))
+		if err != nil {
+			return err
+		}
+		rule.Source = "builtin"
+		pm.builtinRule = []Rule{rule}
+	}
+	return pm.reloadPolicy()
+}
+
+// reloadPolicy rereads the policy file when it changed. Callers must hold
+// pm.policyMu.
+func (pm *Manager) reloadPolicy() error {
+	if pm.policyPath == "" {
+		return nil
+	}
+	info, err := os.Stat(pm.policyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			pm.fileRules, pm.policyMod = nil, time.Time{}
+			return nil
+		}
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+	if info.ModTime().Equal(pm.policyMod) {
+		return nil
+	}
+	data, err := os.ReadFile(pm.policyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read policy file: %w", err)
+	}
+	rules, err := ParseRules(string(data), filepath.Base(pm.policyPath))
+	if err != nil {
+		return err
+	}
+	pm.fileRules, pm.policyMod = rules, info.ModTime()
+	return nil
+}
+
+// Evaluate decides whether a tool call may run, may run after approval, or
+// is denied, and writes the decision to the audit log. Policy rules are
+// consulted first; calls no rule matches are decided by AllowedTools,
+// AllowedCommands (for the commands exec_command runs) and RequiresApproval.
+// The shell audit of exec_command lines then has the last word: see
+// auditCommand.
+func (pm *Manager) Evaluate(tool string, args map[string]any) Decision {
+	pm.policyMu.Lock()
+	reloadErr := pm.reloadPolicy()
+	rules := slices.Concat(pm.builtinRule, pm.rules, pm.fileRules, pm.sessionRules)
+	root, policyPath := pm.root, pm.policyPath
+	targets := callTargets(root, tool, args)
+	pm.policyMu.Unlock()
+
+	d, ok := evaluate(rules, tool, targets)
+	if !ok {
+		d = pm.fallback(tool, targets)
+	}
+	if tool == "exec_command" && d.Effect != Deny {
+		d = auditCommand(root, policyPath, commandLine(tool, args), d)
+	}
+	if reloadErr != nil && d.Effect == Allow {
+		// A broken policy file must not let through what it might deny
+		d = Decision{Effect: Ask, Reason: reloadErr.Error()}
+	}
+
+	pm.logDecision(tool, d)
+	return d
+}
+
+// fallback decides a call with the name-based settings.
+func (pm *Manager) fallback(tool string, targets map[string][]string) Decision {
+	cfg := pm.GetConfig()
+	if !pm.checkAllowed(cfg.AllowedTools, tool) {
+		return Decision{Effect: Deny, Reason: "tool not in allowed_tools"}
+	}
+	if tool == "exec_command" {
+		for _, command := range targets[MatchCmd] {
+			program, _, _ := strings.Cut(command, " ")
+			if !pm.checkAllowed(cfg.AllowedCommands, command) && !pm.checkAllowed(cfg.AllowedCommands, program) {
+				return Decision{Effect: Deny, Target: command, Reason: "command not in allowed_commands"}
+			}
+		}
+	}
+	if pm.RequiresApproval(tool) {
+		return Decision{Effect: Ask, Reason: "requires approval"}
+	}
+	return Decision{Effect: Allow, Reason: "auto-approved"}
+}
+
+// auditCommand applies the shell audit of a command line to a decision.
+// Commands that write to the policy file, or to its directory unless that
+// is the workplace itself, are denied, as are findings exec_command would
+// refuse, whatever the rules say. Medium
+// findings turn an auto-approval into a question; a rule that explicitly
+// allows the command still lets it run.
+func auditCommand(root, policyPath, command string, d Decision) Decision {
+	an := security.NewAuditor(root).AnalyzeCommand(command)
+	if policyPath != "" {
+		policy := filepath.ToSlash(filepath.Clean(policyPath))
+		dir := path.Dir(policy)
+		if dir == filepath.ToSlash(filepath.Clean(root)) {
+			dir = policy
+		}
+		for _, w := range an.Writes {
+			if w == policy || w == dir {
+				return Decision{Effect: Deny, Target: w, Reason: "commands may not change the policy file"}
+			}
+		}
+	}
+
+	var worst security.Finding
+	for _, f := range an.Findings {
+		if f.Severity > worst.Severity {
+			worst = f
+		}
+	}
+	reason := "shell audit: " + worst.String()
+	switch {
+	case worst.Severity >= security.BlockSeverity:
+		return Decision{Effect: Deny, Target: worst.Command, Reason: reason}
+	case worst.Severity == security.SeverityMedium && d.Effect == Allow && d.Rule == nil:
+		return Decision{Effect: Ask, Target: worst.Command, Reason: reason}
+	}
+	return d
+}
+
+// logDecision writes a policy decision to the audit log.
+func (pm *Manager) logDecision(tool string, d Decision) {
+	pm.writeAudit(AuditEntry{
+		Action:  "policy",
+		Name:    tool,
+		Allowed: d.Effect != Deny,
+		Effect:  string(d.Effect),
+		Target:  d.Target,
+		Reason:  d.Reason,
+	})
+}
+
 // LogApprovalDecision logs an approval decision to the audit log
 func (pm *Manager) LogApprovalDecision(tool string, approved bool, userID int64) {
 	reason := "approved by user"
@@ -131,6 +302,16 @@ func (pm *Manager) contains(slice []string, item string) bool {
 
 // logAudit writes an entry to the audit log
 func (pm *Manager) logAudit(action, name string, allowed bool, reason string) {
+	pm.writeAudit(AuditEntry{
+		Action:  action,
+		Name:    name,
+		Allowed: allowed,
+		Reason:  reason,
+	})
+}
+
+// writeAudit appends an entry to the audit log
+func (pm *Manager) writeAudit(entry AuditEntry) {
 	if !pm.config.EnableAuditLog || pm.auditFile == nil {
 		return
 	}
@@ -138,14 +319,7 @@ func (pm *Manager) logAudit(action, name string, allowed bool, reason string) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	entry := AuditEntry{
-		Timestamp: time.Now().Format(time.RFC3339),
-		Action:    action,
-		Name:      name,
-		Allowed:   allowed,
-		Reason:    reason,
-	}
-
+	entry.Timestamp = time.Now().Format(time.RFC3339)
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return // Silent failure for audit logging
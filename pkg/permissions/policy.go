@@ -0,0 +1,362 @@
+package permissions
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"ClosedWheeler/pkg/editor"
+	"ClosedWheeler/pkg/security"
+)
+
+// Effect is what a policy rule does with a matching tool call.
+type Effect string
+
+// Rule effects. When several rules match a call, deny wins over ask and ask
+// over allow.
+const (
+	Allow Effect = "allow"
+	Ask   Effect = "ask"
+	Deny  Effect = "deny"
+)
+
+// Matcher kinds: the tool arguments a rule can match on.
+const (
+	MatchPath   = "path"   // file paths relative to the workplace, as editor.MatchPath globs
+	MatchCmd    = "cmd"    // shell commands, by word prefix
+	MatchHost   = "host"   // SSH host labels, as globs
+	MatchDomain = "domain" // URL hosts; a domain also matches its subdomains
+)
+
+// Rule is one policy line: "<effect> <tool> [<kind>:<pattern>...]", e.g.
+//
+//	deny exec_command cmd:"git push"
+//	allow write_file path:src/**
+//	ask web_fetch domain:*
+//
+// The tool is a glob on the tool name. A rule matches a call when the tool
+// matches and every matcher matches the call's arguments of its kind: any of
+// them for deny and ask rules, all of them for allow rules.
+type Rule struct {
+	Effect   Effect
+	Tool     string
+	Matchers []Matcher
+	Source   string // where the rule was written, e.g. "config" or "policy.rules:3"
+	Text     string // the rule as written
+}
+
+// Matcher restricts a rule to calls with matching arguments.
+type Matcher struct {
+	Kind    string
+	Pattern string
+}
+
+// Decision is the outcome of evaluating a tool call.
+type Decision struct {
+	Effect Effect
+	Rule   *Rule  // the deciding rule; nil when decided by the name-based settings
+	Target string // the argument the rule matched, if any
+	Reason string
+}
+
+// ParseRule parses a single policy line.
+func ParseRule(line string) (Rule, error) {
+	fields, err := splitRule(line)
+	if err != nil {
+		return Rule{}, err
+	}
+	if len(fields) < 2 {
+		return Rule{}, fmt.Errorf("expected \"<allow|ask|deny> <tool> [kind:pattern...]\"")
+	}
+
+	r := Rule{Effect: Effect(strings.ToLower(fields[0])), Tool: fields[1], Text: strings.TrimSpace(line)}
+	switch r.Effect {
+	case Allow, Ask, Deny:
+	default:
+		return Rule{}, fmt.Errorf("unknown effect %q (want allow, ask or deny)", fields[0])
+	}
+	if _, err := path.Match(r.Tool, ""); err != nil {
+		return Rule{}, fmt.Errorf("invalid tool pattern %q: %w", r.Tool, err)
+	}
+	for _, field := range fields[2:] {
+		kind, pattern, ok := strings.Cut(field, ":")
+		if !ok || pattern == "" {
+			return Rule{}, fmt.Errorf("invalid matcher %q (want kind:pattern)", field)
+		}
+		switch kind {
+		case MatchPath, MatchCmd, MatchHost, MatchDomain:
+		default:
+			return Rule{}, fmt.Errorf("unknown matcher %q (want path, cmd, host or domain)", kind)
+		}
+		r.Matchers = append(r.Matchers, Matcher{Kind: kind, Pattern: pattern})
+	}
+	return r, nil
+}
+
+// ParseRules parses policy text, one rule per line. Blank lines and lines
+// starting with "#" are skipped.
+func ParseRules(text, source string) ([]Rule, error) {
+	var rules []Rule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, i+1, err)
+		}
+		r.Source = fmt.Sprintf("%s:%d", source, i+1)
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// splitRule splits a rule on whitespace; double quotes keep spaces in a
+// pattern, as in cmd:"git push".
+func splitRule(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	quoted, inField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted, inField = !quoted, true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
+
+// evaluate returns the decision of the rules for a call, or false when no
+// rule matches.
+func evaluate(rules []Rule, tool string, targets map[string][]string) (Decision, bool) {
+	var best *Decision
+	for i := range rules {
+		r := &rules[i]
+		target, ok := r.match(tool, targets)
+		if !ok {
+			continue
+		}
+		if best == nil || rank(r.Effect) > rank(best.Effect) {
+			best = &Decision{
+				Effect: r.Effect,
+				Rule:   r,
+				Target: target,
+				Reason: fmt.Sprintf("rule %q (%s)", r.Text, r.Source),
+			}
+		}
+	}
+	if best == nil {
+		return Decision{}, false
+	}
+	return *best, true
+}
+
+func rank(e Effect) int {
+	switch e {
+	case Deny:
+		return 2
+	case Ask:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// match reports whether the rule applies to a call, returning the argument
+// that matched.
+func (r *Rule) match(tool string, targets map[string][]string) (string, bool) {
+	if ok, _ := path.Match(r.Tool, tool); !ok {
+		return "", false
+	}
+	target := ""
+	for _, m := range r.Matchers {
+		values := targets[m.Kind]
+		if len(values) == 0 {
+			return "", false
+		}
+		matched := 0
+		for _, v := range values {
+			// Allowing a command does not allow the ones it substitutes
+			if r.Effect == Allow && m.Kind == MatchCmd && m.Pattern != "*" && substitutes(v) {
+				continue
+			}
+			if m.match(v) {
+				matched++
+				if target == "" {
+					target = v
+				}
+			}
+		}
+		// An allow rule must cover every path or command of the call
+		if matched == 0 || (r.Effect == Allow && matched < len(values)) {
+			return "", false
+		}
+	}
+	return target, true
+}
+
+func (m Matcher) match(value string) bool {
+	switch m.Kind {
+	case MatchPath:
+		return editor.MatchPath(m.Pattern, value)
+	case MatchCmd:
+		return matchCommand(m.Pattern, value)
+	case MatchHost:
+		ok, _ := path.Match(m.Pattern, value)
+		return ok
+	case MatchDomain:
+		pattern := strings.ToLower(m.Pattern)
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+		return !strings.ContainsAny(pattern, "*?[") && (value == pattern || strings.HasSuffix(value, "."+pattern))
+	}
+	return false
+}
+
+// matchCommand reports whether a command starts with the pattern's words.
+// A pattern ending in "*" is a plain prefix, and "*" matches everything.
+func matchCommand(pattern, command string) bool {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	if pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(command, prefix)
+	}
+	return command == pattern || strings.HasPrefix(command, pattern+" ")
+}
+
+// substitutes reports whether a command runs other commands through $(...)
+// or backticks.
+func substitutes(command string) bool {
+	return strings.Contains(command, "$(") || strings.Contains(command, "`")
+}
+
+// callTargets extracts the arguments rules match on: paths relative to root,
+// each command of a command line, SSH host labels and URL hosts.
+func callTargets(root, tool string, args map[string]any) map[string][]string {
+	targets := make(map[string][]string)
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return strings.TrimSpace(s)
+	}
+
+	for _, key := range []string{"path", "local_path"} {
+		if p := str(key); p != "" {
+			targets[MatchPath] = append(targets[MatchPath], relPath(root, p))
+		}
+	}
+	if patch := str("patch"); patch != "" {
+		for _, p := range patchPaths(patch) {
+			targets[MatchPath] = append(targets[MatchPath], relPath(root, p))
+		}
+	}
+
+	if command := commandLine(tool, args); command != "" {
+		targets[MatchCmd] = shellCommands(root, command)
+	}
+
+	if strings.HasPrefix(tool, "ssh_") {
+		for _, key := range []string{"host", "label"} {
+			if h := str(key); h != "" {
+				targets[MatchHost] = append(targets[MatchHost], h)
+			}
+		}
+	}
+
+	if raw := str("url"); raw != "" {
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+			targets[MatchDomain] = append(targets[MatchDomain], strings.ToLower(u.Hostname()))
+		}
+	}
+	return targets
+}
+
+// commandLine returns the shell command line of an exec_command or SSH
+// call, with exec_command's separate arguments appended.
+func commandLine(tool string, args map[string]any) string {
+	if tool != "exec_command" && !strings.HasPrefix(tool, "ssh_") {
+		return ""
+	}
+	command, _ := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if extra, _ := args["args"].(string); command != "" && strings.TrimSpace(extra) != "" {
+		command += " " + strings.TrimSpace(extra)
+	}
+	return command
+}
+
+// relPath returns p relative to root with forward slashes; paths outside
+// root are kept absolute.
+func relPath(root, p string) string {
+	if filepath.IsAbs(p) && root != "" {
+		if rel, err := filepath.Rel(root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// patchPaths returns the files a unified diff changes.
+func patchPaths(patch string) []string {
+	patches, err := editor.ParsePatch(patch)
+	if err != nil {
+		return nil // apply_patch rejects it too
+	}
+	var paths []string
+	for _, p := range patches {
+		for _, name := range []string{p.OldPath, p.NewPath} {
+			if name != editor.DevNull && !slices.Contains(paths, name) {
+				paths = append(paths, name)
+			}
+		}
+	}
+	return paths
+}
+
+// shellCommands returns the programs a command line runs with their
+// arguments, as the shell audit parses them: including those in
+// substitutions and find -exec, without wrappers such as env or nohup, and
+// with the program reduced to its name. Lines it cannot parse are split
+// with splitCommands.
+func shellCommands(root, command string) []string {
+	an := security.NewAuditor(root).AnalyzeCommand(command)
+	if len(an.Runs) == 0 || slices.ContainsFunc(an.Findings, func(f security.Finding) bool { return f.Rule == "unparsable" }) {
+		return splitCommands(command)
+	}
+	return an.Runs
+}
+
+// splitCommands splits a command line on ;, &&, || , | and newlines, with
+// whitespace collapsed.
+func splitCommands(command string) []string {
+	var commands []string
+	for _, part := range strings.FieldsFunc(command, func(r rune) bool {
+		return r == ';' || r == '&' || r == '|' || r == '\n'
+	}) {
+		if part = strings.Join(strings.Fields(part), " "); part != "" {
+			commands = append(commands, part)
+		}
+	}
+	return commands
+}
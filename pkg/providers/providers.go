@@ -3,6 +3,7 @@ package providers
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -44,9 +45,14 @@ type Provider struct {
 	totalCost      float64
 	avgLatency     time.Duration
 	lastUsed       time.Time
+	lastFailure    time.Time
 	healthy        bool
 }
 
+// HealthRetryAfter is how long an unhealthy provider is skipped before it is
+// tried again. A success restores it; another failure restarts the wait.
+var HealthRetryAfter = time.Minute
+
 // ProviderManager manages multiple providers
 type ProviderManager struct {
 	providers map[string]*Provider
@@ -156,7 +162,7 @@ func (pm *ProviderManager) GetEnabledProviders() []*Provider {
 
 	providers := make([]*Provider, 0)
 	for _, p := range pm.providers {
-		if p.Enabled && p.healthy {
+		if p.Enabled && p.IsHealthy() {
 			providers = append(providers, p)
 		}
 	}
@@ -205,6 +211,41 @@ func (pm *ProviderManager) GetFallbackChain() []*Provider {
 	return providers
 }
 
+// RoutingOrder returns the providers to try for one request: the best
+// provider for criteria ("primary", "cheapest", "fastest", "most_reliable")
+// first, then the rest of the fallback chain. "priority" or an empty criteria
+// uses the fallback chain as is. When every provider is unhealthy all enabled
+// providers are returned by priority, so a request is still attempted.
+func (pm *ProviderManager) RoutingOrder(criteria string) []*Provider {
+	chain := pm.GetFallbackChain()
+	if len(chain) == 0 {
+		pm.mu.RLock()
+		for _, p := range pm.providers {
+			if p.Enabled {
+				chain = append(chain, p)
+			}
+		}
+		pm.mu.RUnlock()
+		sort.SliceStable(chain, func(i, j int) bool { return chain[i].Priority < chain[j].Priority })
+		return chain
+	}
+
+	if criteria == "" || criteria == "priority" {
+		return chain
+	}
+	best, err := pm.SelectBestProvider(criteria)
+	if err != nil || best == nil || !best.Enabled || !best.IsHealthy() {
+		return chain
+	}
+	order := []*Provider{best}
+	for _, p := range chain {
+		if p != best {
+			order = append(order, p)
+		}
+	}
+	return order
+}
+
 // RecordSuccess records a successful request
 func (p *Provider) RecordSuccess(tokens int64, latency time.Duration, cost float64) {
 	p.mu.Lock()
@@ -229,7 +270,9 @@ func (p *Provider) RecordFailure() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.totalRequests++
 	p.failedRequests++
+	p.lastFailure = time.Now()
 
 	// Mark unhealthy if failure rate > 50%
 	if p.totalRequests > 0 {
@@ -262,11 +305,25 @@ func (p *Provider) GetStats() map[string]interface{} {
 	}
 }
 
-// IsHealthy returns whether the provider is healthy
+// IsHealthy returns whether the provider is healthy. An unhealthy provider
+// counts as healthy again once HealthRetryAfter has passed since its last
+// failure, so it gets another chance.
 func (p *Provider) IsHealthy() bool {
+	return p.RetryIn() == 0
+}
+
+// RetryIn returns how long the provider will still be skipped, or zero when it
+// can take requests.
+func (p *Provider) RetryIn() time.Duration {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return p.healthy
+	if p.healthy {
+		return 0
+	}
+	if wait := HealthRetryAfter - time.Since(p.lastFailure); wait > 0 {
+		return wait
+	}
+	return 0
 }
 
 // Reset resets provider statistics
@@ -279,6 +336,7 @@ func (p *Provider) Reset() {
 	p.totalTokens = 0
 	p.totalCost = 0
 	p.avgLatency = 0
+	p.lastFailure = time.Time{}
 	p.healthy = true
 }
 
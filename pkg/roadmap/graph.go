@@ -0,0 +1,264 @@
+package roadmap
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ClosedWheeler/pkg/tasks"
+)
+
+// defaultEstimateHours is the remaining work assumed for a goal without an
+// estimate when measuring the critical path.
+const defaultEstimateHours = 1
+
+// Progress counts the task.md items linked to a goal.
+type Progress struct {
+	Done       int
+	InProgress int
+	Total      int
+}
+
+// Percent returns the share of linked tasks that are done.
+func (p Progress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Done * 100 / p.Total
+}
+
+// GoalState is a goal with what the roadmap derives for it.
+type GoalState struct {
+	Goal
+	Progress    Progress
+	LinkedTasks []tasks.Task // the task.md item of each of Goal.Tasks; "todo" when missing
+	OpenTasks   []tasks.Task // linked tasks not done yet
+	BlockedBy   []string     // dependencies not completed yet
+}
+
+// Actionable reports whether work can start on the goal.
+func (g GoalState) Actionable() bool {
+	return g.Status != StatusCompleted && g.Status != StatusBlocked && len(g.BlockedBy) == 0
+}
+
+// remainingHours estimates the work left on the goal.
+func (g GoalState) remainingHours() float64 {
+	if g.Status == StatusCompleted {
+		return 0
+	}
+	hours := g.EstimateHours
+	if hours <= 0 {
+		hours = defaultEstimateHours
+	}
+	if g.Progress.Total > 0 {
+		hours *= 1 - float64(g.Progress.Done)/float64(g.Progress.Total)
+	}
+	return hours
+}
+
+// states derives the progress and blockers of every goal.
+func (s *store) states() []GoalState {
+	status := make(map[string]string, len(s.Goals))
+	for _, g := range s.Goals {
+		status[g.ID] = g.Status
+	}
+
+	states := make([]GoalState, len(s.Goals))
+	for i, g := range s.Goals {
+		st := GoalState{Goal: g}
+		for _, link := range g.Tasks {
+			task := tasks.Task{Title: link, Status: tasks.StatusTodo}
+			if t := findTask(s.tasks, link); t != nil {
+				task = *t
+			}
+			st.LinkedTasks = append(st.LinkedTasks, task)
+			st.Progress.Total++
+			switch task.Status {
+			case tasks.StatusDone:
+				st.Progress.Done++
+			case tasks.StatusInProgress:
+				st.Progress.InProgress++
+				st.OpenTasks = append(st.OpenTasks, task)
+			default:
+				st.OpenTasks = append(st.OpenTasks, task)
+			}
+		}
+		for _, dep := range g.Dependencies {
+			if status[dep] != StatusCompleted {
+				st.BlockedBy = append(st.BlockedBy, dep)
+			}
+		}
+		states[i] = st
+	}
+	return states
+}
+
+// checkDependencies reports unknown dependencies of the goal with an ID and
+// dependency cycles through it.
+func checkDependencies(goals []Goal, id string) error {
+	byID := make(map[string]*Goal, len(goals))
+	for i := range goals {
+		byID[goals[i].ID] = &goals[i]
+	}
+	for _, dep := range byID[id].Dependencies {
+		if dep == id {
+			return fmt.Errorf("goal %s cannot depend on itself", id)
+		}
+		if byID[dep] == nil {
+			return fmt.Errorf("goal %s depends on unknown goal %s", id, dep)
+		}
+	}
+
+	// A cycle through id means id is reachable from its own dependencies
+	seen := make(map[string]bool)
+	var path []string
+	var reaches func(from string) bool
+	reaches = func(from string) bool {
+		if from == id {
+			return true
+		}
+		if seen[from] || byID[from] == nil {
+			return false
+		}
+		seen[from] = true
+		path = append(path, from)
+		for _, dep := range byID[from].Dependencies {
+			if reaches(dep) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	for _, dep := range byID[id].Dependencies {
+		path = path[:0]
+		if reaches(dep) {
+			return fmt.Errorf("dependency cycle: %s → %s → %s", id, strings.Join(path, " → "), id)
+		}
+	}
+	return nil
+}
+
+// downstream returns, for each goal, the remaining hours of the longest
+// chain of unfinished goals starting at it and continuing through the goals
+// that depend on it.
+func downstream(states []GoalState) map[string]float64 {
+	dependents := make(map[string][]int)
+	for i, g := range states {
+		for _, dep := range g.Dependencies {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+	memo := make(map[string]float64)
+	visiting := make(map[string]bool)
+	var walk func(i int) float64
+	walk = func(i int) float64 {
+		g := states[i]
+		if v, ok := memo[g.ID]; ok {
+			return v
+		}
+		if visiting[g.ID] { // cycle from a hand edit
+			return 0
+		}
+		visiting[g.ID] = true
+		longest := 0.0
+		for _, j := range dependents[g.ID] {
+			longest = max(longest, walk(j))
+		}
+		visiting[g.ID] = false
+		memo[g.ID] = g.remainingHours() + longest
+		return memo[g.ID]
+	}
+	for i := range states {
+		walk(i)
+	}
+	return memo
+}
+
+// actionable returns the actionable goals, best first.
+func actionable(states []GoalState) []GoalState {
+	rest := downstream(states)
+	var goals []GoalState
+	for _, g := range states {
+		if g.Actionable() {
+			goals = append(goals, g)
+		}
+	}
+	sort.SliceStable(goals, func(i, j int) bool {
+		a, b := goals[i], goals[j]
+		if (a.Status == StatusInProgress) != (b.Status == StatusInProgress) {
+			return a.Status == StatusInProgress
+		}
+		if pa, pb := priorityRank(a.Priority), priorityRank(b.Priority); pa != pb {
+			return pa < pb
+		}
+		return rest[a.ID] > rest[b.ID]
+	})
+	return goals
+}
+
+// criticalPath returns the longest chain of unfinished goals by remaining
+// hours, first goal first, and its length.
+func criticalPath(states []GoalState) ([]GoalState, float64) {
+	rest := downstream(states)
+	var start *GoalState
+	for i, g := range states {
+		if g.Status == StatusCompleted || len(g.BlockedBy) > 0 {
+			continue
+		}
+		if start == nil || rest[g.ID] > rest[start.ID] {
+			start = &states[i]
+		}
+	}
+	if start == nil {
+		return nil, 0
+	}
+
+	path := []GoalState{*start}
+	seen := map[string]bool{start.ID: true}
+	for {
+		cur := path[len(path)-1]
+		var next *GoalState
+		for i, g := range states {
+			if seen[g.ID] || g.Status == StatusCompleted || !containsFold(g.Dependencies, cur.ID) {
+				continue
+			}
+			if next == nil || rest[g.ID] > rest[next.ID] {
+				next = &states[i]
+			}
+		}
+		if next == nil {
+			break
+		}
+		seen[next.ID] = true
+		path = append(path, *next)
+	}
+	return path, rest[start.ID]
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case "high":
+		return 0
+	case "low":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// pathIDs joins the IDs of a path with arrows.
+func pathIDs(path []GoalState) string {
+	ids := make([]string, len(path))
+	for i, g := range path {
+		ids[i] = g.ID
+	}
+	return strings.Join(ids, " → ")
+}
+
+// formatHours formats an estimate to a tenth of an hour, e.g. "4h" or "1.5h".
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*10)/10, 'f', -1, 64) + "h"
+}
@@ -1,44 +1,66 @@
 // Package roadmap provides strategic long-term planning capabilities.
-// The roadmap is stored in workplace/roadmap.md as a visible, editable document.
+// Goals and milestones are stored in workplace/roadmap.json as a dependency
+// graph; workplace/roadmap.md is regenerated from it as a visible, editable
+// document. Goal progress is derived from the task.md items linked to it.
 package roadmap
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"ClosedWheeler/pkg/tasks"
 )
 
 // Goal represents a strategic goal
 type Goal struct {
-	ID           string
-	Title        string
-	Description  string
-	Status       string // "planned", "in-progress", "blocked", "completed"
-	Priority     string // "high", "medium", "low"
-	DueDate      *time.Time
-	Dependencies []string // IDs of goals this depends on
-	Tags         []string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Status        string     `json:"status"`   // "planned", "in-progress", "blocked", "completed"
+	Priority      string     `json:"priority"` // "high", "medium", "low"
+	DueDate       *time.Time `json:"due_date,omitempty"`
+	Dependencies  []string   `json:"dependencies,omitempty"` // IDs of goals this depends on
+	Tags          []string   `json:"tags,omitempty"`
+	EstimateHours float64    `json:"estimate_hours,omitempty"`
+	Tasks         []string   `json:"tasks,omitempty"` // IDs of the task.md items that make up the goal
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // Milestone represents a major achievement
 type Milestone struct {
-	Title       string
-	Description string
-	Goals       []string // Goal IDs
-	TargetDate  *time.Time
-	CompletedAt *time.Time
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Goals       []string   `json:"goals,omitempty"` // Goal IDs
+	TargetDate  *time.Time `json:"target_date,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
+// Goal statuses.
+const (
+	StatusPlanned    = "planned"
+	StatusInProgress = "in-progress"
+	StatusBlocked    = "blocked"
+	StatusCompleted  = "completed"
+)
+
+// Statuses and Priorities list the valid goal statuses and priorities.
+var (
+	Statuses   = []string{StatusPlanned, StatusInProgress, StatusBlocked, StatusCompleted}
+	Priorities = []string{"high", "medium", "low"}
+)
+
 // Roadmap manages strategic planning
 type Roadmap struct {
 	projectPath string
-	roadmapPath string
-	mu          sync.RWMutex
+	roadmapPath string      // generated markdown view
+	storePath   string      // goals and milestones
+	tasks       *tasks.List // task.md the goals link to
+	mu          sync.Mutex
 }
 
 // NewRoadmap creates a new roadmap instance
@@ -46,217 +68,202 @@ func NewRoadmap(projectPath string) *Roadmap {
 	return &Roadmap{
 		projectPath: projectPath,
 		roadmapPath: filepath.Join(projectPath, "roadmap.md"),
+		storePath:   filepath.Join(projectPath, "roadmap.json"),
+		tasks:       tasks.NewList(filepath.Join(projectPath, "task.md")),
 	}
 }
 
-// Initialize creates the roadmap.md file if it doesn't exist
+// Initialize creates the store if it doesn't exist, importing the goals and
+// milestones of an existing roadmap.md, and writes roadmap.md.
 func (r *Roadmap) Initialize() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Ensure parent directory exists
-	parentDir := filepath.Dir(r.roadmapPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return fmt.Errorf("failed to create roadmap directory: %w", err)
+	s, err := r.load()
+	if err != nil {
+		return err
 	}
+	return r.save(s)
+}
 
-	// Check if roadmap.md exists
-	if _, err := os.Stat(r.roadmapPath); os.IsNotExist(err) {
-		initialContent := `# 🗺️ Strategic Roadmap
-
-Strategic long-term planning for the project.
-
-## 🎯 Vision
-
-<!-- Describe the long-term vision of the project -->
-
-## 🏆 Milestones
-
-<!-- Important milestones and their goals -->
-
-## 📊 Strategic Objectives
-
-### 🔴 High Priority
-
-<!-- High priority objectives -->
-
-### 🟡 Medium Priority
-
-<!-- Medium priority objectives -->
-
-### 🟢 Low Priority
-
-<!-- Low priority objectives -->
-
-## ✅ Completed
-
-<!-- Already completed objectives -->
+// AddGoal adds a new strategic goal to the roadmap
+func (r *Roadmap) AddGoal(goal Goal) error {
+	_, err := r.Add(goal)
+	return err
+}
 
-## 🚫 Blocked
+// Add stores a new goal and returns it with its ID assigned when it had
+// none. Dependencies must exist and must not form a cycle.
+func (r *Roadmap) Add(goal Goal) (Goal, error) {
+	var added Goal
+	err := r.modify(func(s *store) error {
+		if goal.ID != "" && s.goal(goal.ID) != nil {
+			return fmt.Errorf("goal %s already exists", goal.ID)
+		}
+		added = s.addGoal(goal)
+		if err := checkDependencies(s.Goals, added.ID); err != nil {
+			s.Goals = s.Goals[:len(s.Goals)-1]
+			return err
+		}
+		g := s.goal(added.ID)
+		var err error
+		if g.Tasks, err = r.resolveTasks(s, g.Tasks); err != nil {
+			return err
+		}
+		added = *g
+		return nil
+	})
+	return added, err
+}
 
-<!-- Blocked objectives and reasons -->
+// UpdateGoal changes a goal in place; fn must not change its ID. New task
+// links are added to task.md.
+func (r *Roadmap) UpdateGoal(id string, fn func(*Goal)) error {
+	return r.modify(func(s *store) error {
+		g := s.goal(id)
+		if g == nil {
+			return fmt.Errorf("goal not found: %s", id)
+		}
+		before := *g
+		fn(g)
+		g.ID = before.ID
+		g.Status = normalizeStatus(g.Status)
+		g.Priority = normalizePriority(g.Priority)
+		g.UpdatedAt = time.Now()
+		if err := checkDependencies(s.Goals, g.ID); err != nil {
+			*g = before
+			return err
+		}
+		var err error
+		g.Tasks, err = r.resolveTasks(s, g.Tasks)
+		return err
+	})
+}
 
----
+// UpdateGoalStatus updates the status of a goal
+func (r *Roadmap) UpdateGoalStatus(goalID, newStatus string) error {
+	return r.UpdateGoal(goalID, func(g *Goal) { g.Status = newStatus })
+}
 
-*Last update: ` + time.Now().Format("2006-01-02 15:04:05") + `*
-`
-		return os.WriteFile(r.roadmapPath, []byte(initialContent), 0644)
-	}
+// LinkTasks links task.md items to a goal, adding the ones task.md does
+// not have yet as open tasks.
+func (r *Roadmap) LinkTasks(goalID string, links ...string) error {
+	return r.UpdateGoal(goalID, func(g *Goal) {
+		for _, task := range links {
+			if task = strings.TrimSpace(task); task != "" && !containsFold(g.Tasks, task) {
+				g.Tasks = append(g.Tasks, task)
+			}
+		}
+	})
+}
 
-	return nil
+// AddMilestone adds a milestone to the roadmap
+func (r *Roadmap) AddMilestone(milestone Milestone) error {
+	return r.modify(func(s *store) error {
+		for _, id := range milestone.Goals {
+			if s.goal(id) == nil {
+				return fmt.Errorf("goal not found: %s", id)
+			}
+		}
+		s.addMilestone(milestone)
+		return nil
+	})
 }
 
-// AddGoal adds a new strategic goal to the roadmap
-func (r *Roadmap) AddGoal(goal Goal) error {
+// Goals returns every goal with its derived progress, in creation order.
+func (r *Roadmap) Goals() ([]GoalState, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Read current content
-	content, err := os.ReadFile(r.roadmapPath)
+	s, err := r.load()
 	if err != nil {
-		return fmt.Errorf("failed to read roadmap.md: %w", err)
-	}
-
-	lines := strings.Split(string(content), "\n")
-
-	// Determine section based on priority
-	sectionMarker := r.getPrioritySection(goal.Priority)
-	sectionIdx := r.findSectionIndex(lines, sectionMarker)
-
-	if sectionIdx == -1 {
-		return fmt.Errorf("section not found for priority: %s", goal.Priority)
+		return nil, err
 	}
-
-	// Format the new goal
-	formattedGoal := r.formatGoal(goal)
-
-	// Insert after section header and comment
-	insertIdx := sectionIdx + 2
-
-	// Insert the goal
-	newLines := append(lines[:insertIdx], append([]string{formattedGoal, ""}, lines[insertIdx:]...)...)
-
-	// Update timestamp
-	r.updateTimestamp(newLines)
-
-	// Write back
-	return os.WriteFile(r.roadmapPath, []byte(strings.Join(newLines, "\n")), 0644)
+	return s.states(), nil
 }
 
-// UpdateGoalStatus updates the status of a goal (moves it between sections if needed)
-func (r *Roadmap) UpdateGoalStatus(goalID, newStatus string) error {
+// Milestones returns the milestones in creation order.
+func (r *Roadmap) Milestones() ([]Milestone, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	content, err := os.ReadFile(r.roadmapPath)
+	s, err := r.load()
 	if err != nil {
-		return fmt.Errorf("failed to read roadmap.md: %w", err)
+		return nil, err
 	}
+	return append([]Milestone(nil), s.Milestones...), nil
+}
 
-	lines := strings.Split(string(content), "\n")
-
-	// Find the goal and its current section
-	goalStart, goalEnd := r.findGoal(lines, goalID)
-	if goalStart == -1 {
-		return fmt.Errorf("goal not found: %s", goalID)
-	}
-
-	// Extract goal lines
-	goalLines := lines[goalStart:goalEnd]
-
-	// Update status in goal text
-	for i := range goalLines {
-		if strings.HasPrefix(goalLines[i], "**Status:**") {
-			goalLines[i] = fmt.Sprintf("**Status:** %s", newStatus)
-		}
-		if strings.HasPrefix(goalLines[i], "**Updated:**") {
-			goalLines[i] = fmt.Sprintf("**Updated:** %s", time.Now().Format("2006-01-02 15:04"))
-		}
-	}
+// Actionable returns the unfinished goals whose dependencies are all
+// completed and that are not blocked, best first: goals in progress, then
+// by priority, then by the remaining work that depends on them.
+func (r *Roadmap) Actionable() ([]GoalState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Remove goal from current position
-	newLines := append(lines[:goalStart], lines[goalEnd:]...)
-
-	// Find target section based on status
-	var targetSection string
-	if newStatus == "completed" {
-		targetSection = "## ✅ Completed"
-	} else if newStatus == "blocked" {
-		targetSection = "## 🚫 Blocked"
-	} else {
-		// Stay in current priority section
-		return r.updateGoalInPlace(goalLines, goalStart)
+	s, err := r.load()
+	if err != nil {
+		return nil, err
 	}
+	return actionable(s.states()), nil
+}
 
-	insertIdx := r.findSectionIndex(newLines, targetSection)
-	if insertIdx == -1 {
-		return fmt.Errorf("target section not found: %s", targetSection)
+// Next returns the goal to work on next, or nil when no goal is actionable.
+func (r *Roadmap) Next() (*GoalState, error) {
+	goals, err := r.Actionable()
+	if err != nil || len(goals) == 0 {
+		return nil, err
 	}
-
-	// Insert at target section (right after the header)
-	insertIdx++
-	finalLines := append(newLines[:insertIdx], append(goalLines, newLines[insertIdx:]...)...)
-
-	// Update timestamp
-	r.updateTimestamp(finalLines)
-
-	return os.WriteFile(r.roadmapPath, []byte(strings.Join(finalLines, "\n")), 0644)
+	return &goals[0], nil
 }
 
-// AddMilestone adds a milestone to the roadmap
-func (r *Roadmap) AddMilestone(milestone Milestone) error {
+// CriticalPath returns the longest chain of unfinished goals, measured in
+// remaining estimated hours, in the order they must be done, and its length.
+func (r *Roadmap) CriticalPath() ([]GoalState, float64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	content, err := os.ReadFile(r.roadmapPath)
+	s, err := r.load()
 	if err != nil {
-		return fmt.Errorf("failed to read roadmap.md: %w", err)
+		return nil, 0, err
 	}
-
-	lines := strings.Split(string(content), "\n")
-
-	sectionIdx := r.findSectionIndex(lines, "## 🏆 Milestones")
-	if sectionIdx == -1 {
-		return fmt.Errorf("milestones section not found")
-	}
-
-	formattedMilestone := r.formatMilestone(milestone)
-	insertIdx := sectionIdx + 2
-
-	newLines := append(lines[:insertIdx], append([]string{formattedMilestone, ""}, lines[insertIdx:]...)...)
-
-	r.updateTimestamp(newLines)
-
-	return os.WriteFile(r.roadmapPath, []byte(strings.Join(newLines, "\n")), 0644)
+	path, hours := criticalPath(s.states())
+	return path, hours, nil
 }
 
 // Read returns the current roadmap content
 func (r *Roadmap) Read() (string, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	content, err := os.ReadFile(r.roadmapPath)
+	s, err := r.load()
 	if err != nil {
-		return "", fmt.Errorf("failed to read roadmap.md: %w", err)
+		return "", err
 	}
-
-	return string(content), nil
+	return renderMarkdown(s), nil
 }
 
 // GetSummary returns a brief summary of the roadmap status
 func (r *Roadmap) GetSummary() (string, error) {
-	content, err := r.Read()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.load()
 	if err != nil {
 		return "", err
 	}
-
-	lines := strings.Split(content, "\n")
-
-	highPriority := r.countGoalsInSection(lines, "### 🔴 High Priority")
-	mediumPriority := r.countGoalsInSection(lines, "### 🟡 Medium Priority")
-	lowPriority := r.countGoalsInSection(lines, "### 🟢 Low Priority")
-	completed := r.countGoalsInSection(lines, "## ✅ Completed")
-	blocked := r.countGoalsInSection(lines, "## 🚫 Blocked")
+	states := s.states()
+
+	counts := make(map[string]int)
+	for _, g := range states {
+		switch g.Status {
+		case StatusCompleted, StatusBlocked:
+			counts[g.Status]++
+		default:
+			counts[g.Priority]++
+		}
+	}
+	active := counts["high"] + counts["medium"] + counts["low"]
 
 	summary := fmt.Sprintf(`📊 Roadmap Status:
 - High Priority: %d objectives
@@ -265,159 +272,71 @@ func (r *Roadmap) GetSummary() (string, error) {
 - Completed: %d objectives
 - Blocked: %d objectives
 Total Active: %d | Total General: %d`,
-		highPriority, mediumPriority, lowPriority, completed, blocked,
-		highPriority+mediumPriority+lowPriority,
-		highPriority+mediumPriority+lowPriority+completed+blocked)
-
-	return summary, nil
-}
-
-// Helper methods
-
-func (r *Roadmap) getPrioritySection(priority string) string {
-	switch strings.ToLower(priority) {
-	case "high", "alta", "alto":
-		return "### 🔴 High Priority"
-	case "medium", "média", "medio":
-		return "### 🟡 Medium Priority"
-	case "low", "baixa", "baixo":
-		return "### 🟢 Low Priority"
-	default:
-		return "### 🟡 Medium Priority"
-	}
-}
-
-func (r *Roadmap) findSectionIndex(lines []string, marker string) int {
-	for i, line := range lines {
-		if strings.TrimSpace(line) == marker {
-			return i
-		}
-	}
-	return -1
-}
-
-func (r *Roadmap) formatGoal(goal Goal) string {
-	var sb strings.Builder
-
-	sb.WriteString(fmt.Sprintf("#### %s\n", goal.Title))
-	sb.WriteString(fmt.Sprintf("*ID: `%s`* | ", goal.ID))
-	sb.WriteString(fmt.Sprintf("**Status:** %s | ", goal.Status))
-	sb.WriteString(fmt.Sprintf("**Created:** %s\n\n", goal.CreatedAt.Format("2006-01-02")))
-	sb.WriteString(goal.Description)
-
-	if goal.DueDate != nil {
-		sb.WriteString(fmt.Sprintf("\n\n**Deadline:** %s", goal.DueDate.Format("2006-01-02")))
-	}
+		counts["high"], counts["medium"], counts["low"], counts[StatusCompleted], counts[StatusBlocked],
+		active, len(states))
 
-	if len(goal.Dependencies) > 0 {
-		sb.WriteString("\n\n**Dependencies:** ")
-		for i, dep := range goal.Dependencies {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("`" + dep + "`")
-		}
+	if next := actionable(states); len(next) > 0 {
+		summary += fmt.Sprintf("\nNext: `%s` %s", next[0].ID, next[0].Title)
 	}
-
-	if len(goal.Tags) > 0 {
-		sb.WriteString("\n\n**Tags:** ")
-		for i, tag := range goal.Tags {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("`" + tag + "`")
-		}
+	if path, hours := criticalPath(states); len(path) > 0 {
+		summary += fmt.Sprintf("\nCritical path: %s (%s)", pathIDs(path), formatHours(hours))
 	}
-
-	return sb.String()
+	return summary, nil
 }
 
-func (r *Roadmap) formatMilestone(milestone Milestone) string {
-	var sb strings.Builder
-
-	sb.WriteString(fmt.Sprintf("### %s\n\n", milestone.Title))
-	sb.WriteString(milestone.Description)
+// modify loads the store, applies fn and saves it, holding the lock
+// throughout so concurrent writers cannot lose each other's changes.
+func (r *Roadmap) modify(fn func(*store) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if milestone.TargetDate != nil {
-		sb.WriteString(fmt.Sprintf("\n\n**Target Date:** %s", milestone.TargetDate.Format("2006-01-02")))
+	s, err := r.load()
+	if err != nil {
+		return err
 	}
-
-	if milestone.CompletedAt != nil {
-		sb.WriteString(fmt.Sprintf(" | **Completed on:** %s ✅", milestone.CompletedAt.Format("2006-01-02")))
+	if err := fn(s); err != nil {
+		return err
 	}
-
-	if len(milestone.Goals) > 0 {
-		sb.WriteString("\n\n**Related Objectives:** ")
-		for i, goalID := range milestone.Goals {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString("`" + goalID + "`")
-		}
+	// Newly linked tasks change the derived progress
+	if s.tasks, err = r.tasks.All(); err != nil {
+		return err
 	}
-
-	return sb.String()
+	s.derive()
+	return r.save(s)
 }
 
-func (r *Roadmap) findGoal(lines []string, goalID string) (start, end int) {
-	idMarker := fmt.Sprintf("*ID: `%s`*", goalID)
-
-	for i, line := range lines {
-		if strings.Contains(line, idMarker) {
-			start = i - 1 // Include the title line
-
-			// Find the end of this goal (next #### or section marker)
-			for j := i + 1; j < len(lines); j++ {
-				if strings.HasPrefix(lines[j], "####") ||
-					strings.HasPrefix(lines[j], "###") ||
-					strings.HasPrefix(lines[j], "##") {
-					return start, j
-				}
-			}
-			return start, len(lines)
-		}
+// normalizeStatus maps status spellings to the Statuses, defaulting to planned.
+func normalizeStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "in-progress", "in_progress", "in progress", "active":
+		return StatusInProgress
+	case "blocked":
+		return StatusBlocked
+	case "completed", "complete", "done":
+		return StatusCompleted
+	default:
+		return StatusPlanned
 	}
-
-	return -1, -1
 }
 
-func (r *Roadmap) updateGoalInPlace(goalLines []string, position int) error {
-	// This is a simplified update - just rewrite the file
-	content, _ := os.ReadFile(r.roadmapPath)
-	lines := strings.Split(string(content), "\n")
-
-	r.updateTimestamp(lines)
-	return os.WriteFile(r.roadmapPath, []byte(strings.Join(lines, "\n")), 0644)
-}
-
-func (r *Roadmap) updateTimestamp(lines []string) {
-	for i := len(lines) - 1; i >= 0; i-- {
-		if strings.HasPrefix(lines[i], "*Last update:") {
-			lines[i] = "*Last update: " + time.Now().Format("2006-01-02 15:04:05") + "*"
-			break
-		}
+// normalizePriority maps priority spellings to the Priorities, defaulting
+// to medium.
+func normalizePriority(priority string) string {
+	switch strings.ToLower(strings.TrimSpace(priority)) {
+	case "high", "alta", "alto":
+		return "high"
+	case "low", "baixa", "baixo":
+		return "low"
+	default:
+		return "medium"
 	}
 }
 
-func (r *Roadmap) countGoalsInSection(lines []string, section string) int {
-	count := 0
-	inSection := false
-
-	for _, line := range lines {
-		if strings.TrimSpace(line) == section {
-			inSection = true
-			continue
-		}
-
-		if inSection {
-			if strings.HasPrefix(line, "##") || strings.HasPrefix(line, "---") {
-				break
-			}
-			if strings.HasPrefix(line, "####") {
-				count++
-			}
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
 		}
 	}
-
-	return count
+	return false
 }
@@ -0,0 +1,215 @@
+package roadmap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestRoadmap(t *testing.T) (*Roadmap, string) {
+	t.Helper()
+	dir := t.TempDir()
+	r := NewRoadmap(dir)
+	if err := r.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	return r, dir
+}
+
+func TestRoadmap_DependenciesAndNext(t *testing.T) {
+	r, dir := newTestRoadmap(t)
+
+	parser, err := r.Add(Goal{Title: "Parser", Priority: "high", EstimateHours: 2, Tasks: []string{"Write parser", "Add parser tests"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Add(Goal{Title: "Formatter", EstimateHours: 3, Dependencies: []string{parser.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Add(Goal{Title: "Docs", Priority: "low"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Add(Goal{Title: "Broken", Dependencies: []string{"g42"}}); err == nil {
+		t.Error("Expected an error for an unknown dependency")
+	}
+	if err := r.UpdateGoal("g1", func(g *Goal) { g.Dependencies = []string{"g2"} }); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a dependency cycle error, got %v", err)
+	}
+
+	// Linked tasks are added to task.md and linked by ID
+	if strings.Join(parser.Tasks, ",") != "t1,t2" {
+		t.Errorf("Goal tasks = %v, want t1,t2", parser.Tasks)
+	}
+	tasks, _ := os.ReadFile(filepath.Join(dir, "task.md"))
+	if !strings.Contains(string(tasks), "- [ ] Write parser <!-- id:t1 ") || !strings.Contains(string(tasks), "- [ ] Add parser tests <!-- id:t2 ") {
+		t.Errorf("Linked tasks not added to task.md:\n%s", tasks)
+	}
+
+	actionable, err := r.Actionable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actionable) != 2 || actionable[0].ID != "g1" || actionable[1].ID != "g3" {
+		t.Errorf("Actionable() = %+v, want g1 then g3", actionable)
+	}
+	path, hours, err := r.CriticalPath()
+	if err != nil || pathIDs(path) != "g1 → g2" || hours != 5 {
+		t.Errorf("CriticalPath() = %s, %v, %v", pathIDs(path), hours, err)
+	}
+
+	// Progress follows task.md
+	done := strings.Replace(string(tasks), "- [ ] Write parser", "- [x] Write parser", 1)
+	if err := os.WriteFile(filepath.Join(dir, "task.md"), []byte(done), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goals, err := r.Goals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := goals[0]; g.Status != StatusInProgress || g.Progress.Done != 1 || g.Progress.Total != 2 {
+		t.Errorf("Goal after one task done: %+v", g)
+	}
+	if _, hours, _ := r.CriticalPath(); hours != 4 {
+		t.Errorf("Critical path length = %v, want 4", hours)
+	}
+
+	done = strings.Replace(done, "- [ ] Add parser tests", "- [x] Add parser tests", 1)
+	if err := os.WriteFile(filepath.Join(dir, "task.md"), []byte(done), 0644); err != nil {
+		t.Fatal(err)
+	}
+	next, err := r.Next()
+	if err != nil || next == nil || next.ID != "g2" {
+		t.Fatalf("Next() = %+v, %v, want g2 once g1 is done", next, err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "roadmap.md"))
+	for _, want := range []string{"## ✅ Completed\n\n<!-- Already completed objectives -->\n\n#### Parser", "**Critical path:** `g2`", "- [x] `t2` Add parser tests"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("roadmap.md is missing %q:\n%s", want, content)
+		}
+	}
+
+	// Reading does not rewrite an unchanged roadmap
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := os.ReadFile(filepath.Join(dir, "roadmap.md"))
+	if string(again) != string(content) {
+		t.Error("roadmap.md was rewritten without changes")
+	}
+}
+
+func TestRoadmap_HandEditsAndMilestones(t *testing.T) {
+	r, dir := newTestRoadmap(t)
+	if err := r.AddGoal(Goal{Title: "Ship v1", Priority: "high"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMilestone(Milestone{Title: "Launch", Goals: []string{"g1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMilestone(Milestone{Title: "Bad", Goals: []string{"g9"}}); err == nil {
+		t.Error("Expected an error for a milestone with an unknown goal")
+	}
+
+	path := filepath.Join(dir, "roadmap.md")
+	content, _ := os.ReadFile(path)
+	edited := strings.Replace(string(content), "**Status:** planned", "**Status:** blocked", 1)
+	edited = strings.Replace(edited, "<!-- Low priority objectives -->\n", "<!-- Low priority objectives -->\n\n#### Write a blog post\nAnnounce the release\n\n**Dependencies:** `g1`\n\n**Tasks:**\n- [ ] Draft post\n", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	goals, err := r.Goals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goals) != 2 || goals[0].Status != StatusBlocked {
+		t.Fatalf("Hand edits not applied: %+v", goals)
+	}
+	if g := goals[1]; g.ID != "g2" || g.Priority != "low" || g.Description != "Announce the release" || len(g.BlockedBy) != 1 {
+		t.Errorf("Hand-written goal not imported: %+v", g)
+	}
+	if g := goals[1]; len(g.Tasks) != 1 || g.Tasks[0] != "t1" || g.LinkedTasks[0].Title != "Draft post" {
+		t.Errorf("Hand-written task link not resolved: %v %+v", g.Tasks, g.LinkedTasks)
+	}
+
+	if err := r.UpdateGoalStatus("g1", "done"); err != nil {
+		t.Fatal(err)
+	}
+	milestones, _ := r.Milestones()
+	if len(milestones) != 1 || milestones[0].CompletedAt == nil {
+		t.Errorf("Milestone not completed with its goals: %+v", milestones)
+	}
+	summary, _ := r.GetSummary()
+	if !strings.Contains(summary, "Completed: 1 objectives") || !strings.Contains(summary, "Next: `g2` Write a blog post") {
+		t.Errorf("Unexpected summary:\n%s", summary)
+	}
+}
+
+func TestRoadmap_ImportsMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `# 🗺️ Strategic Roadmap
+
+## 🎯 Vision
+
+A fast, friendly CLI
+
+## 🏆 Milestones
+
+### Beta
+
+First public build
+
+**Related Objectives:** ` + "`api`" + `
+
+## 📊 Strategic Objectives
+
+### 🔴 High Priority
+
+<!-- High priority objectives -->
+#### Stable API
+*ID: ` + "`api`" + `* | **Status:** in-progress | **Created:** 2024-02-01
+
+Freeze the public API
+
+**Tags:** ` + "`api`" + `
+
+### 🟡 Medium Priority
+
+## ✅ Completed
+
+#### Prototype
+*ID: ` + "`proto`" + `* | **Status:** completed | **Created:** 2024-01-01
+
+---
+
+*Last update: 2024-02-01 10:00:00*
+`
+	if err := os.WriteFile(filepath.Join(dir, "roadmap.md"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRoadmap(dir)
+	if err := r.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	goals, err := r.Goals()
+	if err != nil || len(goals) != 2 {
+		t.Fatalf("Goals() = %+v, %v", goals, err)
+	}
+	if g := goals[0]; g.ID != "api" || g.Priority != "high" || g.Status != StatusInProgress || g.Description != "Freeze the public API" || g.CreatedAt.Year() != 2024 {
+		t.Errorf("Unexpected imported goal: %+v", g)
+	}
+	if goals[1].Status != StatusCompleted {
+		t.Errorf("Unexpected completed goal: %+v", goals[1])
+	}
+	milestones, _ := r.Milestones()
+	if len(milestones) != 1 || milestones[0].ID != "m1" || milestones[0].Goals[0] != "api" {
+		t.Errorf("Unexpected milestones: %+v", milestones)
+	}
+	content, _ := r.Read()
+	if !strings.Contains(content, "A fast, friendly CLI") {
+		t.Errorf("Vision lost:\n%s", content)
+	}
+}
@@ -0,0 +1,226 @@
+package roadmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/tasks"
+	"ClosedWheeler/pkg/utils"
+)
+
+// store is the content of roadmap.json.
+type store struct {
+	NextGoal      int       `json:"next_goal"`
+	NextMilestone int       `json:"next_milestone"`
+	Vision        string    `json:"vision,omitempty"`
+	Updated       time.Time `json:"updated_at"`
+	// ViewHash is the SHA-256 of the roadmap.md last written, used to
+	// notice when it was edited by hand.
+	ViewHash   string      `json:"view_hash"`
+	Goals      []Goal      `json:"goals"`
+	Milestones []Milestone `json:"milestones"`
+
+	tasks []tasks.Task // task.md items, read on load
+}
+
+// goal returns the goal with an ID, or nil.
+func (s *store) goal(id string) *Goal {
+	for i := range s.Goals {
+		if s.Goals[i].ID == id {
+			return &s.Goals[i]
+		}
+	}
+	return nil
+}
+
+// milestone returns the milestone with an ID, or nil.
+func (s *store) milestone(id string) *Milestone {
+	for i := range s.Milestones {
+		if s.Milestones[i].ID == id {
+			return &s.Milestones[i]
+		}
+	}
+	return nil
+}
+
+// addGoal appends a goal, assigning the next free ID when it has none.
+func (s *store) addGoal(goal Goal) Goal {
+	for goal.ID == "" || s.goal(goal.ID) != nil {
+		s.NextGoal = max(s.NextGoal, 1)
+		goal.ID = "g" + strconv.Itoa(s.NextGoal)
+		s.NextGoal++
+	}
+	now := time.Now()
+	if goal.CreatedAt.IsZero() {
+		goal.CreatedAt = now
+	}
+	goal.UpdatedAt = now
+	goal.Status = normalizeStatus(goal.Status)
+	goal.Priority = normalizePriority(goal.Priority)
+	s.Goals = append(s.Goals, goal)
+	return goal
+}
+
+// addMilestone appends a milestone, assigning the next free ID when it has none.
+func (s *store) addMilestone(m Milestone) Milestone {
+	for m.ID == "" || s.milestone(m.ID) != nil {
+		s.NextMilestone = max(s.NextMilestone, 1)
+		m.ID = "m" + strconv.Itoa(s.NextMilestone)
+		s.NextMilestone++
+	}
+	s.Milestones = append(s.Milestones, m)
+	return m
+}
+
+// derive updates goal statuses from their linked tasks and milestone
+// completion from their goals. A goal
+// whose tasks are all done is completed; one with work started is in
+// progress. Blocked and completed goals are left alone.
+func (s *store) derive() {
+	now := time.Now()
+	for i, st := range s.states() {
+		g := &s.Goals[i]
+		p := st.Progress
+		status := g.Status
+		switch {
+		case p.Total == 0 || g.Status == StatusBlocked || g.Status == StatusCompleted:
+		case p.Done == p.Total:
+			status = StatusCompleted
+		case p.Done > 0 || p.InProgress > 0:
+			status = StatusInProgress
+		}
+		if status != g.Status {
+			g.Status, g.UpdatedAt = status, now
+		}
+	}
+
+	for i := range s.Milestones {
+		m := &s.Milestones[i]
+		done := len(m.Goals) > 0 && !slices.ContainsFunc(m.Goals, func(id string) bool {
+			g := s.goal(id)
+			return g == nil || g.Status != StatusCompleted
+		})
+		switch {
+		case done && m.CompletedAt == nil:
+			m.CompletedAt = &now
+		case !done:
+			m.CompletedAt = nil
+		}
+	}
+}
+
+// load reads roadmap.json. Without one, the goals of an existing roadmap.md
+// are imported; when roadmap.md was edited since it was written, the edits
+// are applied. Task links are then resolved to task IDs and statuses
+// derived from task.md, and roadmap.md is rewritten when any of this
+// changed it. Callers must hold r.mu.
+func (r *Roadmap) load() (*store, error) {
+	s := &store{NextGoal: 1, NextMilestone: 1}
+	data, err := os.ReadFile(r.storePath)
+	exists := err == nil
+	switch {
+	case exists:
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse roadmap.json: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read roadmap.json: %w", err)
+	}
+
+	view, err := os.ReadFile(r.roadmapPath)
+	switch {
+	case err == nil:
+		if !exists || utils.HashContent(view) != s.ViewHash {
+			s.applyView(parseMarkdown(string(view)))
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read roadmap.md: %w", err)
+	case !exists:
+		return s, nil
+	}
+
+	if s.tasks, err = r.tasks.All(); err != nil {
+		return nil, err
+	}
+	if err := r.resolveAll(s); err != nil {
+		return nil, err
+	}
+	s.derive()
+	if utils.HashContent([]byte(renderMarkdown(s))) != s.ViewHash {
+		if err := r.save(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// applyView merges goals and milestones parsed from roadmap.md into the
+// store: unknown ones are added, known ones updated. Ones missing from
+// roadmap.md are kept.
+func (s *store) applyView(v view) {
+	if v.Vision != "" {
+		s.Vision = v.Vision
+	}
+	for _, p := range v.Goals {
+		g := s.goal(p.ID)
+		if g == nil {
+			s.addGoal(p)
+			continue
+		}
+		if !sameGoal(*g, p) {
+			g.Title, g.Description, g.Status, g.Priority = p.Title, p.Description, p.Status, p.Priority
+			g.DueDate, g.Dependencies, g.Tags, g.EstimateHours, g.Tasks = p.DueDate, p.Dependencies, p.Tags, p.EstimateHours, p.Tasks
+			g.UpdatedAt = time.Now()
+		}
+	}
+	// Dependencies written by hand may be unknown
+	for i := range s.Goals {
+		g := &s.Goals[i]
+		g.Dependencies = slices.DeleteFunc(g.Dependencies, func(dep string) bool { return s.goal(dep) == nil })
+	}
+	for _, p := range v.Milestones {
+		m := s.milestone(p.ID)
+		if m == nil {
+			s.addMilestone(p)
+			continue
+		}
+		m.Title, m.Description, m.Goals, m.TargetDate = p.Title, p.Description, p.Goals, p.TargetDate
+	}
+}
+
+// sameGoal compares the fields roadmap.md shows.
+func sameGoal(a, b Goal) bool {
+	return a.Title == b.Title && strings.TrimSpace(a.Description) == b.Description && a.Status == b.Status &&
+		a.Priority == b.Priority && formatDate(a.DueDate) == formatDate(b.DueDate) &&
+		slices.Equal(a.Dependencies, b.Dependencies) && slices.Equal(a.Tags, b.Tags) &&
+		a.EstimateHours == b.EstimateHours && slices.Equal(a.Tasks, b.Tasks)
+}
+
+// save writes roadmap.json and regenerates roadmap.md. Callers must hold r.mu.
+func (r *Roadmap) save(s *store) error {
+	if err := os.MkdirAll(filepath.Dir(r.storePath), 0755); err != nil {
+		return fmt.Errorf("failed to create roadmap directory: %w", err)
+	}
+
+	s.Updated = time.Now()
+	view := []byte(renderMarkdown(s))
+	s.ViewHash = utils.HashContent(view)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal roadmap: %w", err)
+	}
+	if err := utils.WriteFileAtomic(r.storePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write roadmap.json: %w", err)
+	}
+	if err := utils.WriteFileAtomic(r.roadmapPath, view, 0644); err != nil {
+		return fmt.Errorf("failed to write roadmap.md: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package roadmap
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"ClosedWheeler/pkg/tasks"
+)
+
+// taskIDPattern matches the IDs the task list assigns.
+var taskIDPattern = regexp.MustCompile(`^t\d+$`)
+
+// findTask returns the task a goal links to: the one with the ID, else the
+// one with the same title, else the first whose title contains the link,
+// ignoring case.
+func findTask(list []tasks.Task, link string) *tasks.Task {
+	for i := range list {
+		if list[i].ID == link {
+			return &list[i]
+		}
+	}
+	if taskIDPattern.MatchString(link) {
+		return nil
+	}
+	for i := range list {
+		if strings.EqualFold(list[i].Title, link) {
+			return &list[i]
+		}
+	}
+	lower := strings.ToLower(link)
+	for i := range list {
+		if strings.Contains(strings.ToLower(list[i].Title), lower) {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+// resolveTasks turns task links into task IDs. Links naming a task by its
+// title get that task's ID; titles task.md does not have yet are added as
+// open agent tasks. IDs of deleted tasks are kept as they are.
+func (r *Roadmap) resolveTasks(s *store, links []string) ([]string, error) {
+	var ids []string
+	for _, link := range links {
+		id := link
+		if t := findTask(s.tasks, link); t != nil {
+			id = t.ID
+		} else if !taskIDPattern.MatchString(link) {
+			added, err := r.tasks.Add(tasks.Task{Title: link, Assignee: tasks.AssigneeAgent})
+			if err != nil {
+				return nil, err
+			}
+			s.tasks = append(s.tasks, added)
+			id = added.ID
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// resolveAll resolves the task links of every goal, such as those written
+// into roadmap.md by hand or stored before tasks had IDs.
+func (r *Roadmap) resolveAll(s *store) error {
+	for i := range s.Goals {
+		ids, err := r.resolveTasks(s, s.Goals[i].Tasks)
+		if err != nil {
+			return err
+		}
+		s.Goals[i].Tasks = ids
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package roadmap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/tasks"
+)
+
+// Section headings of roadmap.md.
+const (
+	visionSection     = "## 🎯 Vision"
+	nextSection       = "## 🧭 Next Up"
+	milestoneSection  = "## 🏆 Milestones"
+	objectiveSection  = "## 📊 Strategic Objectives"
+	completedSection  = "## ✅ Completed"
+	blockedSection    = "## 🚫 Blocked"
+	highPrioritySub   = "### 🔴 High Priority"
+	mediumPrioritySub = "### 🟡 Medium Priority"
+	lowPrioritySub    = "### 🟢 Low Priority"
+)
+
+// view is what roadmap.md holds besides the derived sections.
+type view struct {
+	Vision     string
+	Goals      []Goal
+	Milestones []Milestone
+}
+
+// renderMarkdown renders the roadmap as roadmap.md.
+func renderMarkdown(s *store) string {
+	states := s.states()
+	byID := make(map[string]GoalState, len(states))
+	for _, g := range states {
+		byID[g.ID] = g
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`# 🗺️ Strategic Roadmap
+
+Strategic long-term planning for the project.
+
+> Generated from roadmap.json and the task.md items linked to each goal. Goals and milestones added here by hand are imported, and edits to existing ones are kept.
+
+`)
+	// Every block ends with a blank line
+	section := func(heading, comment string) {
+		sb.WriteString(heading + "\n\n<!-- " + comment + " -->\n\n")
+	}
+
+	section(visionSection, "Describe the long-term vision of the project")
+	if s.Vision != "" {
+		sb.WriteString(s.Vision + "\n\n")
+	}
+
+	section(nextSection, "Goals ready to work on, and the longest chain of remaining work")
+	next := actionable(states)
+	for i, g := range next {
+		if i == 5 {
+			sb.WriteString(fmt.Sprintf("- … %d more\n", len(next)-5))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%d. `%s` %s — %s priority%s\n", i+1, g.ID, g.Title, g.Priority, progressNote(g)))
+	}
+	if len(next) > 0 {
+		sb.WriteString("\n")
+	}
+	if path, hours := criticalPath(states); len(path) > 0 {
+		sb.WriteString(fmt.Sprintf("**Critical path:** %s (%s remaining)\n\n", quotedPath(path), formatHours(hours)))
+	}
+
+	section(milestoneSection, "Important milestones and their goals")
+	for _, m := range s.Milestones {
+		sb.WriteString(formatMilestone(m, byID) + "\n\n")
+	}
+
+	sb.WriteString(objectiveSection + "\n\n")
+	for _, sub := range []struct{ heading, priority, comment string }{
+		{highPrioritySub, "high", "High priority objectives"},
+		{mediumPrioritySub, "medium", "Medium priority objectives"},
+		{lowPrioritySub, "low", "Low priority objectives"},
+	} {
+		section(sub.heading, sub.comment)
+		for _, g := range states {
+			if g.Priority == sub.priority && g.Status != StatusCompleted && g.Status != StatusBlocked {
+				sb.WriteString(formatGoal(g) + "\n\n")
+			}
+		}
+	}
+
+	for _, sec := range []struct{ heading, status, comment string }{
+		{completedSection, StatusCompleted, "Already completed objectives"},
+		{blockedSection, StatusBlocked, "Blocked objectives and reasons"},
+	} {
+		section(sec.heading, sec.comment)
+		for _, g := range states {
+			if g.Status == sec.status {
+				sb.WriteString(formatGoal(g) + "\n\n")
+			}
+		}
+	}
+
+	updated := s.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	sb.WriteString("---\n\n*Last update: " + updated.Format("2006-01-02 15:04:05") + "*\n")
+	return sb.String()
+}
+
+// formatGoal renders one goal as it appears in roadmap.md.
+func formatGoal(g GoalState) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("#### %s\n", g.Title))
+	sb.WriteString(fmt.Sprintf("*ID: `%s`* | ", g.ID))
+	sb.WriteString(fmt.Sprintf("**Status:** %s | ", g.Status))
+	sb.WriteString(fmt.Sprintf("**Priority:** %s | ", g.Priority))
+	sb.WriteString(fmt.Sprintf("**Created:** %s", g.CreatedAt.Format("2006-01-02")))
+	if g.Description != "" {
+		sb.WriteString("\n\n" + g.Description)
+	}
+
+	var facts []string
+	if g.Progress.Total > 0 {
+		facts = append(facts, fmt.Sprintf("**Progress:** %d/%d tasks (%d%%)", g.Progress.Done, g.Progress.Total, g.Progress.Percent()))
+	}
+	if g.EstimateHours > 0 {
+		facts = append(facts, "**Estimate:** "+formatHours(g.EstimateHours))
+	}
+	if len(g.BlockedBy) > 0 {
+		facts = append(facts, "**Waiting on:** "+quoteIDs(g.BlockedBy))
+	}
+	if len(facts) > 0 {
+		sb.WriteString("\n\n" + strings.Join(facts, " | "))
+	}
+
+	if g.DueDate != nil {
+		sb.WriteString(fmt.Sprintf("\n\n**Deadline:** %s", formatDate(g.DueDate)))
+	}
+	if len(g.Dependencies) > 0 {
+		sb.WriteString("\n\n**Dependencies:** " + quoteIDs(g.Dependencies))
+	}
+	if len(g.Tasks) > 0 {
+		sb.WriteString("\n\n**Tasks:**")
+		for i, link := range g.Tasks {
+			task := g.LinkedTasks[i]
+			if task.ID != "" {
+				link = fmt.Sprintf("`%s` %s", task.ID, task.Title)
+			}
+			sb.WriteString(fmt.Sprintf("\n- [%s] %s", taskBox(task.Status), link))
+		}
+	}
+	if len(g.Tags) > 0 {
+		sb.WriteString("\n\n**Tags:** " + quoteIDs(g.Tags))
+	}
+
+	return sb.String()
+}
+
+// formatMilestone renders one milestone as it appears in roadmap.md.
+func formatMilestone(m Milestone, goals map[string]GoalState) string {
+	var sb strings.Builder
+
+	done := 0
+	for _, id := range m.Goals {
+		if goals[id].Status == StatusCompleted {
+			done++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("### %s\n", m.Title))
+	sb.WriteString(fmt.Sprintf("*ID: `%s`* | **Progress:** %d/%d objectives", m.ID, done, len(m.Goals)))
+	if m.Description != "" {
+		sb.WriteString("\n\n" + m.Description)
+	}
+
+	if m.TargetDate != nil || m.CompletedAt != nil {
+		var dates []string
+		if m.TargetDate != nil {
+			dates = append(dates, "**Target Date:** "+formatDate(m.TargetDate))
+		}
+		if m.CompletedAt != nil {
+			dates = append(dates, fmt.Sprintf("**Completed on:** %s ✅", formatDate(m.CompletedAt)))
+		}
+		sb.WriteString("\n\n" + strings.Join(dates, " | "))
+	}
+
+	if len(m.Goals) > 0 {
+		sb.WriteString("\n\n**Related Objectives:** " + quoteIDs(m.Goals))
+	}
+
+	return sb.String()
+}
+
+// parseMarkdown reads goals and milestones back from roadmap.md. Goals are
+// "#### " titles under the objective, completed and blocked sections, and
+// milestones "### " titles under the milestones section; ones written by
+// hand have no ID.
+func parseMarkdown(content string) view {
+	var v view
+	var vision []string
+	var goal *Goal
+	var milestone *Milestone
+	var desc []string
+	section, priority := "", ""
+	inTasks := false
+
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(desc, "\n"))
+		switch {
+		case goal != nil:
+			goal.Description = text
+			v.Goals = append(v.Goals, *goal)
+		case milestone != nil:
+			milestone.Description = text
+			v.Milestones = append(v.Milestones, *milestone)
+		}
+		goal, milestone, desc, inTasks = nil, nil, nil, false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			flush()
+			section, priority = trimmed, ""
+			continue
+		case strings.HasPrefix(line, "---"):
+			flush()
+			section = ""
+			continue
+		case strings.HasPrefix(trimmed, "<!--"):
+			continue
+		}
+
+		switch section {
+		case visionSection:
+			vision = append(vision, line)
+			continue
+		case milestoneSection:
+			if strings.HasPrefix(line, "### ") {
+				flush()
+				milestone = &Milestone{Title: strings.TrimSpace(strings.TrimPrefix(line, "### "))}
+				continue
+			}
+		case objectiveSection, completedSection, blockedSection:
+			switch {
+			case strings.HasPrefix(line, "#### "):
+				flush()
+				goal = &Goal{Title: strings.TrimSpace(strings.TrimPrefix(line, "#### ")), Priority: priority}
+				switch section {
+				case completedSection:
+					goal.Status = StatusCompleted
+				case blockedSection:
+					goal.Status = StatusBlocked
+				}
+				continue
+			case strings.HasPrefix(line, "### "):
+				flush()
+				priority = sectionPriority(trimmed)
+				continue
+			}
+		default:
+			continue
+		}
+
+		switch {
+		case goal != nil:
+			parseGoalLine(goal, line, trimmed, &desc, &inTasks)
+		case milestone != nil:
+			parseMilestoneLine(milestone, trimmed, &desc)
+		}
+	}
+	flush()
+
+	v.Vision = strings.TrimSpace(strings.Join(vision, "\n"))
+	for i := range v.Goals {
+		g := &v.Goals[i]
+		g.Status, g.Priority = normalizeStatus(g.Status), normalizePriority(g.Priority)
+	}
+	return v
+}
+
+func parseGoalLine(g *Goal, line, trimmed string, desc *[]string, inTasks *bool) {
+	if *inTasks {
+		if link, ok := parseTaskLink(trimmed); ok {
+			g.Tasks = append(g.Tasks, link)
+			return
+		}
+		*inTasks = false
+	}
+	switch {
+	case strings.HasPrefix(trimmed, "*ID:"):
+		for _, field := range strings.Split(trimmed, " | ") {
+			name, value, _ := strings.Cut(strings.TrimSpace(field), ":")
+			value = strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "**")), "*`")
+			switch strings.Trim(name, "*") {
+			case "ID":
+				g.ID = value
+			case "Status":
+				g.Status = value
+			case "Priority":
+				g.Priority = value
+			case "Created":
+				if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+					g.CreatedAt = t
+				}
+			}
+		}
+	case strings.HasPrefix(trimmed, "**Progress:**"), strings.HasPrefix(trimmed, "**Estimate:**"), strings.HasPrefix(trimmed, "**Waiting on:**"):
+		if _, est, ok := strings.Cut(trimmed, "**Estimate:** "); ok {
+			est, _, _ = strings.Cut(est, " ")
+			if h, err := strconv.ParseFloat(strings.TrimSuffix(est, "h"), 64); err == nil {
+				g.EstimateHours = h
+			}
+		}
+	case strings.HasPrefix(trimmed, "**Deadline:**"):
+		if t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(strings.TrimPrefix(trimmed, "**Deadline:**")), time.Local); err == nil {
+			g.DueDate = &t
+		}
+	case strings.HasPrefix(trimmed, "**Dependencies:**"):
+		g.Dependencies = parseIDs(strings.TrimPrefix(trimmed, "**Dependencies:**"))
+	case strings.HasPrefix(trimmed, "**Tags:**"):
+		g.Tags = parseIDs(strings.TrimPrefix(trimmed, "**Tags:**"))
+	case trimmed == "**Tasks:**":
+		*inTasks = true
+	case strings.HasPrefix(trimmed, "**Updated:**"):
+	default:
+		*desc = append(*desc, line)
+	}
+}
+
+func parseMilestoneLine(m *Milestone, trimmed string, desc *[]string) {
+	switch {
+	case strings.HasPrefix(trimmed, "*ID:"):
+		id, _, _ := strings.Cut(strings.TrimPrefix(trimmed, "*ID:"), "*")
+		m.ID = strings.Trim(strings.TrimSpace(id), "`")
+	case strings.HasPrefix(trimmed, "**Target Date:**"), strings.HasPrefix(trimmed, "**Completed on:**"):
+		if _, date, ok := strings.Cut(trimmed, "**Target Date:** "); ok {
+			date, _, _ = strings.Cut(date, " ")
+			if t, err := time.ParseInLocation("2006-01-02", date, time.Local); err == nil {
+				m.TargetDate = &t
+			}
+		}
+	case strings.HasPrefix(trimmed, "**Related Objectives:**"):
+		m.Goals = parseIDs(strings.TrimPrefix(trimmed, "**Related Objectives:**"))
+	default:
+		*desc = append(*desc, trimmed)
+	}
+}
+
+// parseIDs splits a "`a`, `b`" list.
+func parseIDs(list string) []string {
+	var ids []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.Trim(strings.TrimSpace(id), "`"); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func quoteIDs(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = "`" + id + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func quotedPath(path []GoalState) string {
+	ids := make([]string, len(path))
+	for i, g := range path {
+		ids[i] = "`" + g.ID + "`"
+	}
+	return strings.Join(ids, " → ")
+}
+
+// progressNote describes the task progress of a goal for the Next Up list.
+func progressNote(g GoalState) string {
+	if g.Progress.Total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d/%d tasks", g.Progress.Done, g.Progress.Total)
+}
+
+// taskBox returns the task.md checkbox mark of a task status.
+func taskBox(status string) string {
+	switch status {
+	case tasks.StatusDone:
+		return "x"
+	case tasks.StatusInProgress:
+		return "/"
+	default:
+		return " "
+	}
+}
+
+// parseTaskLink parses a "- [ ] `t3` title" line of a goal's task list,
+// returning the task ID, or the text when the line has none.
+func parseTaskLink(line string) (string, bool) {
+	if !strings.HasPrefix(line, "- [") && !strings.HasPrefix(line, "* [") {
+		return "", false
+	}
+	if len(line) < 6 || line[4] != ']' {
+		return "", false
+	}
+	text := strings.TrimSpace(line[5:])
+	if rest, ok := strings.CutPrefix(text, "`"); ok {
+		if id, _, ok := strings.Cut(rest, "`"); ok && taskIDPattern.MatchString(id) {
+			return id, true
+		}
+	}
+	return text, text != ""
+}
+
+func sectionPriority(heading string) string {
+	switch heading {
+	case highPrioritySub:
+		return "high"
+	case lowPrioritySub:
+		return "low"
+	default:
+		return "medium"
+	}
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
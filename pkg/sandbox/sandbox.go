@@ -0,0 +1,146 @@
+// Package sandbox runs commands isolated from the host. On Linux a sandboxed
+// command gets its own user, mount, PID and network namespaces: the file
+// system is read-only except the writable directories and a private /tmp,
+// the network is unreachable unless allowed, CPU time, memory and processes
+// are limited and the environment is scrubbed.
+package sandbox
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"ClosedWheeler/pkg/config"
+)
+
+// Defaults for the limits the config leaves at zero.
+const (
+	DefaultCPUSeconds   = 300
+	DefaultMemoryMB     = 4096
+	DefaultMaxProcesses = 256
+)
+
+// initArg is argv[0] of the sandbox's init process, and specEnv the
+// variable that passes it the spec. The init is this executable started
+// again; Init recognizes it.
+const (
+	initArg = "agi-sandbox-init"
+	specEnv = "_AGI_SANDBOX_SPEC"
+)
+
+// keptEnv are the variables a sandboxed command inherits besides those the
+// config names.
+var keptEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "LANGUAGE", "LC_ALL", "LC_CTYPE", "TERM", "TZ"}
+
+// Sandbox starts commands in the sandbox when the config enables it. A nil
+// Sandbox, or one that is disabled, starts them directly with the full
+// environment.
+type Sandbox struct {
+	mu       sync.Mutex
+	cfg      config.SandboxConfig
+	writable []string
+	readOnly []string
+}
+
+// spec is what the init process sets up before running the command.
+type spec struct {
+	Writable     []string `json:"writable"`
+	ReadOnly     []string `json:"read_only,omitempty"`
+	Network      bool     `json:"network,omitempty"`
+	CPUSeconds   int      `json:"cpu_seconds"`
+	MemoryMB     int      `json:"memory_mb"`
+	MaxProcesses int      `json:"max_processes"`
+}
+
+// New creates a sandbox in which the workplace is writable.
+func New(cfg config.SandboxConfig, workplace string) *Sandbox {
+	return &Sandbox{cfg: cfg, writable: []string{workplace}}
+}
+
+// ReadOnly keeps paths inside the writable directories read-only, such as a
+// policy file in the workplace. Paths that do not exist when a command
+// starts are skipped.
+func (s *Sandbox) ReadOnly(paths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly = append(s.readOnly, paths...)
+}
+
+// Configure replaces the sandbox settings, e.g. after a config reload.
+func (s *Sandbox) Configure(cfg config.SandboxConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+}
+
+// Enabled reports whether commands run sandboxed.
+func (s *Sandbox) Enabled() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg.Enabled
+}
+
+// Command returns a command that runs name with args, in the sandbox when
+// it is enabled. Set Dir, Stdout and Stderr on it as with exec.Command. It
+// fails when the sandbox is enabled but cannot be used on this system.
+func (s *Sandbox) Command(name string, args ...string) (*exec.Cmd, error) {
+	if !s.Enabled() {
+		cmd := exec.Command(name, args...)
+		cmd.Env = os.Environ()
+		return cmd, nil
+	}
+
+	s.mu.Lock()
+	sp := spec{
+		Writable:     absPaths(append(s.writable, s.cfg.WritablePaths...)),
+		ReadOnly:     absPaths(s.readOnly),
+		Network:      s.cfg.AllowNetwork,
+		CPUSeconds:   orDefault(s.cfg.CPUSeconds, DefaultCPUSeconds),
+		MemoryMB:     orDefault(s.cfg.MemoryMB, DefaultMemoryMB),
+		MaxProcesses: orDefault(s.cfg.MaxProcesses, DefaultMaxProcesses),
+	}
+	env := environ(s.cfg.Env)
+	s.mu.Unlock()
+
+	data, err := json.Marshal(sp)
+	if err != nil {
+		return nil, err
+	}
+	return command(sp, append(env, specEnv+"="+string(data)), name, args)
+}
+
+// environ returns the scrubbed environment of a sandboxed command: the kept
+// variables, those named in extra, and temporary and cache directories in
+// the private /tmp.
+func environ(extra []string) []string {
+	env := []string{"TMPDIR=/tmp", "XDG_CACHE_HOME=/tmp/.cache"}
+	for _, name := range append(append([]string(nil), keptEnv...), extra...) {
+		if value, ok := os.LookupEnv(name); ok && !strings.ContainsRune(name, '=') {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+func absPaths(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			out = append(out, abs)
+		}
+	}
+	return out
+}
+
+func orDefault(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,267 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// nobody is the user a sandboxed command runs as when the agent runs as
+// root, so that it has no capabilities inside the sandbox either.
+const nobody = 65534
+
+// lockedFlags are the mount flags a user namespace may not clear, which a
+// remount must therefore repeat.
+const lockedFlags = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME
+
+// command starts this executable as the sandbox's init in new namespaces.
+// The init keeps CAP_SYS_ADMIN and CAP_NET_ADMIN as ambient capabilities
+// to set up the mounts and loopback; the command it runs has none.
+func command(sp spec, env []string, name string, args []string) (*exec.Cmd, error) {
+	cmd := exec.Command("/proc/self/exe", append([]string{name}, args...)...)
+	cmd.Args[0] = initArg
+	cmd.Env = env
+
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID)
+	if !sp.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	inner := uid
+	if inner == 0 {
+		inner = nobody
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:                 flags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: inner, HostID: uid, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: inner, HostID: gid, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		AmbientCaps:                []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_NET_ADMIN},
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+	return cmd, nil
+}
+
+// Init sets up the sandbox and runs the command when this process was
+// started as a sandbox's init, and does not return then. Call it first
+// thing in main, and in TestMain of tests that run sandboxed commands.
+func Init() {
+	if len(os.Args) < 2 || os.Args[0] != initArg {
+		return
+	}
+	data, ok := os.LookupEnv(specEnv)
+	if !ok {
+		return
+	}
+	os.Unsetenv(specEnv)
+
+	var sp spec
+	if err := json.Unmarshal([]byte(data), &sp); err != nil {
+		fail(126, fmt.Errorf("invalid spec: %w", err))
+	}
+	if err := setup(sp); err != nil {
+		fail(126, err)
+	}
+
+	path, err := exec.LookPath(os.Args[1])
+	if err != nil {
+		fail(127, err)
+	}
+	// Drop the capabilities the setup needed before running the command
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		fail(126, fmt.Errorf("failed to drop capabilities: %w", err))
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		fail(126, fmt.Errorf("failed to set no_new_privs: %w", err))
+	}
+	err = unix.Exec(path, os.Args[1:], os.Environ())
+	fail(126, err)
+}
+
+func fail(code int, err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(code)
+}
+
+// setup builds the sandbox inside the new namespaces.
+func setup(sp spec) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := setupMounts(sp); err != nil {
+		return err
+	}
+	// The working directory may now be hidden by a mount over it
+	if err := os.Chdir(wd); err != nil {
+		return err
+	}
+	if !sp.Network {
+		if err := loopbackUp(); err != nil {
+			return fmt.Errorf("failed to bring up loopback: %w", err)
+		}
+	}
+
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{unix.RLIMIT_CPU, uint64(sp.CPUSeconds)},
+		{unix.RLIMIT_AS, uint64(sp.MemoryMB) << 20},
+		{unix.RLIMIT_NPROC, uint64(sp.MaxProcesses)},
+	}
+	for _, l := range limits {
+		if err := unix.Setrlimit(l.resource, &unix.Rlimit{Cur: l.value, Max: l.value}); err != nil {
+			return fmt.Errorf("failed to set resource limit %d: %w", l.resource, err)
+		}
+	}
+	return nil
+}
+
+// setupMounts makes every mount read-only, puts a private tmpfs on /tmp,
+// binds the writable directories back read-write and the read-only paths
+// over them, and mounts a /proc for the new PID namespace.
+func setupMounts(sp spec) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	// Open the writable directories first; /tmp is replaced below and
+	// they may lie in it
+	type bind struct {
+		path  string
+		fd    int
+		flags uintptr
+	}
+	var binds []bind
+	for _, p := range sp.Writable {
+		fd, err := unix.Open(p, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			continue // nothing to make writable
+		}
+		var st unix.Statfs_t
+		if err := unix.Fstatfs(fd, &st); err != nil {
+			return fmt.Errorf("failed to stat %s: %w", p, err)
+		}
+		binds = append(binds, bind{path: p, fd: fd, flags: uintptr(st.Flags) & lockedFlags})
+	}
+
+	points, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	for _, p := range points {
+		var st unix.Statfs_t
+		if err := unix.Statfs(p, &st); err != nil {
+			continue // hidden under another mount
+		}
+		flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | uintptr(st.Flags)&lockedFlags
+		if err := unix.Mount("", p, "", flags, ""); err != nil {
+			if strings.HasPrefix(p, "/proc/") || strings.HasPrefix(p, "/sys/") {
+				continue // kernel interfaces check their own permissions
+			}
+			return fmt.Errorf("failed to make %s read-only: %w", p, err)
+		}
+	}
+
+	if err := unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("failed to mount /tmp: %w", err)
+	}
+
+	for _, b := range binds {
+		if err := os.MkdirAll(b.path, 0755); err != nil {
+			return err
+		}
+		source := "/proc/self/fd/" + strconv.Itoa(b.fd)
+		if err := unix.Mount(source, b.path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", b.path, err)
+		}
+		if err := unix.Mount("", b.path, "", unix.MS_BIND|unix.MS_REMOUNT|b.flags, ""); err != nil {
+			return fmt.Errorf("failed to make %s writable: %w", b.path, err)
+		}
+		unix.Close(b.fd)
+	}
+
+	for _, p := range sp.ReadOnly {
+		var st unix.Statfs_t
+		if err := unix.Statfs(p, &st); err != nil {
+			continue
+		}
+		if err := unix.Mount(p, p, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", p, err)
+		}
+		flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | uintptr(st.Flags)&lockedFlags
+		if err := unix.Mount("", p, "", flags, ""); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", p, err)
+		}
+	}
+
+	// Without a fresh /proc the command still sees the host's processes,
+	// but cannot signal them from the new PID namespace
+	_ = unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	return nil
+}
+
+// mountPoints lists the mount points of this mount namespace.
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mounts: %w", err)
+	}
+	defer f.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		points = append(points, unescapeMount(fields[4]))
+	}
+	return points, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes (\040 for a space) of a path in
+// /proc/self/mountinfo.
+func unescapeMount(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// loopbackUp brings up lo in the new network namespace, so servers the
+// command starts on localhost are reachable.
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return err
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
@@ -0,0 +1,114 @@
+package sandbox
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/config"
+)
+
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+// run runs a shell command and returns its combined output.
+func run(t *testing.T, sb *Sandbox, dir, script string) (string, error) {
+	t.Helper()
+	cmd, err := sb.Command("sh", "-c", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err = cmd.Run()
+	return out.String(), err
+}
+
+func newSandbox(t *testing.T, cfg config.SandboxConfig) (*Sandbox, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	workplace, outside := filepath.Join(dir, "workplace"), filepath.Join(dir, "outside")
+	for _, d := range []string{workplace, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.Enabled = true
+	sb := New(cfg, workplace)
+	if out, err := run(t, sb, workplace, "true"); err != nil {
+		t.Skipf("sandbox unavailable here: %v %s", err, out)
+	}
+	return sb, workplace, outside
+}
+
+func TestSandbox_FileSystemAndEnvironment(t *testing.T) {
+	t.Setenv("AGI_TEST_SECRET", "hunter2")
+	t.Setenv("AGI_TEST_PASSED", "yes")
+	sb, workplace, outside := newSandbox(t, config.SandboxConfig{Env: []string{"AGI_TEST_PASSED"}})
+	policy := filepath.Join(workplace, ".agi")
+	if err := os.Mkdir(policy, 0755); err != nil {
+		t.Fatal(err)
+	}
+	sb.ReadOnly(policy)
+
+	out, err := run(t, sb, workplace, `echo made > made.txt && echo tmp > /tmp/x && cat /tmp/x &&
+		echo "secret=$AGI_TEST_SECRET passed=$AGI_TEST_PASSED" &&
+		(echo bad > `+outside+`/escaped.txt || echo outside-blocked) &&
+		(echo bad > .agi/policy.rules || echo policy-blocked)`)
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{"tmp\n", "secret= passed=yes", "outside-blocked", "policy-blocked"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(workplace, "made.txt")); err != nil || string(data) != "made\n" {
+		t.Errorf("workplace write: %q, %v", data, err)
+	}
+	for _, p := range []string{filepath.Join(outside, "escaped.txt"), filepath.Join(policy, "policy.rules")} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s was written from the sandbox", p)
+		}
+	}
+}
+
+func TestSandbox_NetworkAndLimits(t *testing.T) {
+	sb, workplace, _ := newSandbox(t, config.SandboxConfig{MaxProcesses: 64, CPUSeconds: 30})
+
+	host, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// A network namespace of its own with only loopback (setup fails unless
+	// it comes up), no capabilities and the limits
+	out, err := run(t, sb, workplace, `readlink /proc/self/ns/net; grep -c : /proc/net/dev; grep CapEff /proc/self/status; grep -E "Max (cpu time|processes)" /proc/self/limits`)
+	if err != nil {
+		t.Fatalf("sandboxed command failed: %v\n%s", err, out)
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) < 5 || lines[0] == host || lines[1] != "1" || !strings.HasSuffix(lines[2], "0000000000000000") ||
+		!strings.Contains(lines[3], "30") || !strings.Contains(lines[4], "64") {
+		t.Errorf("unexpected namespace, capabilities or limits:\n%s", out)
+	}
+
+	sb.Configure(config.SandboxConfig{Enabled: true, AllowNetwork: true})
+	if out, err := run(t, sb, workplace, `readlink /proc/self/ns/net`); err != nil || strings.TrimSpace(out) != host {
+		t.Errorf("allow_network should keep the host network: %v\n%s", err, out)
+	}
+}
+
+func TestSandbox_Disabled(t *testing.T) {
+	t.Setenv("AGI_TEST_SECRET", "hunter2")
+	var sb *Sandbox
+	out, err := run(t, sb, t.TempDir(), `echo "$AGI_TEST_SECRET"`)
+	if err != nil || strings.TrimSpace(out) != "hunter2" {
+		t.Errorf("unsandboxed command: %q, %v", out, err)
+	}
+}
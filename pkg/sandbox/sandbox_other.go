@@ -0,0 +1,16 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func command(spec, []string, string, []string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("sandbox is not supported on %s; disable sandbox.enabled to run commands", runtime.GOOS)
+}
+
+// Init does nothing; the sandbox runs only on Linux.
+func Init() {}
@@ -0,0 +1,324 @@
+// Package search provides an incremental trigram index for searching the
+// workplace.
+package search
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	projectcontext "ClosedWheeler/pkg/context"
+	"ClosedWheeler/pkg/ignore"
+	"ClosedWheeler/pkg/utils"
+)
+
+const (
+	// indexVersion changes whenever the persisted format does; an index
+	// written by another version is rebuilt from scratch.
+	indexVersion = 1
+
+	// indexFileName is the file the index is persisted to inside its dir.
+	indexFileName = "trigrams.gob"
+
+	// maxIndexedFileSize skips generated or data files too large to be
+	// useful search results.
+	maxIndexedFileSize = 1024 * 1024
+)
+
+// Symbol is a declaration found in an indexed file.
+type Symbol struct {
+	Name      string
+	Kind      string // function, method, struct, class, interface...
+	Receiver  string // type a method belongs to
+	Line      int
+	EndLine   int
+	Signature string
+}
+
+// fileEntry is what the index remembers about one file. The file is re-read
+// when its modification time or size changes.
+type fileEntry struct {
+	ModTime int64
+	Size    int64
+	Grams   []uint32 // sorted, lower-cased trigrams of the file's lines
+	Symbols []Symbol
+}
+
+// persistedIndex is the on-disk form of an Index.
+type persistedIndex struct {
+	Version int
+	Root    string
+	Files   map[string]*fileEntry
+}
+
+// Index is a trigram index of the text files under a root directory. Each
+// search first refreshes it from file modification times, so only files
+// changed since the last search are read again.
+type Index struct {
+	root   string
+	dir    string
+	ignore *ignore.Patterns
+
+	mu       sync.Mutex
+	loaded   bool
+	files    map[string]*fileEntry // by slash-separated path relative to root
+	postings map[uint32][]string   // trigram -> paths; nil until needed
+}
+
+// NewIndex creates an index of root persisted in dir. Paths matched by
+// patterns are not indexed; patterns may be nil. Nothing is read until the
+// first search.
+func NewIndex(root, dir string, patterns *ignore.Patterns) *Index {
+	absRoot, _ := filepath.Abs(root)
+	if patterns == nil {
+		patterns = &ignore.Patterns{}
+	}
+	return &Index{
+		root:   absRoot,
+		dir:    dir,
+		ignore: patterns,
+		files:  make(map[string]*fileEntry),
+	}
+}
+
+// Root returns the indexed directory.
+func (x *Index) Root() string {
+	return x.root
+}
+
+// Refresh brings the index up to date with the files on disk and saves it
+// when anything changed. It returns the number of files added, updated or
+// removed.
+func (x *Index) Refresh() (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.refresh()
+}
+
+// refresh implements Refresh. Callers hold x.mu.
+func (x *Index) refresh() (int, error) {
+	if !x.loaded {
+		x.load()
+		x.loaded = true
+	}
+
+	absDir, _ := filepath.Abs(x.dir)
+	seen := make(map[string]bool, len(x.files))
+	changed := 0
+
+	err := filepath.WalkDir(x.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(x.root, path)
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path == absDir || x.ignore.ShouldIgnore(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || x.ignore.ShouldIgnore(rel) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxIndexedFileSize {
+			return nil
+		}
+
+		if e, ok := x.files[rel]; ok && e.ModTime == info.ModTime().UnixNano() && e.Size == info.Size() {
+			seen[rel] = true
+			return nil
+		}
+		e, ok := indexFile(path, info)
+		if !ok {
+			// Binary or unreadable: forget any older version
+			if _, had := x.files[rel]; had {
+				delete(x.files, rel)
+				changed++
+			}
+			return nil
+		}
+		x.files[rel] = e
+		seen[rel] = true
+		changed++
+		return nil
+	})
+	if err != nil {
+		return changed, err
+	}
+
+	for rel := range x.files {
+		if !seen[rel] {
+			delete(x.files, rel)
+			changed++
+		}
+	}
+
+	if changed > 0 {
+		x.postings = nil
+		if err := x.save(); err != nil {
+			return changed, fmt.Errorf("failed to save search index: %w", err)
+		}
+	}
+	return changed, nil
+}
+
+// indexFile reads and indexes one file. It reports false for binary files
+// and files that cannot be read.
+func indexFile(path string, info fs.FileInfo) (*fileEntry, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || isBinary(data) {
+		return nil, false
+	}
+	content := string(data)
+	e := &fileEntry{
+		ModTime: info.ModTime().UnixNano(),
+		Size:    info.Size(),
+		Grams:   trigrams(content),
+	}
+
+	if outline, ok := projectcontext.OutlineFile(path, content); ok {
+		for _, fn := range outline.Functions {
+			kind := "function"
+			if fn.Receiver != "" {
+				kind = "method"
+			}
+			e.Symbols = append(e.Symbols, Symbol{
+				Name:      fn.Name,
+				Kind:      kind,
+				Receiver:  fn.Receiver,
+				Line:      fn.StartLine,
+				EndLine:   fn.EndLine,
+				Signature: fn.Signature,
+			})
+		}
+		for _, t := range outline.Types {
+			e.Symbols = append(e.Symbols, Symbol{
+				Name:    t.Name,
+				Kind:    t.Kind,
+				Line:    t.StartLine,
+				EndLine: t.EndLine,
+			})
+		}
+	}
+	return e, true
+}
+
+// isBinary guesses whether data is binary from a NUL byte near the start.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// trigrams returns the sorted, distinct lower-cased byte trigrams of s that
+// do not span a line break.
+func trigrams(s string) []uint32 {
+	s = strings.ToLower(s)
+	set := make(map[uint32]struct{})
+	for i := 0; i+3 <= len(s); i++ {
+		if s[i] == '\n' || s[i+1] == '\n' || s[i+2] == '\n' {
+			continue
+		}
+		set[uint32(s[i])<<16|uint32(s[i+1])<<8|uint32(s[i+2])] = struct{}{}
+	}
+	grams := make([]uint32, 0, len(set))
+	for g := range set {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool { return grams[i] < grams[j] })
+	return grams
+}
+
+// candidates returns the paths that contain every trigram of every literal,
+// or all paths when the literals are too short to narrow the search.
+// Callers hold x.mu.
+func (x *Index) candidates(literals []string) []string {
+	var grams []uint32
+	for _, lit := range literals {
+		grams = append(grams, trigrams(lit)...)
+	}
+
+	var paths []string
+	if len(grams) == 0 {
+		for rel := range x.files {
+			paths = append(paths, rel)
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	if x.postings == nil {
+		x.postings = make(map[uint32][]string)
+		for rel, e := range x.files {
+			for _, g := range e.Grams {
+				x.postings[g] = append(x.postings[g], rel)
+			}
+		}
+	}
+
+	// Intersect starting from the rarest trigram
+	sort.Slice(grams, func(i, j int) bool { return len(x.postings[grams[i]]) < len(x.postings[grams[j]]) })
+	set := make(map[string]bool)
+	for _, rel := range x.postings[grams[0]] {
+		set[rel] = true
+	}
+	for _, g := range grams[1:] {
+		if len(set) == 0 {
+			break
+		}
+		next := make(map[string]bool, len(set))
+		for _, rel := range x.postings[g] {
+			if set[rel] {
+				next[rel] = true
+			}
+		}
+		set = next
+	}
+	for rel := range set {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// load reads the persisted index, keeping an empty one when it is missing,
+// unreadable, or was built for another root or version. Callers hold x.mu.
+func (x *Index) load() {
+	f, err := os.Open(filepath.Join(x.dir, indexFileName))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var p persistedIndex
+	if err := gob.NewDecoder(f).Decode(&p); err != nil {
+		return
+	}
+	if p.Version != indexVersion || p.Root != x.root || p.Files == nil {
+		return
+	}
+	x.files = p.Files
+}
+
+// save writes the index to disk atomically. Callers hold x.mu.
+func (x *Index) save() error {
+	if err := os.MkdirAll(x.dir, 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	p := persistedIndex{Version: indexVersion, Root: x.root, Files: x.files}
+	if err := gob.NewEncoder(&buf).Encode(&p); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(x.dir, indexFileName), buf.Bytes(), 0644)
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp/syntax"
+	"strings"
+	"testing"
+	"time"
+
+	"ClosedWheeler/pkg/ignore"
+)
+
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestIndex(t *testing.T) (*Index, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "index")
+	writeFile(t, root, "server/handler.go", `package server
+
+// ServeUser answers user requests.
+func ServeUser() string {
+	return "user"
+}
+
+type UserStore struct{}
+
+func (s *UserStore) Load(id int) {}
+`)
+	writeFile(t, root, "web/app.py", "class UserView:\n    def get(self):\n        return load_user()\n")
+	writeFile(t, root, "README.md", "Users are stored in the UserStore.\n")
+	writeFile(t, root, "node_modules/lib/user.js", "function user() {}\n")
+	writeFile(t, root, "data.bin", "user\x00\x01\x02")
+
+	patterns := &ignore.Patterns{}
+	patterns.Add("node_modules/")
+	return NewIndex(root, dir, patterns), root, dir
+}
+
+func paths(results []Result) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.Path+":"+strings.TrimSpace(r.Text))
+	}
+	return out
+}
+
+func TestSearchTextRanksAndFilters(t *testing.T) {
+	x, _, _ := newTestIndex(t)
+
+	results, total, err := x.Search(Query{Pattern: "userstore", Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("got %d results, want 3: %v", total, paths(results))
+	}
+	// The declaration ranks first
+	if r := results[0]; r.Path != "server/handler.go" || r.Line != 8 || r.Column != 6 {
+		t.Errorf("first result = %+v", r)
+	}
+	if want := []string{""}; !reflect.DeepEqual(results[0].Before, want) {
+		t.Errorf("before = %q, want %q", results[0].Before, want)
+	}
+	if want := []string{""}; !reflect.DeepEqual(results[0].After, want) {
+		t.Errorf("after = %q, want %q", results[0].After, want)
+	}
+
+	results, _, err = x.Search(Query{Pattern: "user", CaseSensitive: true, Glob: "*.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Path != "server/handler.go" {
+			t.Errorf("glob let through %s", r.Path)
+		}
+	}
+
+	results, _, _ = x.Search(Query{Pattern: "function user"})
+	if len(results) != 0 {
+		t.Errorf("ignored directory was searched: %v", paths(results))
+	}
+}
+
+func TestSearchRegex(t *testing.T) {
+	x, _, _ := newTestIndex(t)
+
+	results, _, err := x.Search(Query{Pattern: `func \(s \*\w+\) Lo+ad`, Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Line != 10 {
+		t.Errorf("results = %v", paths(results))
+	}
+
+	if _, _, err := x.Search(Query{Pattern: "(", Regex: true}); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestSearchSymbols(t *testing.T) {
+	x, _, _ := newTestIndex(t)
+
+	results, _, err := x.Search(Query{Pattern: "user", Symbols: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Symbol.Name)
+	}
+	// Methods match through their receiver, after direct name matches
+	want := []string{"UserStore", "UserView", "ServeUser", "Load", "get"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("symbols = %v, want %v", names, want)
+	}
+
+	results, _, _ = x.Search(Query{Pattern: "UserStore.Load", Symbols: true})
+	if len(results) != 1 || results[0].Symbol.Kind != "method" || results[0].Text != "func (s *UserStore) Load(id int) {}" {
+		t.Errorf("method lookup = %+v", results)
+	}
+}
+
+func TestIndexRefreshesAndPersists(t *testing.T) {
+	x, root, dir := newTestIndex(t)
+
+	changed, err := x.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("first refresh indexed %d files, want 3", changed)
+	}
+	if changed, _ := x.Refresh(); changed != 0 {
+		t.Errorf("unchanged tree reindexed %d files", changed)
+	}
+
+	// A new index over the same dir starts from the saved state
+	y := NewIndex(root, dir, x.ignore)
+	if changed, _ := y.Refresh(); changed != 0 {
+		t.Errorf("reloaded index reindexed %d files", changed)
+	}
+
+	writeFile(t, root, "README.md", "Nothing to see.\n")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(root, "README.md"), future, future)
+	os.Remove(filepath.Join(root, "web/app.py"))
+
+	if changed, _ := y.Refresh(); changed != 2 {
+		t.Errorf("refresh after edits changed %d files, want 2", changed)
+	}
+	results, _, _ := y.Search(Query{Pattern: "UserStore"})
+	for _, r := range results {
+		if r.Path == "README.md" {
+			t.Error("stale content matched")
+		}
+	}
+	if results, _, _ := y.Search(Query{Pattern: "nothing to"}); len(results) != 1 {
+		t.Errorf("new content not found: %v", paths(results))
+	}
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`hello`, []string{"hello"}},
+		{`foo\d+bar`, []string{"foo", "bar"}},
+		{`(abc)+x?def`, []string{"abc", "def"}},
+		{`foo|bar`, nil},
+		{`a.*`, []string{"a"}},
+	}
+	for _, tt := range tests {
+		re, err := syntax.Parse(tt.expr, syntax.Perl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := requiredLiterals(re.Simplify()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("requiredLiterals(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
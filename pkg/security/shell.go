@@ -0,0 +1,1003 @@
+package security
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Severity ranks how dangerous a finding of the shell audit is.
+type Severity int
+
+// Severities, from the lowest. AuditCommand refuses commands with a finding
+// of BlockSeverity or above; the permission policy asks about medium ones.
+const (
+	SeverityLow      Severity = iota + 1 // worth knowing, e.g. a script being sourced
+	SeverityMedium                       // effects the audit cannot verify
+	SeverityHigh                         // reaches outside the workplace or the machine
+	SeverityCritical                     // destroys data or the system
+)
+
+// BlockSeverity is the lowest severity AuditCommand refuses to run.
+const BlockSeverity = SeverityHigh
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "none"
+}
+
+// Finding is one problem the shell audit found in a command line.
+type Finding struct {
+	Severity Severity
+	Rule     string // short identifier, e.g. "delete-protected"
+	Command  string // the simple command it concerns
+	Target   string // the path, host or word involved, if any
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+// Analysis is what the shell audit found in a command line.
+type Analysis struct {
+	// Commands are the simple commands the line runs, including those in
+	// pipelines, subshells and substitutions, with quoting resolved and
+	// words only known at runtime kept as written.
+	Commands []string
+	// Runs are the programs actually run with their arguments: wrappers
+	// such as env, nohup and sudo removed, the commands of find -exec
+	// added, and each program reduced to its name, so /usr/bin/git push
+	// is "git push".
+	Runs []string
+	// Writes are the paths the line writes, creates, moves, deletes or
+	// changes permissions of, through redirections or commands such as
+	// tee, cp, mv and sed -i, resolved against the workplace and the cd
+	// commands before them. Paths only known at runtime are reported as
+	// dynamic-target findings instead.
+	Writes   []string
+	Findings []Finding
+}
+
+// Severity returns the highest severity of the findings, or 0 without any.
+func (an Analysis) Severity() Severity {
+	var s Severity
+	for _, f := range an.Findings {
+		s = max(s, f.Severity)
+	}
+	return s
+}
+
+// maxShellDepth limits how deep code passed to sh -c or eval is audited.
+const maxShellDepth = 4
+
+// Programs by what the audit checks for.
+var (
+	shells       = []string{"sh", "bash", "dash", "zsh", "ksh", "mksh", "ash", "fish", "busybox"}
+	interpreters = map[string][]string{
+		"python": {"-c"}, "python2": {"-c"}, "python3": {"-c"}, "perl": {"-e", "-E"},
+		"ruby": {"-e"}, "node": {"-e", "-p", "--eval", "--print"}, "nodejs": {"-e", "-p", "--eval", "--print"},
+		"php": {"-r"},
+	}
+	downloaders  = []string{"curl", "wget", "aria2c", "fetch", "invoke-webrequest", "iwr", "certutil", "bitsadmin"}
+	rawNetwork   = []string{"nc", "ncat", "netcat", "socat", "telnet"}
+	remoteAccess = []string{"ssh", "scp", "sftp", "rsync"}
+	firewalls    = []string{"iptables", "ip6tables", "nft", "ufw", "firewall-cmd", "netsh"}
+	diskTools    = []string{"mkfs", "mke2fs", "mkswap", "fdisk", "sfdisk", "cfdisk", "parted", "wipefs", "format", "diskpart"}
+	powerTools   = []string{"shutdown", "reboot", "halt", "poweroff", "init", "telinit"}
+	services     = []string{"crontab", "systemctl", "service", "launchctl", "schtasks"}
+	winShells    = []string{"powershell", "pwsh", "cmd"}
+	// wrappers run the command that follows their options
+	wrappers = []string{"sudo", "doas", "env", "nohup", "time", "nice", "ionice", "stdbuf", "timeout",
+		"exec", "command", "builtin", "xargs", "watch", "setsid", "strace", "unbuffer", "chronic"}
+	// wrapperValueFlags are the options of wrappers that take a separate value
+	wrapperValueFlags = map[string][]string{
+		"sudo": {"-u", "-g", "-C", "-D", "-h", "-p", "-r", "-t", "-U"}, "doas": {"-u", "-C"},
+		"env": {"-u", "-C", "-S"}, "nice": {"-n"}, "ionice": {"-c", "-n", "-p"}, "timeout": {"-s", "-k"},
+		"xargs": {"-I", "-L", "-P", "-n", "-s", "-E", "-d", "-a"}, "stdbuf": {"-i", "-o", "-e"},
+		"watch": {"-n"}, "strace": {"-o", "-e", "-p", "-s"},
+	}
+)
+
+// codeExec are calls in inline interpreter code that run other programs.
+var codeExec = []string{"os.system", "subprocess", "popen", "system(", "exec(", "eval(", "shell_exec(", "child_process", "spawn("}
+
+// protectedDirs are deleted or re-permissioned only by accident.
+var protectedDirs = []string{"/", "/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib32", "/lib64",
+	"/opt", "/proc", "/root", "/sbin", "/srv", "/sys", "/tmp", "/usr", "/var"}
+
+// sensitiveFiles hold secrets; the value is the severity of naming them.
+var sensitiveFiles = map[string]Severity{
+	"/etc/passwd":  SeverityMedium,
+	"/etc/group":   SeverityMedium,
+	"/etc/shadow":  SeverityHigh,
+	"/etc/gshadow": SeverityHigh,
+	"/etc/sudoers": SeverityHigh,
+}
+
+// credentialPaths are path fragments of credential stores.
+var credentialPaths = []string{"/.ssh/", "/id_rsa", "/id_ed25519", "/id_ecdsa", "/.aws/credentials",
+	"/.netrc", "/.gnupg/", "/.kube/config", "/.docker/config.json", "/.git-credentials"}
+
+// blockDevices are device name prefixes of disks and memory.
+var blockDevices = []string{"/dev/sd", "/dev/hd", "/dev/vd", "/dev/xvd", "/dev/nvme", "/dev/mmcblk", "/dev/mem", "/dev/kmem", "/dev/disk"}
+
+// IsInterpreter reports whether a program runs whatever code or script it
+// is given, as shells, scripting languages and eval do; allowing such a
+// program allows anything.
+func IsInterpreter(program string) bool {
+	name := programName(program)
+	return slices.Contains(shells, name) || slices.Contains(winShells, name) || interpreters[name] != nil ||
+		strings.HasPrefix(name, "python3.") || name == "eval" || name == "source" || name == "."
+}
+
+// AnalyzeCommand parses a command line as a POSIX (bash) shell would and
+// audits every program it runs, through pipelines, lists, subshells,
+// command and process substitutions, and the code given to sh -c and eval.
+// Each program is classified by what it does to its target paths and
+// hosts, and redirections by where they write. A line that does not parse
+// is reported as a high finding, since its effect cannot be known.
+func (a *Auditor) AnalyzeCommand(command string) Analysis {
+	root, err := filepath.Abs(a.projectPath)
+	if err != nil {
+		root = filepath.Clean(a.projectPath)
+	}
+	home, _ := os.UserHomeDir()
+	s := &shellAudit{root: root, dir: root, home: home, inputs: map[*syntax.CallExpr]shellInput{}}
+	s.analyze(command, 0)
+	return s.an
+}
+
+// shellAudit walks the syntax tree of a command line.
+type shellAudit struct {
+	root string // absolute workplace
+	dir  string // working directory after the cd commands so far
+	home string
+	an   Analysis
+
+	inputs map[*syntax.CallExpr]shellInput // standard input of commands that do not inherit it
+	input  shellInput                      // standard input of the command being classified
+}
+
+// shellInput is what a command reads on standard input.
+type shellInput struct {
+	from string    // "", "pipe", "file" or "here-document"
+	body shellWord // the here-string or here-document
+}
+
+// shellWord is a word of a command with its quoting resolved.
+type shellWord struct {
+	value  string // the word as the program receives it, when static
+	raw    string // the word as written
+	static bool   // no expansion other than $HOME changes it at runtime
+	glob   bool   // unquoted pattern characters
+}
+
+// text is the word for display and matching: its value when known.
+func (w shellWord) text() string {
+	if w.static {
+		return w.value
+	}
+	return w.raw
+}
+
+func (s *shellAudit) add(f Finding) {
+	s.an.Findings = append(s.an.Findings, f)
+}
+
+// analyze parses src and audits each node; depth counts the sh -c and eval
+// levels src is nested in.
+func (s *shellAudit) analyze(src string, depth int) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(src), "")
+	if err != nil {
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "unparsable",
+			Command:  src,
+			Message:  fmt.Sprintf("cannot parse the command line: %v", err),
+		})
+		return
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			for _, r := range n.Redirs {
+				s.redirect(src, n, r)
+				if call, ok := n.Cmd.(*syntax.CallExpr); ok {
+					if in, ok := s.stdin(src, r); ok {
+						s.inputs[call] = in
+					}
+				}
+			}
+		case *syntax.CallExpr:
+			if len(n.Args) == 0 {
+				return true // assignments only
+			}
+			words := make([]shellWord, len(n.Args))
+			for i, w := range n.Args {
+				words[i] = s.word(src, w)
+			}
+			text := joinWords(words)
+			s.an.Commands = append(s.an.Commands, text)
+			s.input = s.inputs[n]
+			s.call(text, words, depth)
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				s.pipe(src, n)
+				if call, ok := n.Y.Cmd.(*syntax.CallExpr); ok {
+					s.inputs[call] = shellInput{from: "pipe"}
+				}
+			}
+		case *syntax.FuncDecl:
+			s.funcDecl(src, n)
+		}
+		return true
+	})
+}
+
+// word resolves the quoting of a shell word.
+func (s *shellAudit) word(src string, w *syntax.Word) shellWord {
+	sw := shellWord{raw: src[w.Pos().Offset():w.End().Offset()], static: true}
+	// SplitBraces replaces the parts of the word it is given, and reports
+	// braces such as find's {} that do not expand
+	split := &syntax.Word{Parts: slices.Clone(w.Parts)}
+	if syntax.SplitBraces(split) && slices.ContainsFunc(split.Parts, func(p syntax.WordPart) bool {
+		_, ok := p.(*syntax.BraceExp)
+		return ok
+	}) {
+		sw.static = false // {rm,-rf,/} expands to several words
+		return sw
+	}
+	var b strings.Builder
+	var part func(p syntax.WordPart, quoted bool)
+	part = func(p syntax.WordPart, quoted bool) {
+		switch p := p.(type) {
+		case *syntax.Lit:
+			if !quoted && strings.ContainsAny(p.Value, "*?[") {
+				sw.glob = true
+			}
+			b.WriteString(unescape(p.Value, quoted))
+		case *syntax.SglQuoted:
+			if p.Dollar && strings.Contains(p.Value, `\`) {
+				sw.static = false // $'\x72m' is decoded by the shell
+			}
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, inner := range p.Parts {
+				part(inner, true)
+			}
+		case *syntax.ParamExp:
+			if p.Param != nil && p.Param.Value == "HOME" && s.home != "" && !p.Excl && !p.Length &&
+				p.Index == nil && p.Slice == nil && p.Repl == nil && p.Exp == nil {
+				b.WriteString(s.home)
+				return
+			}
+			sw.static = false
+		default:
+			sw.static = false // substitutions, arithmetic and extended globs
+		}
+	}
+	for i, p := range w.Parts {
+		if lit, ok := p.(*syntax.Lit); ok && i == 0 && s.home != "" &&
+			(lit.Value == "~" || strings.HasPrefix(lit.Value, "~/")) {
+			b.WriteString(s.home)
+			b.WriteString(unescape(lit.Value[1:], false))
+			continue
+		}
+		part(p, false)
+	}
+	sw.value = b.String()
+	return sw
+}
+
+// unescape removes the backslashes the shell removes from a literal, which
+// in double quotes escape only $, `, ", \ and newlines.
+func unescape(s string, quoted bool) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (!quoted || strings.IndexByte("$`\"\\\n", s[i+1]) >= 0) {
+			i++
+			if s[i] != '\n' {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func joinWords(words []shellWord) string {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.text()
+	}
+	return strings.Join(texts, " ")
+}
+
+// programName returns the command name a word invokes, without directory,
+// case or .exe suffix.
+func programName(value string) string {
+	name := path.Base(strings.ReplaceAll(value, `\`, "/"))
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// operands returns the words that are not options.
+func operands(words []shellWord) []shellWord {
+	var out []shellWord
+	for _, w := range words {
+		if w.static && strings.HasPrefix(w.value, "-") && w.value != "-" {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// hasFlag reports whether a short option letter or a long option is among
+// the words.
+func hasFlag(words []shellWord, short string, long ...string) bool {
+	for _, w := range words {
+		v := w.value
+		switch {
+		case !w.static || !strings.HasPrefix(v, "-") || v == "-":
+		case strings.HasPrefix(v, "--"):
+			if slices.Contains(long, v) {
+				return true
+			}
+		case short != "" && strings.ContainsAny(v[1:], short):
+			return true
+		}
+	}
+	return false
+}
+
+// inPlaceFiles returns the files an in-place edit such as sed -i writes: its
+// operands other than the script, which is the first operand unless one of
+// scriptFlags gives it.
+func inPlaceFiles(args []shellWord, scriptFlags ...string) []shellWord {
+	var files []shellWord
+	given := false
+	for i := 0; i < len(args); i++ {
+		w := args[i]
+		switch {
+		case w.static && slices.Contains(scriptFlags, w.value):
+			given = true
+			i++ // the script
+		case w.static && strings.HasPrefix(w.value, "--") && slices.ContainsFunc(scriptFlags, func(f string) bool {
+			return strings.HasPrefix(w.value, f+"=")
+		}):
+			given = true
+		case w.static && strings.HasPrefix(w.value, "-") && w.value != "-":
+		default:
+			files = append(files, w)
+		}
+	}
+	if !given && len(files) > 0 {
+		files = files[1:]
+	}
+	return files
+}
+
+// call classifies a simple command: its program, then its arguments.
+func (s *shellAudit) call(text string, words []shellWord, depth int) {
+	prog := words[0]
+	if !prog.static || prog.glob {
+		s.an.Runs = append(s.an.Runs, joinWords(words))
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "dynamic-command",
+			Command:  text,
+			Target:   prog.raw,
+			Message:  fmt.Sprintf("the program %s is only known at runtime", prog.raw),
+		})
+		return
+	}
+	name, args := programName(prog.value), words[1:]
+	if rest := unwrap(name, args); !slices.Contains(wrappers, name) || len(rest) == 0 {
+		s.an.Runs = append(s.an.Runs, joinWords(append([]shellWord{{value: name, static: true}}, args...)))
+	}
+	finding := func(sev Severity, rule, target, format string, a ...any) {
+		s.add(Finding{Severity: sev, Rule: rule, Command: text, Target: target, Message: fmt.Sprintf(format, a...)})
+	}
+
+	switch {
+	case slices.Contains(wrappers, name):
+		if name == "sudo" || name == "doas" {
+			finding(SeverityHigh, "privilege-escalation", name, "%s runs the command with elevated privileges", name)
+		}
+		if rest := unwrap(name, args); len(rest) > 0 {
+			s.call(text, rest, depth)
+		}
+		return
+
+	case slices.Contains(shells, name):
+		if i := slices.IndexFunc(args, func(w shellWord) bool { return w.static && w.value == "-c" }); i >= 0 && i+1 < len(args) {
+			s.code(text, args[i+1], depth)
+		} else if ops := operands(args); len(ops) > 0 && ops[0].value != "-" {
+			finding(SeverityLow, "script", ops[0].text(), "runs the script %s", ops[0].text())
+		} else {
+			s.stdinCode(text, name, depth, s.code)
+		}
+		return
+
+	case name == "eval":
+		s.code(text, shellWord{value: joinWords(args), raw: joinWords(args), static: allStatic(args)}, depth)
+		return
+
+	case name == "cd" || name == "pushd":
+		ops := operands(args)
+		switch {
+		case len(ops) == 0:
+			s.dir = s.resolve(s.home)
+		case ops[0].static:
+			s.dir = s.resolve(ops[0].value)
+		default:
+			finding(SeverityMedium, "dynamic-directory", ops[0].raw, "changes to %s, which is only known at runtime", ops[0].raw)
+		}
+
+	case name == "source" || name == ".":
+		if ops := operands(args); len(ops) > 0 {
+			finding(SeverityLow, "script", ops[0].text(), "sources the script %s", ops[0].text())
+		}
+
+	case interpreters[name] != nil || strings.HasPrefix(name, "python3."):
+		if name == "perl" && hasFlag(args, "i") {
+			for _, w := range inPlaceFiles(args, "-e", "-E") {
+				s.write(text, w)
+			}
+		}
+		flags := interpreters[name]
+		if flags == nil {
+			flags = []string{"-c"}
+		}
+		inline := func(text string, code shellWord, _ int) {
+			lower := strings.ToLower(code.raw)
+			if slices.ContainsFunc(codeExec, func(p string) bool { return strings.Contains(lower, p) }) {
+				finding(SeverityHigh, "inline-code-exec", name, "inline %s code runs other programs", name)
+			} else {
+				finding(SeverityLow, "inline-code", name, "runs inline %s code", name)
+			}
+		}
+		i := slices.IndexFunc(args[:max(len(args)-1, 0)], func(w shellWord) bool {
+			return w.static && slices.Contains(flags, w.value)
+		})
+		switch ops := operands(args); {
+		case i >= 0:
+			inline(text, args[i+1], depth)
+		case len(ops) == 0 || ops[0].value == "-":
+			s.stdinCode(text, name, depth, inline)
+		}
+
+	case name == "find":
+		s.find(text, args, depth)
+
+	case name == "rm" || name == "rmdir" || name == "shred" || name == "unlink":
+		recursive := name == "rm" && hasFlag(args, "rR", "--recursive")
+		for _, w := range operands(args) {
+			s.modify(text, name, "delete", w, recursive)
+		}
+
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		recursive := hasFlag(args, "R", "--recursive")
+		ops := operands(args)
+		if len(ops) == 0 {
+			break
+		}
+		if name == "chmod" && worldWritable(ops[0].value) {
+			finding(SeverityMedium, "world-writable", ops[0].value, "chmod %s makes files writable by anyone", ops[0].value)
+		}
+		for _, w := range ops[1:] {
+			s.modify(text, name, "change permissions of", w, recursive)
+		}
+
+	case name == "mv" || name == "cp" || name == "tee" || name == "touch" || name == "truncate" ||
+		name == "mkdir" || name == "ln" || name == "install":
+		ops := operands(args)
+		if name == "cp" || name == "ln" || name == "install" {
+			ops = ops[max(len(ops)-1, 0):] // only the destination is written
+		}
+		for _, w := range ops {
+			s.write(text, w)
+		}
+
+	case name == "sed" && hasFlag(args, "i", "--in-place"):
+		for _, w := range inPlaceFiles(args, "-e", "-f", "--expression", "--file") {
+			s.write(text, w)
+		}
+
+	case name == "dd":
+		for _, w := range args {
+			if target, ok := strings.CutPrefix(w.value, "of="); ok && w.static {
+				s.write(text, shellWord{value: target, raw: target, static: true})
+			}
+		}
+
+	case slices.Contains(diskTools, name) || strings.HasPrefix(name, "mkfs."):
+		finding(SeverityCritical, "format-disk", name, "%s formats or partitions disks", name)
+
+	case slices.Contains(powerTools, name):
+		finding(SeverityHigh, "system-power", name, "%s stops or restarts the system", name)
+
+	case name == "kill" || name == "pkill" || name == "killall":
+		if name == "killall" || slices.ContainsFunc(args, func(w shellWord) bool { return w.value == "-1" && w.static }) {
+			finding(SeverityMedium, "kill-all", name, "%s may stop processes outside the task", name)
+		}
+
+	case slices.Contains(downloaders, name):
+		finding(SeverityHigh, "network-download", urlArg(args), "%s downloads from or uploads to the network", name)
+
+	case slices.Contains(rawNetwork, name):
+		if hasFlag(args, "l", "--listen") || slices.ContainsFunc(args, func(w shellWord) bool {
+			return strings.Contains(strings.ToLower(w.raw), "listen")
+		}) {
+			finding(SeverityHigh, "network-listener", name, "%s listens for network connections", name)
+		} else {
+			finding(SeverityMedium, "raw-network", name, "%s opens raw network connections", name)
+		}
+
+	case slices.Contains(remoteAccess, name):
+		finding(SeverityMedium, "remote-access", name, "%s connects to another machine", name)
+
+	case slices.Contains(firewalls, name):
+		finding(SeverityHigh, "firewall", name, "%s changes the firewall", name)
+
+	case slices.Contains(winShells, name):
+		finding(SeverityHigh, "windows-shell", name, "%s runs code the audit cannot parse", name)
+
+	case slices.Contains(services, name):
+		finding(SeverityMedium, "system-service", name, "%s changes system services or schedules", name)
+
+	default:
+		// Reading outside the workplace through a relative path
+		for _, w := range args {
+			v := w.value
+			if _, after, ok := strings.Cut(v, "="); ok && strings.HasPrefix(v, "-") {
+				v = after
+			}
+			if w.static && !filepath.IsAbs(v) && strings.Contains(v, "..") {
+				if p := s.resolve(v); !s.inWorkplace(p) && !s.inTemp(p) {
+					finding(SeverityMedium, "path-outside-workplace", v, "%s refers to %s outside the workplace", name, p)
+				}
+			}
+		}
+	}
+
+	for _, w := range args {
+		s.sensitive(text, w)
+	}
+}
+
+// unwrap returns the command a wrapper runs: the words after its options,
+// option values and, for env, variable assignments.
+func unwrap(name string, args []shellWord) []shellWord {
+	for i := 0; i < len(args); i++ {
+		w := args[i]
+		if !w.static {
+			return args[i:]
+		}
+		v := w.value
+		switch {
+		case v == "--":
+			return args[i+1:]
+		case strings.HasPrefix(v, "-") && v != "-":
+			if slices.Contains(wrapperValueFlags[name], v) {
+				i++
+			}
+		case name == "env" && strings.Contains(v, "="):
+		case name == "timeout" || (name == "nice" && isNumber(v)):
+			name = "" // the duration or niceness comes first
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+func isNumber(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789+-") == ""
+}
+
+func allStatic(words []shellWord) bool {
+	return !slices.ContainsFunc(words, func(w shellWord) bool { return !w.static })
+}
+
+// stdinCode audits the code a shell or interpreter reads on standard
+// input: a literal here-string or here-document is audited with audit;
+// input from a pipe, a file or an expansion is only known at runtime.
+func (s *shellAudit) stdinCode(text, name string, depth int, audit func(text string, code shellWord, depth int)) {
+	in := s.input
+	switch {
+	case in.from == "here-document" && in.body.static:
+		audit(text, in.body, depth)
+	case in.from != "":
+		target := in.from
+		if in.from == "here-document" {
+			target = in.body.raw
+		}
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "dynamic-code",
+			Command:  text,
+			Target:   target,
+			Message:  fmt.Sprintf("%s runs code from a %s, which is only known at runtime", name, in.from),
+		})
+	}
+}
+
+// stdin returns the standard input a redirection gives a command.
+func (s *shellAudit) stdin(src string, r *syntax.Redirect) (shellInput, bool) {
+	switch r.Op {
+	case syntax.WordHdoc:
+		return shellInput{from: "here-document", body: s.word(src, r.Word)}, true
+	case syntax.Hdoc, syntax.DashHdoc:
+		if r.Hdoc == nil {
+			return shellInput{from: "here-document", body: shellWord{static: true}}, true
+		}
+		if r.Word.Lit() == "" {
+			// A quoted delimiter keeps the body literal
+			body := src[r.Hdoc.Pos().Offset():r.Hdoc.End().Offset()]
+			return shellInput{from: "here-document", body: shellWord{value: body, raw: body, static: true}}, true
+		}
+		return shellInput{from: "here-document", body: s.word(src, r.Hdoc)}, true
+	case syntax.RdrIn:
+		return shellInput{from: "file"}, true
+	}
+	return shellInput{}, false
+}
+
+// find audits the commands find runs with -exec and the files it removes
+// with -delete, taking {} to be each of its start paths.
+func (s *shellAudit) find(text string, args []shellWord, depth int) {
+	i := 0
+	for i < len(args) && args[i].static && slices.Contains([]string{"-H", "-L", "-P"}, args[i].value) {
+		i++
+	}
+	var starts []shellWord
+	for ; i < len(args); i++ {
+		if v := args[i].value; args[i].static && (strings.HasPrefix(v, "-") || v == "(" || v == "!") {
+			break
+		}
+		starts = append(starts, args[i])
+	}
+	if len(starts) == 0 {
+		starts = []shellWord{{value: ".", raw: ".", static: true}}
+	}
+
+	for ; i < len(args); i++ {
+		if !args[i].static {
+			continue
+		}
+		switch args[i].value {
+		case "-delete":
+			for _, start := range starts {
+				s.modify(text, "find", "delete", start, true)
+			}
+		case "-exec", "-execdir", "-ok", "-okdir":
+			end := i + 1
+			for end < len(args) && !(args[end].static && (args[end].value == ";" || args[end].value == "+")) {
+				end++
+			}
+			if cmd := args[i+1 : end]; len(cmd) > 0 {
+				for _, start := range starts {
+					s.call(text, placeholder(cmd, start), depth)
+				}
+			}
+			i = end
+		}
+	}
+}
+
+// placeholder replaces find's {} in a command with a path, as a pattern
+// for the files found under it.
+func placeholder(cmd []shellWord, path shellWord) []shellWord {
+	out := slices.Clone(cmd)
+	for i, w := range out {
+		if w.static && strings.Contains(w.value, "{}") {
+			out[i] = shellWord{
+				value:  strings.ReplaceAll(w.value, "{}", path.value),
+				raw:    strings.ReplaceAll(w.raw, "{}", path.raw),
+				static: path.static,
+				glob:   true,
+			}
+		}
+	}
+	return out
+}
+
+// code audits shell code a command runs, as with sh -c or eval.
+func (s *shellAudit) code(text string, code shellWord, depth int) {
+	switch {
+	case !code.static:
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "dynamic-code",
+			Command:  text,
+			Target:   code.raw,
+			Message:  "runs shell code that is only known at runtime",
+		})
+	case depth >= maxShellDepth:
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "nested-code",
+			Command:  text,
+			Message:  fmt.Sprintf("shell code nested more than %d levels deep", maxShellDepth),
+		})
+	default:
+		s.analyze(code.value, depth+1)
+	}
+}
+
+// modify checks a path a command deletes or changes permissions of.
+func (s *shellAudit) modify(text, program, verb string, w shellWord, recursive bool) {
+	if !w.static {
+		if recursive {
+			s.add(Finding{
+				Severity: SeverityMedium,
+				Rule:     "dynamic-target",
+				Command:  text,
+				Target:   w.raw,
+				Message:  fmt.Sprintf("%s -r on %s, which is only known at runtime", program, w.raw),
+			})
+		}
+		return
+	}
+	p := s.resolve(globDir(w))
+	s.an.Writes = append(s.an.Writes, p)
+	switch {
+	case (recursive || w.glob) && s.protected(p):
+		s.add(Finding{
+			Severity: SeverityCritical,
+			Rule:     "delete-protected",
+			Command:  text,
+			Target:   p,
+			Message:  fmt.Sprintf("%s would %s everything in %s", program, verb, p),
+		})
+	case p == s.root && recursive:
+		s.add(Finding{
+			Severity: SeverityMedium,
+			Rule:     "delete-workplace",
+			Command:  text,
+			Target:   p,
+			Message:  fmt.Sprintf("%s would %s the whole workplace", program, verb),
+		})
+	case !s.inWorkplace(p) && !s.inTemp(p):
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "modify-outside-workplace",
+			Command:  text,
+			Target:   p,
+			Message:  fmt.Sprintf("%s would %s %s outside the workplace", program, verb, p),
+		})
+	}
+}
+
+// write checks a path a command or redirection writes to.
+func (s *shellAudit) write(text string, w shellWord) {
+	if !w.static {
+		s.add(Finding{
+			Severity: SeverityMedium,
+			Rule:     "dynamic-target",
+			Command:  text,
+			Target:   w.raw,
+			Message:  fmt.Sprintf("writes to %s, which is only known at runtime", w.raw),
+		})
+		return
+	}
+	p := s.resolve(globDir(w))
+	switch {
+	case p == "/dev/null" || p == "/dev/stdout" || p == "/dev/stderr" || p == "/dev/tty" || strings.HasPrefix(p, "/dev/fd/"):
+		return
+	}
+	s.an.Writes = append(s.an.Writes, p)
+	switch {
+	case isBlockDevice(p):
+		s.add(Finding{
+			Severity: SeverityCritical,
+			Rule:     "write-device",
+			Command:  text,
+			Target:   p,
+			Message:  fmt.Sprintf("writes directly to the device %s", p),
+		})
+	case !s.inWorkplace(p) && !s.inTemp(p):
+		s.add(Finding{
+			Severity: SeverityHigh,
+			Rule:     "write-outside-workplace",
+			Command:  text,
+			Target:   p,
+			Message:  fmt.Sprintf("writes to %s outside the workplace", p),
+		})
+	}
+}
+
+// sensitive checks whether an argument names secrets or a raw device.
+func (s *shellAudit) sensitive(text string, w shellWord) {
+	if !w.static {
+		return
+	}
+	v := w.value
+	if _, after, ok := strings.Cut(v, "="); ok {
+		v = after // --file=/etc/shadow, if=/dev/sda
+	}
+	if v == "" {
+		return
+	}
+	p := s.resolve(v)
+	if sev, ok := sensitiveFiles[p]; ok {
+		s.add(Finding{Severity: sev, Rule: "sensitive-file", Command: text, Target: p,
+			Message: fmt.Sprintf("accesses %s", p)})
+		return
+	}
+	if slices.ContainsFunc(credentialPaths, func(c string) bool { return strings.Contains(p, c) }) {
+		s.add(Finding{Severity: SeverityHigh, Rule: "credentials", Command: text, Target: p,
+			Message: fmt.Sprintf("accesses the credentials in %s", p)})
+		return
+	}
+	if isBlockDevice(p) {
+		s.add(Finding{Severity: SeverityHigh, Rule: "raw-device", Command: text, Target: p,
+			Message: fmt.Sprintf("accesses the device %s", p)})
+	}
+}
+
+// redirect checks where a redirection of a statement reads or writes.
+func (s *shellAudit) redirect(src string, stmt *syntax.Stmt, r *syntax.Redirect) {
+	if r.Word == nil {
+		return
+	}
+	text := src[stmt.Pos().Offset():stmt.End().Offset()]
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+		w := s.word(src, r.Word)
+		s.write(text, w)
+		s.sensitive(text, w)
+	case syntax.RdrIn:
+		s.sensitive(text, s.word(src, r.Word))
+	}
+}
+
+// pipe flags downloads piped into a shell or interpreter.
+func (s *shellAudit) pipe(src string, b *syntax.BinaryCmd) {
+	downloads := false
+	syntax.Walk(b.X, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+			if w := s.word(src, call.Args[0]); w.static && slices.Contains(downloaders, programName(w.value)) {
+				downloads = true
+			}
+		}
+		return !downloads
+	})
+	call, ok := b.Y.Cmd.(*syntax.CallExpr)
+	if !downloads || !ok || len(call.Args) == 0 {
+		return
+	}
+	w := s.word(src, call.Args[0])
+	name := programName(w.value)
+	if slices.Contains(shells, name) || interpreters[name] != nil || slices.Contains(winShells, name) {
+		s.add(Finding{
+			Severity: SeverityCritical,
+			Rule:     "download-and-execute",
+			Command:  src[b.Pos().Offset():b.End().Offset()],
+			Target:   name,
+			Message:  fmt.Sprintf("pipes a download into %s", name),
+		})
+	}
+}
+
+// funcDecl flags functions that call themselves, as fork bombs do.
+func (s *shellAudit) funcDecl(src string, fn *syntax.FuncDecl) {
+	recursive := false
+	syntax.Walk(fn.Body, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 && call.Args[0].Lit() == fn.Name.Value {
+			recursive = true
+		}
+		return !recursive
+	})
+	if recursive {
+		s.add(Finding{
+			Severity: SeverityCritical,
+			Rule:     "fork-bomb",
+			Command:  src[fn.Pos().Offset():fn.End().Offset()],
+			Target:   fn.Name.Value,
+			Message:  fmt.Sprintf("the function %s calls itself", fn.Name.Value),
+		})
+	}
+}
+
+// resolve returns the absolute, cleaned path a command refers to.
+func (s *shellAudit) resolve(p string) string {
+	p = filepath.FromSlash(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.dir, p)
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+func (s *shellAudit) inWorkplace(p string) bool {
+	root := filepath.ToSlash(s.root)
+	return p == root || strings.HasPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
+
+func (s *shellAudit) inTemp(p string) bool {
+	for _, dir := range []string{"/tmp", filepath.ToSlash(os.TempDir())} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// protected reports whether p is a system directory, the home directory or
+// a directory holding the workplace.
+func (s *shellAudit) protected(p string) bool {
+	if slices.Contains(protectedDirs, p) || p == filepath.ToSlash(s.home) {
+		return true
+	}
+	root := filepath.ToSlash(s.root)
+	return p != root && strings.HasPrefix(root, strings.TrimSuffix(p, "/")+"/")
+}
+
+// globDir returns the path of a word, or for a pattern the directory its
+// matches are in.
+func globDir(w shellWord) string {
+	if !w.glob {
+		return w.value
+	}
+	i := strings.IndexAny(w.value, "*?[")
+	if i < 0 {
+		return w.value // find's {}: the files under the path
+	}
+	return path.Dir(w.value[:i] + "x")
+}
+
+func isBlockDevice(p string) bool {
+	return slices.ContainsFunc(blockDevices, func(d string) bool { return strings.HasPrefix(p, d) })
+}
+
+// worldWritable reports whether a chmod mode lets anyone write.
+func worldWritable(mode string) bool {
+	if len(mode) >= 3 && isNumber(mode) {
+		switch mode[len(mode)-1] {
+		case '2', '3', '6', '7':
+			return true
+		}
+		return false
+	}
+	for _, clause := range strings.Split(mode, ",") {
+		who, perms, ok := strings.Cut(clause, "+")
+		if !ok {
+			who, perms, ok = strings.Cut(clause, "=")
+		}
+		if ok && strings.ContainsAny(who, "ao") && strings.Contains(perms, "w") {
+			return true
+		}
+	}
+	return false
+}
+
+// urlArg returns the first argument that looks like a URL.
+func urlArg(args []shellWord) string {
+	for _, w := range args {
+		if strings.Contains(w.value, "://") {
+			return w.value
+		}
+	}
+	return ""
+}
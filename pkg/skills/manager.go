@@ -2,6 +2,7 @@
 package skills
 
 import (
+	"ClosedWheeler/pkg/sandbox"
 	"ClosedWheeler/pkg/security"
 	"ClosedWheeler/pkg/tools"
 	"ClosedWheeler/pkg/tools/builtin"
@@ -37,6 +38,7 @@ type Manager struct {
 	skillsDir string
 	auditor   *security.Auditor
 	registry  *tools.Registry
+	sandbox   *sandbox.Sandbox // runs skill scripts when enabled
 	mu        sync.RWMutex
 	loaded    []SkillInfo // currently loaded skills
 }
@@ -57,6 +59,13 @@ func NewManager(appPath string, auditor *security.Auditor, registry *tools.Regis
 	}
 }
 
+// SetSandbox runs skill scripts in sb when it is enabled.
+func (m *Manager) SetSandbox(sb *sandbox.Sandbox) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sandbox = sb
+}
+
 // LoadSkills scans the skills directory and registers safe skills.
 // On reload, previously loaded skills are unregistered first to avoid duplicates.
 func (m *Manager) LoadSkills() error {
@@ -146,6 +155,7 @@ func (m *Manager) loadSkill(skillFolderName string) error {
 	// 3. Register as tool
 	absScriptPath, _ := filepath.Abs(scriptPath)
 	appRoot := m.appPath
+	sb := m.sandbox
 
 	tool := &tools.Tool{
 		Name:        meta.Name,
@@ -157,7 +167,7 @@ func (m *Manager) loadSkill(skillFolderName string) error {
 				argStrings = append(argStrings, fmt.Sprintf("--%s=%v", k, v))
 			}
 
-			cmdTool := builtin.ExecCommandTool(appRoot, 30*time.Second, m.auditor)
+			cmdTool := builtin.ExecCommandTool(appRoot, 30*time.Second, m.auditor, sb)
 			return cmdTool.Handler(map[string]any{
 				"command": absScriptPath,
 				"args":    strings.Join(argStrings, " "),
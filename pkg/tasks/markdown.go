@@ -0,0 +1,330 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFormat is how timestamps are written in task metadata.
+const timeFormat = time.RFC3339
+
+// docLine is one line of task.md: a task, or text kept as written.
+type docLine struct {
+	text   string
+	task   *Task
+	parent *Task
+	bullet byte // list marker of a task line, '-' or '*'
+}
+
+// document is a parsed task.md. Headings, prose and blank lines are kept
+// in place around the tasks.
+type document struct {
+	lines []docLine
+}
+
+// parse reads task.md. A checkbox line indented under another is its
+// sub-task; "> " lines indented under a task are its notes. Fenced code
+// blocks are kept as text.
+func parse(content string) *document {
+	d := &document{}
+	type open struct {
+		indent int
+		task   *Task
+	}
+	var stack []open
+	var last *Task // task whose notes may follow
+	lastIndent := 0
+	fence := "" // marker of the open code block
+
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		indent := indentOf(line)
+		trimmed := strings.TrimSpace(line)
+
+		if marker := fenceOf(trimmed); fence != "" || marker != "" {
+			switch {
+			case fence == "":
+				fence = marker
+			case marker == trimmed && strings.HasPrefix(marker, fence):
+				fence = ""
+			}
+			last, stack = nil, nil
+			d.lines = append(d.lines, docLine{text: line})
+			continue
+		}
+
+		if t, ok := parseTaskLine(trimmed); ok {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			l := docLine{task: t, bullet: trimmed[0]}
+			if len(stack) > 0 {
+				l.parent = stack[len(stack)-1].task
+				t.Depth = len(stack)
+			}
+			d.lines = append(d.lines, l)
+			stack = append(stack, open{indent, t})
+			last, lastIndent = t, indent
+			continue
+		}
+
+		if last != nil && indent > lastIndent && strings.HasPrefix(trimmed, ">") {
+			last.Notes = append(last.Notes, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+			continue
+		}
+
+		last = nil
+		if trimmed != "" {
+			stack = nil
+		}
+		d.lines = append(d.lines, docLine{text: line})
+	}
+	return d
+}
+
+// fenceOf returns the run of backticks or tildes opening a code fence, or
+// "" when line is not one.
+func fenceOf(line string) string {
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return ""
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	return line[:n]
+}
+
+// parseTaskLine parses a "- [ ] title <!-- meta -->" line; "[/]" is in
+// progress and "[x]" done.
+func parseTaskLine(line string) (*Task, bool) {
+	if !strings.HasPrefix(line, "- [") && !strings.HasPrefix(line, "* [") {
+		return nil, false
+	}
+	if len(line) < 5 || line[4] != ']' {
+		return nil, false
+	}
+	t := &Task{Status: StatusTodo}
+	switch line[3] {
+	case 'x', 'X':
+		t.Status = StatusDone
+	case '/', '~':
+		t.Status = StatusInProgress
+	}
+
+	title := strings.TrimSpace(line[5:])
+	if start := strings.LastIndex(title, "<!--"); start >= 0 && strings.HasSuffix(title, "-->") {
+		parseMeta(t, strings.TrimSuffix(title[start+len("<!--"):], "-->"))
+		title = strings.TrimSpace(title[:start])
+	}
+	t.Title = title
+	return t, true
+}
+
+// parseMeta reads "key:value" fields of the metadata comment.
+func parseMeta(t *Task, meta string) {
+	for _, field := range strings.Fields(meta) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "id":
+			t.ID = value
+		case "pri":
+			t.Priority = value
+		case "who":
+			t.Assignee = value
+		case "created":
+			t.Created, _ = time.Parse(timeFormat, value)
+		case "updated":
+			t.Updated, _ = time.Parse(timeFormat, value)
+		}
+	}
+}
+
+// indentOf returns the width of a line's leading whitespace; a tab counts
+// as four spaces.
+func indentOf(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// tasks returns the tasks in file order with their Parent set.
+func (d *document) tasks() []*Task {
+	var tasks []*Task
+	for _, l := range d.lines {
+		if l.task == nil {
+			continue
+		}
+		l.task.Parent = ""
+		if l.parent != nil {
+			l.task.Parent = l.parent.ID
+		}
+		tasks = append(tasks, l.task)
+	}
+	return tasks
+}
+
+// find returns the task with an ID, or nil.
+func (d *document) find(id string) *Task {
+	for _, t := range d.tasks() {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// assignIDs gives an ID to tasks without one, or with one already taken,
+// and fills in their missing fields.
+func (d *document) assignIDs() {
+	seen := make(map[string]bool)
+	now := time.Now()
+	for _, l := range d.lines {
+		t := l.task
+		if t == nil {
+			continue
+		}
+		if t.ID == "" || seen[t.ID] {
+			t.ID = d.nextID(seen)
+		}
+		seen[t.ID] = true
+		if t.Created.IsZero() {
+			t.Created = now
+		}
+		if t.Updated.IsZero() {
+			t.Updated = t.Created
+		}
+		normalize(t)
+	}
+}
+
+// nextID returns the lowest "tN" above every ID in use.
+func (d *document) nextID(seen map[string]bool) string {
+	n := 0
+	for _, l := range d.lines {
+		if l.task == nil {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimPrefix(l.task.ID, "t")); err == nil && strings.HasPrefix(l.task.ID, "t") {
+			n = max(n, v)
+		}
+	}
+	for seen["t"+strconv.Itoa(n+1)] {
+		n++
+	}
+	return "t" + strconv.Itoa(n+1)
+}
+
+// add inserts a task: after its parent's last sub-task, or else at the end
+// of the file, before any trailing blank lines.
+func (d *document) add(task Task) *Task {
+	t := &task
+	parentID := t.Parent
+	normalize(t)
+	t.Parent, t.Depth = "", 0
+	t.ID = d.nextID(nil)
+	now := time.Now()
+	t.Created, t.Updated = now, now
+
+	if len(d.lines) == 0 {
+		d.lines = append(d.lines, docLine{text: "# 📋 Project Tasks"}, docLine{text: ""})
+	}
+
+	at := len(d.lines)
+	for at > 0 && d.lines[at-1].task == nil && strings.TrimSpace(d.lines[at-1].text) == "" {
+		at--
+	}
+	// Keep the blank line under a heading or paragraph
+	if at > 0 && at < len(d.lines) && d.lines[at-1].task == nil {
+		at++
+	}
+	var parent *Task
+	if parentID != "" {
+		parent = d.find(parentID)
+	}
+	bullet := byte('-')
+	if parent != nil {
+		t.Depth = parent.Depth + 1
+		for i, l := range d.lines {
+			if l.task == parent {
+				bullet = l.bullet
+				at = i + 1
+				for at < len(d.lines) && d.lines[at].task != nil && d.lines[at].task.Depth > parent.Depth {
+					at++
+				}
+				break
+			}
+		}
+	}
+
+	d.lines = append(d.lines[:at], append([]docLine{{task: t, parent: parent, bullet: bullet}}, d.lines[at:]...)...)
+	d.tasks()
+	return t
+}
+
+// remove deletes a task and its sub-tasks, returning how many were removed.
+func (d *document) remove(id string) int {
+	d.tasks()
+	gone := map[string]bool{id: true}
+	kept := d.lines[:0]
+	for _, l := range d.lines {
+		if l.task != nil && (gone[l.task.ID] || (l.parent != nil && gone[l.parent.ID])) {
+			gone[l.task.ID] = true
+			continue
+		}
+		kept = append(kept, l)
+	}
+	d.lines = kept
+	return len(gone)
+}
+
+// render writes task.md back out, tasks in their canonical form under their
+// original list marker.
+func (d *document) render() string {
+	var sb strings.Builder
+	for _, l := range d.lines {
+		if l.task == nil {
+			sb.WriteString(l.text + "\n")
+			continue
+		}
+		sb.WriteString(formatTask(l.task, l.bullet))
+	}
+	return sb.String()
+}
+
+// formatTask writes a task line and its notes.
+func formatTask(t *Task, bullet byte) string {
+	indent := strings.Repeat("  ", t.Depth)
+	box := " "
+	switch t.Status {
+	case StatusInProgress:
+		box = "/"
+	case StatusDone:
+		box = "x"
+	}
+	meta := []string{"id:" + t.ID, "pri:" + t.Priority}
+	if t.Assignee != "" {
+		meta = append(meta, "who:"+t.Assignee)
+	}
+	meta = append(meta, "created:"+t.Created.Format(timeFormat), "updated:"+t.Updated.Format(timeFormat))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s%c [%s] %s <!-- %s -->\n", indent, bullet, box, t.Title, strings.Join(meta, " ")))
+	for _, note := range t.Notes {
+		sb.WriteString(fmt.Sprintf("%s  > %s\n", indent, note))
+	}
+	return sb.String()
+}
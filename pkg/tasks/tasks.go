@@ -0,0 +1,301 @@
+// Package tasks manages the project's task list in workplace/task.md.
+// Tasks keep their markdown checkbox form so people and the agent can edit
+// the file directly; each task line carries an HTML comment with its ID,
+// priority, assignee and timestamps, sub-tasks are indented under their
+// parent, and notes are indented "> " lines below a task.
+package tasks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ClosedWheeler/pkg/utils"
+)
+
+// Task statuses, as written in the checkbox: "[ ]", "[/]" and "[x]".
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// Assignees.
+const (
+	AssigneeAgent = "agent"
+	AssigneeUser  = "user"
+)
+
+// Statuses, Priorities and Assignees list the valid field values.
+var (
+	Statuses   = []string{StatusTodo, StatusInProgress, StatusDone}
+	Priorities = []string{"high", "medium", "low"}
+	Assignees  = []string{AssigneeAgent, AssigneeUser}
+)
+
+// Task is one checkbox item of task.md.
+type Task struct {
+	ID       string
+	Title    string
+	Status   string // "todo", "in_progress", "done"
+	Priority string // "high", "medium", "low"
+	Assignee string // "agent", "user", or "" when unassigned
+	Parent   string // ID of the parent task, "" at the top level
+	Depth    int    // nesting level, 0 at the top level
+	Notes    []string
+	Created  time.Time
+	Updated  time.Time
+}
+
+// Open reports whether the task is not done.
+func (t Task) Open() bool {
+	return t.Status != StatusDone
+}
+
+// Filter selects tasks; empty fields match everything.
+type Filter struct {
+	Status   string // a status, or "open" for every task not done
+	Priority string
+	Assignee string
+	Parent   string // direct children of this task
+	Query    string // text in the title or notes, ignoring case
+}
+
+// Match reports whether a task passes the filter.
+func (f Filter) Match(t Task) bool {
+	switch {
+	case f.Status == "open" && !t.Open():
+		return false
+	case f.Status != "" && f.Status != "open" && t.Status != f.Status:
+		return false
+	case f.Priority != "" && t.Priority != f.Priority:
+		return false
+	case f.Assignee != "" && t.Assignee != f.Assignee:
+		return false
+	case f.Parent != "" && t.Parent != f.Parent:
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		text := strings.ToLower(t.Title + "\n" + strings.Join(t.Notes, "\n"))
+		return strings.Contains(text, q)
+	}
+	return true
+}
+
+// locks serializes access to each task file across List values, since the
+// tools, the roadmap and the agent each open their own.
+var locks sync.Map
+
+// List is the task list stored in a task.md file.
+type List struct {
+	path string
+	mu   *sync.Mutex
+}
+
+// NewList opens the task list at path; the file is created on first write.
+func NewList(path string) *List {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	mu, _ := locks.LoadOrStore(abs, &sync.Mutex{})
+	return &List{path: path, mu: mu.(*sync.Mutex)}
+}
+
+// Path returns the path of task.md.
+func (l *List) Path() string {
+	return l.path
+}
+
+// Initialize creates task.md with the given top-level tasks if it doesn't exist.
+func (l *List) Initialize(titles ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := os.Stat(l.path); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	d := &document{}
+	d.lines = append(d.lines, docLine{text: "# 📋 Project Tasks"}, docLine{text: ""})
+	for _, title := range titles {
+		d.add(Task{Title: title, Assignee: AssigneeAgent})
+	}
+	return l.save(d)
+}
+
+// All returns every task in file order, parents before their sub-tasks.
+func (l *List) All() ([]Task, error) {
+	return l.Find(Filter{})
+}
+
+// Find returns the tasks matching a filter in file order.
+func (l *List) Find(f Filter) ([]Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Task
+	for _, t := range d.tasks() {
+		if f.Match(*t) {
+			matches = append(matches, copyTask(t))
+		}
+	}
+	return matches, nil
+}
+
+// Get returns the task with an ID.
+func (l *List) Get(id string) (Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, err := l.load()
+	if err != nil {
+		return Task{}, err
+	}
+	t := d.find(id)
+	if t == nil {
+		return Task{}, fmt.Errorf("task not found: %s", id)
+	}
+	return copyTask(t), nil
+}
+
+// Add appends a task, under its parent when it has one, and returns it with
+// its ID assigned.
+func (l *List) Add(task Task) (Task, error) {
+	var added Task
+	err := l.modify(func(d *document) error {
+		if strings.TrimSpace(task.Title) == "" {
+			return fmt.Errorf("task title is empty")
+		}
+		if task.Parent != "" && d.find(task.Parent) == nil {
+			return fmt.Errorf("parent task not found: %s", task.Parent)
+		}
+		added = copyTask(d.add(task))
+		return nil
+	})
+	return added, err
+}
+
+// Update changes a task in place and returns it; fn must not change its ID
+// or parent.
+func (l *List) Update(id string, fn func(*Task)) (Task, error) {
+	var updated Task
+	err := l.modify(func(d *document) error {
+		t := d.find(id)
+		if t == nil {
+			return fmt.Errorf("task not found: %s", id)
+		}
+		before := *t
+		fn(t)
+		t.ID, t.Parent, t.Depth = before.ID, before.Parent, before.Depth
+		normalize(t)
+		t.Updated = time.Now()
+		updated = copyTask(t)
+		return nil
+	})
+	return updated, err
+}
+
+// Delete removes a task and its sub-tasks, returning how many were removed.
+func (l *List) Delete(id string) (int, error) {
+	removed := 0
+	err := l.modify(func(d *document) error {
+		if d.find(id) == nil {
+			return fmt.Errorf("task not found: %s", id)
+		}
+		removed = d.remove(id)
+		return nil
+	})
+	return removed, err
+}
+
+// modify loads task.md, applies fn and writes it back.
+func (l *List) modify(fn func(*document) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, err := l.load()
+	if err != nil {
+		return err
+	}
+	if err := fn(d); err != nil {
+		return err
+	}
+	return l.save(d)
+}
+
+// load parses task.md; a missing file is an empty list. Tasks written
+// without metadata get the next free ID in file order, so reads agree on
+// it until the next write stores it. Callers must hold l.mu.
+func (l *List) load() (*document, error) {
+	content, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &document{}, nil
+		}
+		return nil, fmt.Errorf("failed to read task.md: %w", err)
+	}
+	d := parse(string(content))
+	d.assignIDs()
+	return d, nil
+}
+
+// save writes task.md atomically. Callers must hold l.mu.
+func (l *List) save(d *document) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create task directory: %w", err)
+	}
+	if err := utils.WriteFileAtomic(l.path, []byte(d.render()), 0644); err != nil {
+		return fmt.Errorf("failed to write task.md: %w", err)
+	}
+	return nil
+}
+
+// normalize maps field spellings to the valid values.
+func normalize(t *Task) {
+	t.Title = strings.TrimSpace(t.Title)
+	switch strings.ToLower(strings.TrimSpace(t.Status)) {
+	case "in_progress", "in-progress", "in progress", "doing":
+		t.Status = StatusInProgress
+	case "done", "completed", "complete":
+		t.Status = StatusDone
+	default:
+		t.Status = StatusTodo
+	}
+	switch strings.ToLower(strings.TrimSpace(t.Priority)) {
+	case "high":
+		t.Priority = "high"
+	case "low":
+		t.Priority = "low"
+	default:
+		t.Priority = "medium"
+	}
+	switch strings.ToLower(strings.TrimSpace(t.Assignee)) {
+	case AssigneeAgent, "ai", "bot":
+		t.Assignee = AssigneeAgent
+	case AssigneeUser, "me", "human":
+		t.Assignee = AssigneeUser
+	default:
+		t.Assignee = ""
+	}
+	var notes []string
+	for _, n := range t.Notes {
+		if n = strings.TrimSpace(n); n != "" {
+			notes = append(notes, n)
+		}
+	}
+	t.Notes = notes
+}
+
+func copyTask(t *Task) Task {
+	c := *t
+	c.Notes = append([]string(nil), t.Notes...)
+	return c
+}
@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestList_HandWrittenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.md")
+	hand := `# Sprint
+
+Things to finish this week.
+
+- [ ] Ship release
+  - [x] Write changelog
+    > Covers every PR
+  - [/] Tag release
+- [ ] Fix flaky test <!-- id:t7 pri:high who:user -->
+
+## Later
+* [ ] Refactor config
+
+Example:
+
+~~~~markdown
+- [ ] Not a task
+` + "```" + `
+- [x] Still not a task
+~~~~
+`
+	if err := os.WriteFile(path, []byte(hand), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list := NewList(path)
+	all, err := list.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range all {
+		got = append(got, task.ID+":"+task.Parent+":"+task.Status+":"+task.Title)
+	}
+	want := []string{
+		"t8::todo:Ship release",
+		"t9:t8:done:Write changelog",
+		"t10:t8:in_progress:Tag release",
+		"t7::todo:Fix flaky test",
+		"t11::todo:Refactor config",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("All() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if all[1].Notes[0] != "Covers every PR" || all[3].Priority != "high" || all[3].Assignee != AssigneeUser {
+		t.Errorf("Unexpected fields: %+v %+v", all[1], all[3])
+	}
+
+	// Reading leaves the file alone and agrees on the assigned IDs
+	if content, _ := os.ReadFile(path); string(content) != hand {
+		t.Errorf("task.md rewritten by a read:\n%s", content)
+	}
+	if task, err := list.Get("t10"); err != nil || task.Title != "Tag release" {
+		t.Errorf("Get(t10) = %+v, %v", task, err)
+	}
+
+	// A sub-task goes after its parent's last sub-task
+	added, err := list.Add(Task{Title: "Announce release", Parent: "t8", Priority: "low"})
+	if err != nil || added.ID != "t12" || added.Depth != 1 {
+		t.Fatalf("Add() = %+v, %v", added, err)
+	}
+	if _, err := list.Add(Task{Title: "Orphan", Parent: "t99"}); err == nil {
+		t.Error("Expected an error for an unknown parent")
+	}
+	content, _ := os.ReadFile(path)
+	for _, line := range []string{
+		"# Sprint\n\nThings to finish this week.\n\n- [ ] Ship release <!-- id:t8 pri:medium created:",
+		"\n  - [x] Write changelog <!-- id:t9 ",
+		"\n    > Covers every PR\n  - [/] Tag release <!-- id:t10 ",
+		"\n  - [ ] Announce release <!-- id:t12 pri:low ",
+		"\n- [ ] Fix flaky test <!-- id:t7 pri:high who:user ",
+		"\n\n## Later\n* [ ] Refactor config <!-- id:t11 ",
+		"\n\nExample:\n\n~~~~markdown\n- [ ] Not a task\n```\n- [x] Still not a task\n~~~~\n",
+	} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("task.md is missing %q:\n%s", line, content)
+		}
+	}
+
+	open, err := list.Find(Filter{Status: "open", Parent: "t8"})
+	if err != nil || len(open) != 2 || open[0].ID != "t10" || open[1].ID != "t12" {
+		t.Errorf("Find(open sub-tasks of t8) = %+v, %v", open, err)
+	}
+
+	if n, err := list.Delete("t8"); err != nil || n != 4 {
+		t.Errorf("Delete(t8) = %d, %v, want 4 removed", n, err)
+	}
+	if all, _ := list.All(); len(all) != 2 {
+		t.Errorf("Tasks left after delete: %+v", all)
+	}
+
+	// Sub-tasks take their parent's list marker
+	if _, err := list.Add(Task{Title: "Split loader", Parent: "t11"}); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(path)
+	if !strings.Contains(string(content), "* [ ] Refactor config <!-- id:t11 ") || !strings.Contains(string(content), "\n  * [ ] Split loader <!-- id:t12 ") {
+		t.Errorf("task.md lost the list marker:\n%s", content)
+	}
+}
+
+func TestList_UpdateAndInitialize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workplace", "task.md")
+	list := NewList(path)
+	if err := list.Initialize("Initial project audit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Initialize("Ignored"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(content), "# 📋 Project Tasks\n\n- [ ] Initial project audit <!-- id:t1 pri:medium who:agent ") {
+		t.Errorf("Unexpected initial task.md:\n%s", content)
+	}
+
+	before, _ := list.Get("t1")
+	updated, err := list.Update("t1", func(task *Task) {
+		task.Status = "completed"
+		task.Notes = append(task.Notes, "No issues found")
+		task.Parent = "t9"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != StatusDone || updated.Parent != "" || updated.Updated.Before(before.Updated) || len(updated.Notes) != 1 {
+		t.Errorf("Update() = %+v", updated)
+	}
+	if _, err := list.Update("t5", func(*Task) {}); err == nil {
+		t.Error("Expected an error for an unknown task")
+	}
+	if pending, _ := list.Find(Filter{Status: "open"}); len(pending) != 0 {
+		t.Errorf("Pending tasks after completing t1: %+v", pending)
+	}
+}
@@ -3,14 +3,16 @@ package builtin
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"ClosedWheeler/pkg/context"
+	"ClosedWheeler/pkg/ignore"
 	"ClosedWheeler/pkg/tools"
 )
 
 // GetCodeOutlineTool creates a tool for getting a high-level outline of a code file
-func GetCodeOutlineTool(projectRoot string) *tools.Tool {
+func GetCodeOutlineTool(project *context.ProjectCache) *tools.Tool {
 	return &tools.Tool{
 		Name:        "get_code_outline",
 		Description: "Get a high-level outline of a code file (functions, methods, classes)",
@@ -29,11 +31,11 @@ func GetCodeOutlineTool(projectRoot string) *tools.Tool {
 			if !ok || path == "" {
 				return tools.ToolResult{Success: false, Error: "missing required parameter: path"}, nil
 			}
-			fullPath := filepath.Join(projectRoot, path)
+			fullPath := filepath.Join(project.Root(), path)
 
 			// Use project context for analysis if possible
-			pc := context.NewProjectContext(projectRoot)
-			if err := pc.Load([]string{}); err != nil {
+			pc, err := project.Get()
+			if err != nil {
 				return tools.ToolResult{Success: false, Error: err.Error()}, nil
 			}
 
@@ -42,7 +44,7 @@ func GetCodeOutlineTool(projectRoot string) *tools.Tool {
 				return tools.ToolResult{Success: false, Error: "file not found in project"}, nil
 			}
 
-			if len(fi.Functions) == 0 {
+			if len(fi.Functions) == 0 && len(fi.Types) == 0 {
 				return tools.ToolResult{
 					Success: true,
 					Output:  fmt.Sprintf("Outline for %s:\nNo functions or methods detected.", path),
@@ -54,9 +56,24 @@ func GetCodeOutlineTool(projectRoot string) *tools.Tool {
 			sb.WriteString(fmt.Sprintf("%-30s | %-10s | %s\n", "Symbol", "Lines", "Signature"))
 			sb.WriteString(strings.Repeat("-", 80) + "\n")
 
+			for _, t := range fi.Types {
+				lines := fmt.Sprintf("%d-%d", t.StartLine, t.EndLine)
+				decl := fmt.Sprintf("%s %s", t.Kind, t.Name)
+				if fi.Language == "go" {
+					decl = fmt.Sprintf("type %s %s", t.Name, t.Kind)
+				}
+				sb.WriteString(fmt.Sprintf("%-30s | %-10s | %s\n", t.Name, lines, decl))
+			}
 			for _, fn := range fi.Functions {
+				name := fn.Name
+				if fn.Receiver != "" {
+					name = fn.Receiver + "." + fn.Name
+				}
 				lines := fmt.Sprintf("%d-%d", fn.StartLine, fn.EndLine)
-				sb.WriteString(fmt.Sprintf("%-30s | %-10s | %s\n", fn.Name, lines, fn.Signature))
+				sb.WriteString(fmt.Sprintf("%-30s | %-10s | %s\n", name, lines, fn.Signature))
+			}
+			if len(fi.Imports) > 0 {
+				sb.WriteString(fmt.Sprintf("\nImports: %s\n", strings.Join(fi.Imports, ", ")))
 			}
 
 			return tools.ToolResult{
@@ -64,6 +81,8 @@ func GetCodeOutlineTool(projectRoot string) *tools.Tool {
 				Output:  sb.String(),
 				Data: map[string]any{
 					"functions": fi.Functions,
+					"types":     fi.Types,
+					"imports":   fi.Imports,
 					"language":  fi.Language,
 				},
 			}, nil
@@ -72,7 +91,7 @@ func GetCodeOutlineTool(projectRoot string) *tools.Tool {
 }
 
 // GetProjectMetricsTool creates a tool for getting project-wide metrics
-func GetProjectMetricsTool(projectRoot string) *tools.Tool {
+func GetProjectMetricsTool(project *context.ProjectCache) *tools.Tool {
 	return &tools.Tool{
 		Name:        "get_project_metrics",
 		Description: "Get summary metrics for the entire project",
@@ -81,9 +100,8 @@ func GetProjectMetricsTool(projectRoot string) *tools.Tool {
 			Properties: map[string]tools.Property{},
 		},
 		Handler: func(args map[string]any) (tools.ToolResult, error) {
-			pc := context.NewProjectContext(projectRoot)
-			// Minimal load for metrics (might need refined ignore patterns)
-			if err := pc.Load([]string{".git", "node_modules", "vendor"}); err != nil {
+			pc, err := project.Get()
+			if err != nil {
 				return tools.ToolResult{Success: false, Error: err.Error()}, nil
 			}
 
@@ -96,8 +114,180 @@ func GetProjectMetricsTool(projectRoot string) *tools.Tool {
 	}
 }
 
-// RegisterAnalysisTools registers analysis-related tools
-func RegisterAnalysisTools(registry *tools.Registry, projectRoot string) {
-	registry.Register(GetCodeOutlineTool(projectRoot))
-	registry.Register(GetProjectMetricsTool(projectRoot))
+// symbolProperty is the parameter naming a Go symbol.
+var symbolProperty = tools.Property{
+	Type:        "string",
+	Description: "Go symbol: a name optionally qualified by package and type, e.g. 'NewManager', 'Manager.ApplyChanges' or 'editor.Manager'",
+}
+
+// loadGoSymbols resolves a symbol argument in the project's type-checked Go
+// code.
+func loadGoSymbols(project *context.ProjectCache, args map[string]any) (*context.ProjectContext, []*context.GoSymbol, *tools.ToolResult) {
+	name, ok := args["symbol"].(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return nil, nil, &tools.ToolResult{Success: false, Error: "missing required parameter: symbol"}
+	}
+	name = strings.TrimSpace(name)
+
+	pc, err := project.Get()
+	if err != nil {
+		return nil, nil, &tools.ToolResult{Success: false, Error: err.Error()}
+	}
+	symbols := pc.Go.Lookup(name)
+	if len(symbols) == 0 {
+		return nil, nil, &tools.ToolResult{Success: false, Error: fmt.Sprintf("no Go declaration named %q (try qualifying it, e.g. 'pkg.Type.Method')", name)}
+	}
+	return pc, symbols, nil
+}
+
+// sourceLine returns the trimmed source line at a location.
+func sourceLine(pc *context.ProjectContext, loc context.Location) string {
+	content, err := pc.GetFileContent(loc.File)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(content, "\n")
+	if loc.Line < 1 || loc.Line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[loc.Line-1])
+}
+
+// FindDefinitionTool creates a tool that locates Go declarations
+func FindDefinitionTool(project *context.ProjectCache) *tools.Tool {
+	return &tools.Tool{
+		Name:        "find_definition",
+		Description: "Find where a Go type, function, method, field, variable or constant is declared, with its signature",
+		Parameters: &tools.JSONSchema{
+			Type:       "object",
+			Properties: map[string]tools.Property{"symbol": symbolProperty},
+			Required:   []string{"symbol"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			_, symbols, failed := loadGoSymbols(project, args)
+			if failed != nil {
+				return *failed, nil
+			}
+
+			const limit = 20
+			var sb strings.Builder
+			for i, sym := range symbols {
+				if i == limit {
+					sb.WriteString(fmt.Sprintf("... and %d more; qualify the name to narrow it down\n", len(symbols)-limit))
+					break
+				}
+				sb.WriteString(fmt.Sprintf("%s %s\n  %s\n  %s\n", sym.Kind, sym.QualifiedName(), sym.Pos, sym.Signature))
+			}
+			return tools.ToolResult{Success: true, Output: sb.String(), Data: symbols}, nil
+		},
+	}
+}
+
+// FindReferencesTool creates a tool that lists the uses of Go declarations
+func FindReferencesTool(project *context.ProjectCache) *tools.Tool {
+	return &tools.Tool{
+		Name:        "find_references",
+		Description: "List every use of a Go declaration across the project (resolved by type-checking, not text search), and its callers",
+		Parameters: &tools.JSONSchema{
+			Type:       "object",
+			Properties: map[string]tools.Property{"symbol": symbolProperty},
+			Required:   []string{"symbol"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			pc, symbols, failed := loadGoSymbols(project, args)
+			if failed != nil {
+				return *failed, nil
+			}
+
+			const limit = 100
+			shown := 0
+			var sb strings.Builder
+			for _, sym := range symbols {
+				refs := pc.Go.References[sym.ID]
+				sb.WriteString(fmt.Sprintf("%s %s (%s): %d reference(s)\n", sym.Kind, sym.QualifiedName(), sym.Pos, len(refs)))
+				for _, ref := range refs {
+					if shown == limit {
+						break
+					}
+					shown++
+					sb.WriteString(fmt.Sprintf("  %s  %s\n", ref, sourceLine(pc, ref)))
+				}
+				callers := make(map[string]bool)
+				for _, call := range pc.Go.Callers(sym.ID) {
+					if caller, ok := pc.Go.Symbols[call.Caller]; ok {
+						callers[caller.QualifiedName()] = true
+					}
+				}
+				if len(callers) > 0 {
+					names := make([]string, 0, len(callers))
+					for name := range callers {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					sb.WriteString("  called by: " + strings.Join(names, ", ") + "\n")
+				}
+			}
+			if shown == limit {
+				sb.WriteString(fmt.Sprintf("(output limited to %d references)\n", limit))
+			}
+			return tools.ToolResult{Success: true, Output: sb.String()}, nil
+		},
+	}
+}
+
+// ListImplementationsTool creates a tool that relates Go interfaces to the
+// types implementing them
+func ListImplementationsTool(project *context.ProjectCache) *tools.Tool {
+	return &tools.Tool{
+		Name:        "list_implementations",
+		Description: "List the project types implementing a Go interface, or the interfaces a type implements",
+		Parameters: &tools.JSONSchema{
+			Type: "object",
+			Properties: map[string]tools.Property{"symbol": {
+				Type:        "string",
+				Description: "Interface or type name, optionally qualified by package, e.g. 'Provider' or 'llm.Provider'",
+			}},
+			Required: []string{"symbol"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			pc, symbols, failed := loadGoSymbols(project, args)
+			if failed != nil {
+				return *failed, nil
+			}
+
+			var sb strings.Builder
+			for _, sym := range symbols {
+				var related []*context.GoSymbol
+				switch sym.Kind {
+				case "interface":
+					related = pc.Go.Implementers(sym.ID)
+					sb.WriteString(fmt.Sprintf("interface %s (%s) is implemented by %d type(s)\n", sym.QualifiedName(), sym.Pos, len(related)))
+				case "type":
+					related = pc.Go.Interfaces(sym.ID)
+					sb.WriteString(fmt.Sprintf("type %s (%s) implements %d interface(s)\n", sym.QualifiedName(), sym.Pos, len(related)))
+				default:
+					continue
+				}
+				for _, r := range related {
+					sb.WriteString(fmt.Sprintf("  %s  %s\n", r.QualifiedName(), r.Pos))
+				}
+			}
+			if sb.Len() == 0 {
+				return tools.ToolResult{Success: false, Error: fmt.Sprintf("%q is not a type or interface", args["symbol"])}, nil
+			}
+			sb.WriteString("(only interfaces and types declared in this project are considered)\n")
+			return tools.ToolResult{Success: true, Output: sb.String()}, nil
+		},
+	}
+}
+
+// RegisterAnalysisTools registers analysis-related tools. They share one
+// project index, which skips the files patterns ignore.
+func RegisterAnalysisTools(registry *tools.Registry, projectRoot string, patterns *ignore.Patterns) {
+	project := context.NewProjectCache(projectRoot, patterns)
+	registry.Register(GetCodeOutlineTool(project))
+	registry.Register(GetProjectMetricsTool(project))
+	registry.Register(FindDefinitionTool(project))
+	registry.Register(FindReferencesTool(project))
+	registry.Register(ListImplementationsTool(project))
 }
@@ -0,0 +1,152 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+
+	"ClosedWheeler/pkg/brain"
+	"ClosedWheeler/pkg/tools"
+)
+
+// BrainQueryTool finds knowledge base entries by tag, category or text.
+func BrainQueryTool(b *brain.Brain) *tools.Tool {
+	return &tools.Tool{
+		Name:        "brain_query",
+		Description: "Queries the knowledge base (brain) for recorded errors, patterns, decisions and insights. Filter by tags, category and/or text; results are newest first with their IDs.",
+		Parameters: &tools.JSONSchema{
+			Type: "object",
+			Properties: map[string]tools.Property{
+				"tags": {
+					Type:        "string",
+					Description: "Comma-separated tags; entries must carry all of them",
+				},
+				"category": {
+					Type:        "string",
+					Enum:        brain.Categories,
+					Description: "Only entries of this category",
+				},
+				"query": {
+					Type:        "string",
+					Description: "Text that must appear in the title or description (case-insensitive)",
+				},
+				"include_superseded": {
+					Type:        "boolean",
+					Description: "Also return entries replaced by newer ones (default: false)",
+				},
+				"max_results": {
+					Type:        "integer",
+					Description: "Maximum number of entries to return (default: 20)",
+				},
+			},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			tagArg, _ := args["tags"].(string)
+			category, _ := args["category"].(string)
+			query, _ := args["query"].(string)
+			includeSuperseded, _ := args["include_superseded"].(bool)
+			maxResults := 20
+			if v, ok := args["max_results"].(float64); ok && v > 0 {
+				maxResults = int(v)
+			}
+
+			var tags []string
+			for _, tag := range strings.Split(tagArg, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+
+			all, err := b.All()
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
+			queryLower := strings.ToLower(query)
+			var matches []brain.Entry
+			for i := len(all) - 1; i >= 0 && len(matches) < maxResults; i-- {
+				e := all[i]
+				if !e.Active() && !includeSuperseded {
+					continue
+				}
+				if category != "" && e.Category != category {
+					continue
+				}
+				if !e.HasTags(tags...) {
+					continue
+				}
+				if query != "" && !strings.Contains(strings.ToLower(e.Title+"\n"+e.Description), queryLower) {
+					continue
+				}
+				matches = append(matches, e)
+			}
+
+			if len(matches) == 0 {
+				return tools.ToolResult{Success: true, Output: "No matching brain entries."}, nil
+			}
+
+			ids := make([]string, len(matches))
+			var sb strings.Builder
+			for i, e := range matches {
+				ids[i] = e.ID
+				if i > 0 {
+					sb.WriteString("\n\n")
+				}
+				sb.WriteString(fmt.Sprintf("[%s] ", e.Category))
+				if !e.Active() {
+					sb.WriteString(fmt.Sprintf("(superseded by #%s) ", e.SupersededBy))
+				}
+				sb.WriteString(brainEntryText(e))
+			}
+			// Entries the agent looked up count as used, like recalled ones
+			_ = b.MarkUsed(ids...)
+
+			return tools.ToolResult{
+				Success: true,
+				Output:  sb.String(),
+				Data:    map[string]any{"count": len(matches), "ids": ids},
+			}, nil
+		},
+	}
+}
+
+// BrainTagsTool lists the tags in the knowledge base.
+func BrainTagsTool(b *brain.Brain) *tools.Tool {
+	return &tools.Tool{
+		Name:        "brain_tags",
+		Description: "Lists the tags used in the knowledge base (brain) with how many entries carry each, to pick tags for brain_query.",
+		Parameters: &tools.JSONSchema{
+			Type:       "object",
+			Properties: map[string]tools.Property{},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			tags, err := b.Tags()
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
+			if len(tags) == 0 {
+				return tools.ToolResult{Success: true, Output: "The brain has no tagged entries."}, nil
+			}
+			var sb strings.Builder
+			for _, t := range tags {
+				sb.WriteString(fmt.Sprintf("%s (%d)\n", t.Tag, t.Count))
+			}
+			return tools.ToolResult{Success: true, Output: strings.TrimSuffix(sb.String(), "\n")}, nil
+		},
+	}
+}
+
+// RegisterBrainTools registers the knowledge base tools
+func RegisterBrainTools(registry *tools.Registry, b *brain.Brain) {
+	registry.Register(BrainQueryTool(b))
+	registry.Register(BrainTagsTool(b))
+}
+
+// brainEntryText formats an entry with its ID, date and tags.
+func brainEntryText(e brain.Entry) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("#%s %s (%s)\n", e.ID, e.Title, e.Timestamp.Format("2006-01-02")))
+	sb.WriteString(strings.TrimSpace(e.Description))
+	if len(e.Tags) > 0 {
+		sb.WriteString("\nTags: " + strings.Join(e.Tags, ", "))
+	}
+	return sb.String()
+}
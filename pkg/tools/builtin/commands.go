@@ -3,17 +3,22 @@ package builtin
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"time"
 
+	"ClosedWheeler/pkg/sandbox"
 	"ClosedWheeler/pkg/security"
 	"ClosedWheeler/pkg/tools"
 )
 
 // execDescription returns a runtime-accurate description for exec_command.
-func execDescription() string {
+func execDescription(sandboxed bool) string {
+	if sandboxed {
+		return "Execute a shell command via sh in the workplace directory, inside a sandbox: " +
+			"only the workplace and /tmp are writable, the network may be unreachable and " +
+			"the environment has only PATH, HOME and locale settings."
+	}
 	if runtime.GOOS == "windows" {
 		return "Execute a shell command via cmd.exe in the workplace directory. " +
 			"IMPORTANT: This is Windows — use Windows commands only: " +
@@ -25,11 +30,12 @@ func execDescription() string {
 		"The $PATH is the system PATH — all installed programs are accessible."
 }
 
-// ExecCommandTool creates a tool for executing shell commands with a security auditor
-func ExecCommandTool(projectRoot string, timeout time.Duration, auditor *security.Auditor) *tools.Tool {
+// ExecCommandTool creates a tool for executing shell commands with a security
+// auditor. Commands run in sb when it is enabled; sb may be nil.
+func ExecCommandTool(projectRoot string, timeout time.Duration, auditor *security.Auditor, sb *sandbox.Sandbox) *tools.Tool {
 	return &tools.Tool{
 		Name:        "exec_command",
-		Description: execDescription(),
+		Description: execDescription(sb.Enabled()),
 		Parameters: &tools.JSONSchema{
 			Type: "object",
 			Properties: map[string]tools.Property{
@@ -64,16 +70,19 @@ func ExecCommandTool(projectRoot string, timeout time.Duration, auditor *securit
 				}, nil
 			}
 
-			// Build command
+			// Build command; outside the sandbox it inherits the full system
+			// PATH and environment
 			var cmd *exec.Cmd
+			var err error
 			if runtime.GOOS == "windows" {
-				cmd = exec.Command("cmd", "/c", fullCmd)
+				cmd, err = sb.Command("cmd", "/c", fullCmd)
 			} else {
-				cmd = exec.Command("sh", "-c", fullCmd)
+				cmd, err = sb.Command("sh", "-c", fullCmd)
+			}
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
 			}
-
 			cmd.Dir = projectRoot
-			cmd.Env = os.Environ() // Inherit full system PATH and environment
 
 			// Capture output
 			var stdout, stderr bytes.Buffer
@@ -124,8 +133,8 @@ func ExecCommandTool(projectRoot string, timeout time.Duration, auditor *securit
 	}
 }
 
-// RunTestsTool creates a tool for running tests
-func RunTestsTool(projectRoot string) *tools.Tool {
+// RunTestsTool creates a tool for running tests, in sb when it is enabled
+func RunTestsTool(projectRoot string, sb *sandbox.Sandbox) *tools.Tool {
 	return &tools.Tool{
 		Name:        "run_tests",
 		Description: "Run tests for the project",
@@ -158,14 +167,17 @@ func RunTestsTool(projectRoot string) *tools.Tool {
 			}
 			cmdArgs = append(cmdArgs, testPath)
 
-			cmd := exec.Command("go", cmdArgs...)
+			cmd, err := sb.Command("go", cmdArgs...)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
 			cmd.Dir = projectRoot
 
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
 			cmd.Stderr = &stderr
 
-			err := cmd.Run()
+			err = cmd.Run()
 
 			output := stdout.String()
 			if stderr.Len() > 0 {
@@ -190,8 +202,8 @@ func RunTestsTool(projectRoot string) *tools.Tool {
 	}
 }
 
-// GoBuildTool creates a tool for building Go projects
-func GoBuildTool(projectRoot string) *tools.Tool {
+// GoBuildTool creates a tool for building Go projects, in sb when it is enabled
+func GoBuildTool(projectRoot string, sb *sandbox.Sandbox) *tools.Tool {
 	return &tools.Tool{
 		Name:        "go_build",
 		Description: "Build the Go project",
@@ -213,14 +225,17 @@ func GoBuildTool(projectRoot string) *tools.Tool {
 
 			cmdArgs = append(cmdArgs, ".")
 
-			cmd := exec.Command("go", cmdArgs...)
+			cmd, err := sb.Command("go", cmdArgs...)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
 			cmd.Dir = projectRoot
 
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
 			cmd.Stderr = &stderr
 
-			err := cmd.Run()
+			err = cmd.Run()
 
 			if err != nil {
 				out := stdout.String()
@@ -242,9 +257,10 @@ func GoBuildTool(projectRoot string) *tools.Tool {
 	}
 }
 
-// RegisterCommandTools registers command-related tools
-func RegisterCommandTools(registry *tools.Registry, projectRoot string, auditor *security.Auditor) {
-	registry.Register(ExecCommandTool(projectRoot, 60*time.Second, auditor))
-	registry.Register(RunTestsTool(projectRoot))
-	registry.Register(GoBuildTool(projectRoot))
+// RegisterCommandTools registers command-related tools; they run commands in
+// sb when it is enabled
+func RegisterCommandTools(registry *tools.Registry, projectRoot string, auditor *security.Auditor, sb *sandbox.Sandbox) {
+	registry.Register(ExecCommandTool(projectRoot, 60*time.Second, auditor, sb))
+	registry.Register(RunTestsTool(projectRoot, sb))
+	registry.Register(GoBuildTool(projectRoot, sb))
 }
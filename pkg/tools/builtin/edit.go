@@ -0,0 +1,438 @@
+package builtin
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"ClosedWheeler/pkg/editor"
+	"ClosedWheeler/pkg/security"
+	"ClosedWheeler/pkg/tools"
+)
+
+// replacement is one search/replace block of edit_file.
+type replacement struct {
+	old string
+	new string
+	all bool
+}
+
+// EditFileTool creates a tool for targeted search/replace edits. Changes are
+// recorded in the edit manager's session so they can be reviewed and undone.
+func EditFileTool(projectRoot string, auditor *security.Auditor, edits *editor.Manager) *tools.Tool {
+	return &tools.Tool{
+		Name: "edit_file",
+		Description: "Edit a file by replacing exact text. Prefer this over write_file for changes to existing files. " +
+			"old_string must match the file exactly (including indentation) and only once; include enough surrounding lines to make it unique. " +
+			"For several changes in one file, pass SEARCH/REPLACE blocks in 'blocks' instead. " +
+			"An empty old_string creates a new file with new_string.",
+		Parameters: &tools.JSONSchema{
+			Type: "object",
+			Properties: map[string]tools.Property{
+				"path": {
+					Type:        "string",
+					Description: "Path to the file (relative to project root)",
+				},
+				"old_string": {
+					Type:        "string",
+					Description: "Exact text to replace",
+				},
+				"new_string": {
+					Type:        "string",
+					Description: "Replacement text",
+				},
+				"replace_all": {
+					Type:        "boolean",
+					Description: "Replace every occurrence of old_string instead of requiring a unique match",
+				},
+				"blocks": {
+					Type: "string",
+					Description: "One or more blocks applied in order, each:\n<<<<<<< SEARCH\nexact lines to find\n=======\nreplacement lines\n>>>>>>> REPLACE\n" +
+						"Each SEARCH must match exactly once. Use instead of old_string/new_string.",
+				},
+			},
+			Required: []string{"path"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			path, ok := args["path"].(string)
+			if !ok {
+				return tools.ToolResult{
+					Success: false,
+					Error:   "invalid path parameter: must be a string",
+				}, fmt.Errorf("path parameter must be a string, got %T", args["path"])
+			}
+			fullPath := filepath.Join(projectRoot, path)
+
+			// Security check using auditor
+			if err := auditor.AuditPath(fullPath); err != nil {
+				return tools.ToolResult{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+
+			blocks, err := editReplacements(args)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
+
+			var added []string
+			for _, b := range blocks {
+				added = append(added, b.new)
+			}
+			if err := auditor.AuditScript(strings.Join(added, "\n")); err != nil {
+				return tools.ToolResult{
+					Success: false,
+					Error:   fmt.Sprintf("content validation failed: %s", err.Error()),
+				}, nil
+			}
+
+			replaced := 0
+			records, err := edits.ApplyChanges("edit_file "+path, []editor.FileChange{{
+				Path: fullPath,
+				Update: func(content string, exists bool) (string, bool, error) {
+					var err error
+					content, replaced, err = applyReplacements(path, content, exists, blocks)
+					return content, false, err
+				},
+			}})
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
+			if len(records) == 0 {
+				return tools.ToolResult{
+					Success: true,
+					Output:  fmt.Sprintf("No changes: the replacement leaves %s unchanged", path),
+				}, nil
+			}
+
+			result := editResult(edits, records, fmt.Sprintf("Edited %s (%d replacements)", path, replaced))
+			result.Data = map[string]any{
+				"path":         path,
+				"edit_id":      records[0].ID,
+				"operation":    records[0].Operation,
+				"replacements": replaced,
+				"review":       records[0].Review,
+			}
+			return result, nil
+		},
+	}
+}
+
+// editReplacements reads either the blocks parameter or
+// old_string/new_string/replace_all.
+func editReplacements(args map[string]any) ([]replacement, error) {
+	if text, ok := args["blocks"].(string); ok && strings.TrimSpace(text) != "" {
+		return parseSearchReplace(text)
+	}
+
+	oldStr, ok := args["old_string"].(string)
+	if !ok {
+		return nil, fmt.Errorf("provide old_string and new_string, or blocks")
+	}
+	newStr, ok := args["new_string"].(string)
+	if !ok {
+		return nil, fmt.Errorf("new_string parameter must be a string")
+	}
+	all, _ := args["replace_all"].(bool)
+	return []replacement{{old: oldStr, new: newStr, all: all}}, nil
+}
+
+// parseSearchReplace parses SEARCH/REPLACE blocks. Block text keeps its
+// trailing newline so removing whole lines leaves no blank line behind.
+func parseSearchReplace(text string) ([]replacement, error) {
+	const (
+		outside = iota
+		inSearch
+		inReplace
+	)
+	var blocks []replacement
+	var search, repl []string
+	state := outside
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		marker := strings.TrimSpace(line)
+		switch {
+		case state == outside && strings.HasPrefix(marker, "<<<<<<<") && strings.Contains(marker, "SEARCH"):
+			search, repl = nil, nil
+			state = inSearch
+		case state == inSearch && strings.HasPrefix(marker, "=======") && strings.Trim(marker, "=") == "":
+			state = inReplace
+		case state == inReplace && strings.HasPrefix(marker, ">>>>>>>") && strings.Contains(marker, "REPLACE"):
+			b := replacement{old: blockText(search), new: blockText(repl)}
+			if b.old == "" {
+				return nil, fmt.Errorf("SEARCH block %d is empty", len(blocks)+1)
+			}
+			blocks = append(blocks, b)
+			state = outside
+		case state == inSearch:
+			search = append(search, line)
+		case state == inReplace:
+			repl = append(repl, line)
+		}
+	}
+
+	if state != outside {
+		return nil, fmt.Errorf("unterminated SEARCH/REPLACE block %d", len(blocks)+1)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no SEARCH/REPLACE blocks found")
+	}
+	return blocks, nil
+}
+
+func blockText(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// applyReplacements applies the blocks in order, requiring each to match
+// exactly once unless it replaces all occurrences.
+func applyReplacements(path, content string, exists bool, blocks []replacement) (string, int, error) {
+	if !exists {
+		if len(blocks) == 1 && blocks[0].old == "" {
+			return blocks[0].new, 1, nil
+		}
+		return "", 0, fmt.Errorf("%s does not exist; use an empty old_string to create it", path)
+	}
+
+	nl := "\n"
+	if strings.Contains(content, "\r\n") {
+		nl = "\r\n"
+	}
+	total := 0
+	for i, b := range blocks {
+		if b.old == "" {
+			return "", 0, fmt.Errorf("old_string must not be empty when %s already exists; use write_file to replace the whole file", path)
+		}
+		if nl == "\r\n" && !strings.Contains(b.old, "\r\n") {
+			b.old = strings.ReplaceAll(b.old, "\n", "\r\n")
+			b.new = strings.ReplaceAll(b.new, "\n", "\r\n")
+		}
+
+		count := strings.Count(content, b.old)
+		if count == 0 && strings.HasSuffix(b.old, nl) {
+			// A block ending at the last line of a file without a final newline.
+			if trimmed := strings.TrimSuffix(b.old, nl); strings.HasSuffix(content, trimmed) {
+				b.old, b.new = trimmed, strings.TrimSuffix(b.new, nl)
+				count = strings.Count(content, b.old)
+			}
+		}
+
+		switch {
+		case count == 0:
+			msg := fmt.Sprintf("text to replace (block %d) not found in %s", i+1, path)
+			if line := similarLine(content, b.old); line > 0 {
+				msg += fmt.Sprintf("; similar text with different whitespace starts at line %d, copy it exactly", line)
+			}
+			return "", 0, fmt.Errorf("%s", msg)
+		case count > 1 && !b.all:
+			return "", 0, fmt.Errorf("text to replace (block %d) matches %d times in %s; include more surrounding lines to make it unique, or set replace_all", i+1, count, path)
+		}
+
+		if b.all {
+			content = strings.ReplaceAll(content, b.old, b.new)
+			total += count
+		} else {
+			content = strings.Replace(content, b.old, b.new, 1)
+			total++
+		}
+	}
+	return content, total, nil
+}
+
+// similarLine returns the 1-based line where text matches content when
+// leading and trailing whitespace is ignored, or 0.
+func similarLine(content, text string) int {
+	lines := strings.Split(content, "\n")
+	want := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	for i := 0; i+len(want) <= len(lines); i++ {
+		match := true
+		for k, w := range want {
+			if strings.TrimSpace(lines[i+k]) != strings.TrimSpace(w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ApplyPatchTool creates a tool that applies a unified diff to one or more
+// files. All files are patched or none are, and each change is recorded in
+// the edit manager's session.
+func ApplyPatchTool(projectRoot string, auditor *security.Auditor, edits *editor.Manager) *tools.Tool {
+	return &tools.Tool{
+		Name: "apply_patch",
+		Description: "Apply a unified diff (as produced by `diff -u` or `git diff`) to one or more files. " +
+			"Use --- /dev/null to create a file and +++ /dev/null to delete one. " +
+			"Hunks are located by their context lines, so line numbers may be approximate.",
+		Parameters: &tools.JSONSchema{
+			Type: "object",
+			Properties: map[string]tools.Property{
+				"patch": {
+					Type:        "string",
+					Description: "Unified diff with --- a/path and +++ b/path headers (paths relative to project root)",
+				},
+			},
+			Required: []string{"patch"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			patchText, ok := args["patch"].(string)
+			if !ok {
+				return tools.ToolResult{
+					Success: false,
+					Error:   "invalid patch parameter: must be a string",
+				}, fmt.Errorf("patch parameter must be a string, got %T", args["patch"])
+			}
+
+			patches, err := editor.ParsePatch(patchText)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: fmt.Sprintf("invalid patch: %v", err)}, nil
+			}
+
+			var changes []editor.FileChange
+			var added []string
+			seen := make(map[string]bool)
+			for _, p := range patches {
+				if p.Operation() == "modify" && p.OldPath != p.NewPath {
+					return tools.ToolResult{
+						Success: false,
+						Error:   fmt.Sprintf("renaming %s to %s is not supported; delete and create the file instead", p.OldPath, p.NewPath),
+					}, nil
+				}
+				path := p.Path()
+				fullPath := filepath.Join(projectRoot, path)
+				if err := auditor.AuditPath(fullPath); err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				if seen[fullPath] {
+					return tools.ToolResult{Success: false, Error: fmt.Sprintf("patch changes %s more than once; merge its hunks", path)}, nil
+				}
+				seen[fullPath] = true
+
+				for _, h := range p.Hunks {
+					for _, line := range h.Lines {
+						if strings.HasPrefix(line, "+") {
+							added = append(added, line[1:])
+						}
+					}
+				}
+				changes = append(changes, editor.FileChange{Path: fullPath, Update: patchUpdate(path, p)})
+			}
+
+			if err := auditor.AuditScript(strings.Join(added, "\n")); err != nil {
+				return tools.ToolResult{
+					Success: false,
+					Error:   fmt.Sprintf("content validation failed: %s", err.Error()),
+				}, nil
+			}
+
+			records, err := edits.ApplyChanges(fmt.Sprintf("apply_patch (%d files)", len(patches)), changes)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
+
+			ids := make([]string, 0, len(records))
+			for _, r := range records {
+				ids = append(ids, r.ID)
+			}
+			result := editResult(edits, records, fmt.Sprintf("Applied patch to %d file(s)", len(records)))
+			result.Data = map[string]any{
+				"files":    len(records),
+				"edit_ids": ids,
+			}
+			return result, nil
+		},
+	}
+}
+
+// editResult reports the outcome of ApplyChanges, including edits held for
+// or changed by the user's review. It fails only when every edit was
+// rejected, so the model does not assume the change is in place.
+func editResult(edits *editor.Manager, records []editor.EditRecord, summary string) tools.ToolResult {
+	var out strings.Builder
+	var applied, pending, rejected, partial []string
+	for _, r := range records {
+		name := edits.RelPath(r.FilePath)
+		switch {
+		case r.Review == editor.ReviewPending:
+			pending = append(pending, name)
+		case r.Review == editor.ReviewRejected:
+			rejected = append(rejected, name)
+		default:
+			if r.Review == editor.ReviewPartial {
+				partial = append(partial, name)
+			}
+			applied = append(applied, edits.EditDiff(r))
+		}
+	}
+
+	if len(pending) > 0 {
+		return tools.ToolResult{
+			Success: true,
+			Output: fmt.Sprintf("Changes to %s are awaiting the user's review (/review) and have not been applied yet.",
+				strings.Join(pending, ", ")),
+		}
+	}
+	if len(applied) == 0 {
+		return tools.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("The user rejected the changes to %s. Nothing was written.", strings.Join(rejected, ", ")),
+		}
+	}
+
+	out.WriteString(summary + "\n")
+	if len(rejected) > 0 {
+		fmt.Fprintf(&out, "The user rejected the changes to %s; they were not written.\n", strings.Join(rejected, ", "))
+	}
+	if len(partial) > 0 {
+		fmt.Fprintf(&out, "The user accepted only some hunks in %s; the diff below is what was written.\n", strings.Join(partial, ", "))
+	}
+	out.WriteString("\n" + strings.Join(applied, ""))
+	return tools.ToolResult{Success: true, Output: out.String()}
+}
+
+// patchUpdate returns the FileChange update that applies one file's patch.
+func patchUpdate(path string, p editor.FilePatch) func(string, bool) (string, bool, error) {
+	return func(content string, exists bool) (string, bool, error) {
+		switch p.Operation() {
+		case "create":
+			if exists {
+				return "", false, fmt.Errorf("cannot create %s: file already exists", path)
+			}
+			newContent, err := editor.ApplyHunks("", p.Hunks)
+			return newContent, false, err
+		case "delete":
+			if exists && len(p.Hunks) > 0 {
+				if rest, err := editor.ApplyHunks(content, p.Hunks); err != nil || rest != "" {
+					return "", false, fmt.Errorf("patch deletes %s but its removed lines do not match the file", path)
+				}
+			}
+			return "", true, nil
+		default:
+			if !exists {
+				return "", false, fmt.Errorf("cannot patch %s: file does not exist", path)
+			}
+			newContent, err := editor.ApplyHunks(content, p.Hunks)
+			if err != nil {
+				return "", false, fmt.Errorf("%s: %w", path, err)
+			}
+			return newContent, false, nil
+		}
+	}
+}
+
+// RegisterEditTools registers edit_file and apply_patch, and replaces
+// write_file with a version that writes through the edit manager, so every
+// file change can be reviewed and undone.
+func RegisterEditTools(registry *tools.Registry, projectRoot string, auditor *security.Auditor, edits *editor.Manager) {
+	registry.Register(writeFileTool(projectRoot, auditor, edits))
+	registry.Register(EditFileTool(projectRoot, auditor, edits))
+	registry.Register(ApplyPatchTool(projectRoot, auditor, edits))
+}
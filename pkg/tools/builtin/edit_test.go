@@ -0,0 +1,238 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/editor"
+	"ClosedWheeler/pkg/security"
+)
+
+func editTools(t *testing.T) (string, *editor.Manager, func()) {
+	t.Helper()
+	root, cleanup := testRoot(t)
+	return root, editor.NewManager(root, filepath.Join(root, ".agi")), cleanup
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return string(data)
+}
+
+// ----- edit_file -----
+
+func TestEditFile_ReplacesUniqueMatch(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	path := filepath.Join(root, "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc a() int {\n\treturn 1\n}\n"), 0644)
+
+	tool := EditFileTool(root, security.NewAuditor(root), edits)
+	result, err := tool.Handler(map[string]any{
+		"path":       "main.go",
+		"old_string": "\treturn 1",
+		"new_string": "\treturn 2",
+	})
+	if err != nil || !result.Success {
+		t.Fatalf("expected success, got %v / %s", err, result.Error)
+	}
+	if got := readFile(t, path); got != "package main\n\nfunc a() int {\n\treturn 2\n}\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if !strings.Contains(result.Output, "-\treturn 1\n+\treturn 2") {
+		t.Errorf("output should show the diff: %s", result.Output)
+	}
+	if pending := edits.CurrentSession().Edits; len(pending) != 1 || !pending[0].Applied {
+		t.Errorf("edit should be recorded in the session, got %+v", pending)
+	}
+}
+
+func TestEditFile_RejectsAmbiguousAndMissingText(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	path := filepath.Join(root, "a.txt")
+	os.WriteFile(path, []byte("x = 1\nx = 1\n    indented line\n"), 0644)
+	tool := EditFileTool(root, security.NewAuditor(root), edits)
+
+	result, _ := tool.Handler(map[string]any{"path": "a.txt", "old_string": "x = 1", "new_string": "x = 2"})
+	if result.Success || !strings.Contains(result.Error, "matches 2 times") {
+		t.Errorf("expected ambiguity error, got %+v", result)
+	}
+
+	result, _ = tool.Handler(map[string]any{"path": "a.txt", "old_string": "indented line\n", "new_string": "y\n"})
+	if !result.Success {
+		t.Errorf("substring match should succeed: %s", result.Error)
+	}
+
+	result, _ = tool.Handler(map[string]any{"path": "a.txt", "old_string": "  x = 1\n  x = 1", "new_string": "z"})
+	if result.Success || !strings.Contains(result.Error, "line 1") {
+		t.Errorf("expected a whitespace hint, got %+v", result)
+	}
+
+	result, _ = tool.Handler(map[string]any{"path": "a.txt", "old_string": "x = 1", "new_string": "x = 3", "replace_all": true})
+	if !result.Success {
+		t.Fatalf("replace_all failed: %s", result.Error)
+	}
+	if got := readFile(t, path); got != "x = 3\nx = 3\n    y\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestEditFile_SearchReplaceBlocks(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	path := filepath.Join(root, "list.txt")
+	os.WriteFile(path, []byte("one\ntwo\nthree\nfour"), 0644)
+
+	tool := EditFileTool(root, security.NewAuditor(root), edits)
+	result, _ := tool.Handler(map[string]any{
+		"path": "list.txt",
+		"blocks": "<<<<<<< SEARCH\ntwo\n=======\n>>>>>>> REPLACE\n" +
+			"<<<<<<< SEARCH\nfour\n=======\n4\n>>>>>>> REPLACE\n",
+	})
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Error)
+	}
+	if got := readFile(t, path); got != "one\nthree\n4" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
+
+func TestEditFile_FailedBlockChangesNothing(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	path := filepath.Join(root, "a.txt")
+	os.WriteFile(path, []byte("a\nb\n"), 0644)
+
+	tool := EditFileTool(root, security.NewAuditor(root), edits)
+	result, _ := tool.Handler(map[string]any{
+		"path":   "a.txt",
+		"blocks": "<<<<<<< SEARCH\na\n=======\nA\n>>>>>>> REPLACE\n<<<<<<< SEARCH\nmissing\n=======\nx\n>>>>>>> REPLACE\n",
+	})
+	if result.Success {
+		t.Fatal("expected failure for the missing block")
+	}
+	if got := readFile(t, path); got != "a\nb\n" {
+		t.Errorf("file should be unchanged, got %q", got)
+	}
+}
+
+func TestEditFile_CreatesAndRejectsTraversal(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	tool := EditFileTool(root, security.NewAuditor(root), edits)
+
+	result, _ := tool.Handler(map[string]any{"path": "new/file.txt", "old_string": "", "new_string": "hi\n"})
+	if !result.Success {
+		t.Fatalf("expected create to succeed: %s", result.Error)
+	}
+	if got := readFile(t, filepath.Join(root, "new", "file.txt")); got != "hi\n" {
+		t.Errorf("unexpected content %q", got)
+	}
+
+	result, _ = tool.Handler(map[string]any{"path": "../../etc/passwd", "old_string": "root", "new_string": "x"})
+	if result.Success {
+		t.Error("expected security violation for path traversal")
+	}
+}
+
+// ----- apply_patch -----
+
+func TestApplyPatch_MultipleFilesAndUndo(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("1\n2\n3\n"), 0644)
+	os.WriteFile(filepath.Join(root, "old.txt"), []byte("bye\n"), 0644)
+
+	patch := `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ 1
+-2
++two
+ 3
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	edits.BeginTurn("patch")
+	tool := ApplyPatchTool(root, security.NewAuditor(root), edits)
+	result, _ := tool.Handler(map[string]any{"patch": patch})
+	if !result.Success {
+		t.Fatalf("expected success, got %s", result.Error)
+	}
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "1\ntwo\n3\n" {
+		t.Errorf("unexpected a.txt %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, "new.txt")); got != "hello\n" {
+		t.Errorf("unexpected new.txt %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should be deleted")
+	}
+
+	undone, err := edits.UndoTurns(1)
+	if err != nil || len(undone) != 1 || len(undone[0].Files) != 3 {
+		t.Fatalf("the whole patch should undo at once: %v, %+v", err, undone)
+	}
+	if got := readFile(t, filepath.Join(root, "old.txt")); got != "bye\n" {
+		t.Errorf("old.txt not restored: %q", got)
+	}
+}
+
+func TestApplyPatch_IsAllOrNothing(t *testing.T) {
+	root, edits, cleanup := editTools(t)
+	defer cleanup()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a\n"), 0644)
+	os.WriteFile(filepath.Join(root, "b.txt"), []byte("b\n"), 0644)
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-not b\n+B\n"
+	tool := ApplyPatchTool(root, security.NewAuditor(root), edits)
+	result, _ := tool.Handler(map[string]any{"patch": patch})
+	if result.Success || !strings.Contains(result.Error, "b.txt") {
+		t.Fatalf("expected failure naming b.txt, got %+v", result)
+	}
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "a\n" {
+		t.Errorf("a.txt should be unchanged, got %q", got)
+	}
+}
+
+// ----- previews -----
+
+func TestPreviewChange(t *testing.T) {
+	root, _, cleanup := editTools(t)
+	defer cleanup()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("one\ntwo\n"), 0644)
+
+	edit := PreviewChange(root, "edit_file", map[string]any{"path": "a.txt", "old_string": "two", "new_string": "2"})
+	if !strings.Contains(edit, "--- a/a.txt\n+++ b/a.txt\n") || !strings.Contains(edit, "-two\n+2\n") {
+		t.Errorf("edit_file preview:\n%s", edit)
+	}
+	if got := readFile(t, filepath.Join(root, "a.txt")); got != "one\ntwo\n" {
+		t.Errorf("preview changed the file: %q", got)
+	}
+
+	created := PreviewChange(root, "write_file", map[string]any{"path": "b.txt", "content": "new\n"})
+	if !strings.Contains(created, "--- /dev/null\n+++ b/b.txt\n") || !strings.Contains(created, "+new\n") {
+		t.Errorf("write_file preview:\n%s", created)
+	}
+	if got := PreviewChange(root, "edit_file", map[string]any{"path": "a.txt", "old_string": "missing", "new_string": "x"}); got != "" {
+		t.Errorf("failing edit should have no preview, got:\n%s", got)
+	}
+	if got := PreviewChange(root, "exec_command", map[string]any{"command": "ls"}); got != "" {
+		t.Errorf("exec_command preview = %q", got)
+	}
+}
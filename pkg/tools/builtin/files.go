@@ -6,9 +6,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"ClosedWheeler/pkg/config"
+	"ClosedWheeler/pkg/editor"
+	"ClosedWheeler/pkg/ignore"
+	"ClosedWheeler/pkg/sandbox"
+	"ClosedWheeler/pkg/search"
 	"ClosedWheeler/pkg/security"
 	"ClosedWheeler/pkg/tools"
 )
@@ -108,6 +113,12 @@ func ReadFileTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 
 // WriteFileTool creates a tool for writing files
 func WriteFileTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
+	return writeFileTool(projectRoot, auditor, nil)
+}
+
+// writeFileTool writes directly, or through edits when it is set so the
+// write is reviewed and recorded like edit_file changes.
+func writeFileTool(projectRoot string, auditor *security.Auditor, edits *editor.Manager) *tools.Tool {
 	return &tools.Tool{
 		Name:        "write_file",
 		Description: "Write content to a file. Creates the file if it doesn't exist.",
@@ -169,6 +180,31 @@ func WriteFileTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 				}, nil
 			}
 
+			if edits != nil {
+				records, err := edits.ApplyChanges("write_file "+path, []editor.FileChange{{
+					Path: fullPath,
+					Update: func(existing string, exists bool) (string, bool, error) {
+						if appendMode {
+							return existing + content, false, nil
+						}
+						return content, false, nil
+					},
+				}})
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				summary := fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), path)
+				if len(records) == 0 {
+					return tools.ToolResult{Success: true, Output: summary + " (unchanged)"}, nil
+				}
+				result := editResult(edits, records, summary)
+				if result.Success && records[0].Applied {
+					// Whole-file diffs are as long as the file; keep the summary.
+					result.Output = summary
+				}
+				return result, nil
+			}
+
 			// Create directory if needed
 			dir := filepath.Dir(fullPath)
 			if err := os.MkdirAll(dir, 0755); err != nil {
@@ -320,33 +356,50 @@ func ListFilesTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 	}
 }
 
-// SearchCodeTool creates a tool for searching code
-func SearchCodeTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
+// SearchCodeTool creates a tool for searching code through the workplace
+// search index
+func SearchCodeTool(index *search.Index, auditor *security.Auditor) *tools.Tool {
 	return &tools.Tool{
 		Name:        "search_code",
-		Description: "Search for text or patterns in code files",
+		Description: "Search code by text, regular expression or symbol name; results are ranked and shown with context lines",
 		Parameters: &tools.JSONSchema{
 			Type: "object",
 			Properties: map[string]tools.Property{
 				"query": {
 					Type:        "string",
-					Description: "Text or pattern to search for",
+					Description: "Text, regular expression or symbol name to search for",
+				},
+				"regex": {
+					Type:        "boolean",
+					Description: "If true, query is a regular expression (RE2 syntax)",
+				},
+				"symbols": {
+					Type:        "boolean",
+					Description: "If true, search declared functions, methods and types instead of file text",
 				},
 				"file_pattern": {
 					Type:        "string",
-					Description: "Glob pattern for files to search (e.g., '*.go')",
+					Description: "Glob pattern for files to search (e.g., '*.go', or 'pkg/*/*.go' to match the path)",
 				},
 				"case_sensitive": {
 					Type:        "boolean",
 					Description: "If true, search is case sensitive",
 				},
+				"context_lines": {
+					Type:        "integer",
+					Description: "Lines of context to show around each match (default 1)",
+				},
+				"max_results": {
+					Type:        "integer",
+					Description: "Maximum number of results (default 50)",
+				},
 			},
 			Required: []string{"query"},
 		},
 		Handler: func(args map[string]any) (tools.ToolResult, error) {
-			// Search always starts at projectRoot and is internal,
-			// but we can still audit the projectRoot just in case.
-			if err := auditor.AuditPath(projectRoot); err != nil {
+			// Search always starts at the index root and is internal,
+			// but we can still audit it just in case.
+			if err := auditor.AuditPath(index.Root()); err != nil {
 				return tools.ToolResult{Success: false, Error: err.Error()}, nil
 			}
 
@@ -357,62 +410,26 @@ func SearchCodeTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 					Error:   "invalid query parameter: must be a string",
 				}, fmt.Errorf("query parameter must be a string, got %T", args["query"])
 			}
-			filePattern := "*"
-			if p, ok := args["file_pattern"].(string); ok && p != "" {
-				filePattern = p
+			q := search.Query{Pattern: query, CaseSensitive: true, Context: 1}
+			if p, ok := args["file_pattern"].(string); ok {
+				q.Glob = p
 			}
-			caseSensitive := true
 			if cs, ok := args["case_sensitive"].(bool); ok {
-				caseSensitive = cs
+				q.CaseSensitive = cs
 			}
-
-			searchQuery := query
-			if !caseSensitive {
-				searchQuery = strings.ToLower(query)
+			q.Regex, _ = args["regex"].(bool)
+			q.Symbols, _ = args["symbols"].(bool)
+			if n, ok := args["context_lines"].(float64); ok && n >= 0 {
+				q.Context = min(int(n), 10)
+			}
+			if n, ok := args["max_results"].(float64); ok && n > 0 {
+				q.Limit = int(n)
 			}
 
-			var results []string
-
-			filepath.WalkDir(projectRoot, func(path string, d os.DirEntry, err error) error {
-				if err != nil || d == nil || d.IsDir() {
-					return nil
-				}
-
-				// Skip common ignores
-				relPath, _ := filepath.Rel(projectRoot, path)
-				if strings.Contains(relPath, "node_modules") ||
-					strings.Contains(relPath, "vendor") ||
-					strings.Contains(relPath, ".git") {
-					return nil
-				}
-
-				// Check file pattern
-				matched, _ := filepath.Match(filePattern, filepath.Base(path))
-				if !matched && filePattern != "*" {
-					return nil
-				}
-
-				content, err := os.ReadFile(path)
-				if err != nil {
-					return nil
-				}
-
-				lines := strings.Split(string(content), "\n")
-				for i, line := range lines {
-					searchLine := line
-					if !caseSensitive {
-						searchLine = strings.ToLower(line)
-					}
-
-					if strings.Contains(searchLine, searchQuery) {
-						results = append(results, fmt.Sprintf("%s:%d: %s",
-							relPath, i+1, strings.TrimSpace(line)))
-					}
-				}
-
-				return nil
-			})
-
+			results, total, err := index.Search(q)
+			if err != nil {
+				return tools.ToolResult{Success: false, Error: err.Error()}, nil
+			}
 			if len(results) == 0 {
 				return tools.ToolResult{
 					Success: true,
@@ -420,17 +437,34 @@ func SearchCodeTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 				}, nil
 			}
 
-			// Limit results
-			if len(results) > 50 {
-				results = results[:50]
-				results = append(results, "... and more (showing first 50)")
+			// grep-style: "path:line: match", "path-line- context", "--" between groups
+			var sb strings.Builder
+			for i, r := range results {
+				if i > 0 && q.Context > 0 {
+					sb.WriteString("--\n")
+				}
+				for j, line := range r.Before {
+					sb.WriteString(fmt.Sprintf("%s-%d- %s\n", r.Path, r.Line-len(r.Before)+j, line))
+				}
+				text := strings.TrimSpace(r.Text)
+				if r.Symbol != nil {
+					text = fmt.Sprintf("[%s] %s", r.Symbol.Kind, text)
+				}
+				sb.WriteString(fmt.Sprintf("%s:%d: %s\n", r.Path, r.Line, text))
+				for j, line := range r.After {
+					sb.WriteString(fmt.Sprintf("%s-%d- %s\n", r.Path, r.Line+1+j, line))
+				}
+			}
+			if total > len(results) {
+				sb.WriteString(fmt.Sprintf("... and %d more (showing the best %d)\n", total-len(results), len(results)))
 			}
 
 			return tools.ToolResult{
 				Success: true,
-				Output:  strings.Join(results, "\n"),
+				Output:  strings.TrimRight(sb.String(), "\n"),
 				Data: map[string]any{
-					"count": len(results),
+					"count":   total,
+					"results": results,
 				},
 			}, nil
 		},
@@ -439,8 +473,26 @@ func SearchCodeTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 
 // BuiltinOption configures optional tool sets in RegisterBuiltinTools.
 type BuiltinOption struct {
-	EnableSSH bool              // Register SSH tools
-	SSHConfig *config.SSHConfig // SSH configuration (hosts, deny commands, visual mode)
+	EnableSSH      bool              // Register SSH tools
+	SSHConfig      *config.SSHConfig // SSH configuration (hosts, deny commands, visual mode)
+	Sandbox        *sandbox.Sandbox  // Sandbox for exec_command, run_tests and go_build
+	IgnorePatterns []string          // Configured ignore_patterns, skipped by the search index
+}
+
+// dependencyDirs are never indexed: they are large and not the project's own code.
+var dependencyDirs = []string{"node_modules/", "vendor/"}
+
+// IndexIgnore returns the patterns the search index skips: the workplace's
+// .agiignore (or its defaults), the configured patterns and the dependency
+// directories.
+func IndexIgnore(projectRoot string, configured []string) *ignore.Patterns {
+	patterns := ignore.Load(projectRoot)
+	for _, p := range slices.Concat(configured, dependencyDirs) {
+		if !slices.Contains(patterns.List(), p) {
+			patterns.Add(p)
+		}
+	}
+	return patterns
 }
 
 // RegisterBuiltinTools registers all builtin tools to a registry.
@@ -449,7 +501,16 @@ func RegisterBuiltinTools(registry *tools.Registry, projectRoot string, appPath
 	registry.Register(ReadFileTool(projectRoot, auditor))
 	registry.Register(WriteFileTool(projectRoot, auditor))
 	registry.Register(ListFilesTool(projectRoot, auditor))
-	registry.Register(SearchCodeTool(projectRoot, auditor))
+
+	var opt BuiltinOption
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	// Index the workplace for search_code; the index lives with the app data
+	patterns := IndexIgnore(projectRoot, opt.IgnorePatterns)
+	index := search.NewIndex(projectRoot, filepath.Join(appPath, ".agi", "index"), patterns)
+	registry.Register(SearchCodeTool(index, auditor))
 
 	// Register Git tools only if explicitly enabled
 	if enableGitTools {
@@ -460,10 +521,10 @@ func RegisterBuiltinTools(registry *tools.Registry, projectRoot string, appPath
 	RegisterDiagnosticsTools(registry)
 
 	// Register Analysis tools
-	RegisterAnalysisTools(registry, projectRoot)
+	RegisterAnalysisTools(registry, projectRoot, patterns)
 
 	// Register Command tools
-	RegisterCommandTools(registry, projectRoot, auditor)
+	RegisterCommandTools(registry, projectRoot, auditor, opt.Sandbox)
 
 	// Register Task Management tools
 	registry.Register(TaskManagerTool(projectRoot, auditor))
@@ -475,7 +536,7 @@ func RegisterBuiltinTools(registry *tools.Registry, projectRoot string, appPath
 	}
 
 	// Register SSH tools only if explicitly enabled
-	if len(opts) > 0 && opts[0].EnableSSH && opts[0].SSHConfig != nil {
-		RegisterSSHTools(registry, appPath, opts[0].SSHConfig)
+	if opt.EnableSSH && opt.SSHConfig != nil {
+		RegisterSSHTools(registry, appPath, opt.SSHConfig)
 	}
 }
@@ -0,0 +1,57 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+
+	"ClosedWheeler/pkg/editor"
+)
+
+// PreviewChange returns the unified diff a write_file, edit_file or
+// apply_patch call would make, so it can be shown before the call runs. It
+// returns "" for other tools and for calls whose change cannot be worked
+// out; those calls report their error when they run.
+func PreviewChange(projectRoot, tool string, args map[string]any) string {
+	if tool == "apply_patch" {
+		patch, _ := args["patch"].(string)
+		return patch
+	}
+
+	path, _ := args["path"].(string)
+	if path == "" {
+		return ""
+	}
+	old, exists := "", false
+	if data, err := os.ReadFile(filepath.Join(projectRoot, path)); err == nil {
+		old, exists = string(data), true
+	}
+
+	var updated string
+	switch tool {
+	case "write_file":
+		content, ok := args["content"].(string)
+		if !ok {
+			return ""
+		}
+		updated = content
+		if appendMode, _ := args["append"].(bool); appendMode {
+			updated = old + content
+		}
+	case "edit_file":
+		blocks, err := editReplacements(args)
+		if err != nil {
+			return ""
+		}
+		if updated, _, err = applyReplacements(path, old, exists, blocks); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	oldPath := path
+	if !exists {
+		oldPath = editor.DevNull
+	}
+	return editor.UnifiedDiff(oldPath, path, old, updated)
+}
@@ -0,0 +1,226 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+
+	"ClosedWheeler/pkg/roadmap"
+	"ClosedWheeler/pkg/tools"
+)
+
+// RoadmapTool manages the roadmap goal graph: goals with dependencies,
+// estimates and linked task.md items.
+func RoadmapTool(r *roadmap.Roadmap) *tools.Tool {
+	return &tools.Tool{
+		Name: "roadmap",
+		Description: "Manages the strategic roadmap (workplace/roadmap.md): goals with dependencies, estimates and linked task.md items. " +
+			"Use 'next' to find the goals that can be worked on now, 'add'/'update' to plan, 'link_task' to connect task.md items " +
+			"(their checkboxes drive goal progress), 'list' and 'critical_path' for an overview.",
+		Parameters: &tools.JSONSchema{
+			Type: "object",
+			Properties: map[string]tools.Property{
+				"action": {
+					Type:        "string",
+					Enum:        []string{"add", "update", "link_task", "next", "list", "critical_path"},
+					Description: "Action to perform",
+				},
+				"id": {
+					Type:        "string",
+					Description: "Goal ID (for update/link_task)",
+				},
+				"title": {
+					Type:        "string",
+					Description: "Goal title (required for add)",
+				},
+				"description": {
+					Type:        "string",
+					Description: "Goal description",
+				},
+				"priority": {
+					Type:        "string",
+					Enum:        roadmap.Priorities,
+					Description: "Goal priority (default: medium)",
+				},
+				"status": {
+					Type:        "string",
+					Enum:        roadmap.Statuses,
+					Description: "Goal status; goals with linked tasks move to in-progress and completed on their own",
+				},
+				"depends_on": {
+					Type:        "string",
+					Description: "Comma-separated IDs of goals that must be completed first (replaces the current list on update)",
+				},
+				"estimate_hours": {
+					Type:        "number",
+					Description: "Estimated hours of work",
+				},
+				"tasks": {
+					Type:        "string",
+					Description: "task.md items that make up the goal, one per line, by ID (t3) or title; missing ones are added to task.md",
+				},
+			},
+			Required: []string{"action"},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			action, _ := args["action"].(string)
+			id, _ := args["id"].(string)
+
+			switch action {
+			case "add":
+				title, _ := args["title"].(string)
+				if title == "" {
+					return tools.ToolResult{Success: false, Error: "missing required parameter: title"}, nil
+				}
+				goal := roadmap.Goal{Title: title}
+				applyGoalArgs(&goal, args)
+				added, err := r.Add(goal)
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				return tools.ToolResult{
+					Success: true,
+					Output:  fmt.Sprintf("Added goal %s: %s", added.ID, added.Title),
+					Data:    map[string]any{"id": added.ID},
+				}, nil
+
+			case "update", "link_task":
+				if id == "" {
+					return tools.ToolResult{Success: false, Error: "missing required parameter: id"}, nil
+				}
+				var err error
+				if action == "update" {
+					err = r.UpdateGoal(id, func(g *roadmap.Goal) { applyGoalArgs(g, args) })
+				} else {
+					tasks, _ := args["tasks"].(string)
+					if strings.TrimSpace(tasks) == "" {
+						return tools.ToolResult{Success: false, Error: "missing required parameter: tasks"}, nil
+					}
+					err = r.LinkTasks(id, splitTasks(tasks)...)
+				}
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				return tools.ToolResult{Success: true, Output: fmt.Sprintf("Goal %s updated.", id)}, nil
+
+			case "next":
+				goals, err := r.Actionable()
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				if len(goals) == 0 {
+					return tools.ToolResult{Success: true, Output: "No actionable goals: every goal is completed, blocked or waiting on a dependency."}, nil
+				}
+				var sb strings.Builder
+				for i, g := range goals {
+					if i > 0 {
+						sb.WriteString("\n")
+					}
+					sb.WriteString(formatGoalState(g))
+				}
+				return tools.ToolResult{Success: true, Output: sb.String(), Data: map[string]any{"next": goals[0].ID}}, nil
+
+			case "list":
+				goals, err := r.Goals()
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				if len(goals) == 0 {
+					return tools.ToolResult{Success: true, Output: "The roadmap has no goals."}, nil
+				}
+				var sb strings.Builder
+				for i, g := range goals {
+					if i > 0 {
+						sb.WriteString("\n")
+					}
+					sb.WriteString(formatGoalState(g))
+				}
+				return tools.ToolResult{Success: true, Output: sb.String()}, nil
+
+			case "critical_path":
+				path, hours, err := r.CriticalPath()
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				if len(path) == 0 {
+					return tools.ToolResult{Success: true, Output: "No remaining work on the roadmap."}, nil
+				}
+				var sb strings.Builder
+				sb.WriteString(fmt.Sprintf("Critical path (%.1fh remaining):\n", hours))
+				for i, g := range path {
+					sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatGoalState(g)))
+				}
+				return tools.ToolResult{Success: true, Output: strings.TrimSuffix(sb.String(), "\n")}, nil
+			}
+
+			return tools.ToolResult{Success: false, Error: "Invalid action"}, nil
+		},
+	}
+}
+
+// RegisterRoadmapTools registers the roadmap tool
+func RegisterRoadmapTools(registry *tools.Registry, r *roadmap.Roadmap) {
+	registry.Register(RoadmapTool(r))
+}
+
+// applyGoalArgs copies the goal fields present in the tool arguments.
+func applyGoalArgs(g *roadmap.Goal, args map[string]any) {
+	if v, ok := args["title"].(string); ok && v != "" {
+		g.Title = v
+	}
+	if v, ok := args["description"].(string); ok && v != "" {
+		g.Description = v
+	}
+	if v, ok := args["priority"].(string); ok && v != "" {
+		g.Priority = v
+	}
+	if v, ok := args["status"].(string); ok && v != "" {
+		g.Status = v
+	}
+	if v, ok := args["estimate_hours"].(float64); ok && v >= 0 {
+		g.EstimateHours = v
+	}
+	if v, ok := args["depends_on"].(string); ok {
+		g.Dependencies = nil
+		for _, dep := range strings.Split(v, ",") {
+			if dep = strings.TrimSpace(dep); dep != "" {
+				g.Dependencies = append(g.Dependencies, dep)
+			}
+		}
+	}
+	if v, ok := args["tasks"].(string); ok {
+		g.Tasks = append(g.Tasks, splitTasks(v)...)
+	}
+}
+
+// splitTasks splits one task per line, dropping list and checkbox markers.
+func splitTasks(s string) []string {
+	var tasks []string
+	for _, task := range strings.Split(s, "\n") {
+		task = strings.TrimPrefix(strings.TrimSpace(task), "- ")
+		task = strings.TrimSpace(strings.TrimPrefix(task, "[ ]"))
+		if task != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// formatGoalState describes a goal on one line, followed by its open tasks.
+func formatGoalState(g roadmap.GoalState) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[%s] %s (%s, %s priority", g.ID, g.Title, g.Status, g.Priority))
+	if g.Progress.Total > 0 {
+		sb.WriteString(fmt.Sprintf(", %d/%d tasks", g.Progress.Done, g.Progress.Total))
+	}
+	if g.EstimateHours > 0 {
+		sb.WriteString(fmt.Sprintf(", ~%gh", g.EstimateHours))
+	}
+	sb.WriteString(")")
+	if len(g.BlockedBy) > 0 {
+		sb.WriteString(" waiting on " + strings.Join(g.BlockedBy, ", "))
+	}
+	for _, task := range g.OpenTasks {
+		sb.WriteString(fmt.Sprintf("\n    - %s %s", task.ID, task.Title))
+	}
+	return sb.String()
+}
@@ -7,30 +7,59 @@ import (
 	"strings"
 
 	"ClosedWheeler/pkg/security"
+	"ClosedWheeler/pkg/tasks"
 	"ClosedWheeler/pkg/tools"
 )
 
 // TaskManagerTool provides tools to manage the project's task.md file
 func TaskManagerTool(projectRoot string, auditor *security.Auditor) *tools.Tool {
 	return &tools.Tool{
-		Name:        "manage_tasks",
-		Description: "Manages the project's task.md file. Use to add, update, or list tasks.",
+		Name: "manage_tasks",
+		Description: "Manages the project's task list (workplace/task.md). Tasks have stable IDs (t1, t2, ...), a priority, " +
+			"an assignee (agent or user), notes and sub-tasks. Use 'add' to create a task (pass 'parent' for a sub-task), " +
+			"'update' to change one by ID, 'list' with optional filters, and 'delete' to remove a task with its sub-tasks.",
 		Parameters: &tools.JSONSchema{
 			Type: "object",
 			Properties: map[string]tools.Property{
 				"action": {
 					Type:        "string",
-					Enum:        []string{"add", "update", "list", "sync"},
+					Enum:        []string{"add", "update", "list", "delete", "sync"},
 					Description: "Action to perform",
 				},
+				"id": {
+					Type:        "string",
+					Description: "Task ID (for update/delete)",
+				},
 				"task": {
 					Type:        "string",
-					Description: "Description of the task (for add/update)",
+					Description: "Task title (for add; for update, the new title, or the task to find when no id is given)",
 				},
 				"status": {
 					Type:        "string",
-					Enum:        []string{"todo", "in_progress", "done"},
-					Description: "Status of the task",
+					Enum:        append([]string{"open"}, tasks.Statuses...),
+					Description: "Status of the task (for add/update), or status to filter by (for list; 'open' is anything not done)",
+				},
+				"priority": {
+					Type:        "string",
+					Enum:        tasks.Priorities,
+					Description: "Task priority (default: medium), or priority to filter by",
+				},
+				"assignee": {
+					Type:        "string",
+					Enum:        tasks.Assignees,
+					Description: "Who does the task (default: agent), or assignee to filter by",
+				},
+				"parent": {
+					Type:        "string",
+					Description: "ID of the parent task (for add), or list only its sub-tasks",
+				},
+				"note": {
+					Type:        "string",
+					Description: "Note to attach to the task (for add/update)",
+				},
+				"query": {
+					Type:        "string",
+					Description: "Text to search for in titles and notes (for list)",
 				},
 			},
 			Required: []string{"action"},
@@ -54,92 +83,166 @@ func TaskManagerTool(projectRoot string, auditor *security.Auditor) *tools.Tool
 				}, nil
 			}
 
+			list := tasks.NewList(taskPath)
+			id, _ := args["id"].(string)
+			title, _ := args["task"].(string)
+			status, _ := args["status"].(string)
+			priority, _ := args["priority"].(string)
+			assignee, _ := args["assignee"].(string)
+			parent, _ := args["parent"].(string)
+			note, _ := args["note"].(string)
+
 			switch action {
 			case "list":
-				content, err := os.ReadFile(taskPath)
+				query, _ := args["query"].(string)
+				found, err := list.Find(tasks.Filter{Status: status, Priority: priority, Assignee: assignee, Parent: parent, Query: query})
 				if err != nil {
-					if os.IsNotExist(err) {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				}
+				if len(found) == 0 {
+					if _, err := os.Stat(taskPath); os.IsNotExist(err) {
 						return tools.ToolResult{Success: true, Output: "No task.md found."}, nil
 					}
-					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+					return tools.ToolResult{Success: true, Output: "No matching tasks."}, nil
 				}
-				return tools.ToolResult{Success: true, Output: string(content)}, nil
+				open := 0
+				var sb strings.Builder
+				for _, t := range found {
+					if t.Open() {
+						open++
+					}
+					sb.WriteString(formatTask(t) + "\n")
+				}
+				header := fmt.Sprintf("%d tasks (%d open):\n", len(found), open)
+				return tools.ToolResult{
+					Success: true,
+					Output:  header + strings.TrimSuffix(sb.String(), "\n"),
+					Data:    map[string]any{"count": len(found), "open": open},
+				}, nil
 
 			case "sync":
 				// This is a special internal call to ensure task.md exists
 				if _, err := os.Stat(taskPath); os.IsNotExist(err) {
-					initialContent := "# 📋 Project Tasks\n\n- [ ] Initial project audit\n"
-					if wErr := os.WriteFile(taskPath, []byte(initialContent), 0644); wErr != nil {
-						return tools.ToolResult{Success: false, Error: fmt.Sprintf("failed to create task.md: %v", wErr)}, nil
+					if err := list.Initialize("Initial project audit"); err != nil {
+						return tools.ToolResult{Success: false, Error: fmt.Sprintf("failed to create task.md: %v", err)}, nil
 					}
 					return tools.ToolResult{Success: true, Output: "Created initial task.md"}, nil
 				}
 				return tools.ToolResult{Success: true, Output: "task.md already exists"}, nil
 
 			case "add":
-				task, ok := args["task"].(string)
-				if !ok || task == "" {
+				if title == "" {
 					return tools.ToolResult{Success: false, Error: "missing required parameter: task"}, nil
 				}
-				f, err := os.OpenFile(taskPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-				if err != nil {
-					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				if assignee == "" {
+					assignee = tasks.AssigneeAgent
 				}
-				defer f.Close()
-
-				if _, err := f.WriteString(fmt.Sprintf("- [ ] %s\n", task)); err != nil {
-					return tools.ToolResult{Success: false, Error: err.Error()}, nil
+				t := tasks.Task{Title: title, Status: status, Priority: priority, Assignee: assignee, Parent: parent}
+				if note != "" {
+					t.Notes = []string{note}
 				}
-				return tools.ToolResult{Success: true, Output: "Task added successfully."}, nil
-
-			case "update":
-				task, ok := args["task"].(string)
-				if !ok || task == "" {
-					return tools.ToolResult{Success: false, Error: "missing required parameter: task"}, nil
-				}
-				status, ok := args["status"].(string)
-				if !ok || status == "" {
-					return tools.ToolResult{Success: false, Error: "missing required parameter: status"}, nil
+				added, err := list.Add(t)
+				if err != nil {
+					return tools.ToolResult{Success: false, Error: err.Error()}, nil
 				}
+				return tools.ToolResult{
+					Success: true,
+					Output:  fmt.Sprintf("Task added successfully: %s", formatTask(added)),
+					Data:    map[string]any{"id": added.ID},
+				}, nil
 
-				content, err := os.ReadFile(taskPath)
+			case "update", "delete":
+				t, err := resolveTask(list, id, title)
 				if err != nil {
 					return tools.ToolResult{Success: false, Error: err.Error()}, nil
 				}
-
-				lines := strings.Split(string(content), "\n")
-				found := false
-				for i, line := range lines {
-					if strings.Contains(line, task) {
-						char := " "
-						switch status {
-						case "in_progress":
-							char = "/"
-						case "done":
-							char = "x"
-						}
-
-						// Replace early part of line (e.g. - [ ] or - [/])
-						idx := strings.Index(line, "[")
-						if idx != -1 && len(line) > idx+2 {
-							lines[i] = line[:idx+1] + char + line[idx+2:]
-							found = true
-						}
+				if action == "delete" {
+					n, err := list.Delete(t.ID)
+					if err != nil {
+						return tools.ToolResult{Success: false, Error: err.Error()}, nil
 					}
+					return tools.ToolResult{Success: true, Output: fmt.Sprintf("Deleted %s and %d sub-tasks.", t.ID, n-1)}, nil
 				}
 
-				if !found {
-					return tools.ToolResult{Success: false, Error: "Task not found."}, nil
+				if status == "" && priority == "" && assignee == "" && note == "" && (id == "" || title == "") {
+					return tools.ToolResult{Success: false, Error: "nothing to update: pass status, priority, assignee, note or a new task title"}, nil
 				}
-
-				err = os.WriteFile(taskPath, []byte(strings.Join(lines, "\n")), 0644)
+				updated, err := list.Update(t.ID, func(t *tasks.Task) {
+					if id != "" && title != "" {
+						t.Title = title
+					}
+					if status != "" && status != "open" {
+						t.Status = status
+					}
+					if priority != "" {
+						t.Priority = priority
+					}
+					if assignee != "" {
+						t.Assignee = assignee
+					}
+					if note != "" {
+						t.Notes = append(t.Notes, note)
+					}
+				})
 				if err != nil {
 					return tools.ToolResult{Success: false, Error: err.Error()}, nil
 				}
-				return tools.ToolResult{Success: true, Output: "Task updated successfully."}, nil
+				return tools.ToolResult{Success: true, Output: fmt.Sprintf("Task updated successfully: %s", formatTask(updated))}, nil
 			}
 
 			return tools.ToolResult{Success: false, Error: "Invalid action"}, nil
 		},
 	}
 }
+
+// resolveTask finds the task to change: by ID, or else the one whose title
+// is, or uniquely contains, the given text.
+func resolveTask(list *tasks.List, id, title string) (tasks.Task, error) {
+	if id != "" {
+		return list.Get(id)
+	}
+	if title == "" {
+		return tasks.Task{}, fmt.Errorf("missing required parameter: id")
+	}
+	found, err := list.Find(tasks.Filter{Query: title})
+	if err != nil {
+		return tasks.Task{}, err
+	}
+	var contains []tasks.Task
+	for _, t := range found {
+		if strings.EqualFold(t.Title, title) {
+			return t, nil
+		}
+		if strings.Contains(strings.ToLower(t.Title), strings.ToLower(title)) {
+			contains = append(contains, t)
+		}
+	}
+	switch len(contains) {
+	case 0:
+		return tasks.Task{}, fmt.Errorf("task not found: %s", title)
+	case 1:
+		return contains[0], nil
+	}
+	ids := make([]string, len(contains))
+	for i, t := range contains {
+		ids[i] = t.ID
+	}
+	return tasks.Task{}, fmt.Errorf("%q matches several tasks (%s); pass an id", title, strings.Join(ids, ", "))
+}
+
+// formatTask describes a task on one line, indented by its depth, followed
+// by its notes.
+func formatTask(t tasks.Task) string {
+	indent := strings.Repeat("  ", t.Depth)
+	box := map[string]string{tasks.StatusTodo: "[ ]", tasks.StatusInProgress: "[/]", tasks.StatusDone: "[x]"}[t.Status]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s%s %s %s (%s", indent, t.ID, box, t.Title, t.Priority))
+	if t.Assignee != "" {
+		sb.WriteString(", " + t.Assignee)
+	}
+	sb.WriteString(")")
+	for _, note := range t.Notes {
+		sb.WriteString(fmt.Sprintf("\n%s    > %s", indent, note))
+	}
+	return sb.String()
+}
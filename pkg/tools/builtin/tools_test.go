@@ -7,6 +7,10 @@ import (
 	"strings"
 	"testing"
 
+	"ClosedWheeler/pkg/brain"
+	"ClosedWheeler/pkg/context"
+	"ClosedWheeler/pkg/roadmap"
+	"ClosedWheeler/pkg/search"
 	"ClosedWheeler/pkg/security"
 )
 
@@ -147,7 +151,7 @@ func TestExecCommand_SimpleCommand(t *testing.T) {
 	defer cleanup()
 
 	auditor := security.NewAuditor(root)
-	tool := ExecCommandTool(root, 10e9, auditor)
+	tool := ExecCommandTool(root, 10e9, auditor, nil)
 
 	var cmd string
 	if runtime.GOOS == "windows" {
@@ -174,7 +178,7 @@ func TestExecCommand_EnvHasPath(t *testing.T) {
 	defer cleanup()
 
 	auditor := security.NewAuditor(root)
-	tool := ExecCommandTool(root, 30e9, auditor)
+	tool := ExecCommandTool(root, 30e9, auditor, nil)
 
 	result, err := tool.Handler(map[string]any{"command": "go version"})
 	if err != nil {
@@ -193,7 +197,7 @@ func TestExecCommand_SecurityBlock(t *testing.T) {
 	defer cleanup()
 
 	auditor := security.NewAuditor(root)
-	tool := ExecCommandTool(root, 10e9, auditor)
+	tool := ExecCommandTool(root, 10e9, auditor, nil)
 
 	result, _ := tool.Handler(map[string]any{"command": "rm -rf /"})
 	if result.Success {
@@ -212,7 +216,7 @@ func TestSearchCode_Found(t *testing.T) {
 	}
 
 	auditor := security.NewAuditor(root)
-	tool := SearchCodeTool(root, auditor)
+	tool := SearchCodeTool(search.NewIndex(root, filepath.Join(root, ".agi", "index"), nil), auditor)
 	result, _ := tool.Handler(map[string]any{"query": "func main"})
 	if !result.Success {
 		t.Fatalf("expected success, got: %s", result.Error)
@@ -231,7 +235,7 @@ func TestSearchCode_NotFound(t *testing.T) {
 	}
 
 	auditor := security.NewAuditor(root)
-	tool := SearchCodeTool(root, auditor)
+	tool := SearchCodeTool(search.NewIndex(root, filepath.Join(root, ".agi", "index"), nil), auditor)
 	result, _ := tool.Handler(map[string]any{"query": "nonexistentXYZ123"})
 	if !result.Success {
 		t.Fatalf("expected success (no results != error), got: %s", result.Error)
@@ -241,6 +245,30 @@ func TestSearchCode_NotFound(t *testing.T) {
 	}
 }
 
+func TestIndexIgnore(t *testing.T) {
+	root, cleanup := testRoot(t)
+	defer cleanup()
+
+	patterns := IndexIgnore(root, []string{"*.min.js"})
+	for _, p := range []string{".git/config", "node_modules/x/index.js", "vendor/a/a.go", "web/app.min.js"} {
+		if !patterns.ShouldIgnore(p) {
+			t.Errorf("%s should be ignored by default", p)
+		}
+	}
+	if patterns.ShouldIgnore("src/main.go") {
+		t.Error("src/main.go should be indexed")
+	}
+
+	// The workplace's .agiignore, not the app directory's, is read
+	if err := os.WriteFile(filepath.Join(root, ".agiignore"), []byte("generated/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patterns = IndexIgnore(root, nil)
+	if !patterns.ShouldIgnore("generated/api.go") || !patterns.ShouldIgnore("vendor/a/a.go") {
+		t.Errorf("patterns = %v", patterns.List())
+	}
+}
+
 // ----- manage_tasks -----
 
 func TestManageTasks_AddAndList(t *testing.T) {
@@ -277,6 +305,113 @@ func TestManageTasks_AddAndList(t *testing.T) {
 	}
 }
 
+func TestManageTasks_SubTasksAndUpdateByID(t *testing.T) {
+	root, cleanup := testRoot(t)
+	defer cleanup()
+
+	tool := TaskManagerTool(root, security.NewAuditor(root))
+	result, _ := tool.Handler(map[string]any{"action": "add", "task": "Release v1", "priority": "high"})
+	if !result.Success || result.Data.(map[string]any)["id"] != "t1" {
+		t.Fatalf("add: %+v", result)
+	}
+	for _, title := range []string{"Write changelog", "Tag release"} {
+		result, _ = tool.Handler(map[string]any{"action": "add", "task": title, "parent": "t1"})
+		if !result.Success {
+			t.Fatalf("add sub-task: %+v", result)
+		}
+	}
+	result, _ = tool.Handler(map[string]any{"action": "add", "task": "Review changelog", "assignee": "user"})
+	if !result.Success {
+		t.Fatalf("add user task: %+v", result)
+	}
+
+	result, _ = tool.Handler(map[string]any{"action": "update", "id": "t2", "status": "done", "note": "Covers every PR since v0.9"})
+	if !result.Success {
+		t.Fatalf("update: %+v", result)
+	}
+	// Without an ID, an ambiguous title is rejected
+	result, _ = tool.Handler(map[string]any{"action": "update", "task": "changelog", "status": "done"})
+	if result.Success || !strings.Contains(result.Error, "t2, t4") {
+		t.Errorf("expected an ambiguous match error, got %+v", result)
+	}
+
+	result, _ = tool.Handler(map[string]any{"action": "list"})
+	want := "4 tasks (3 open):\n" +
+		"t1 [ ] Release v1 (high, agent)\n" +
+		"  t2 [x] Write changelog (medium, agent)\n" +
+		"      > Covers every PR since v0.9\n" +
+		"  t3 [ ] Tag release (medium, agent)\n" +
+		"t4 [ ] Review changelog (medium, user)"
+	if result.Output != want {
+		t.Errorf("list:\n%s\nwant:\n%s", result.Output, want)
+	}
+	result, _ = tool.Handler(map[string]any{"action": "list", "status": "open", "assignee": "agent"})
+	if !strings.HasPrefix(result.Output, "2 tasks") || strings.Contains(result.Output, "t2") {
+		t.Errorf("filtered list: %q", result.Output)
+	}
+
+	result, _ = tool.Handler(map[string]any{"action": "delete", "id": "t1"})
+	if !result.Success || !strings.Contains(result.Output, "2 sub-tasks") {
+		t.Errorf("delete: %+v", result)
+	}
+}
+
+// ----- find_definition / find_references / list_implementations -----
+
+func TestGoSymbolTools(t *testing.T) {
+	root, cleanup := testRoot(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"go.mod": "module example.com/app\n",
+		"store/store.go": "package store\n\ntype Store interface {\n\tGet(key string) string\n}\n\n" +
+			"type Memory map[string]string\n\nfunc (m Memory) Get(key string) string { return m[key] }\n",
+		"main.go": "package main\n\nimport \"example.com/app/store\"\n\nfunc main() {\n\tvar s store.Store = store.Memory{}\n\t_ = s.Get(\"k\")\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project := context.NewProjectCache(root, IndexIgnore(root, nil))
+	result, _ := FindDefinitionTool(project).Handler(map[string]any{"symbol": "Memory.Get"})
+	if !result.Success || !strings.Contains(result.Output, "store/store.go:9") || !strings.Contains(result.Output, "func (m Memory) Get(key string) string") {
+		t.Errorf("find_definition: %+v", result)
+	}
+
+	result, _ = FindReferencesTool(project).Handler(map[string]any{"symbol": "store.Store.Get"})
+	if !result.Success || !strings.Contains(result.Output, "main.go:7") || !strings.Contains(result.Output, "called by: main.main") {
+		t.Errorf("find_references: %+v", result)
+	}
+
+	result, _ = ListImplementationsTool(project).Handler(map[string]any{"symbol": "Store"})
+	if !result.Success || !strings.Contains(result.Output, "store.Memory") {
+		t.Errorf("list_implementations: %+v", result)
+	}
+
+	result, _ = FindDefinitionTool(project).Handler(map[string]any{"symbol": "Missing"})
+	if result.Success {
+		t.Error("expected an unknown symbol to fail")
+	}
+
+	// Vendored code is not indexed; new code is picked up on the next call.
+	vendored := filepath.Join(root, "vendor", "lib", "lib.go")
+	os.MkdirAll(filepath.Dir(vendored), 0755)
+	os.WriteFile(vendored, []byte("package lib\n\nfunc Vendored() {}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "store", "extra.go"), []byte("package store\n\nfunc Missing() {}\n"), 0644)
+	result, _ = FindDefinitionTool(project).Handler(map[string]any{"symbol": "Missing"})
+	if !result.Success || !strings.Contains(result.Output, "store/extra.go:3") {
+		t.Errorf("find_definition after a change: %+v", result)
+	}
+	result, _ = FindDefinitionTool(project).Handler(map[string]any{"symbol": "Vendored"})
+	if result.Success {
+		t.Errorf("vendored code should not be indexed: %+v", result)
+	}
+}
+
 // ----- get_system_info -----
 
 func TestGetSystemInfo(t *testing.T) {
@@ -293,3 +428,88 @@ func TestGetSystemInfo(t *testing.T) {
 		t.Errorf("expected OS %q in output, got: %s", runtime.GOOS, result.Output)
 	}
 }
+
+// ----- brain_query / brain_tags -----
+
+func TestBrainTools(t *testing.T) {
+	root, cleanup := testRoot(t)
+	defer cleanup()
+
+	b := brain.NewBrain(root)
+	if err := b.AddError("Nil map write", "Panic on save", "Make the map", []string{"go", "panic"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddPattern("Wrap errors", "Use %w", []string{"go"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Supersede("2", brain.Entry{Title: "Wrap errors with context", Description: "Use %w and the operation", Tags: []string{"go"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, _ := BrainQueryTool(b).Handler(map[string]any{"tags": "go"})
+	if !result.Success || !strings.Contains(result.Output, "#3 Wrap errors with context") || strings.Contains(result.Output, "#2 ") {
+		t.Errorf("brain_query by tag: %+v", result)
+	}
+	result, _ = BrainQueryTool(b).Handler(map[string]any{"tags": "go, panic", "category": "error"})
+	if !result.Success || !strings.HasPrefix(result.Output, "[error] #1 Nil map write") {
+		t.Errorf("brain_query by tags and category: %+v", result)
+	}
+	result, _ = BrainQueryTool(b).Handler(map[string]any{"query": "wrap", "include_superseded": true})
+	if !strings.Contains(result.Output, "(superseded by #3) #2 Wrap errors") {
+		t.Errorf("brain_query with superseded: %+v", result)
+	}
+	if e, _ := b.Get("1"); e.Uses != 2 { // returned by the first two queries
+		t.Errorf("queried entry not marked used: %+v", e)
+	}
+
+	result, _ = BrainTagsTool(b).Handler(map[string]any{})
+	if !result.Success || result.Output != "go (2)\npanic (1)" {
+		t.Errorf("brain_tags: %q", result.Output)
+	}
+}
+
+// ----- roadmap -----
+
+func TestRoadmapTool(t *testing.T) {
+	root, cleanup := testRoot(t)
+	defer cleanup()
+
+	r := roadmap.NewRoadmap(root)
+	tool := RoadmapTool(r)
+	result, _ := tool.Handler(map[string]any{"action": "add", "title": "Storage layer", "priority": "high", "estimate_hours": float64(3), "tasks": "- [ ] Define schema\n- [ ] Write migrations"})
+	if !result.Success || !strings.Contains(result.Output, "g1") {
+		t.Fatalf("add: %+v", result)
+	}
+	result, _ = tool.Handler(map[string]any{"action": "add", "title": "API", "depends_on": "g1"})
+	if !result.Success {
+		t.Fatalf("add with dependency: %+v", result)
+	}
+	result, _ = tool.Handler(map[string]any{"action": "update", "id": "g1", "depends_on": "g2"})
+	if result.Success {
+		t.Error("expected a dependency cycle to be rejected")
+	}
+
+	result, _ = tool.Handler(map[string]any{"action": "next"})
+	if !result.Success || !strings.HasPrefix(result.Output, "[g1] Storage layer (planned, high priority, 0/2 tasks, ~3h)\n    - t1 Define schema") || strings.Contains(result.Output, "[g2]") {
+		t.Errorf("next: %q", result.Output)
+	}
+	result, _ = tool.Handler(map[string]any{"action": "critical_path"})
+	if !strings.Contains(result.Output, "Critical path (4.0h remaining)") {
+		t.Errorf("critical_path: %q", result.Output)
+	}
+
+	result, _ = tool.Handler(map[string]any{"action": "link_task", "id": "g2", "tasks": "Document endpoints\nt1"})
+	if !result.Success {
+		t.Fatalf("link_task: %+v", result)
+	}
+	tasks, _ := os.ReadFile(filepath.Join(root, "task.md"))
+	for _, want := range []string{"- [ ] Define schema <!-- id:t1 ", "- [ ] Write migrations <!-- id:t2 ", "- [ ] Document endpoints <!-- id:t3 "} {
+		if !strings.Contains(string(tasks), want) {
+			t.Errorf("task.md is missing %q:\n%s", want, tasks)
+		}
+	}
+	result, _ = tool.Handler(map[string]any{"action": "list"})
+	if !strings.Contains(result.Output, "[g2] API (planned, medium priority, 0/2 tasks) waiting on g1\n    - t3 Document endpoints\n    - t1 Define schema") {
+		t.Errorf("list: %q", result.Output)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+
+	"ClosedWheeler/pkg/secrets"
 )
 
 // Tool represents a callable tool/function
@@ -127,6 +129,17 @@ func (r *Registry) GetOpenAIFormat() []map[string]any {
 	return functions
 }
 
+// sourceTools return file contents the model edits by quoting them, so
+// only secrets of a known format are redacted from their output: the
+// heuristic detectors would also replace ordinary code.
+var sourceTools = map[string]bool{
+	"read_file":       true,
+	"search_code":     true,
+	"find_definition": true,
+	"find_references": true,
+	"git_diff":        true,
+}
+
 // Executor executes tool calls with detailed debug logging
 type Executor struct {
 	registry    *Registry
@@ -183,8 +196,14 @@ func (e *Executor) Execute(call ToolCall) (ToolResult, error) {
 	// Add metadata
 	e.debugLogger.AddMetadata(trace, "tool_description", tool.Description)
 
-	// Execute tool
+	// Execute tool; secrets in the output go no further than the tool
 	result, err := tool.Handler(call.Arguments)
+	redact := secrets.Redact
+	if sourceTools[call.Name] {
+		redact = secrets.RedactKnown
+	}
+	result.Output = redact(result.Output)
+	result.Error = redact(result.Error)
 
 	// Capture error details if failed
 	if err != nil {
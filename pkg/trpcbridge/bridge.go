@@ -1,6 +1,8 @@
 package trpcbridge
 
 import (
+	"slices"
+
 	"ClosedWheeler/pkg/llm"
 	"ClosedWheeler/pkg/logger"
 	"ClosedWheeler/pkg/tools"
@@ -98,6 +100,16 @@ func (b *Bridge) NewRunner(appName string, ag trpcagent.Agent) runner.Runner {
 	)
 }
 
+// SetToolPolicy makes the tools of every role pass policy before they run,
+// so pipeline calls get the same permission checks as the agent's own.
+func (b *Bridge) SetToolPolicy(policy ToolPolicy) {
+	for _, t := range slices.Concat(b.allTools, b.readOnlyTools) {
+		if ta, ok := t.(*ToolAdapter); ok {
+			ta.SetPolicy(policy)
+		}
+	}
+}
+
 // ModelAdapter returns the underlying model adapter.
 func (b *Bridge) ModelAdapter() *ModelAdapter {
 	return b.modelAdapter
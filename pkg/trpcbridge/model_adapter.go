@@ -19,6 +19,21 @@ type ModelAdapter struct {
 	name   string
 	logger *logger.Logger
 	mu     sync.Mutex // protects reasoning effort swap
+
+	onUsage func(llm.Usage) // optional; called after every completed request
+}
+
+// SetUsageCallback registers fn to receive the token usage of each request,
+// e.g. for cost tracking.
+func (m *ModelAdapter) SetUsageCallback(fn func(llm.Usage)) {
+	m.onUsage = fn
+}
+
+// reportUsage forwards a response's usage to the usage callback.
+func (m *ModelAdapter) reportUsage(resp *llm.ChatResponse) {
+	if m.onUsage != nil && resp != nil {
+		m.onUsage(resp.Usage)
+	}
 }
 
 // NewModelAdapter creates a ModelAdapter from an existing llm.Client.
@@ -83,6 +98,7 @@ func (m *ModelAdapter) generateBlocking(
 		sendErrorResponse(ch, err)
 		return
 	}
+	m.reportUsage(resp)
 
 	trpcResp := llmResponseToTrpc(resp)
 	trpcResp.Done = true
@@ -129,6 +145,7 @@ func (m *ModelAdapter) generateStreaming(
 		sendErrorResponse(ch, err)
 		return
 	}
+	m.reportUsage(resp)
 
 	// Send a final complete response with full content and usage.
 	final := llmResponseToTrpc(resp)
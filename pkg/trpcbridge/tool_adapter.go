@@ -18,8 +18,13 @@ var readOnlyTools = map[string]bool{
 	"search_code":         true,
 	"get_code_outline":    true,
 	"get_project_metrics": true,
+	"find_definition":     true,
+	"find_references":     true,
+	"list_implementations": true,
 	"get_system_info":     true,
 	"manage_tasks":        true,
+	"brain_query":         true,
+	"brain_tags":          true,
 	"git_diff":            true,
 	"git_status":          true,
 	"git_log":             true,
@@ -28,10 +33,15 @@ var readOnlyTools = map[string]bool{
 	"browser_get_page_text": true,
 }
 
+// ToolPolicy decides whether a tool call may run, asking the user first when
+// needed. A non-nil error refuses the call.
+type ToolPolicy func(name string, args map[string]any) error
+
 // ToolAdapter wraps a tools.Tool and its Executor as a trpc-agent-go CallableTool.
 type ToolAdapter struct {
 	inner    *tools.Tool
 	executor *tools.Executor
+	policy   ToolPolicy // optional; consulted before every call
 }
 
 // NewToolAdapter creates a ToolAdapter for the given tool and executor.
@@ -51,7 +61,13 @@ func (ta *ToolAdapter) Declaration() *trpctool.Declaration {
 	return decl
 }
 
-// Call unmarshals the JSON arguments and delegates to the Executor.
+// SetPolicy makes every call pass policy before it runs; nil removes it.
+func (ta *ToolAdapter) SetPolicy(policy ToolPolicy) {
+	ta.policy = policy
+}
+
+// Call unmarshals the JSON arguments, checks the policy and delegates to the
+// Executor.
 func (ta *ToolAdapter) Call(_ context.Context, jsonArgs []byte) (any, error) {
 	var args map[string]any
 	if len(jsonArgs) > 0 {
@@ -62,6 +78,11 @@ func (ta *ToolAdapter) Call(_ context.Context, jsonArgs []byte) (any, error) {
 	if args == nil {
 		args = map[string]any{}
 	}
+	if ta.policy != nil {
+		if err := ta.policy(ta.inner.Name, args); err != nil {
+			return nil, fmt.Errorf("tool %s not allowed: %w", ta.inner.Name, err)
+		}
+	}
 
 	result, err := ta.executor.Execute(tools.ToolCall{
 		Name:      ta.inner.Name,
@@ -3,6 +3,8 @@ package trpcbridge
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"ClosedWheeler/pkg/tools"
@@ -299,3 +301,41 @@ func TestToolAdapterDeclaration_RoundTrip(t *testing.T) {
 		t.Error("empty JSON")
 	}
 }
+
+func TestToolAdapterCall_PolicyRefuses(t *testing.T) {
+	ran := false
+	tool := &tools.Tool{
+		Name:        "exec_command",
+		Description: "Runs a command",
+		Parameters: &tools.JSONSchema{
+			Type:       "object",
+			Properties: map[string]tools.Property{"command": {Type: "string"}},
+		},
+		Handler: func(args map[string]any) (tools.ToolResult, error) {
+			ran = true
+			return tools.ToolResult{Success: true, Output: "pushed"}, nil
+		},
+	}
+
+	registry := tools.NewRegistry()
+	if err := registry.Register(tool); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	adapter := NewToolAdapter(tool, tools.NewExecutor(registry))
+	var checked map[string]any
+	adapter.SetPolicy(func(name string, args map[string]any) error {
+		checked = args
+		return fmt.Errorf("permission denied by policy: deny exec_command cmd:\"git push\"")
+	})
+
+	_, err := adapter.Call(context.Background(), []byte(`{"command":"git push"}`))
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected the policy to refuse the call, got %v", err)
+	}
+	if ran {
+		t.Error("refused call must not run")
+	}
+	if checked["command"] != "git push" {
+		t.Errorf("policy got args %v", checked)
+	}
+}
@@ -0,0 +1,256 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/agent"
+	"ClosedWheeler/pkg/permissions"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toolApprovalMsg asks the user to approve a tool call. The agent waits for
+// the answer on reply.
+type toolApprovalMsg struct {
+	req   agent.ToolApproval
+	reply chan permissions.Approval
+}
+
+// previewArgs are the arguments a diff preview already shows.
+var previewArgs = []string{"content", "patch", "old_string", "new_string", "blocks"}
+
+// ApprovalDialog shows a tool call waiting for approval: the tool, its
+// arguments and the change it would make, with the ways to answer.
+type ApprovalDialog struct {
+	req    agent.ToolApproval
+	reply  chan permissions.Approval
+	scroll int
+	help   help.Model
+	keys   approvalKeyMap
+}
+
+type approvalKeyMap struct {
+	once    key.Binding
+	session key.Binding
+	pattern key.Binding
+	deny    key.Binding
+	up      key.Binding
+	down    key.Binding
+	help    key.Binding
+}
+
+func (k approvalKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.once, k.session, k.pattern, k.deny, k.help}
+}
+
+func (k approvalKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.once, k.session, k.pattern, k.deny},
+		{k.up, k.down, k.help},
+	}
+}
+
+func newApprovalKeyMap(canRemember bool) approvalKeyMap {
+	k := approvalKeyMap{
+		// Not enter: one typed into the input as the dialog opens would approve
+		once:    key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "allow once")),
+		session: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "allow for this session")),
+		pattern: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "always allow")),
+		deny:    key.NewBinding(key.WithKeys("n", "esc"), key.WithHelp("n/esc", "deny")),
+		up:      key.NewBinding(key.WithKeys("up", "k", "pgup"), key.WithHelp("↑/pgup", "scroll up")),
+		down:    key.NewBinding(key.WithKeys("down", "j", "pgdown"), key.WithHelp("↓/pgdown", "scroll down")),
+		help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
+	}
+	k.session.SetEnabled(canRemember)
+	k.pattern.SetEnabled(canRemember)
+	return k
+}
+
+// NewApprovalDialog creates the dialog for one approval request.
+func NewApprovalDialog(req agent.ToolApproval, reply chan permissions.Approval) *ApprovalDialog {
+	return &ApprovalDialog{
+		req:   req,
+		reply: reply,
+		help:  help.New(),
+		keys:  newApprovalKeyMap(req.CanRemember),
+	}
+}
+
+// answer maps a key to the answer it gives, if any.
+func (d *ApprovalDialog) answer(msg tea.KeyMsg) (permissions.Approval, bool) {
+	switch {
+	case key.Matches(msg, d.keys.once):
+		return permissions.ApproveOnce, true
+	case key.Matches(msg, d.keys.session):
+		return permissions.ApproveSession, true
+	case key.Matches(msg, d.keys.pattern):
+		return permissions.ApprovePattern, true
+	case key.Matches(msg, d.keys.deny):
+		return permissions.Reject, true
+	}
+	return "", false
+}
+
+// lines renders the request: reason, arguments and diff preview.
+func (d *ApprovalDialog) lines(width int) []string {
+	var lines []string
+	if d.req.Reason != "" {
+		lines = append(lines, PanelFooterStyle.Render(clipLine("Policy: "+d.req.Reason, width)), "")
+	}
+
+	args := maps.Clone(d.req.Args)
+	if d.req.Diff != "" {
+		for _, name := range previewArgs {
+			delete(args, name)
+		}
+	}
+	if len(args) > 0 {
+		lines = append(lines, ReviewTitleStyle.Render("Arguments"))
+		pretty, err := json.MarshalIndent(args, "", "  ")
+		if err != nil {
+			pretty = []byte(fmt.Sprint(args))
+		}
+		for _, line := range strings.Split(string(pretty), "\n") {
+			lines = append(lines, clipLine(line, width))
+		}
+		lines = append(lines, "")
+	}
+
+	if d.req.Diff != "" {
+		lines = append(lines, ReviewTitleStyle.Render("Changes"))
+		path, _ := d.req.Args["path"].(string)
+		lang := syntaxFor(path)
+		for _, line := range strings.Split(strings.TrimSuffix(d.req.Diff, "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+				lines = append(lines, PanelFooterStyle.Render(clipLine(line, width)))
+			case strings.HasPrefix(line, "@@"):
+				lines = append(lines, DiffHunkStyle.Render(clipLine(line, width)))
+			default:
+				lines = append(lines, diffLine(line, width, lang, true))
+			}
+		}
+	}
+	return lines
+}
+
+// startApproval shows the approval dialog, or queues the request behind the
+// one on screen.
+func (m *EnhancedModel) startApproval(req agent.ToolApproval, reply chan permissions.Approval) {
+	d := NewApprovalDialog(req, reply)
+	if m.approval != nil {
+		m.approvalQueue = append(m.approvalQueue, d)
+		return
+	}
+	m.approval = d
+}
+
+// finishApproval hands the answer to the waiting agent and moves on to the
+// next queued request.
+func (m *EnhancedModel) finishApproval(answer permissions.Approval) {
+	d := m.approval
+	d.reply <- answer
+
+	summary := fmt.Sprintf("🔐 %s: ", d.req.Tool)
+	switch answer {
+	case permissions.ApproveOnce:
+		summary += "allowed once"
+	case permissions.ApproveSession:
+		summary += fmt.Sprintf("allowed for this session (%s)", d.req.Rule.Text)
+	case permissions.ApprovePattern:
+		summary += fmt.Sprintf("always allowed (%s added to the policy)", d.req.Rule.Text)
+	default:
+		summary += "denied"
+	}
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "system",
+		Content:   summary,
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+
+	m.approval = nil
+	if len(m.approvalQueue) > 0 {
+		m.approval = m.approvalQueue[0]
+		m.approvalQueue = m.approvalQueue[1:]
+	}
+	m.updateViewport()
+}
+
+// approvalVisibleHeight returns how many request lines fit in the dialog.
+func (m *EnhancedModel) approvalVisibleHeight() int {
+	// total height minus: border(2) + margin(2) + padding(2) + title(2) + footer(4)
+	return max(m.height-12, 5)
+}
+
+// approvalUpdate handles keyboard input while the approval dialog is
+// active. Every answer releases the waiting agent.
+func (m EnhancedModel) approvalUpdate(msg tea.KeyMsg) (EnhancedModel, tea.Cmd) {
+	d := m.approval
+	if answer, ok := d.answer(msg); ok {
+		m.finishApproval(answer)
+		return m, nil
+	}
+
+	page := 1
+	if msg.String() == "pgup" || msg.String() == "pgdown" {
+		page = m.approvalVisibleHeight()
+	}
+	switch {
+	case key.Matches(msg, d.keys.up):
+		d.scroll = max(d.scroll-page, 0)
+	case key.Matches(msg, d.keys.down):
+		lines := d.lines(m.reviewContentWidth())
+		d.scroll = min(d.scroll+page, max(len(lines)-m.approvalVisibleHeight(), 0))
+	case key.Matches(msg, d.keys.help):
+		d.help.ShowAll = !d.help.ShowAll
+	}
+	return m, nil
+}
+
+// approvalView renders the approval dialog.
+func (m EnhancedModel) approvalView() string {
+	d := m.approval
+	width := m.reviewContentWidth()
+	visibleHeight := m.approvalVisibleHeight()
+
+	var s strings.Builder
+	title := fmt.Sprintf("🔐 Approve %s?", d.req.Tool)
+	if n := len(m.approvalQueue); n > 0 {
+		title += fmt.Sprintf(" (%d more waiting)", n)
+	}
+	s.WriteString(ReviewTitleStyle.Render(title))
+	s.WriteString("\n\n")
+
+	lines := d.lines(width)
+	scroll := min(d.scroll, max(len(lines)-visibleHeight, 0))
+	end := min(scroll+visibleHeight, len(lines))
+	for _, line := range lines[scroll:end] {
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+	if remaining := len(lines) - end; remaining > 0 {
+		s.WriteString(PanelScrollStyle.Render(fmt.Sprintf("  ▼ %d more", remaining)))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	if d.req.CanRemember {
+		s.WriteString(PanelFooterStyle.Render(clipLine("Remembered rule: "+d.req.Rule.Text, width)))
+		s.WriteString("\n")
+	}
+	d.help.Width = width
+	if d.help.ShowAll {
+		s.WriteString(d.help.FullHelpView(d.keys.FullHelp()))
+	} else {
+		s.WriteString(d.help.ShortHelpView(d.keys.ShortHelp()))
+	}
+
+	return ReviewBoxStyle.Width(width + 6).Render(s.String())
+}
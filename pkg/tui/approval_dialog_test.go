@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/agent"
+	"ClosedWheeler/pkg/permissions"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestApprovalDialog verifies the answers keys give and what the dialog
+// shows for a file change.
+func TestApprovalDialog(t *testing.T) {
+	req := agent.ToolApproval{
+		Tool: "write_file",
+		Args: map[string]any{"path": "a.txt", "content": "new\n"},
+		Diff: "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-old\n+new\n",
+	}
+	d := NewApprovalDialog(req, nil)
+
+	keys := func(s string) tea.KeyMsg {
+		switch s {
+		case "esc":
+			return tea.KeyMsg{Type: tea.KeyEsc}
+		case "enter":
+			return tea.KeyMsg{Type: tea.KeyEnter}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+	if a, ok := d.answer(keys("y")); !ok || a != permissions.ApproveOnce {
+		t.Errorf("y = %q, %v", a, ok)
+	}
+	if _, ok := d.answer(keys("enter")); ok {
+		t.Error("enter should not answer")
+	}
+	if a, ok := d.answer(keys("esc")); !ok || a != permissions.Reject {
+		t.Errorf("esc = %q, %v", a, ok)
+	}
+	// Without a rule to remember, only the call itself can be approved
+	if _, ok := d.answer(keys("a")); ok {
+		t.Error("a should do nothing without a rule")
+	}
+	d = NewApprovalDialog(agent.ToolApproval{Tool: "exec_command", CanRemember: true}, nil)
+	if a, ok := d.answer(keys("s")); !ok || a != permissions.ApproveSession {
+		t.Errorf("s = %q, %v", a, ok)
+	}
+
+	// The diff stands in for the content argument
+	d = NewApprovalDialog(req, nil)
+	text := strings.Join(d.lines(60), "\n")
+	if !strings.Contains(text, `"path": "a.txt"`) || strings.Contains(text, `"content"`) {
+		t.Errorf("arguments should list the path but not the content:\n%s", text)
+	}
+	if !strings.Contains(text, "-old") || !strings.Contains(text, "new") {
+		t.Errorf("diff preview missing:\n%s", text)
+	}
+}
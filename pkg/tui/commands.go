@@ -4,12 +4,17 @@ package tui
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"ClosedWheeler/pkg/brain"
 	"ClosedWheeler/pkg/recovery"
+	"ClosedWheeler/pkg/roadmap"
+	"ClosedWheeler/pkg/tasks"
 	"ClosedWheeler/pkg/telegram"
 	"ClosedWheeler/pkg/tools"
 
@@ -109,7 +114,7 @@ type CommandCategory struct {
 // Command Categories:
 //   - Conversation (💬): clear, retry, continue
 //   - Information (📊): status, stats, memory, context, tools
-//   - Project (📁): reload, rules, git, health
+//   - Project (📁): reload, rules, git, health, tasks
 //   - Features (⚙️): verbose, debug, timestamps, browser, heartbeat, pipeline
 //   - Memory & Brain (🧠): brain, roadmap, save
 //   - Integration (🔗): telegram, model, skill, mcp
@@ -179,6 +184,14 @@ func GetAllCommands() []CommandCategory {
 					Usage:       "/stats",
 					Handler:     cmdStats,
 				},
+				{
+					Name:        "cost",
+					Aliases:     []string{"spend"},
+					Category:    "Information",
+					Description: "Show spending by model and role, and budget limits",
+					Usage:       "/cost [resume]",
+					Handler:     cmdCost,
+				},
 				{
 					Name:        "memory",
 					Aliases:     []string{"mem"},
@@ -233,6 +246,34 @@ func GetAllCommands() []CommandCategory {
 					Usage:       "/git [status|diff|log]",
 					Handler:     cmdGit,
 				},
+				{
+					Name:        "undo",
+					Category:    "Project",
+					Description: "Revert the file changes of the last n turns",
+					Usage:       "/undo [n]",
+					Handler:     cmdUndo,
+				},
+				{
+					Name:        "redo",
+					Category:    "Project",
+					Description: "Reapply the last n undone turns",
+					Usage:       "/redo [n]",
+					Handler:     cmdRedo,
+				},
+				{
+					Name:        "timeline",
+					Category:    "Project",
+					Description: "Show the turns that changed files",
+					Usage:       "/timeline",
+					Handler:     cmdTimeline,
+				},
+				{
+					Name:        "review",
+					Category:    "Project",
+					Description: "Review file changes waiting for approval",
+					Usage:       "/review",
+					Handler:     cmdReview,
+				},
 				{
 					Name:        "health",
 					Aliases:     []string{"check"},
@@ -241,6 +282,14 @@ func GetAllCommands() []CommandCategory {
 					Usage:       "/health",
 					Handler:     cmdHealth,
 				},
+				{
+					Name:        "tasks",
+					Aliases:     []string{"todo"},
+					Category:    "Project",
+					Description: "Show the task board or add and update tasks",
+					Usage:       "/tasks [filter...|add [@me] <title>|start|done|reopen|delete <id>]",
+					Handler:     cmdTasks,
+				},
 			},
 		},
 		{
@@ -306,15 +355,15 @@ func GetAllCommands() []CommandCategory {
 					Aliases:     []string{"knowledge"},
 					Category:    "Memory & Brain",
 					Description: "View or search knowledge base",
-					Usage:       "/brain [search <query>|recent]",
+					Usage:       "/brain [search <query>|edit <id> <field> <text>|delete <id>|tags [tag|<id> +tag -tag]]",
 					Handler:     cmdBrain,
 				},
 				{
 					Name:        "roadmap",
 					Aliases:     []string{"goals"},
 					Category:    "Memory & Brain",
-					Description: "View strategic roadmap",
-					Usage:       "/roadmap [summary]",
+					Description: "View strategic roadmap and its critical path",
+					Usage:       "/roadmap [summary|graph]",
 					Handler:     cmdRoadmap,
 				},
 				{
@@ -344,7 +393,7 @@ func GetAllCommands() []CommandCategory {
 					Aliases:     []string{"m"},
 					Category:    "Integration",
 					Description: "Interactive model/provider picker",
-					Usage:       "/model [model-name [effort]]",
+					Usage:       "/model [model-name [effort]] | /model pull <model>",
 					Handler:     cmdModel,
 				},
 				{
@@ -394,8 +443,8 @@ func GetAllCommands() []CommandCategory {
 					Name:        "session",
 					Aliases:     []string{"dual"},
 					Category:    "Dual Session",
-					Description: "Enable/disable dual session mode",
-					Usage:       "/session [on|off|status]",
+					Description: "Dual session mode and saved conversation sessions",
+					Usage:       "/session [on|off|status|list|resume <id>|fork [id]|delete <id>|new]",
 					Handler:     cmdSession,
 				},
 				{
@@ -460,6 +509,13 @@ func GetAllCommands() []CommandCategory {
 					Usage:       "/errors [n|clear]",
 					Handler:     cmdErrors,
 				},
+				{
+					Name:        "secrets",
+					Category:    "System",
+					Description: "Scan logs, memory and saved debates for leaked secrets",
+					Usage:       "/secrets scan",
+					Handler:     cmdSecrets,
+				},
 				{
 					Name:        "resilience",
 					Aliases:     []string{"recovery"},
@@ -753,6 +809,13 @@ func cmdStats(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	}
 	content.WriteString(fmt.Sprintf("- Avg Tokens/Message: %d\n", avgTokensPerMsg))
 
+	if cache, ok := m.agent.GetCacheStats(); ok {
+		content.WriteString("\n**Response Cache:**\n")
+		content.WriteString(fmt.Sprintf("- Hit Rate: %.0f%% (%d hits, %d misses)\n", cache.HitRate()*100, cache.Hits, cache.Misses))
+		content.WriteString(fmt.Sprintf("- Tokens Saved: %s\n", formatK(int(cache.SavedTokens))))
+		content.WriteString(fmt.Sprintf("- Entries: %d (%.1f MB)\n", cache.Entries, float64(cache.Bytes)/(1<<20)))
+	}
+
 	m.openPanel("API Statistics", content.String())
 	return m, nil
 }
@@ -817,7 +880,31 @@ func cmdContext(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	content.WriteString(fmt.Sprintf("\n**Messages:** %d\n", contextStats.MessageCount))
 	content.WriteString(fmt.Sprintf("**API Calls:** %d\n", contextStats.CompletionCount))
 
-	if contextStats.MessageCount > 15 {
+	budget := m.agent.GetContextBudget()
+	if budget.Tokenizer != "" {
+		used := budget.Used()
+		if budget.Window > 0 {
+			content.WriteString(fmt.Sprintf("\n**Token Budget:** %s / %s (%d%%), %s reserved for reply\n",
+				formatK(used), formatK(budget.Window), used*100/budget.Window, formatK(budget.Reserved)))
+		} else {
+			content.WriteString(fmt.Sprintf("\n**Token Budget:** %s, window unknown (trimmed only if the provider rejects the prompt)\n", formatK(used)))
+		}
+		content.WriteString(fmt.Sprintf("Tokenizer: %s\n\n", budget.Tokenizer))
+		for _, section := range budget.Sections {
+			note := ""
+			if section.Trimmed {
+				note = " (trimmed)"
+			}
+			content.WriteString(fmt.Sprintf("- %s: %d tokens%s\n", section.Name, section.Tokens, note))
+		}
+		if budget.DroppedMessages > 0 {
+			content.WriteString(fmt.Sprintf("\n%d oldest messages did not fit and were left out.\n", budget.DroppedMessages))
+		}
+	}
+
+	if budget.Window > 0 && budget.Used() > (budget.Window-budget.Reserved)*8/10 {
+		content.WriteString("\n⚠️ **Warning:** Context is nearly full. Older history will be left out.\n")
+	} else if budget.Window == 0 && contextStats.MessageCount > 15 {
 		content.WriteString("\n⚠️ **Warning:** High message count. Context may be compressed soon.\n")
 	}
 
@@ -830,7 +917,7 @@ func cmdTools(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	content.WriteString("🔧 **Available Tools**\n\n")
 
 	categories := map[string][]string{
-		"File Operations":        {"read_file", "write_file", "edit_file", "list_files"},
+		"File Operations":        {"read_file", "write_file", "edit_file", "apply_patch", "list_files"},
 		"Browser":                {"browser_navigate", "browser_click", "browser_type", "browser_screenshot"},
 		"Git (enable_git_tools)": {"git_status", "git_diff", "git_commit", "git_push"},
 		"Analysis":               {"analyze_code", "security_scan", "run_diagnostics"},
@@ -1190,16 +1277,150 @@ func cmdBrain(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 				Complete:  true,
 			})
 		}
+	} else if args[0] == "edit" || args[0] == "delete" || args[0] == "tags" {
+		role, content := "system", ""
+		var err error
+		switch args[0] {
+		case "edit":
+			content, err = brainEdit(brain, args[1:])
+		case "delete":
+			content, err = brainDelete(brain, args[1:])
+		default:
+			content, err = brainTags(brain, args[1:])
+		}
+		if err != nil {
+			role, content = "error", fmt.Sprintf("❌ %v", err)
+		}
+		m.messageQueue.Add(QueuedMessage{
+			Role:      role,
+			Content:   content,
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
 	}
 
 	m.updateViewport()
 	return m, nil
 }
 
+// brainEdit handles "/brain edit <id> title|description|category <text>".
+func brainEdit(b *brain.Brain, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", fmt.Errorf("usage: /brain edit <id> title|description|category <text>")
+	}
+	id, field, text := strings.TrimPrefix(args[0], "#"), args[1], strings.Join(args[2:], " ")
+	var apply func(*brain.Entry)
+	switch field {
+	case "title":
+		apply = func(e *brain.Entry) { e.Title = text }
+	case "description":
+		apply = func(e *brain.Entry) { e.Description = text }
+	case "category":
+		if !slices.Contains(brain.Categories, text) {
+			return "", fmt.Errorf("unknown category %q (use %s)", text, strings.Join(brain.Categories, ", "))
+		}
+		apply = func(e *brain.Entry) { e.Category = text }
+	default:
+		return "", fmt.Errorf("unknown field %q (use title, description or category)", field)
+	}
+	if err := b.Update(id, apply); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("✅ Updated the %s of brain entry #%s", field, id), nil
+}
+
+// brainDelete handles "/brain delete <id>".
+func brainDelete(b *brain.Brain, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: /brain delete <id>")
+	}
+	id := strings.TrimPrefix(args[0], "#")
+	entry, err := b.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if err := b.Delete(id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🗑️ Deleted brain entry #%s: %s", id, entry.Title), nil
+}
+
+// brainTags handles "/brain tags" (list tags), "/brain tags <tag>" (list
+// entries) and "/brain tags <id> +add -remove" (change an entry's tags).
+func brainTags(b *brain.Brain, args []string) (string, error) {
+	var sb strings.Builder
+	switch {
+	case len(args) == 0:
+		tags, err := b.Tags()
+		if err != nil {
+			return "", err
+		}
+		if len(tags) == 0 {
+			return "🏷️ No tagged brain entries.", nil
+		}
+		sb.WriteString("🏷️ **Brain tags**\n\n")
+		for _, t := range tags {
+			sb.WriteString(fmt.Sprintf("  `%s` %d\n", t.Tag, t.Count))
+		}
+	case len(args) == 1:
+		entries, err := b.ByTag(args[0])
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			return fmt.Sprintf("🏷️ No brain entries tagged `%s`.", args[0]), nil
+		}
+		sb.WriteString(fmt.Sprintf("🏷️ **Entries tagged `%s`**\n\n", args[0]))
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			sb.WriteString(fmt.Sprintf("  #%s [%s] %s\n", e.ID, e.Category, e.Title))
+		}
+	default:
+		id := strings.TrimPrefix(args[0], "#")
+		for _, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "+") && !strings.HasPrefix(arg, "-") {
+				return "", fmt.Errorf("usage: /brain tags <id> +tag -tag")
+			}
+		}
+		err := b.Update(id, func(e *brain.Entry) {
+			for _, arg := range args[1:] {
+				tag := arg[1:]
+				if arg[0] == '+' {
+					e.Tags = append(e.Tags, tag)
+					continue
+				}
+				e.Tags = slices.DeleteFunc(e.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+		entry, err := b.Get(id)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🏷️ Tags of #%s: %s", id, strings.Join(entry.Tags, ", ")), nil
+	}
+	return sb.String(), nil
+}
+
 func cmdRoadmap(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	roadmap := m.agent.GetRoadmap()
 
-	if len(args) > 0 && args[0] == "summary" {
+	if len(args) > 0 && (args[0] == "graph" || args[0] == "next") {
+		content, err := roadmapGraph(roadmap)
+		if err != nil {
+			m.messageQueue.Add(QueuedMessage{
+				Role:      "error",
+				Content:   fmt.Sprintf("❌ Failed to read roadmap: %v", err),
+				Timestamp: time.Now(),
+				Complete:  true,
+			})
+			m.updateViewport()
+		} else {
+			m.openPanel("Roadmap Graph", content)
+		}
+	} else if len(args) > 0 && args[0] == "summary" {
 		summary, err := roadmap.GetSummary()
 		if err != nil {
 			m.messageQueue.Add(QueuedMessage{
@@ -1230,6 +1451,258 @@ func cmdRoadmap(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// roadmapGraph renders the critical path, the goals ready to start and the
+// dependencies of every unfinished goal.
+func roadmapGraph(r *roadmap.Roadmap) (string, error) {
+	goals, err := r.Goals()
+	if err != nil {
+		return "", err
+	}
+	path, hours, err := r.CriticalPath()
+	if err != nil {
+		return "", err
+	}
+	next, err := r.Actionable()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗺️ **Roadmap Graph**\n\n")
+	if len(goals) == 0 {
+		sb.WriteString("No goals yet. The agent adds them with the roadmap tool.")
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("**Critical path** (%.1fh remaining)\n", hours))
+	if len(path) == 0 {
+		sb.WriteString("  nothing left to do\n")
+	}
+	for i, g := range path {
+		connector := "├─"
+		if i == len(path)-1 {
+			connector = "└─"
+		}
+		sb.WriteString(fmt.Sprintf("  %s %s %s %s%s\n", connector, roadmapStatusIcon(g), g.ID, g.Title, roadmapProgress(g)))
+	}
+
+	sb.WriteString("\n**Ready to start**\n")
+	if len(next) == 0 {
+		sb.WriteString("  none: every open goal is blocked or waiting on a dependency\n")
+	}
+	for _, g := range next {
+		sb.WriteString(fmt.Sprintf("  %s %s %s (%s)%s\n", roadmapStatusIcon(g), g.ID, g.Title, g.Priority, roadmapProgress(g)))
+	}
+
+	sb.WriteString("\n**All goals**\n")
+	for _, g := range goals {
+		sb.WriteString(fmt.Sprintf("  %s %s %s%s", roadmapStatusIcon(g), g.ID, g.Title, roadmapProgress(g)))
+		if len(g.Dependencies) > 0 {
+			sb.WriteString("  ← " + strings.Join(g.Dependencies, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func roadmapStatusIcon(g roadmap.GoalState) string {
+	switch {
+	case g.Status == roadmap.StatusCompleted:
+		return "✅"
+	case g.Status == roadmap.StatusBlocked:
+		return "🚫"
+	case len(g.BlockedBy) > 0:
+		return "⏳"
+	case g.Status == roadmap.StatusInProgress:
+		return "🔄"
+	default:
+		return "⬜"
+	}
+}
+
+func roadmapProgress(g roadmap.GoalState) string {
+	if g.Progress.Total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%d/%d]", g.Progress.Done, g.Progress.Total)
+}
+
+func cmdTasks(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	list := tasks.NewList(filepath.Join(m.agent.GetWorkplacePath(), "task.md"))
+
+	var content string
+	var err error
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "add":
+		content, err = tasksAdd(list, args[1:])
+	case "start", "done", "reopen":
+		status := map[string]string{"start": tasks.StatusInProgress, "done": tasks.StatusDone, "reopen": tasks.StatusTodo}[action]
+		content, err = tasksSetStatus(list, args[1:], status)
+	case "delete":
+		if len(args) != 2 {
+			err = fmt.Errorf("usage: /tasks delete <id>")
+			break
+		}
+		var n int
+		if n, err = list.Delete(args[1]); err == nil {
+			content = fmt.Sprintf("🗑️ Deleted task `%s`", args[1])
+			if n > 1 {
+				content += fmt.Sprintf(" and %d sub-tasks", n-1)
+			}
+		}
+	default:
+		content, err = taskBoard(list, args)
+		if err == nil {
+			m.openPanel("Task Board", content)
+			return m, nil
+		}
+	}
+
+	if err != nil {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "error",
+			Content:   fmt.Sprintf("❌ Tasks: %v", err),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+	} else {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   content,
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+	}
+	m.updateViewport()
+	return m, nil
+}
+
+// taskBoard renders the tasks in a column per status. Arguments naming a
+// status, priority or assignee ("me" is the user) filter the board; any
+// other words search titles and notes.
+func taskBoard(list *tasks.List, args []string) (string, error) {
+	var f tasks.Filter
+	var query []string
+	for _, arg := range args {
+		switch a := strings.ToLower(arg); {
+		case slices.Contains(tasks.Statuses, a) || a == "open":
+			f.Status = a
+		case slices.Contains(tasks.Priorities, a):
+			f.Priority = a
+		case a == "me" || a == "mine":
+			f.Assignee = tasks.AssigneeUser
+		case slices.Contains(tasks.Assignees, a):
+			f.Assignee = a
+		default:
+			query = append(query, arg)
+		}
+	}
+	f.Query = strings.Join(query, " ")
+
+	found, err := list.Find(f)
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "📋 No matching tasks. Add one with /tasks add <title>.", nil
+	}
+
+	titles := make(map[string]string, len(found))
+	for _, t := range found {
+		titles[t.ID] = t.Title
+	}
+	columns := []struct {
+		status, heading string
+	}{
+		{tasks.StatusInProgress, "🔄 In Progress"},
+		{tasks.StatusTodo, "📋 To Do"},
+		{tasks.StatusDone, "✅ Done"},
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📋 **Task Board**\n")
+	for _, col := range columns {
+		var items []tasks.Task
+		for _, t := range found {
+			if t.Status == col.status {
+				items = append(items, t)
+			}
+		}
+		if len(items) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n**%s** (%d)\n", col.heading, len(items)))
+		for _, t := range items {
+			sb.WriteString(fmt.Sprintf("  `%s` %s%s", t.ID, taskPriorityIcon(t.Priority), t.Title))
+			if t.Assignee == tasks.AssigneeUser {
+				sb.WriteString(" 👤")
+			}
+			if t.Parent != "" {
+				parent := titles[t.Parent]
+				if parent == "" {
+					parent = t.Parent
+				}
+				sb.WriteString(fmt.Sprintf(" ↳ %s", parent))
+			}
+			sb.WriteString("\n")
+			for _, note := range t.Notes {
+				sb.WriteString(fmt.Sprintf("      > %s\n", note))
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// tasksAdd adds a task for the agent, or for the user when the title
+// starts with "@me".
+func tasksAdd(list *tasks.List, args []string) (string, error) {
+	task := tasks.Task{Assignee: tasks.AssigneeAgent}
+	if len(args) > 0 && (args[0] == "@me" || args[0] == "@user") {
+		task.Assignee = tasks.AssigneeUser
+		args = args[1:]
+	}
+	task.Title = strings.Join(args, " ")
+	if task.Title == "" {
+		return "", fmt.Errorf("usage: /tasks add [@me] <title>")
+	}
+	added, err := list.Add(task)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("📋 Added task `%s`: %s", added.ID, added.Title), nil
+}
+
+// tasksSetStatus changes the status of the tasks with the given IDs.
+func tasksSetStatus(list *tasks.List, ids []string, status string) (string, error) {
+	if len(ids) == 0 {
+		return "", fmt.Errorf("usage: /tasks start|done|reopen <id>...")
+	}
+	var sb strings.Builder
+	for _, id := range ids {
+		t, err := list.Update(id, func(t *tasks.Task) { t.Status = status })
+		if err != nil {
+			return sb.String(), err
+		}
+		sb.WriteString(fmt.Sprintf("✅ `%s` %s → %s\n", t.ID, t.Title, t.Status))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func taskPriorityIcon(priority string) string {
+	switch priority {
+	case "high":
+		return "🔴 "
+	case "low":
+		return "⚪ "
+	default:
+		return ""
+	}
+}
+
 func cmdSave(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	if err := m.agent.Save(); err != nil {
 		m.messageQueue.Add(QueuedMessage{
@@ -1407,6 +1880,10 @@ func cmdModel(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if args[0] == "pull" {
+		return cmdModelPull(m, args[1:])
+	}
+
 	// Quick switch: /model <name>
 	newModel := args[0]
 	reasoningEffort := ""
@@ -1633,6 +2110,10 @@ func cmdExit(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 // Dual Session Commands
 
 func cmdSession(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	if len(args) > 0 && isSavedSessionAction(strings.ToLower(args[0])) {
+		return cmdSavedSession(m, args)
+	}
+
 	if len(args) == 0 || args[0] == "status" {
 		// Show status
 		var content strings.Builder
@@ -1683,7 +2164,7 @@ func cmdSession(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
 	} else {
 		m.messageQueue.Add(QueuedMessage{
 			Role:      "error",
-			Content:   fmt.Sprintf("❌ Unknown action: %s\n\nUse: /session [on|off|status]", args[0]),
+			Content:   fmt.Sprintf("❌ Unknown action: %s\n\nUse: /session [on|off|status|list|resume|fork|delete|new]", args[0]),
 			Timestamp: time.Now(),
 			Complete:  true,
 		})
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/agent"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cmdCost handles /cost [resume]
+func cmdCost(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	if len(args) > 0 && strings.EqualFold(args[0], "resume") {
+		m.agent.ResumeSpending()
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   "▶️ Spending limits lifted for this run. Soft limit warnings still apply.",
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	status := m.agent.GetSpendingStatus()
+
+	var content strings.Builder
+	content.WriteString("💰 **Cost**\n\n")
+	if status.Paused != "" {
+		content.WriteString(fmt.Sprintf("⛔ **Paused:** %s\nUse `/cost resume` to continue.\n\n", status.Paused))
+	}
+
+	writeCostSummary(&content, "This Session", status.Session, status.SessionSoft, status.SessionHard)
+	writeCostSummary(&content, "Today", status.Today, status.DailySoft, status.DailyHard)
+
+	m.openPanel("Cost", content.String())
+	return m, nil
+}
+
+// writeCostSummary renders one summary with its limits and breakdowns.
+func writeCostSummary(sb *strings.Builder, title string, s agent.CostSummary, soft, hard float64) {
+	sb.WriteString(fmt.Sprintf("**%s:** $%.4f (%d calls, %s in / %s out)\n",
+		title, s.Cost, s.Calls, formatK(s.PromptTokens), formatK(s.CompletionTokens)))
+	if soft > 0 || hard > 0 {
+		sb.WriteString(fmt.Sprintf("Limits: soft %s, hard %s\n", formatLimit(soft), formatLimit(hard)))
+	}
+	if s.Unpriced > 0 {
+		sb.WriteString(fmt.Sprintf("%d calls used models without a known price (add them under \"pricing\" in config)\n", s.Unpriced))
+	}
+
+	writeCostBreakdown(sb, "By model", s.ByModel)
+	writeCostBreakdown(sb, "By role", s.ByRole)
+	sb.WriteString("\n")
+}
+
+// writeCostBreakdown lists costs in descending order.
+func writeCostBreakdown(sb *strings.Builder, title string, costs map[string]float64) {
+	if len(costs) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(costs))
+	for k := range costs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return costs[keys[i]] > costs[keys[j]] })
+
+	sb.WriteString(fmt.Sprintf("\n_%s_\n", title))
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("- %s: $%.4f\n", k, costs[k]))
+	}
+}
+
+func formatLimit(usd float64) string {
+	if usd <= 0 {
+		return "none"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
@@ -0,0 +1,427 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/editor"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editReviewMsg asks the user to review file changes held back by the edit
+// manager. The tool that made them waits for the decisions on reply.
+type editReviewMsg struct {
+	edits []editor.EditRecord
+	reply chan []editor.Review
+}
+
+// diffReview is one batch of edits shown in the review overlay, with the
+// user's accept/reject decision for every hunk.
+type diffReview struct {
+	edits     []editor.EditRecord
+	paths     []string        // display paths, relative to the project
+	hunks     [][]editor.Hunk // per edit; nil when there is nothing to show
+	decisions [][]bool        // per edit and hunk; one entry for edits without hunks
+	file      int             // edit on screen
+	hunk      int             // hunk under the cursor
+	scroll    int
+	done      func([]editor.Review) string
+}
+
+func newDiffReview(edits []editor.EditRecord, paths []string, done func([]editor.Review) string) *diffReview {
+	r := &diffReview{edits: edits, paths: paths, done: done}
+	for _, edit := range edits {
+		hunks := editor.Diff(edit.OldContent, edit.NewContent)
+		n := len(hunks)
+		if n == 0 {
+			n = 1
+		}
+		decisions := make([]bool, n)
+		for i := range decisions {
+			decisions[i] = true
+		}
+		r.hunks = append(r.hunks, hunks)
+		r.decisions = append(r.decisions, decisions)
+	}
+	return r
+}
+
+// setFile accepts or rejects every hunk of the edit on screen.
+func (r *diffReview) setFile(accept bool) {
+	for i := range r.decisions[r.file] {
+		r.decisions[r.file][i] = accept
+	}
+}
+
+// setAll accepts or rejects every hunk of every edit.
+func (r *diffReview) setAll(accept bool) {
+	for f := range r.decisions {
+		for i := range r.decisions[f] {
+			r.decisions[f][i] = accept
+		}
+	}
+}
+
+// reviews converts the decisions into one editor.Review per edit.
+func (r *diffReview) reviews() []editor.Review {
+	reviews := make([]editor.Review, len(r.edits))
+	for i, decisions := range r.decisions {
+		all, any := true, false
+		for _, d := range decisions {
+			all = all && d
+			any = any || d
+		}
+		switch {
+		case all || !any:
+			reviews[i] = editor.Review{Accept: all}
+		default:
+			reviews[i] = editor.Review{Hunks: append([]bool(nil), decisions...)}
+		}
+	}
+	return reviews
+}
+
+// startReview opens the review overlay, or queues the batch behind the one
+// being reviewed. done receives the decisions and may return a line to show
+// in the chat.
+func (m *EnhancedModel) startReview(edits []editor.EditRecord, done func([]editor.Review) string) {
+	paths := make([]string, len(edits))
+	for i, edit := range edits {
+		paths[i] = edit.FilePath
+		if m.agent != nil {
+			paths[i] = m.agent.GetEditManager().RelPath(edit.FilePath)
+		}
+	}
+	r := newDiffReview(edits, paths, done)
+	if m.reviewActive {
+		m.reviewQueue = append(m.reviewQueue, r)
+		return
+	}
+	m.review = r
+	m.reviewActive = true
+}
+
+// finishReview hands the decisions to the waiting caller and moves on to
+// the next queued batch.
+func (m *EnhancedModel) finishReview() {
+	r := m.review
+	reviews := r.reviews()
+
+	accepted, partial, rejected := 0, 0, 0
+	for _, rv := range reviews {
+		switch {
+		case rv.Hunks != nil:
+			partial++
+		case rv.Accept:
+			accepted++
+		default:
+			rejected++
+		}
+	}
+	summary := fmt.Sprintf("📝 Review: %d accepted, %d partially accepted, %d rejected", accepted, partial, rejected)
+	if extra := r.done(reviews); extra != "" {
+		summary += "\n" + extra
+	}
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "system",
+		Content:   summary,
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+
+	m.review = nil
+	m.reviewActive = false
+	if len(m.reviewQueue) > 0 {
+		m.review = m.reviewQueue[0]
+		m.reviewQueue = m.reviewQueue[1:]
+		m.reviewActive = true
+	}
+	m.updateViewport()
+}
+
+// reviewVisibleHeight returns how many diff lines fit in the overlay.
+func (m *EnhancedModel) reviewVisibleHeight() int {
+	// total height minus: border(2) + margin(2) + padding(2) + title(1) + file line(1) + blank(2) + footer(2)
+	h := m.height - 12
+	if h < 5 {
+		h = 5
+	}
+	return h
+}
+
+// reviewUpdate handles keyboard input while the review overlay is active.
+// Every way out of the overlay answers the waiting tool.
+func (m EnhancedModel) reviewUpdate(msg tea.KeyMsg) (EnhancedModel, tea.Cmd) {
+	r := m.review
+	pageSize := m.reviewVisibleHeight()
+
+	switch msg.String() {
+	case "tab", "right", "l", "n":
+		r.file = (r.file + 1) % len(r.edits)
+		r.hunk, r.scroll = 0, 0
+
+	case "shift+tab", "left", "h", "p":
+		r.file = (r.file + len(r.edits) - 1) % len(r.edits)
+		r.hunk, r.scroll = 0, 0
+
+	case "down", "j":
+		if r.hunk < len(r.decisions[r.file])-1 {
+			r.hunk++
+			r.scroll = m.reviewHunkOffset()
+		}
+
+	case "up", "k":
+		if r.hunk > 0 {
+			r.hunk--
+			r.scroll = m.reviewHunkOffset()
+		}
+
+	case "pgdown", "f":
+		lines, _ := m.reviewLines(m.reviewContentWidth())
+		r.scroll = min(r.scroll+pageSize, max(len(lines)-pageSize, 0))
+
+	case "pgup", "b":
+		r.scroll -= pageSize
+		if r.scroll < 0 {
+			r.scroll = 0
+		}
+
+	case " ", "space":
+		r.decisions[r.file][r.hunk] = !r.decisions[r.file][r.hunk]
+
+	case "a", "y":
+		r.setFile(true)
+
+	case "r", "x":
+		r.setFile(false)
+
+	case "A":
+		r.setAll(true)
+
+	case "R":
+		r.setAll(false)
+
+	case "v":
+		m.reviewSideBySide = !m.reviewSideBySide
+		r.scroll = m.reviewHunkOffset()
+
+	case "enter":
+		m.finishReview()
+
+	case "esc":
+		r.setAll(false)
+		m.finishReview()
+	}
+
+	return m, nil
+}
+
+// reviewHunkOffset returns the scroll position that brings the hunk under
+// the cursor to the top.
+func (m *EnhancedModel) reviewHunkOffset() int {
+	_, starts := m.reviewLines(m.reviewContentWidth())
+	if m.review.hunk < len(starts) {
+		return starts[m.review.hunk]
+	}
+	return 0
+}
+
+func (m *EnhancedModel) reviewContentWidth() int {
+	boxWidth := m.width - 6
+	if boxWidth < 40 {
+		boxWidth = 40
+	}
+	// border(2) + padding(4)
+	return boxWidth - 6
+}
+
+// reviewLines renders the diff of the edit on screen and returns the line
+// on which each hunk starts.
+func (m *EnhancedModel) reviewLines(width int) ([]string, []int) {
+	r := m.review
+	hunks := r.hunks[r.file]
+	lang := syntaxFor(r.paths[r.file])
+
+	var lines []string
+	var offsets []int
+	if len(hunks) == 0 {
+		offsets = append(offsets, 0)
+		lines = append(lines, reviewMarker(r.decisions[r.file][0], r.hunk == 0)+PanelScrollStyle.Render("(empty file)"))
+		return lines, offsets
+	}
+	for i, h := range hunks {
+		offsets = append(offsets, len(lines))
+		accepted := r.decisions[r.file][i]
+		lines = append(lines, reviewMarker(accepted, i == r.hunk)+DiffHunkStyle.Render(h.Header()))
+		var body []string
+		if m.reviewSideBySide {
+			body = sideBySideLines(h.Lines, width, lang, accepted)
+		} else {
+			body = unifiedLines(h.Lines, width, lang, accepted)
+		}
+		lines = append(lines, body...)
+		lines = append(lines, "")
+	}
+	return lines, offsets
+}
+
+// reviewMarker renders the cursor and accept/reject state of a hunk.
+func reviewMarker(accepted, cursor bool) string {
+	pointer := "  "
+	if cursor {
+		pointer = "▶ "
+	}
+	if accepted {
+		return pointer + DiffAddStyle.Render("[✓]") + " "
+	}
+	return pointer + DiffRemoveStyle.Render("[✗]") + " "
+}
+
+// clipLine expands tabs and cuts text to width runes.
+func clipLine(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	if width < 1 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
+
+// diffLine renders one line of a hunk, cut to width runes including its
+// +/- sign.
+func diffLine(line string, width int, lang *syntaxLang, accepted bool) string {
+	if line == "" {
+		return line
+	}
+	sign, text := line[:1], clipLine(line[1:], width-1)
+	switch {
+	case !accepted:
+		return DiffRejectedStyle.Render(sign + text)
+	case sign == "+":
+		return DiffAddStyle.Render(sign) + highlightCode(text, lang)
+	case sign == "-":
+		return DiffRemoveStyle.Render(sign + text)
+	case sign == "\\":
+		return PanelScrollStyle.Render(sign + text)
+	default:
+		return sign + highlightCode(text, lang)
+	}
+}
+
+// unifiedLines renders a hunk as a unified diff.
+func unifiedLines(hunk []string, width int, lang *syntaxLang, accepted bool) []string {
+	out := make([]string, 0, len(hunk))
+	for _, line := range hunk {
+		out = append(out, diffLine(line, width, lang, accepted))
+	}
+	return out
+}
+
+// sideBySideLines renders a hunk as two columns, old on the left and new on
+// the right, pairing each run of removed lines with the added lines that
+// follow it.
+func sideBySideLines(hunk []string, width int, lang *syntaxLang, accepted bool) []string {
+	col := (width - 3) / 2
+	cell := func(line string) string {
+		if line == "" {
+			return strings.Repeat(" ", col)
+		}
+		text := clipLine(line[1:], col-1)
+		pad := strings.Repeat(" ", col-1-len([]rune(text)))
+		return diffLine(line[:1]+text, col, lang, accepted) + pad
+	}
+
+	var out, removed, added []string
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			var left, right string
+			if i < len(removed) {
+				left = removed[i]
+			}
+			if i < len(added) {
+				right = added[i]
+			}
+			out = append(out, cell(left)+" │ "+cell(right))
+		}
+		removed, added = nil, nil
+	}
+	for _, line := range hunk {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, line)
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line)
+		case strings.HasPrefix(line, "\\"):
+			// Markers belong to the side of the line before them.
+			if len(added) > 0 {
+				added = append(added, line)
+			} else {
+				removed = append(removed, line)
+			}
+		default:
+			flush()
+			out = append(out, cell(line)+" │ "+cell(line))
+		}
+	}
+	flush()
+	return out
+}
+
+// reviewView renders the review overlay.
+func (m EnhancedModel) reviewView() string {
+	r := m.review
+	width := m.reviewContentWidth()
+	visibleHeight := m.reviewVisibleHeight()
+	edit := r.edits[r.file]
+
+	var s strings.Builder
+	mode := "unified"
+	if m.reviewSideBySide {
+		mode = "side by side"
+	}
+	s.WriteString(ReviewTitleStyle.Render(fmt.Sprintf("📝 Review changes — file %d/%d (%s)", r.file+1, len(r.edits), mode)))
+	s.WriteString("\n")
+	header := fmt.Sprintf("%s %s", edit.Operation, r.paths[r.file])
+	if edit.Description != "" {
+		header += " — " + edit.Description
+	}
+	s.WriteString(PanelFooterStyle.Render(clipLine(header, width)))
+	s.WriteString("\n\n")
+
+	lines, _ := m.reviewLines(width)
+	maxScroll := len(lines) - visibleHeight
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+	scroll := r.scroll
+	if scroll > maxScroll {
+		scroll = maxScroll
+	}
+	end := scroll + visibleHeight
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for _, line := range lines[scroll:end] {
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+	if remaining := len(lines) - end; remaining > 0 {
+		s.WriteString(PanelScrollStyle.Render(fmt.Sprintf("  ▼ %d more", remaining)))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(PanelFooterStyle.Render("←/→ File | ↑/↓ Hunk | Space Toggle hunk | a/r Accept/reject file | A/R All | v View"))
+	s.WriteString("\n")
+	s.WriteString(PanelFooterStyle.Render("Enter Apply decisions | Esc Reject all"))
+
+	return ReviewBoxStyle.Width(width + 6).Render(s.String())
+}
@@ -0,0 +1,58 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"ClosedWheeler/pkg/editor"
+)
+
+// TestDiffReviewDecisions verifies how hunk decisions become editor reviews.
+func TestDiffReviewDecisions(t *testing.T) {
+	var old []string
+	for i := 0; i < 20; i++ {
+		old = append(old, "line")
+	}
+	changed := append([]string(nil), old...)
+	changed[1], changed[17] = "one", "two"
+
+	edits := []editor.EditRecord{
+		{Operation: "modify", OldContent: strings.Join(old, "\n"), NewContent: strings.Join(changed, "\n")},
+		{Operation: "create", NewContent: "new\n"},
+		{Operation: "create"},
+	}
+	r := newDiffReview(edits, []string{"a.go", "b.go", "empty.txt"}, nil)
+	if len(r.hunks[0]) != 2 || len(r.decisions[2]) != 1 {
+		t.Fatalf("unexpected hunks %v / decisions %v", r.hunks, r.decisions)
+	}
+
+	r.decisions[0][0] = false
+	r.file = 1
+	r.setFile(false)
+
+	reviews := r.reviews()
+	if reviews[0].Hunks == nil || reviews[0].Hunks[0] || !reviews[0].Hunks[1] {
+		t.Errorf("partly accepted edit should select hunks, got %+v", reviews[0])
+	}
+	if reviews[1].Accept || reviews[1].Hunks != nil {
+		t.Errorf("rejected edit should be rejected whole, got %+v", reviews[1])
+	}
+	if !reviews[2].Accept {
+		t.Errorf("edit without hunks should default to accepted, got %+v", reviews[2])
+	}
+}
+
+// TestSideBySideLines verifies removed and added lines are paired in rows.
+func TestSideBySideLines(t *testing.T) {
+	hunk := []string{" same", "-old 1", "-old 2", "+new 1", " tail"}
+	rows := sideBySideLines(hunk, 43, nil, true)
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d:\n%s", len(rows), strings.Join(rows, "\n"))
+	}
+	if !strings.Contains(rows[1], "-old 1") || !strings.Contains(rows[1], "+new 1") {
+		t.Errorf("first change row should pair old and new, got %q", rows[1])
+	}
+	if strings.Contains(rows[2], "+") {
+		t.Errorf("second removed line has no counterpart, got %q", rows[2])
+	}
+}
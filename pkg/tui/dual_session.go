@@ -12,6 +12,7 @@ import (
 	"ClosedWheeler/pkg/agent"
 	"ClosedWheeler/pkg/llm"
 	"ClosedWheeler/pkg/logger"
+	"ClosedWheeler/pkg/secrets"
 )
 
 // DualSession manages two agents conversing with each other
@@ -270,6 +271,12 @@ func (ds *DualSession) runConversation(initialPrompt string) {
 		turnNum := ds.currentTurn
 		ds.mu.Unlock()
 
+		// Stop the debate while a spending limit pauses the agents
+		if paused, reason := currentAgent.SpendingPaused(); paused {
+			ds.addMessage("System", fmt.Sprintf("⛔ Debate paused: %s. Use /cost resume to continue.", reason), turnNum)
+			return
+		}
+
 		// Robust Turn Loop: Retry if turn fails or returns empty,
 		// but wait indefinitely while the agent is 'active' (thinking/working)
 		var response string
@@ -456,7 +463,7 @@ func (ds *DualSession) addMessage(speaker, content string, turn int) {
 
 	msg := DualMessage{
 		Speaker:   speaker,
-		Content:   content,
+		Content:   secrets.Redact(content),
 		Timestamp: time.Now(),
 		Turn:      turn,
 		RoleName:  roleName,
@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/editor"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cmdUndo handles /undo [n]: it restores the files changed by the agent
+// during the last n turns (default 1).
+func cmdUndo(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	return timelineStep(m, args, "undo", m.agent.UndoTurns, "↩️ Undid")
+}
+
+// cmdRedo handles /redo [n]: it reapplies the last n undone turns.
+func cmdRedo(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	return timelineStep(m, args, "redo", m.agent.RedoTurns, "↪️ Redid")
+}
+
+// timelineStep runs an undo or redo of n turns and lists the files restored.
+func timelineStep(m *EnhancedModel, args []string, name string, step func(int) ([]editor.Turn, error), verb string) (tea.Model, tea.Cmd) {
+	if m.processing {
+		return editError(m, fmt.Sprintf("Cannot %s while a request is running.", name))
+	}
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return editError(m, fmt.Sprintf("Usage: /%s [n]", name))
+		}
+	}
+
+	turns, err := step(n)
+	if len(turns) > 0 {
+		edits := m.agent.GetEditManager()
+		var content strings.Builder
+		content.WriteString(fmt.Sprintf("%s %d turn(s):\n", verb, len(turns)))
+		for _, t := range turns {
+			content.WriteString(fmt.Sprintf("\n#%d %s\n", t.ID, truncateText(firstLine(t.Label), 60)))
+			for _, f := range t.Files {
+				content.WriteString("  • " + edits.RelPath(f.Path) + "\n")
+			}
+		}
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   content.String(),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+	}
+	if err != nil {
+		return editError(m, fmt.Sprintf("%s failed: %v", strings.ToUpper(name[:1])+name[1:], err))
+	}
+	m.updateViewport()
+	return m, nil
+}
+
+// cmdTimeline handles /timeline: it lists the turns that changed files,
+// marking undone ones, which /redo can bring back.
+func cmdTimeline(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	turns := m.agent.Timeline()
+	if len(turns) == 0 {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   "No file changes recorded yet.",
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	edits := m.agent.GetEditManager()
+	var content strings.Builder
+	for i := len(turns) - 1; i >= 0; i-- {
+		t := turns[i]
+		state := "✅"
+		if t.Undone {
+			state = "↩️ undone"
+		}
+		content.WriteString(fmt.Sprintf("#%-4d %s  %s  %s\n", t.ID, t.Started.Format("15:04:05"), state, truncateText(firstLine(t.Label), 60)))
+		for _, f := range t.Files {
+			change := "modified"
+			switch {
+			case !f.BeforeExists:
+				change = "created"
+			case !f.AfterExists:
+				change = "deleted"
+			}
+			content.WriteString(fmt.Sprintf("        %-8s %s\n", change, edits.RelPath(f.Path)))
+		}
+	}
+	content.WriteString("\n/undo [n] restores the state before the last n turns; /redo [n] reapplies undone turns.")
+	content.WriteString("\nOnly changes made by write_file, edit_file and apply_patch are recorded; files changed by commands, tests, builds or skills are not restored.")
+	m.openPanel("🕘 Timeline", content.String())
+	return m, nil
+}
+
+// editError shows an error from a file change command.
+func editError(m *EnhancedModel, text string) (tea.Model, tea.Cmd) {
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "error",
+		Content:   "❌ " + text,
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+	m.updateViewport()
+	return m, nil
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// cmdReview handles /review: it opens the diff review overlay for changes
+// that are waiting for review but no tool is waiting on, e.g. those made
+// while no reviewer was attached.
+func cmdReview(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	if m.reviewActive {
+		return m, nil
+	}
+
+	edits := m.agent.GetEditManager()
+	var pending []editor.EditRecord
+	for _, edit := range edits.GetPendingEdits() {
+		if edit.Review == editor.ReviewPending {
+			pending = append(pending, edit)
+		}
+	}
+	if len(pending) == 0 {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   "No file changes are waiting for review.",
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	m.startReview(pending, func(reviews []editor.Review) string {
+		if _, err := edits.ApplyReviewed(pending, reviews); err != nil {
+			return fmt.Sprintf("❌ Applying reviewed changes failed: %v", err)
+		}
+		return ""
+	})
+	return m, nil
+}
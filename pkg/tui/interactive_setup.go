@@ -197,7 +197,7 @@ func promptAPI(reader *bufio.Reader) (string, string, string) {
 	fmt.Println(SetupInfoStyle.Render("  1. OpenAI     - https://api.openai.com/v1"))
 	fmt.Println(SetupInfoStyle.Render("  2. NVIDIA     - https://integrate.api.nvidia.com/v1"))
 	fmt.Println(SetupInfoStyle.Render("  3. Anthropic  - https://api.anthropic.com/v1"))
-	fmt.Println(SetupInfoStyle.Render("  4. Local      - http://localhost:11434/v1"))
+	fmt.Println(SetupInfoStyle.Render("  4. Ollama     - http://localhost:11434"))
 	fmt.Println()
 
 	baseURL := promptString(reader, "API Base URL", "https://api.openai.com/v1")
@@ -211,6 +211,9 @@ func promptAPI(reader *bufio.Reader) (string, string, string) {
 	} else if strings.Contains(baseURL, "openai.com") {
 		provider = "openai"
 		fmt.Println(SetupSuccessStyle.Render("  Detected provider: OpenAI"))
+	} else if strings.Contains(baseURL, ":11434") {
+		provider = "ollama"
+		fmt.Println(SetupSuccessStyle.Render("  Detected provider: Ollama (local, no API key needed)"))
 	} else if strings.HasPrefix(apiKey, "sk-ant-") {
 		provider = "anthropic"
 		fmt.Println(SetupSuccessStyle.Render("  Detected provider: Anthropic (from API key)"))
@@ -491,12 +494,12 @@ func buildConfig(agentName, primaryModel, provider string, fallbackModels []stri
 	perm := map[string]interface{}{
 		"allowed_commands": []string{"*"},
 		"allowed_tools":    []string{"*"},
-		"sensitive_tools":  []string{"git_commit", "git_push", "exec_command", "write_file", "delete_file"},
+		"sensitive_tools":  []string{"git_commit", "git_push", "exec_command", "write_file", "edit_file", "apply_patch", "delete_file"},
 	}
 
 	switch permPreset {
 	case "restricted":
-		perm["allowed_tools"] = []string{"read_file", "list_files", "search_files", "edit_file", "write_file"}
+		perm["allowed_tools"] = []string{"read_file", "list_files", "search_files", "edit_file", "apply_patch", "write_file"}
 	case "read-only":
 		perm["allowed_tools"] = []string{"read_file", "list_files", "search_files"}
 	}
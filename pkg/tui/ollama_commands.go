@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ClosedWheeler/pkg/llm"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ollamaPullDoneMsg reports the result of a background model pull.
+type ollamaPullDoneMsg struct {
+	model string
+	err   error
+}
+
+// cmdModelPull handles /model pull <name>: downloads a model onto the
+// configured Ollama server in the background.
+func cmdModelPull(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	cfg := m.agent.Config()
+	if m.agent.ProviderName() != "ollama" {
+		return sessionError(m, "/model pull requires the ollama provider (set \"provider\": \"ollama\").")
+	}
+	if len(args) == 0 {
+		return sessionError(m, "Usage: /model pull <model>")
+	}
+
+	model := args[0]
+	baseURL := cfg.APIBaseURL
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "system",
+		Content:   fmt.Sprintf("⬇️ Pulling **%s** from the Ollama library. This can take a while...", model),
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+	m.updateViewport()
+
+	return m, func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		err := llm.PullOllamaModel(ctx, baseURL, model, nil)
+		return ollamaPullDoneMsg{model: model, err: err}
+	}
+}
+
+// handleOllamaPullDone reports a finished pull in the conversation.
+func (m *EnhancedModel) handleOllamaPullDone(msg ollamaPullDoneMsg) {
+	if msg.err != nil {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "error",
+			Content:   fmt.Sprintf("❌ Failed to pull %s: %v", msg.model, msg.err),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+	} else {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   fmt.Sprintf("✅ Pulled **%s**. Switch to it with `/model %s`.", msg.model, msg.model),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+	}
+	m.updateViewport()
+}
@@ -48,8 +48,8 @@ var pickerProviders = []ProviderOption{
 	{Label: "OpenAI", Provider: "openai", BaseURL: "https://api.openai.com/v1", NeedsKey: true},
 	{Label: "DeepSeek", Provider: "openai", BaseURL: "https://api.deepseek.com", NeedsKey: true},
 	{Label: "Moonshot", Provider: "openai", BaseURL: "https://api.moonshot.ai/v1", NeedsKey: true},
-	{Label: "Google Gemini", Provider: "openai", BaseURL: "https://generativelanguage.googleapis.com/v1beta", NeedsKey: true},
-	{Label: "Local (Ollama)", Provider: "openai", BaseURL: "http://localhost:11434/v1", NeedsKey: false},
+	{Label: "Google Gemini", Provider: "gemini", BaseURL: "https://generativelanguage.googleapis.com/v1beta", NeedsKey: true},
+	{Label: "Local (Ollama)", Provider: "ollama", BaseURL: "http://localhost:11434", NeedsKey: false},
 	{Label: "Custom URL", Provider: "openai", BaseURL: "", NeedsKey: true},
 }
 
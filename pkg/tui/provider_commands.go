@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"ClosedWheeler/pkg/llm"
 	"ClosedWheeler/pkg/providers"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -73,6 +74,8 @@ func cmdListProviders(m *EnhancedModel, _ []string) (tea.Model, tea.Cmd) {
 		content.WriteString("Add a provider with: `/providers add`\n")
 		content.WriteString("See examples: `/providers examples`")
 	} else {
+		writeRoutingState(&content, m.agent.ProviderRouter())
+
 		primary, _ := m.providerManager.GetPrimaryProvider()
 
 		for _, p := range providers {
@@ -82,7 +85,7 @@ func cmdListProviders(m *EnhancedModel, _ []string) (tea.Model, tea.Cmd) {
 				if p.IsHealthy() {
 					status = "🟢 Healthy"
 				} else {
-					status = "🟡 Unhealthy"
+					status = fmt.Sprintf("🟡 Unhealthy (retry in %s)", p.RetryIn().Round(time.Second))
 				}
 			}
 
@@ -116,6 +119,35 @@ func cmdListProviders(m *EnhancedModel, _ []string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// writeRoutingState describes how agent requests are currently routed.
+func writeRoutingState(content *strings.Builder, router *llm.ProviderRouter) {
+	if router == nil {
+		content.WriteString("Routing: off (set `routing` in providers.json to route requests)\n\n")
+		return
+	}
+
+	state := router.State()
+	failover := "off"
+	if state.Failover {
+		failover = "on"
+	}
+	content.WriteString(fmt.Sprintf("**Routing:** %s | Failover: %s\n", state.Criteria, failover))
+	if len(state.Chain) > 0 {
+		content.WriteString(fmt.Sprintf("  Next request: %s\n", strings.Join(state.Chain, " → ")))
+	}
+	if state.Active != "" {
+		content.WriteString(fmt.Sprintf("  Last served by: `%s`", state.Active))
+		if state.Failovers > 0 {
+			content.WriteString(fmt.Sprintf(" | Failovers so far: %d", state.Failovers))
+		}
+		content.WriteString("\n")
+	}
+	if state.LastError != "" {
+		content.WriteString(fmt.Sprintf("  Last failure: %s\n", state.LastError))
+	}
+	content.WriteString("\n")
+}
+
 func cmdAddProvider(m *EnhancedModel, _ []string) (tea.Model, tea.Cmd) {
 	// Interactive provider addition would go here
 	// For now, show usage
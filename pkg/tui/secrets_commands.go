@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/secrets"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configFiles hold credentials on purpose and are left out of a scan.
+var configFiles = []string{"config.json", "providers.json", ".env"}
+
+func cmdSecrets(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	if len(args) > 0 && args[0] != "scan" {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "error",
+			Content:   "❌ Usage: /secrets scan",
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	paths := secretScanPaths(m.agent.GetAppPath(), m.agent.GetWorkplacePath(), m.agent.Config().Memory.StoragePath)
+	findings, err := secrets.ScanPaths(paths, configFiles...)
+	if err != nil {
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "error",
+			Content:   fmt.Sprintf("❌ Secrets scan: %v", err),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	m.openPanel("Secrets Scan", secretsReport(paths, findings))
+	return m, nil
+}
+
+// secretScanPaths lists what the agent writes itself: the .agi directory
+// with logs, sessions and memory, the saved debates, and the memory file
+// when it is kept elsewhere.
+func secretScanPaths(appPath, workplace, memoryPath string) []string {
+	agiDir := filepath.Join(appPath, ".agi")
+	paths := []string{
+		agiDir,
+		filepath.Join(workplace, "debates"),
+		filepath.Join(workplace, "debate_history.log"),
+	}
+	if memoryPath != "" {
+		if !filepath.IsAbs(memoryPath) {
+			memoryPath = filepath.Join(appPath, memoryPath)
+		}
+		if rel, err := filepath.Rel(agiDir, memoryPath); err != nil || strings.HasPrefix(rel, "..") {
+			paths = append(paths, memoryPath)
+		}
+	}
+	return paths
+}
+
+// secretsReport lists the findings grouped by file.
+func secretsReport(paths []string, findings []secrets.FileFinding) string {
+	var b strings.Builder
+	b.WriteString("🔑 **Secrets Scan**\n\n")
+	b.WriteString("**Scanned:**\n")
+	for _, p := range paths {
+		b.WriteString(fmt.Sprintf("- `%s`\n", p))
+	}
+	b.WriteString(fmt.Sprintf("\n_Skipped %s: they hold credentials on purpose._\n\n", strings.Join(configFiles, ", ")))
+
+	if len(findings) == 0 {
+		b.WriteString("✅ No secrets found.\n")
+		return b.String()
+	}
+
+	files := 0
+	last := ""
+	for _, f := range findings {
+		if f.Path != last {
+			files++
+		}
+		last = f.Path
+	}
+	b.WriteString(fmt.Sprintf("⚠️ **%d secrets in %d files**\n", len(findings), files))
+
+	last = ""
+	for _, f := range findings {
+		if f.Path != last {
+			b.WriteString(fmt.Sprintf("\n**%s**\n", f.Path))
+			last = f.Path
+		}
+		b.WriteString(fmt.Sprintf("- line %d: %s `%s`\n", f.Line, f.Detector, f.Masked))
+	}
+	b.WriteString("\nRemove or rotate these secrets; new output is redacted before it is written.\n")
+	return b.String()
+}
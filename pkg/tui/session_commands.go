@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ClosedWheeler/pkg/agent"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// isSavedSessionAction reports whether a /session subcommand targets
+// persistent conversation sessions rather than dual-session mode.
+func isSavedSessionAction(sub string) bool {
+	switch sub {
+	case "list", "ls", "resume", "load", "fork", "delete", "rm", "new":
+		return true
+	}
+	return false
+}
+
+// cmdSavedSession handles /session [list|resume|fork|delete|new]
+func cmdSavedSession(m *EnhancedModel, args []string) (tea.Model, tea.Cmd) {
+	sub := strings.ToLower(args[0])
+	rest := args[1:]
+
+	switch sub {
+	case "list", "ls":
+		return cmdSessionList(m)
+
+	case "resume", "load":
+		if m.processing {
+			return sessionError(m, "Cannot switch sessions while a request is running.")
+		}
+		if len(rest) == 0 {
+			return sessionError(m, "Usage: /session resume <id|last>")
+		}
+		sess, err := m.agent.ResumeSession(rest[0])
+		if err != nil {
+			return sessionError(m, fmt.Sprintf("Failed to resume session: %v", err))
+		}
+		showResumedSession(m, sess, "▶️ Resumed")
+		return m, nil
+
+	case "fork":
+		if m.processing {
+			return sessionError(m, "Cannot switch sessions while a request is running.")
+		}
+		id := ""
+		if len(rest) > 0 {
+			id = rest[0]
+		}
+		name := ""
+		if len(rest) > 1 {
+			name = strings.Join(rest[1:], " ")
+		}
+		sess, err := m.agent.ForkSession(id, name)
+		if err != nil {
+			return sessionError(m, fmt.Sprintf("Failed to fork session: %v", err))
+		}
+		showResumedSession(m, sess, "🍴 Forked into")
+		return m, nil
+
+	case "delete", "rm":
+		if len(rest) == 0 {
+			return sessionError(m, "Usage: /session delete <id>")
+		}
+		if err := m.agent.DeleteSession(rest[0]); err != nil {
+			return sessionError(m, fmt.Sprintf("Failed to delete session: %v", err))
+		}
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   fmt.Sprintf("🗑️ Session %s deleted.", rest[0]),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+
+	case "new":
+		if m.processing {
+			return sessionError(m, "Cannot switch sessions while a request is running.")
+		}
+		m.agent.NewConversation()
+		m.messageQueue.Clear()
+		m.messageQueue.Add(QueuedMessage{
+			Role:      "system",
+			Content:   fmt.Sprintf("✨ Started new session `%s`. The previous one was saved.", m.agent.CurrentSessionID()),
+			Timestamp: time.Now(),
+			Complete:  true,
+		})
+		m.updateViewport()
+		return m, nil
+	}
+
+	return sessionError(m, fmt.Sprintf("Unknown action: %s", sub))
+}
+
+// cmdSessionList shows all saved sessions in a panel.
+func cmdSessionList(m *EnhancedModel) (tea.Model, tea.Cmd) {
+	sessions, err := m.agent.ListSessions()
+	if err != nil {
+		return sessionError(m, fmt.Sprintf("Failed to list sessions: %v", err))
+	}
+
+	current := m.agent.CurrentSessionID()
+	var content strings.Builder
+	content.WriteString("💾 **Saved Sessions**\n\n")
+
+	if len(sessions) == 0 {
+		content.WriteString("No saved sessions yet. Sessions are saved automatically after each message.\n")
+	} else {
+		for _, s := range sessions {
+			marker := "  "
+			if s.ID == current {
+				marker = "▶ "
+			}
+			name := s.Name
+			if name == "" {
+				name = "(untitled)"
+			}
+			content.WriteString(fmt.Sprintf("%s`%s` **%s**\n", marker, s.ID, name))
+			content.WriteString(fmt.Sprintf("    %d messages · %d tokens · %s · %s\n",
+				s.MessageCount, s.TotalTokens, s.Model, s.UpdatedAt.Format("2006-01-02 15:04")))
+			if s.ParentID != "" {
+				content.WriteString(fmt.Sprintf("    forked from `%s`\n", s.ParentID))
+			}
+		}
+	}
+
+	content.WriteString("\n**Commands:**\n")
+	content.WriteString("- `/session resume <id|last>` — continue a saved session\n")
+	content.WriteString("- `/session fork [id] [name]` — branch a session (current if no id)\n")
+	content.WriteString("- `/session delete <id>` — remove a saved session\n")
+	content.WriteString("- `/session new` — save and start a fresh session\n")
+
+	m.openPanel("Sessions", content.String())
+	return m, nil
+}
+
+// showResumedSession replaces the visible conversation with a session transcript.
+func showResumedSession(m *EnhancedModel, sess *agent.SavedSession, verb string) {
+	m.messageQueue.Clear()
+	loadSessionTranscript(m.messageQueue, m.agent)
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "system",
+		Content:   fmt.Sprintf("%s session `%s` — %s (%d messages)", verb, sess.ID, sess.Name, len(sess.Messages)),
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+	m.updateViewport()
+}
+
+// loadSessionTranscript adds the agent's current session transcript to the queue.
+func loadSessionTranscript(mq *MessageQueue, ag *agent.Agent) {
+	for _, msg := range ag.CurrentSessionMessages() {
+		mq.Add(QueuedMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Timestamp: msg.Timestamp,
+			Complete:  true,
+		})
+	}
+}
+
+func sessionError(m *EnhancedModel, msg string) (tea.Model, tea.Cmd) {
+	m.messageQueue.Add(QueuedMessage{
+		Role:      "error",
+		Content:   "❌ " + msg,
+		Timestamp: time.Now(),
+		Complete:  true,
+	})
+	m.updateViewport()
+	return m, nil
+}
@@ -49,7 +49,7 @@ var wizAPIURLs = []struct {
 	{"OpenAI", "https://api.openai.com/v1"},
 	{"NVIDIA", "https://integrate.api.nvidia.com/v1"},
 	{"Anthropic", "https://api.anthropic.com/v1"},
-	{"Local (Ollama)", "http://localhost:11434/v1"},
+	{"Local (Ollama)", "http://localhost:11434"},
 }
 
 // Permissions presets
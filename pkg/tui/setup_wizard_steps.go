@@ -152,6 +152,8 @@ func detectProvider(url string) string {
 		return "anthropic"
 	case strings.Contains(url, "openai.com"):
 		return "openai"
+	case strings.Contains(url, ":11434"):
+		return "ollama"
 	default:
 		return ""
 	}
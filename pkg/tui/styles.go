@@ -244,6 +244,45 @@ var (
 				Foreground(MutedColor).
 				Faint(true)
 
+	// Diff Review Overlay Styles
+	ReviewBoxStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(AccentColor).
+			Padding(1, 2).
+			Margin(1, 1)
+
+	ReviewTitleStyle = lipgloss.NewStyle().
+				Foreground(AccentColor).
+				Bold(true)
+
+	DiffAddStyle = lipgloss.NewStyle().
+			Foreground(SuccessColor)
+
+	DiffRemoveStyle = lipgloss.NewStyle().
+			Foreground(ErrorColor)
+
+	DiffHunkStyle = lipgloss.NewStyle().
+			Foreground(SecondaryColor)
+
+	DiffRejectedStyle = lipgloss.NewStyle().
+				Foreground(TextMuted).
+				Faint(true)
+
+	// Syntax highlighting in diffs
+	SyntaxKeywordStyle = lipgloss.NewStyle().
+				Foreground(HeadingColor).
+				Bold(true)
+
+	SyntaxStringStyle = lipgloss.NewStyle().
+				Foreground(GoldColor)
+
+	SyntaxNumberStyle = lipgloss.NewStyle().
+				Foreground(HotPink)
+
+	SyntaxCommentStyle = lipgloss.NewStyle().
+				Foreground(TextMuted).
+				Italic(true)
+
 	// Settings Overlay Styles (interactive toggle menu)
 	SettingsBoxStyle = lipgloss.NewStyle().
 				Border(lipgloss.RoundedBorder()).
@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// syntaxLang describes just enough of a language to color diff lines:
+// keywords, the line comment marker and which quotes delimit strings.
+type syntaxLang struct {
+	keywords    map[string]bool
+	lineComment string
+	quotes      string
+}
+
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	langGo = &syntaxLang{
+		keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+			import interface map package range return select struct switch type var nil true false iota`),
+		lineComment: "//",
+		quotes:      "\"'`",
+	}
+	langPython = &syntaxLang{
+		keywords: words(`and as assert async await break class continue def del elif else except finally for
+			from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self`),
+		lineComment: "#",
+		quotes:      "\"'",
+	}
+	langJS = &syntaxLang{
+		keywords: words(`async await break case catch class const continue default delete do else export extends
+			finally for from function if import in instanceof interface let new null of return switch this throw try
+			type typeof undefined var void while yield true false`),
+		lineComment: "//",
+		quotes:      "\"'`",
+	}
+	langRust = &syntaxLang{
+		keywords: words(`as async await break const continue crate else enum extern false fn for if impl in let loop
+			match mod move mut pub ref return self Self static struct super trait true type unsafe use where while`),
+		lineComment: "//",
+		quotes:      "\"",
+	}
+	langC = &syntaxLang{
+		keywords: words(`auto bool break case catch char class const continue default delete do double else enum
+			extern false float for goto if include int long namespace new nullptr private protected public return
+			short signed sizeof static struct switch template this throw true try typedef union unsigned using
+			virtual void volatile while abstract extends final implements import package super synchronized`),
+		lineComment: "//",
+		quotes:      "\"'",
+	}
+	langShell = &syntaxLang{
+		keywords:    words(`case do done elif else esac exit export fi for function if in local return then until while`),
+		lineComment: "#",
+		quotes:      "\"'",
+	}
+	langYAML = &syntaxLang{
+		keywords:    words(`true false null yes no`),
+		lineComment: "#",
+		quotes:      "\"'",
+	}
+)
+
+// syntaxLangs maps file extensions to languages.
+var syntaxLangs = map[string]*syntaxLang{
+	".go":   langGo,
+	".py":   langPython,
+	".js":   langJS,
+	".jsx":  langJS,
+	".ts":   langJS,
+	".tsx":  langJS,
+	".mjs":  langJS,
+	".rs":   langRust,
+	".c":    langC,
+	".h":    langC,
+	".cc":   langC,
+	".cpp":  langC,
+	".hpp":  langC,
+	".java": langC,
+	".kt":   langC,
+	".cs":   langC,
+	".sh":   langShell,
+	".bash": langShell,
+	".yml":  langYAML,
+	".yaml": langYAML,
+	".toml": langYAML,
+}
+
+// syntaxFor returns the language of a file, or nil when it is not known.
+func syntaxFor(path string) *syntaxLang {
+	return syntaxLangs[strings.ToLower(filepath.Ext(path))]
+}
+
+// highlightCode colors one line of source. Lines are colored independently,
+// so block comments and strings spanning several lines are only partly
+// colored; that is good enough for reading a diff.
+func highlightCode(line string, lang *syntaxLang) string {
+	if lang == nil || line == "" {
+		return line
+	}
+
+	var out strings.Builder
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case strings.HasPrefix(string(runes[i:]), lang.lineComment):
+			out.WriteString(SyntaxCommentStyle.Render(string(runes[i:])))
+			return out.String()
+
+		case strings.ContainsRune(lang.quotes, r):
+			j := i + 1
+			for j < len(runes) && runes[j] != r {
+				if runes[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(runes) {
+				j = len(runes) - 1
+			}
+			out.WriteString(SyntaxStringStyle.Render(string(runes[i : j+1])))
+			i = j + 1
+
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || unicode.IsLetter(runes[j]) || runes[j] == '.' || runes[j] == '_') {
+				j++
+			}
+			out.WriteString(SyntaxNumberStyle.Render(string(runes[i:j])))
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			word := string(runes[i:j])
+			if lang.keywords[word] {
+				word = SyntaxKeywordStyle.Render(word)
+			}
+			out.WriteString(word)
+			i = j
+
+		default:
+			out.WriteRune(r)
+			i++
+		}
+	}
+	return out.String()
+}
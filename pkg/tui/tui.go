@@ -12,7 +12,11 @@ import (
 	"time"
 
 	"ClosedWheeler/pkg/agent"
+	"ClosedWheeler/pkg/editor"
+	"ClosedWheeler/pkg/llm"
+	"ClosedWheeler/pkg/permissions"
 	"ClosedWheeler/pkg/providers"
+	"ClosedWheeler/pkg/secrets"
 	"ClosedWheeler/pkg/tools"
 	"ClosedWheeler/pkg/utils"
 
@@ -283,6 +287,16 @@ type EnhancedModel struct {
 	panelScroll    int
 	panelMaxScroll int
 
+	// Diff review overlay state (edits awaiting the user's decision)
+	reviewActive     bool
+	review           *diffReview
+	reviewQueue      []*diffReview // batches waiting behind the one on screen
+	reviewSideBySide bool
+
+	// Tool approval dialog state (sensitive calls waiting for the user)
+	approval      *ApprovalDialog
+	approvalQueue []*ApprovalDialog // requests waiting behind the one on screen
+
 	// Settings overlay state (interactive toggle menu)
 	settingsActive     bool
 	settingsCursor     int
@@ -399,16 +413,27 @@ func NewEnhancedModel(ag *agent.Agent) *EnhancedModel {
 		Complete:  true,
 	})
 
+	// Show the transcript when started with --resume
+	loadSessionTranscript(mq, ag)
+
 	// Initialize provider manager
 	providerConfig, _ := providers.LoadProvidersConfig("")
 	var pm *providers.ProviderManager
 	if providerConfig != nil {
+		for _, p := range providerConfig.Providers {
+			secrets.Add("provider-key", p.APIKey)
+		}
 		pm, _ = providers.InitializeFromConfig(providerConfig)
 	}
 	if pm == nil {
 		pm = providers.NewProviderManager()
 	}
 
+	// Route agent requests across the configured providers when enabled
+	if providerConfig != nil && providerConfig.Routing != "" {
+		ag.SetProviderRouter(llm.NewProviderRouter(pm, providerConfig.Routing, providerConfig.FallbackEnabled))
+	}
+
 	// Initialize intelligent retry wrapper
 	// Note: The wrapper will be used by TUI commands to show stats
 	// The agent will use the original executor
@@ -478,6 +503,22 @@ func (m *EnhancedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Intercept keys when the approval dialog is active; the agent is
+		// waiting on it
+		if m.approval != nil {
+			newM, cmd := m.approvalUpdate(msg)
+			*m = newM
+			return m, cmd
+		}
+
+		// Intercept keys when the diff review overlay is active; a tool may
+		// be waiting on it
+		if m.reviewActive {
+			newM, cmd := m.reviewUpdate(msg)
+			*m = newM
+			return m, cmd
+		}
+
 		// Intercept keys when picker is active
 		if m.pickerActive {
 			newM, cmd := m.enhancedPickerUpdate(msg)
@@ -647,6 +688,18 @@ func (m *EnhancedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case toolApprovalMsg:
+		m.startApproval(msg.req, msg.reply)
+		return m, nil
+
+	case editReviewMsg:
+		reply := msg.reply
+		m.startReview(msg.edits, func(reviews []editor.Review) string {
+			reply <- reviews
+			return ""
+		})
+		return m, nil
+
 	case toolStartMsg:
 		wasEmpty := len(m.activeTools) == 0
 		tool := ToolExecution{
@@ -703,6 +756,10 @@ func (m *EnhancedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.status = msg.status
 		return m, nil
 
+	case ollamaPullDoneMsg:
+		m.handleOllamaPullDone(msg)
+		return m, nil
+
 	case pipelineStatusMsg:
 		if m.pipelineStatus == nil {
 			m.pipelineStatus = make(map[agent.AgentRole]string)
@@ -826,6 +883,16 @@ func (m *EnhancedModel) View() string {
 		return m.enhancedPickerView()
 	}
 
+	// Render tool approval dialog if active (replaces main view)
+	if m.approval != nil {
+		return m.approvalView()
+	}
+
+	// Render diff review overlay if active (replaces main view)
+	if m.reviewActive {
+		return m.reviewView()
+	}
+
 	// Render help menu overlay if active (replaces main view)
 	if m.helpActive {
 		return m.helpMenuView()
@@ -1861,9 +1928,39 @@ func RunEnhanced(ag *agent.Agent, ctx ...context.Context) error {
 		p.Send(pipelineStatusMsg{role: role, status: status})
 	})
 
+	// Set edit reviewer — tools block until the user has reviewed their
+	// changes in the diff overlay; changes still open on exit stay pending
+	exited := make(chan struct{})
+	ag.SetEditReviewer(func(edits []editor.EditRecord) []editor.Review {
+		reply := make(chan []editor.Review, 1)
+		p.Send(editReviewMsg{edits: edits, reply: reply})
+		select {
+		case reviews := <-reply:
+			return reviews
+		case <-exited:
+			return nil
+		}
+	})
+
+	// Set tool approver — sensitive tool calls wait for the user's answer
+	// in the approval dialog; calls still waiting on exit are denied
+	ag.SetToolApprover(func(req agent.ToolApproval) permissions.Approval {
+		reply := make(chan permissions.Approval, 1)
+		p.Send(toolApprovalMsg{req: req, reply: reply})
+		select {
+		case answer := <-reply:
+			return answer
+		case <-exited:
+			return permissions.Reject
+		}
+	})
+
 	_, err := p.Run()
+	close(exited)
 
 	// Clear callbacks to prevent sends after program exits
+	ag.SetToolApprover(nil)
+	ag.SetEditReviewer(nil)
 	ag.SetStatusCallback(nil)
 	ag.SetStreamCallback(nil)
 	ag.SetPipelineStatusCallback(nil)
@@ -96,6 +96,12 @@ func ExecuteWithRetryContext(ctx context.Context, operation func() error, config
 	return nil
 }
 
+// Permanent wraps err so ExecuteWithRetry returns it at once instead of
+// retrying.
+func Permanent(err error) error {
+	return backoff.Permanent(err)
+}
+
 // IsRetryableError determines if an HTTP status code is retryable (429 or 5xx)
 func IsRetryableError(statusCode int) bool {
 	return statusCode == http.StatusTooManyRequests || (statusCode >= 500 && statusCode <= 599)